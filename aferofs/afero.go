@@ -0,0 +1,413 @@
+package aferofs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qfs"
+	"github.com/spf13/afero"
+)
+
+// NewAferoFs exposes fs as an afero.Fs. Files are read with Get & directories
+// are read with qfs.List. Files opened for writing are buffered in memory &
+// written with Put when closed, which requires a filesystem that stores files
+// at the path they're given: writes to content-addressed filesystems return
+// qfs.ErrReadOnly. Rename, Chmod & Chtimes return qfs.ErrUnsupported
+func NewAferoFs(fs qfs.Filesystem) afero.Fs {
+	return &aferoFs{fs: fs}
+}
+
+type aferoFs struct {
+	fs qfs.Filesystem
+}
+
+var _ afero.Fs = (*aferoFs)(nil)
+
+func (a *aferoFs) Name() string {
+	return fmt.Sprintf("qfs/%s", a.fs.Type())
+}
+
+func (a *aferoFs) Open(name string) (afero.File, error) {
+	return a.OpenFile(name, os.O_RDONLY, 0)
+}
+
+func (a *aferoFs) Create(name string) (afero.File, error) {
+	return a.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+}
+
+func (a *aferoFs) OpenFile(name string, flag int, perm os.FileMode) (afero.File, error) {
+	ctx := context.Background()
+	if flag&(os.O_WRONLY|os.O_RDWR) != 0 {
+		return a.openWriter(ctx, name, flag, perm)
+	}
+
+	f, err := a.fs.Get(ctx, name)
+	if err != nil {
+		return nil, pathError("open", name, err)
+	}
+	info, err := qfs.Stat(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if f.IsDirectory() {
+		return &qfsDir{name: name, info: info, dir: qfs.NewDirReader(context.Background(), a.fs, name, info.ModTime())}, nil
+	}
+	if sf, ok := f.(qfs.SeekFile); ok && info.Size() >= 0 {
+		return &qfsFile{name: name, info: info, r: sf, c: f}, nil
+	}
+
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return &qfsFile{name: name, info: info, r: bytes.NewReader(data)}, nil
+}
+
+// openWriter opens a buffered file that's written to the filesystem on Close
+func (a *aferoFs) openWriter(ctx context.Context, name string, flag int, perm os.FileMode) (afero.File, error) {
+	if _, ok := a.fs.(qfs.CAFS); ok {
+		return nil, pathError("open", name, qfs.ErrReadOnly)
+	}
+	w := &qfsWriter{fs: a.fs, name: name, perm: perm}
+	if flag&os.O_TRUNC == 0 {
+		f, err := a.fs.Get(ctx, name)
+		if err == nil {
+			defer f.Close()
+			if f.IsDirectory() {
+				return nil, pathError("open", name, qfs.ErrIsDirectory)
+			}
+			data, err := ioutil.ReadAll(f)
+			if err != nil {
+				return nil, err
+			}
+			w.buf = data
+		} else if !errors.Is(err, qfs.ErrNotFound) {
+			return nil, err
+		} else if flag&os.O_CREATE == 0 {
+			return nil, pathError("open", name, err)
+		}
+	}
+	if flag&os.O_APPEND != 0 {
+		w.off = int64(len(w.buf))
+	}
+	return w, nil
+}
+
+func (a *aferoFs) Stat(name string) (os.FileInfo, error) {
+	f, err := a.fs.Get(context.Background(), name)
+	if err != nil {
+		return nil, pathError("stat", name, err)
+	}
+	defer f.Close()
+	return qfs.Stat(f)
+}
+
+// Mkdir is a no-op, qfs filesystems create directories as files are written
+func (a *aferoFs) Mkdir(name string, perm os.FileMode) error {
+	if _, ok := a.fs.(qfs.CAFS); ok {
+		return pathError("mkdir", name, qfs.ErrReadOnly)
+	}
+	return nil
+}
+
+// MkdirAll is a no-op, qfs filesystems create directories as files are
+// written
+func (a *aferoFs) MkdirAll(path string, perm os.FileMode) error {
+	return a.Mkdir(path, perm)
+}
+
+func (a *aferoFs) Remove(name string) error {
+	if err := a.fs.Delete(context.Background(), name); err != nil {
+		return pathError("remove", name, err)
+	}
+	return nil
+}
+
+func (a *aferoFs) RemoveAll(path string) error {
+	err := a.fs.Delete(context.Background(), path)
+	if err != nil && !errors.Is(err, qfs.ErrNotFound) {
+		return pathError("remove", path, err)
+	}
+	return nil
+}
+
+func (a *aferoFs) Rename(oldname, newname string) error {
+	return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: qfs.ErrUnsupported}
+}
+
+func (a *aferoFs) Chmod(name string, mode os.FileMode) error {
+	return pathError("chmod", name, qfs.ErrUnsupported)
+}
+
+func (a *aferoFs) Chtimes(name string, atime, mtime time.Time) error {
+	return pathError("chtimes", name, qfs.ErrUnsupported)
+}
+
+// pathError wraps err in an *os.PathError, translating qfs.ErrNotFound to
+// os.ErrNotExist so os.IsNotExist works as afero callers expect
+func pathError(op, name string, err error) error {
+	if errors.Is(err, qfs.ErrNotFound) {
+		err = os.ErrNotExist
+	}
+	return &os.PathError{Op: op, Path: name, Err: err}
+}
+
+// qfsFile is a read-only afero.File. ReadAt seeks the underlying reader, so
+// reads are serialized with a lock
+type qfsFile struct {
+	name string
+	info os.FileInfo
+
+	lk sync.Mutex
+	r  io.ReadSeeker
+	c  io.Closer
+}
+
+var _ afero.File = (*qfsFile)(nil)
+
+func (f *qfsFile) Name() string               { return f.name }
+func (f *qfsFile) Stat() (os.FileInfo, error) { return f.info, nil }
+func (f *qfsFile) Sync() error                { return nil }
+
+func (f *qfsFile) Close() error {
+	if f.c != nil {
+		return f.c.Close()
+	}
+	return nil
+}
+
+func (f *qfsFile) Read(p []byte) (int, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.r.Read(p)
+}
+
+func (f *qfsFile) ReadAt(p []byte, off int64) (int, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	cur, err := f.r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	defer f.r.Seek(cur, io.SeekStart)
+	if _, err := f.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return io.ReadFull(f.r, p)
+}
+
+func (f *qfsFile) Seek(offset int64, whence int) (int64, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.r.Seek(offset, whence)
+}
+
+func (f *qfsFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, pathError("readdir", f.name, qfs.ErrNotDirectory)
+}
+
+func (f *qfsFile) Readdirnames(n int) ([]string, error) {
+	return nil, pathError("readdir", f.name, qfs.ErrNotDirectory)
+}
+
+func (f *qfsFile) Write(p []byte) (int, error) { return 0, pathError("write", f.name, qfs.ErrReadOnly) }
+func (f *qfsFile) WriteAt(p []byte, off int64) (int, error) {
+	return 0, pathError("write", f.name, qfs.ErrReadOnly)
+}
+func (f *qfsFile) WriteString(s string) (int, error) { return f.Write([]byte(s)) }
+func (f *qfsFile) Truncate(size int64) error {
+	return pathError("truncate", f.name, qfs.ErrReadOnly)
+}
+
+// qfsDir is a directory afero.File, entries are listed on the first call to
+// Readdir or Readdirnames
+type qfsDir struct {
+	name string
+	info os.FileInfo
+	dir  *qfs.DirReader
+}
+
+var _ afero.File = (*qfsDir)(nil)
+
+func (d *qfsDir) Name() string               { return d.name }
+func (d *qfsDir) Stat() (os.FileInfo, error) { return d.info, nil }
+func (d *qfsDir) Sync() error                { return nil }
+func (d *qfsDir) Close() error               { return nil }
+
+func (d *qfsDir) Read(p []byte) (int, error) {
+	return 0, pathError("read", d.name, qfs.ErrNotFile)
+}
+func (d *qfsDir) ReadAt(p []byte, off int64) (int, error) {
+	return 0, pathError("read", d.name, qfs.ErrNotFile)
+}
+func (d *qfsDir) Seek(offset int64, whence int) (int64, error) {
+	return 0, pathError("seek", d.name, qfs.ErrNotFile)
+}
+func (d *qfsDir) Write(p []byte) (int, error) {
+	return 0, pathError("write", d.name, qfs.ErrIsDirectory)
+}
+func (d *qfsDir) WriteAt(p []byte, off int64) (int, error) {
+	return 0, pathError("write", d.name, qfs.ErrIsDirectory)
+}
+func (d *qfsDir) WriteString(s string) (int, error) { return d.Write([]byte(s)) }
+func (d *qfsDir) Truncate(size int64) error {
+	return pathError("truncate", d.name, qfs.ErrIsDirectory)
+}
+
+// Readdir follows the semantics of os.File.Readdir
+func (d *qfsDir) Readdir(count int) ([]os.FileInfo, error) {
+	infos, err := d.dir.Readdir(count)
+	if err != nil && err != io.EOF {
+		return nil, pathError("readdir", d.name, err)
+	}
+	return infos, err
+}
+
+func (d *qfsDir) Readdirnames(n int) ([]string, error) {
+	infos, err := d.Readdir(n)
+	names := make([]string, len(infos))
+	for i, fi := range infos {
+		names[i] = fi.Name()
+	}
+	return names, err
+}
+
+// qfsWriter buffers writes in memory, putting the file when closed
+type qfsWriter struct {
+	fs   qfs.Filesystem
+	name string
+	perm os.FileMode
+
+	lk     sync.Mutex
+	buf    []byte
+	off    int64
+	closed bool
+}
+
+var _ afero.File = (*qfsWriter)(nil)
+
+func (w *qfsWriter) Name() string { return w.name }
+func (w *qfsWriter) Sync() error  { return nil }
+
+func (w *qfsWriter) Stat() (os.FileInfo, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	mf := qfs.NewMemfileBytes(w.name, w.buf)
+	mf.SetMode(w.perm)
+	return mf.Stat()
+}
+
+func (w *qfsWriter) Read(p []byte) (int, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	if w.off >= int64(len(w.buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, w.buf[w.off:])
+	w.off += int64(n)
+	return n, nil
+}
+
+func (w *qfsWriter) ReadAt(p []byte, off int64) (int, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	if off >= int64(len(w.buf)) {
+		return 0, io.EOF
+	}
+	n := copy(p, w.buf[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (w *qfsWriter) Seek(offset int64, whence int) (int64, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	switch whence {
+	case io.SeekCurrent:
+		offset += w.off
+	case io.SeekEnd:
+		offset += int64(len(w.buf))
+	}
+	if offset < 0 {
+		return 0, pathError("seek", w.name, fs.ErrInvalid)
+	}
+	w.off = offset
+	return offset, nil
+}
+
+func (w *qfsWriter) Write(p []byte) (int, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	n := w.writeAt(p, w.off)
+	w.off += int64(n)
+	return n, nil
+}
+
+func (w *qfsWriter) WriteAt(p []byte, off int64) (int, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	return w.writeAt(p, off), nil
+}
+
+func (w *qfsWriter) WriteString(s string) (int, error) { return w.Write([]byte(s)) }
+
+// writeAt copies p into the buffer at off, growing the buffer as needed. the
+// caller must hold the lock
+func (w *qfsWriter) writeAt(p []byte, off int64) int {
+	if end := off + int64(len(p)); end > int64(len(w.buf)) {
+		w.buf = append(w.buf, make([]byte, end-int64(len(w.buf)))...)
+	}
+	return copy(w.buf[off:], p)
+}
+
+func (w *qfsWriter) Truncate(size int64) error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	if size < 0 {
+		return pathError("truncate", w.name, fs.ErrInvalid)
+	}
+	if size <= int64(len(w.buf)) {
+		w.buf = w.buf[:size]
+	} else {
+		w.buf = append(w.buf, make([]byte, size-int64(len(w.buf)))...)
+	}
+	return nil
+}
+
+func (w *qfsWriter) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, pathError("readdir", w.name, qfs.ErrNotDirectory)
+}
+
+func (w *qfsWriter) Readdirnames(n int) ([]string, error) {
+	return nil, pathError("readdir", w.name, qfs.ErrNotDirectory)
+}
+
+// Close writes the buffered contents to the filesystem with Put
+func (w *qfsWriter) Close() error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	if w.closed {
+		return pathError("close", w.name, os.ErrClosed)
+	}
+	w.closed = true
+	mf := qfs.NewMemfileBytes(w.name, w.buf)
+	if w.perm != 0 {
+		mf.SetMode(w.perm)
+	}
+	if _, err := w.fs.Put(context.Background(), mf); err != nil {
+		return pathError("close", w.name, err)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+// Package aferofs bridges qfs and afero, wrapping an afero.Fs as a
+// qfs.Filesystem and exposing any qfs.Filesystem as an afero.Fs
+package aferofs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/qri-io/qfs"
+	"github.com/spf13/afero"
+)
+
+// FilestoreType uniquely identifies this filesystem
+const FilestoreType = "afero"
+
+// FS is a qfs.Filesystem backed by an afero.Fs. Paths are passed to afero
+// unchanged
+type FS struct {
+	afs afero.Fs
+}
+
+var (
+	_ qfs.Filesystem = (*FS)(nil)
+	_ qfs.ListingFS  = (*FS)(nil)
+	_ qfs.StatPathFS = (*FS)(nil)
+)
+
+// NewFS wraps afs as a qfs.Filesystem
+func NewFS(afs afero.Fs) *FS {
+	return &FS{afs: afs}
+}
+
+// Type distinguishes this filesystem from others by a unique string prefix
+func (afs *FS) Type() string {
+	return FilestoreType
+}
+
+// Has returns whether a file or directory exists at path
+func (afs *FS) Has(ctx context.Context, path string) (bool, error) {
+	_, err := afs.afs.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// StatPath describes path with the afero filesystem's Stat method
+func (afs *FS) StatPath(ctx context.Context, path string) (qfs.PathInfo, error) {
+	info := qfs.PathInfo{Size: -1}
+	fi, err := afs.afs.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return info, nil
+		}
+		return info, err
+	}
+	info.Exists = true
+	info.IsDir = fi.IsDir()
+	if !info.IsDir {
+		info.Size = fi.Size()
+	}
+	return info, nil
+}
+
+// Get opens the file or directory at path. Directory children are opened as
+// they're read with NextFile
+func (afs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
+	info, err := afs.afs.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, qfs.ErrNotFound
+		}
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dirFile{afs: afs, path: path, info: info}, nil
+	}
+
+	f, err := afs.afs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &file{File: f, path: path, info: info}, nil
+}
+
+// List returns the entries of the directory at path sorted by name
+func (afs *FS) List(ctx context.Context, path string) ([]qfs.DirEntry, error) {
+	info, err := afs.afs.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, qfs.ErrNotFound
+		}
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, qfs.ErrNotDirectory
+	}
+
+	infos, err := afero.ReadDir(afs.afs, path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]qfs.DirEntry, 0, len(infos))
+	for _, fi := range infos {
+		entry := qfs.DirEntry{
+			Name:  fi.Name(),
+			Path:  filepath.Join(path, fi.Name()),
+			Size:  -1,
+			IsDir: fi.IsDir(),
+		}
+		if !entry.IsDir {
+			entry.Size = fi.Size()
+		}
+		entries = append(entries, entry)
+	}
+	qfs.SortDirEntries(entries)
+	return entries, nil
+}
+
+// Put writes file to its FullPath, creating parent directories as needed.
+// Directories are written recursively. File modes are kept for files that
+// implement qfs.MetadataFile
+func (afs *FS) Put(ctx context.Context, file qfs.File) (string, error) {
+	path := file.FullPath()
+	if file.IsDirectory() {
+		if err := afs.afs.MkdirAll(path, 0755); err != nil {
+			return "", err
+		}
+		for {
+			child, err := file.NextFile()
+			if errors.Is(err, io.EOF) {
+				return path, nil
+			} else if err != nil {
+				return "", err
+			}
+			if _, err := afs.Put(ctx, child); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	if err := afs.afs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	perm := fs.FileMode(0644)
+	if mf, ok := file.(qfs.MetadataFile); ok && mf.Mode().Perm() != 0 {
+		perm = mf.Mode().Perm()
+	}
+	f, err := afs.afs.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(f, file); err != nil {
+		f.Close()
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// Delete removes the file or directory at path, including any children
+func (afs *FS) Delete(ctx context.Context, path string) error {
+	if _, err := afs.afs.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return qfs.ErrNotFound
+		}
+		return err
+	}
+	return afs.afs.RemoveAll(path)
+}
+
+// file adapts an afero.File to qfs.File. Read, Seek, Stat & Close are
+// provided by the embedded afero.File
+type file struct {
+	afero.File
+	path string
+	info os.FileInfo
+}
+
+var (
+	_ qfs.File     = (*file)(nil)
+	_ qfs.SizeFile = (*file)(nil)
+	_ qfs.StatFile = (*file)(nil)
+	_ qfs.SeekFile = (*file)(nil)
+)
+
+func (f *file) IsDirectory() bool           { return false }
+func (f *file) NextFile() (qfs.File, error) { return nil, qfs.ErrNotDirectory }
+func (f *file) FileName() string            { return filepath.Base(f.path) }
+func (f *file) FullPath() string            { return f.path }
+func (f *file) MediaType() string           { return mime.TypeByExtension(filepath.Ext(f.path)) }
+func (f *file) ModTime() time.Time          { return f.info.ModTime() }
+func (f *file) Size() int64                 { return f.info.Size() }
+func (f *file) Stat() (fs.FileInfo, error)  { return f.info, nil }
+
+// dirFile is an afero directory, children are listed on the first call to
+// NextFile & opened one at a time
+type dirFile struct {
+	afs   *FS
+	path  string
+	info  os.FileInfo
+	names []string
+	read  bool
+}
+
+var (
+	_ qfs.File     = (*dirFile)(nil)
+	_ qfs.StatFile = (*dirFile)(nil)
+)
+
+func (d *dirFile) Read(p []byte) (int, error) { return 0, qfs.ErrNotFile }
+func (d *dirFile) Close() error               { return nil }
+func (d *dirFile) IsDirectory() bool          { return true }
+func (d *dirFile) FileName() string           { return filepath.Base(d.path) }
+func (d *dirFile) FullPath() string           { return d.path }
+func (d *dirFile) MediaType() string          { return "application/x-directory" }
+func (d *dirFile) ModTime() time.Time         { return d.info.ModTime() }
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+// NextFile opens the next child of the directory in name order
+func (d *dirFile) NextFile() (qfs.File, error) {
+	if !d.read {
+		f, err := d.afs.afs.Open(d.path)
+		if err != nil {
+			return nil, err
+		}
+		names, err := f.Readdirnames(-1)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		sort.Strings(names)
+		d.names = names
+		d.read = true
+	}
+	if len(d.names) == 0 {
+		return nil, io.EOF
+	}
+	name := d.names[0]
+	d.names = d.names[1:]
+	return d.afs.Get(context.Background(), filepath.Join(d.path, name))
+}
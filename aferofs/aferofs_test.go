@@ -0,0 +1,163 @@
+package aferofs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/qri-io/qfs"
+	"github.com/spf13/afero"
+)
+
+func TestFS(t *testing.T) {
+	ctx := context.Background()
+	mfs := afero.NewMemMapFs()
+	fs := NewFS(mfs)
+
+	dir := qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("/a/b.txt", []byte("b")),
+		qfs.NewMemdir("/a/c",
+			qfs.NewMemfileBytes("/a/c/d.txt", []byte("d")),
+		),
+	)
+	if _, err := fs.Put(ctx, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	if has, err := fs.Has(ctx, "/a/c/d.txt"); err != nil || !has {
+		t.Errorf("expected /a/c/d.txt to exist. has: %t err: %v", has, err)
+	}
+	data, err := afero.ReadFile(mfs, "/a/c/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "d" {
+		t.Errorf("contents mismatch. want: %q got: %q", "d", string(data))
+	}
+
+	entries, err := fs.List(ctx, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "b.txt" || !entries[1].IsDir {
+		t.Errorf("unexpected listing: %#v", entries)
+	}
+
+	names := []string{}
+	err = qfs.Walk(mustGet(t, fs, "/a"), func(f qfs.File) error {
+		names = append(names, f.FullPath())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 4 {
+		t.Errorf("expected walk to visit 4 files, got: %v", names)
+	}
+
+	if err := fs.Delete(ctx, "/a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Get(ctx, "/a/b.txt"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got: %v", err)
+	}
+}
+
+func TestAferoFs(t *testing.T) {
+	afs := NewAferoFs(NewFS(afero.NewMemMapFs()))
+
+	if err := afero.WriteFile(afs, "/a/b.txt", []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	data, err := afero.ReadFile(afs, "/a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("contents mismatch. want: %q got: %q", "hello", string(data))
+	}
+
+	f, err := afs.OpenFile("/a/b.txt", os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(" world"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err = afs.Open("/a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 5)
+	if _, err := f.ReadAt(p, 6); err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != "world" {
+		t.Errorf("ReadAt mismatch. want: %q got: %q", "world", string(p))
+	}
+	all, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(all) != "hello world" {
+		t.Errorf("ReadAt moved the read offset. got: %q", string(all))
+	}
+	f.Close()
+
+	infos, err := afero.ReadDir(afs, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 || infos[0].Name() != "b.txt" || infos[0].Size() != 11 {
+		t.Errorf("unexpected directory listing: %v", infos)
+	}
+
+	if _, err := afs.Stat("/missing"); !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got: %v", err)
+	}
+	if err := afs.Rename("/a/b.txt", "/a/c.txt"); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported renaming, got: %v", err)
+	}
+	if err := afs.RemoveAll("/a"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := afero.Exists(afs, "/a/b.txt"); exists {
+		t.Error("expected /a/b.txt to be removed")
+	}
+}
+
+func TestAferoFsCAFSReadOnly(t *testing.T) {
+	ctx := context.Background()
+	mem := qfs.NewMemFS()
+	path, err := mem.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	afs := NewAferoFs(mem)
+	data, err := afero.ReadFile(afs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a" {
+		t.Errorf("contents mismatch. want: %q got: %q", "a", string(data))
+	}
+	if _, err := afs.Create("/b.txt"); !errors.Is(err, qfs.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly creating a file on a CAFS, got: %v", err)
+	}
+}
+
+func mustGet(t *testing.T, fs qfs.Filesystem, path string) qfs.File {
+	t.Helper()
+	f, err := fs.Get(context.Background(), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
@@ -0,0 +1,28 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// AppendFS is an opt-in interface for mutable filesystems that can add to the
+// end of an existing file without rewriting it, letting log-like files (run
+// outputs, event logs) grow as they're written
+type AppendFS interface {
+	Filesystem
+	// Append writes the contents of r to the end of the file at path, creating
+	// the file if it doesn't exist
+	Append(ctx context.Context, path string, r io.Reader) error
+}
+
+// Append writes the contents of r to the end of the file at path on fs.
+// Filesystems that don't implement AppendFS, including every
+// content-addressed filesystem, return an error that wraps ErrUnsupported
+func Append(ctx context.Context, fs Filesystem, path string, r io.Reader) error {
+	afs, ok := fs.(AppendFS)
+	if !ok {
+		return fmt.Errorf("%w: %s filesystem can't append to files", ErrUnsupported, fs.Type())
+	}
+	return afs.Append(ctx, path, r)
+}
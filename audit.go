@@ -0,0 +1,119 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	cid "github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// BlockLister is an opt-in interface for MerkleDagStores that can enumerate
+// every block they hold
+type BlockLister interface {
+	AllBlocks(ctx context.Context) ([]cid.Cid, error)
+}
+
+// AuditReport is the result of checking the integrity of a MerkleDagStore
+type AuditReport struct {
+	// Checked is the number of reachable blocks that were re-hashed
+	Checked int
+	// Corrupt lists blocks whose data doesn't match their CID
+	Corrupt []cid.Cid
+	// Missing lists blocks that are linked to, but not in the store
+	Missing []cid.Cid
+	// Orphaned lists blocks in the store that aren't reachable from any root.
+	// Orphaned is only populated if the store implements BlockLister
+	Orphaned []cid.Cid
+}
+
+// OK returns true if the audit found no corrupt or missing blocks. Orphaned
+// blocks don't affect the integrity of reachable data
+func (r AuditReport) OK() bool {
+	return len(r.Corrupt) == 0 && len(r.Missing) == 0
+}
+
+// Audit checks every block reachable from roots, re-hashing block data to
+// confirm it matches its CID. Links of corrupt blocks are not followed. If
+// store implements BlockLister, blocks that can't be reached from any root are
+// reported as orphaned
+func Audit(ctx context.Context, store MerkleDagStore, roots []cid.Cid) (AuditReport, error) {
+	report := AuditReport{}
+	if m, ok := store.(*MemFS); ok && m.getHash().UnixFS {
+		return report, fmt.Errorf("%w: auditing a MemFS that hashes with UnixFS", ErrUnsupported)
+	}
+	visited := map[cid.Cid]struct{}{}
+	queue := make([]cid.Cid, len(roots))
+	copy(queue, roots)
+
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+
+		ok, err := auditBlock(store, id)
+		if err != nil {
+			if isNotFound(err) {
+				report.Missing = append(report.Missing, id)
+				continue
+			}
+			return report, err
+		}
+		report.Checked++
+		if !ok {
+			report.Corrupt = append(report.Corrupt, id)
+			continue
+		}
+
+		// raw blocks are leaves, they have no links to follow
+		if id.Type() == cid.Raw {
+			continue
+		}
+		node, err := store.GetNode(id)
+		if err != nil {
+			return report, err
+		}
+		for _, lnk := range node.Links().SortedSlice() {
+			queue = append(queue, lnk.Cid)
+		}
+	}
+
+	if bl, ok := store.(BlockLister); ok {
+		all, err := bl.AllBlocks(ctx)
+		if err != nil {
+			return report, err
+		}
+		for _, id := range all {
+			if _, ok := visited[id]; !ok {
+				report.Orphaned = append(report.Orphaned, id)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// auditBlock reports whether the stored data for id hashes to id
+func auditBlock(store MerkleDagStore, id cid.Cid) (bool, error) {
+	data, err := GetBlockBytes(store, id)
+	if err != nil {
+		return false, err
+	}
+	sum, err := id.Prefix().Sum(data)
+	if err != nil {
+		return false, err
+	}
+	return sum.Equals(id), nil
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound) || errors.Is(err, format.ErrNotFound)
+}
@@ -0,0 +1,69 @@
+package qfs
+
+import (
+	"context"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemFS()
+
+	a, err := store.PutBlock([]byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := store.PutBlock([]byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphan, err := store.PutBlock([]byte("orphan"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	missing, err := NewMemFS().PutBlock([]byte("missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root, err := store.PutNode(NewLinks(
+		Link{Name: "a", Cid: a},
+		Link{Name: "b", Cid: b},
+		Link{Name: "missing", Cid: missing},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Audit(ctx, store, []cid.Cid{root.Cid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked != 3 {
+		t.Errorf("checked count mismatch. want: 3 got: %d", report.Checked)
+	}
+	if len(report.Missing) != 1 || !report.Missing[0].Equals(missing) {
+		t.Errorf("expected missing block %s. got: %v", missing, report.Missing)
+	}
+	if len(report.Orphaned) != 1 || !report.Orphaned[0].Equals(orphan) {
+		t.Errorf("expected orphaned block %s. got: %v", orphan, report.Orphaned)
+	}
+	if len(report.Corrupt) != 0 {
+		t.Errorf("expected no corrupt blocks. got: %v", report.Corrupt)
+	}
+	if report.OK() {
+		t.Error("expected report with missing blocks to not be OK")
+	}
+
+	// corrupt block b
+	store.Files[b.String()] = fsFile{data: bytesData([]byte("not b"))}
+	report, err = Audit(ctx, store, []cid.Cid{root.Cid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Corrupt) != 1 || !report.Corrupt[0].Equals(b) {
+		t.Errorf("expected corrupt block %s. got: %v", b, report.Corrupt)
+	}
+}
@@ -0,0 +1,216 @@
+// Package auditfs records every mutating operation performed on a
+// filesystem to an append-only log of JSON lines
+package auditfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+// Operation names recorded in log entries
+const (
+	OpPut    = "put"
+	OpDelete = "delete"
+	OpPin    = "pin"
+	OpUnpin  = "unpin"
+)
+
+// Entry is a single line in the audit log
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Op        string    `json:"op"`
+	Actor     string    `json:"actor,omitempty"`
+	RequestID string    `json:"requestID,omitempty"`
+	// Path is the path given to the operation
+	Path string `json:"path,omitempty"`
+	// Size is the number of bytes written by a Put, -1 for directories
+	Size int64 `json:"size,omitempty"`
+	// Result is the path returned by a Put, which is the CID-based path on
+	// content-addressed filesystems
+	Result string `json:"result,omitempty"`
+	// Error is set if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// FS wraps a filesystem, appending an Entry for each Put, Delete, Pin & Unpin
+// call as a line of JSON to a log file on a filesystem that implements
+// qfs.AppendFS. Reads are passed through unrecorded. Actors &
+// request IDs are read from the operation context with qfs.Actor and
+// qfs.RequestID. Failed operations are recorded with their error
+type FS struct {
+	qfs.Filesystem
+	clock qfs.Clock
+
+	lk      sync.Mutex
+	log     qfs.Filesystem
+	logPath string
+}
+
+var (
+	_ qfs.Filesystem = (*FS)(nil)
+	_ qfs.PinningFS  = (*FS)(nil)
+)
+
+// Option is a function type for passing to New
+type Option func(afs *FS)
+
+// OptionClock sets the clock entries are timestamped with, defaults to
+// qfs.SystemClock. A nil clock keeps the default
+func OptionClock(c qfs.Clock) Option {
+	return func(afs *FS) {
+		if c != nil {
+			afs.clock = c
+		}
+	}
+}
+
+// New creates an FS that records operations on fs to the file at logPath on
+// log. Each entry is added with a single call to Append. New returns an error
+// that wraps qfs.ErrUnsupported if log doesn't implement qfs.AppendFS
+func New(fs, log qfs.Filesystem, logPath string, opts ...Option) (*FS, error) {
+	if _, ok := log.(qfs.AppendFS); !ok {
+		return nil, fmt.Errorf("%w: %s filesystem can't append to an audit log", qfs.ErrUnsupported, log.Type())
+	}
+	afs := &FS{
+		Filesystem: fs,
+		clock:      qfs.SystemClock,
+		log:        log,
+		logPath:    logPath,
+	}
+	for _, opt := range opts {
+		opt(afs)
+	}
+	return afs, nil
+}
+
+// Put writes file to the underlying filesystem, recording the bytes written
+// and the resulting path
+func (afs *FS) Put(ctx context.Context, file qfs.File) (string, error) {
+	entry := afs.entry(ctx, OpPut, file.FullPath())
+	var cf *countingFile
+	if file.IsDirectory() {
+		entry.Size = -1
+	} else if _, ok := qfs.IsSymlink(file); !ok {
+		cf = &countingFile{File: file}
+		file = cf
+	}
+
+	res, err := afs.Filesystem.Put(ctx, file)
+	if cf != nil {
+		entry.Size = cf.n
+	}
+	entry.Result = res
+	return res, afs.record(ctx, entry, err)
+}
+
+// Delete removes path from the underlying filesystem
+func (afs *FS) Delete(ctx context.Context, path string) error {
+	err := afs.Filesystem.Delete(ctx, path)
+	return afs.record(ctx, afs.entry(ctx, OpDelete, path), err)
+}
+
+// Pin pins key if the underlying filesystem implements qfs.PinningFS
+func (afs *FS) Pin(ctx context.Context, key string, recursive bool) error {
+	pfs, ok := afs.Filesystem.(qfs.PinningFS)
+	if !ok {
+		return fmt.Errorf("%w: %s filesystem doesn't support pinning", qfs.ErrUnsupported, afs.Type())
+	}
+	err := pfs.Pin(ctx, key, recursive)
+	return afs.record(ctx, afs.entry(ctx, OpPin, key), err)
+}
+
+// Unpin unpins key if the underlying filesystem implements qfs.PinningFS
+func (afs *FS) Unpin(ctx context.Context, key string, recursive bool) error {
+	pfs, ok := afs.Filesystem.(qfs.PinningFS)
+	if !ok {
+		return fmt.Errorf("%w: %s filesystem doesn't support pinning", qfs.ErrUnsupported, afs.Type())
+	}
+	err := pfs.Unpin(ctx, key, recursive)
+	return afs.record(ctx, afs.entry(ctx, OpUnpin, key), err)
+}
+
+func (afs *FS) entry(ctx context.Context, op, path string) Entry {
+	return Entry{
+		Time:      afs.clock.Now(),
+		Op:        op,
+		Actor:     qfs.Actor(ctx),
+		RequestID: qfs.RequestID(ctx),
+		Path:      path,
+	}
+}
+
+// record writes an entry for an operation that finished with opErr, returning
+// opErr. A failure to write the log takes precedence over a successful
+// operation, callers must know when an operation goes unrecorded
+func (afs *FS) record(ctx context.Context, e Entry, opErr error) error {
+	if opErr != nil {
+		e.Error = opErr.Error()
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	afs.lk.Lock()
+	defer afs.lk.Unlock()
+	if err := qfs.Append(ctx, afs.log, afs.logPath, bytes.NewReader(data)); err != nil && opErr == nil {
+		return fmt.Errorf("writing audit log: %w", err)
+	}
+	return opErr
+}
+
+// countingFile counts the bytes read from a file. Size, Stat & metadata are
+// forwarded, so writers still see them
+type countingFile struct {
+	qfs.File
+	n int64
+}
+
+var (
+	_ qfs.SizeFile     = (*countingFile)(nil)
+	_ qfs.StatFile     = (*countingFile)(nil)
+	_ qfs.MetadataFile = (*countingFile)(nil)
+)
+
+// Size returns the size of the wrapped file, -1 if it's unknown
+func (f *countingFile) Size() int64 {
+	if sf, ok := f.File.(qfs.SizeFile); ok {
+		return sf.Size()
+	}
+	return -1
+}
+
+// Stat describes the wrapped file
+func (f *countingFile) Stat() (fs.FileInfo, error) {
+	return qfs.Stat(f.File)
+}
+
+// Mode returns the mode of the wrapped file, zero if it has none
+func (f *countingFile) Mode() fs.FileMode {
+	if mf, ok := f.File.(qfs.MetadataFile); ok {
+		return mf.Mode()
+	}
+	return 0
+}
+
+// Metadata returns the attributes of the wrapped file, if any
+func (f *countingFile) Metadata() map[string]string {
+	if mf, ok := f.File.(qfs.MetadataFile); ok {
+		return mf.Metadata()
+	}
+	return nil
+}
+
+func (f *countingFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	f.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,95 @@
+package auditfs
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/localfs"
+)
+
+func TestAuditLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "qfs_auditfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	logFS, err := localfs.NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logPath := filepath.Join(dir, "audit.log")
+
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	inner := &sizeRecordingFS{Filesystem: qfs.NewMemFS()}
+	fs, err := New(inner, logFS, logPath, OptionClock(qfs.NewManualClock(now)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := qfs.WithActor(qfs.WithRequestID(context.Background(), "req_1"), "user_1")
+	path, err := fs.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner.size != 5 {
+		t.Errorf("expected the wrapped filesystem to see a file size of 5. got: %d", inner.size)
+	}
+	if err := fs.Delete(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Pin(ctx, path, true); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected pinning a filesystem without pin support to return ErrUnsupported. got: %v", err)
+	}
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	got := []Entry{}
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		e := Entry{}
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, e)
+	}
+
+	expect := []Entry{
+		{Time: now, Op: OpPut, Actor: "user_1", RequestID: "req_1", Path: "a.txt", Size: 5, Result: path},
+		{Time: now, Op: OpDelete, Actor: "user_1", RequestID: "req_1", Path: path},
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("log entries mismatch. (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewRequiresAppend(t *testing.T) {
+	if _, err := New(qfs.NewMemFS(), qfs.NewMemFS(), "audit.log"); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected a log filesystem without append support to return ErrUnsupported. got: %v", err)
+	}
+}
+
+// sizeRecordingFS records the size of the last file put
+type sizeRecordingFS struct {
+	qfs.Filesystem
+	size int64
+}
+
+func (fs *sizeRecordingFS) Put(ctx context.Context, file qfs.File) (string, error) {
+	fs.size = -1
+	if sf, ok := file.(qfs.SizeFile); ok {
+		fs.size = sf.Size()
+	}
+	return fs.Filesystem.Put(ctx, file)
+}
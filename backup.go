@@ -0,0 +1,389 @@
+package qfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	car "github.com/ipld/go-car"
+	carutil "github.com/ipld/go-car/util"
+)
+
+// DefaultShardSize is the size in bytes Backup fills CAR shards up to when
+// called with a shard size of zero
+const DefaultShardSize = 64 << 20 // 64MiB
+
+// BackupManifest indexes the shards of a backup
+type BackupManifest struct {
+	// Roots are the CIDs the backup was taken of
+	Roots []string `json:"roots"`
+	// Shards lists the CAR files holding the backup's blocks
+	Shards []BackupShard `json:"shards"`
+	// Nodes lists the links of every block with links. Stores that don't
+	// implement RawBlockPutter don't agree on a block encoding, so restores to
+	// them rebuild nodes from their links with PutNode
+	Nodes []BackupNode `json:"nodes,omitempty"`
+}
+
+// BackupShard describes one CAR file of a backup
+type BackupShard struct {
+	// Path is the path the shard was stored at on the backup filesystem
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Blocks int    `json:"blocks"`
+}
+
+// BackupNode records the links of a node block
+type BackupNode struct {
+	Cid   string       `json:"cid"`
+	Links []BackupLink `json:"links"`
+}
+
+// BackupLink is a named link from a node block
+type BackupLink struct {
+	Name string `json:"name"`
+	Cid  string `json:"cid"`
+	Size int64  `json:"size,omitempty"`
+}
+
+// RawBlockPutter is an opt-in interface for MerkleDagStores that can store a
+// block verbatim under its CID, whatever codec the CID names
+type RawBlockPutter interface {
+	PutRawBlock(ctx context.Context, id cid.Cid, data []byte) error
+}
+
+// Backup copies every block reachable from roots in src to dst as a set of
+// CARv1 shards of up to shardSize bytes, followed by a manifest indexing them,
+// returning the path of the manifest. Blocks reachable from more than one root
+// are stored once. Shards are streamed to dst, reading each block from src
+// again as it's written, so no more than a block is held in memory. Shards are
+// named by the hash of their header & block CIDs, which determine their
+// contents, and the manifest by the hash of its contents, so repeated backups
+// of the same content to a path-based filesystem skip shards dst already has,
+// and content-addressed filesystems deduplicate them on put
+func Backup(ctx context.Context, src MerkleDagStore, dst Filesystem, roots []cid.Cid, shardSize int64) (string, error) {
+	if len(roots) == 0 {
+		return "", fmt.Errorf("backup requires at least one root")
+	}
+	if shardSize <= 0 {
+		shardSize = DefaultShardSize
+	}
+
+	b, err := newBackupWriter(ctx, src, dst, roots, shardSize)
+	if err != nil {
+		return "", err
+	}
+	manifest := BackupManifest{}
+	for _, id := range roots {
+		manifest.Roots = append(manifest.Roots, id.String())
+	}
+
+	visited := map[cid.Cid]struct{}{}
+	queue := append([]cid.Cid(nil), roots...)
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+
+		data, err := GetBlockBytes(src, id)
+		if err != nil {
+			return "", fmt.Errorf("reading block %s: %w", id, err)
+		}
+		if err := b.add(id, data); err != nil {
+			return "", err
+		}
+
+		if id.Type() == cid.Raw {
+			continue
+		}
+		node, err := src.GetNode(id)
+		if err != nil {
+			return "", fmt.Errorf("reading node %s: %w", id, err)
+		}
+		if node.Links().Len() == 0 {
+			continue
+		}
+		bn := BackupNode{Cid: id.String()}
+		for _, lnk := range node.Links().SortedSlice() {
+			bn.Links = append(bn.Links, BackupLink{Name: lnk.Name, Cid: lnk.Cid.String(), Size: lnk.Size})
+			queue = append(queue, lnk.Cid)
+		}
+		manifest.Nodes = append(manifest.Nodes, bn)
+	}
+	if err := b.flush(); err != nil {
+		return "", err
+	}
+	manifest.Shards = b.shards
+
+	// nodes are discovered parents-first, restore them children-first
+	for i, j := 0, len(manifest.Nodes)-1; i < j; i, j = i+1, j-1 {
+		manifest.Nodes[i], manifest.Nodes[j] = manifest.Nodes[j], manifest.Nodes[i]
+	}
+
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return "", err
+	}
+	return putContentAddressed(ctx, dst, ".json", data)
+}
+
+// backupWriter groups blocks into CAR shards, streaming each to dst once it's
+// full
+type backupWriter struct {
+	ctx       context.Context
+	src       MerkleDagStore
+	dst       Filesystem
+	shardSize int64
+	// header is the CAR header every shard starts with
+	header []byte
+
+	// ids & size describe the shard being filled
+	ids    []cid.Cid
+	size   int64
+	shards []BackupShard
+}
+
+func newBackupWriter(ctx context.Context, src MerkleDagStore, dst Filesystem, roots []cid.Cid, shardSize int64) (*backupWriter, error) {
+	buf := &bytes.Buffer{}
+	if err := car.WriteHeader(&car.CarHeader{Roots: roots, Version: 1}, buf); err != nil {
+		return nil, err
+	}
+	return &backupWriter{
+		ctx:       ctx,
+		src:       src,
+		dst:       dst,
+		shardSize: shardSize,
+		header:    buf.Bytes(),
+	}, nil
+}
+
+func (b *backupWriter) add(id cid.Cid, data []byte) error {
+	size := int64(carutil.LdSize(id.Bytes(), data))
+	if len(b.ids) > 0 && b.size+size > b.shardSize {
+		if err := b.flush(); err != nil {
+			return err
+		}
+	}
+	if len(b.ids) == 0 {
+		b.size = int64(len(b.header))
+	}
+	b.ids = append(b.ids, id)
+	b.size += size
+	return nil
+}
+
+func (b *backupWriter) flush() error {
+	if len(b.ids) == 0 {
+		return nil
+	}
+	ids := b.ids
+	b.ids = nil
+
+	name := append([]byte(nil), b.header...)
+	for _, id := range ids {
+		name = append(name, id.Bytes()...)
+	}
+	nameID, err := MemHash{CidVersion: 1}.sum(name, cid.Raw)
+	if err != nil {
+		return err
+	}
+
+	path, err := putNamed(b.ctx, b.dst, nameID.String()+".car", func(w io.Writer) error {
+		if _, err := w.Write(b.header); err != nil {
+			return err
+		}
+		for _, id := range ids {
+			data, err := GetBlockBytes(b.src, id)
+			if err != nil {
+				return fmt.Errorf("reading block %s: %w", id, err)
+			}
+			// the block was read once already to plan the shard, make sure
+			// it hasn't changed since
+			if err := checkBlock(id, data); err != nil {
+				return err
+			}
+			if err := carutil.LdWrite(w, id.Bytes(), data); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("writing backup shard: %w", err)
+	}
+	b.shards = append(b.shards, BackupShard{Path: path, Size: b.size, Blocks: len(ids)})
+	return nil
+}
+
+// putNamed streams the bytes write produces to fs as a file called name.
+// Path-based filesystems that already have the name aren't written to
+func putNamed(ctx context.Context, fs Filesystem, name string, write func(w io.Writer) error) (string, error) {
+	if has, err := fs.Has(ctx, name); err == nil && has {
+		return name, nil
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+	path, err := fs.Put(ctx, NewMemfileReader(name, pr))
+	// unblock the writer if Put returned without reading everything
+	pr.Close()
+	return path, err
+}
+
+// putContentAddressed puts data to fs, naming it by its hash. Path-based
+// filesystems that already have the name aren't written to
+func putContentAddressed(ctx context.Context, fs Filesystem, ext string, data []byte) (string, error) {
+	id, err := MemHash{CidVersion: 1}.sum(data, cid.Raw)
+	if err != nil {
+		return "", err
+	}
+	name := id.String() + ext
+	if has, err := fs.Has(ctx, name); err == nil && has {
+		return name, nil
+	}
+	return fs.Put(ctx, NewMemfileBytes(name, data))
+}
+
+// Restore writes the blocks of the backup whose manifest is stored at
+// manifestPath on src into dst, returning the roots of the backup. Stores that
+// implement RawBlockPutter have every block written verbatim. Other stores
+// have raw blocks put with PutBlock & nodes rebuilt with PutNode, and restores
+// check every block is stored under its original CID, which requires dst to
+// derive CIDs the same way the backed-up store did
+func Restore(ctx context.Context, src Filesystem, manifestPath string, dst MerkleDagStore) ([]cid.Cid, error) {
+	f, err := src.Get(ctx, manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest := BackupManifest{}
+	if err := DecodeJSON(f, &manifest); err != nil {
+		return nil, fmt.Errorf("decoding backup manifest: %w", err)
+	}
+
+	nodes := make(map[string]struct{}, len(manifest.Nodes))
+	for _, n := range manifest.Nodes {
+		nodes[n.Cid] = struct{}{}
+	}
+
+	for _, shard := range manifest.Shards {
+		if err := restoreShard(ctx, src, shard, nodes, dst); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := dst.(RawBlockPutter); ok {
+		// node blocks were written verbatim with the rest of the shard
+		manifest.Nodes = nil
+	}
+	for _, n := range manifest.Nodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		links := NewLinks()
+		for _, l := range n.Links {
+			id, err := cid.Decode(l.Cid)
+			if err != nil {
+				return nil, fmt.Errorf("node %s: %w", n.Cid, err)
+			}
+			links.Add(Link{Name: l.Name, Cid: id, Size: l.Size})
+		}
+		res, err := dst.PutNode(links)
+		if err != nil {
+			return nil, fmt.Errorf("restoring node %s: %w", n.Cid, err)
+		}
+		if err := checkRestored(n.Cid, res.Cid); err != nil {
+			return nil, err
+		}
+	}
+
+	roots := make([]cid.Cid, 0, len(manifest.Roots))
+	for _, r := range manifest.Roots {
+		id, err := cid.Decode(r)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, id)
+	}
+	return roots, nil
+}
+
+func restoreShard(ctx context.Context, src Filesystem, shard BackupShard, nodes map[string]struct{}, dst MerkleDagStore) error {
+	f, err := src.Get(ctx, shard.Path)
+	if err != nil {
+		return fmt.Errorf("reading backup shard %q: %w", shard.Path, err)
+	}
+	defer f.Close()
+
+	cr, err := car.NewCarReader(f)
+	if err != nil {
+		return fmt.Errorf("reading backup shard %q: %w", shard.Path, err)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		blk, err := cr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("reading backup shard %q: %w", shard.Path, err)
+		}
+
+		id := blk.Cid()
+		if err := checkBlock(id, blk.RawData()); err != nil {
+			return fmt.Errorf("backup shard %q: %w", shard.Path, err)
+		}
+		if rbp, ok := dst.(RawBlockPutter); ok {
+			if err := rbp.PutRawBlock(ctx, id, blk.RawData()); err != nil {
+				return fmt.Errorf("restoring block %s: %w", id, err)
+			}
+			continue
+		}
+		if _, ok := nodes[id.String()]; ok {
+			continue
+		}
+		res, err := dst.PutBlock(blk.RawData())
+		if err != nil {
+			return fmt.Errorf("restoring block %s: %w", id, err)
+		}
+		if err := checkRestored(id.String(), res); err != nil {
+			return err
+		}
+	}
+}
+
+// checkBlock confirms data hashes to id
+func checkBlock(id cid.Cid, data []byte) error {
+	sum, err := id.Prefix().Sum(data)
+	if err != nil {
+		return err
+	}
+	if !sum.Equals(id) {
+		return fmt.Errorf("block %s is corrupt: contents hash to %s", id, sum)
+	}
+	return nil
+}
+
+// checkRestored confirms a block was restored under its original multihash.
+// CID version & codec can differ between stores that agree on content
+func checkRestored(orig string, got cid.Cid) error {
+	id, err := cid.Decode(orig)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(id.Hash(), got.Hash()) {
+		return fmt.Errorf("restoring block %s: stored as %s, destination must hash blocks the way the source did", orig, got)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package qfs
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+func TestBackupRestore(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemFS()
+
+	a, err := src.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("shared.txt", []byte("shared")),
+		NewMemdir("b",
+			NewMemfileBytes("only_a.txt", []byte(strings.Repeat("a", 100))),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := src.Put(ctx, NewMemdir("/c",
+		NewMemfileBytes("shared.txt", []byte("shared")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := []cid.Cid{mustPathCid(t, a), mustPathCid(t, c)}
+
+	backups := NewMemFS()
+	manifestPath, err := Backup(ctx, src, backups, roots, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := backups.Get(ctx, manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest := BackupManifest{}
+	if err := DecodeJSON(f, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	if len(manifest.Shards) < 2 {
+		t.Errorf("expected a small shard size to split the backup. got %d shards", len(manifest.Shards))
+	}
+	blocks := 0
+	for _, s := range manifest.Shards {
+		blocks += s.Blocks
+	}
+	if blocks != src.ObjectCount() {
+		t.Errorf("expected each block to be backed up once. want %d blocks got: %d", src.ObjectCount(), blocks)
+	}
+
+	dst := NewMemFS()
+	restored, err := Restore(ctx, backups, manifestPath, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restored) != len(roots) {
+		t.Fatalf("root count mismatch. want %d got: %d", len(roots), len(restored))
+	}
+	if dst.ObjectCount() != src.ObjectCount() {
+		t.Errorf("object count mismatch. want %d got: %d", src.ObjectCount(), dst.ObjectCount())
+	}
+
+	f, err = dst.Get(ctx, a+"/b/only_a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != strings.Repeat("a", 100) {
+		t.Errorf("contents mismatch. got: %q", s)
+	}
+	f, err = dst.Get(ctx, c+"/shared.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "shared" {
+		t.Errorf("contents mismatch. want: %q got: %q", "shared", s)
+	}
+
+	again, err := Backup(ctx, src, backups, roots, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again != manifestPath {
+		t.Errorf("expected backing up the same content to produce the same manifest. want %q got: %q", manifestPath, again)
+	}
+}
+
+func TestRestoreRawBlocks(t *testing.T) {
+	ctx := context.Background()
+	src := NewMemFS()
+	a, err := src.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("b")),
+		NewMemdir("c", NewMemfileBytes("d.txt", []byte("d"))),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	backups := NewMemFS()
+	manifestPath, err := Backup(ctx, src, backups, []cid.Cid{mustPathCid(t, a)}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := &rawBlockStore{MemFS: NewMemFS(), blocks: map[cid.Cid][]byte{}}
+	if _, err := Restore(ctx, backups, manifestPath, dst); err != nil {
+		t.Fatal(err)
+	}
+	if len(dst.blocks) != src.ObjectCount() {
+		t.Errorf("expected every block to be restored verbatim. want %d got: %d", src.ObjectCount(), len(dst.blocks))
+	}
+	if dst.ObjectCount() != 0 {
+		t.Errorf("expected no blocks to be rebuilt. got %d objects", dst.ObjectCount())
+	}
+	for id, data := range dst.blocks {
+		want, err := GetBlockBytes(src, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Errorf("block %s mismatch", id)
+		}
+	}
+}
+
+// rawBlockStore records blocks written with PutRawBlock
+type rawBlockStore struct {
+	*MemFS
+	blocks map[cid.Cid][]byte
+}
+
+func (s *rawBlockStore) PutRawBlock(ctx context.Context, id cid.Cid, data []byte) error {
+	s.blocks[id] = data
+	return nil
+}
+
+func mustPathCid(t *testing.T, p string) cid.Cid {
+	t.Helper()
+	id, err := cidFromPath(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
@@ -0,0 +1,66 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+)
+
+// BatchFS is an opt-in interface for filesystems that can get, check & delete
+// many paths more efficiently than one at a time, eg: by fetching content
+// from the network concurrently
+type BatchFS interface {
+	HasManyFS
+	// GetMany opens each path, returning files in the same order as paths. If
+	// any path can't be opened GetMany closes any files it opened & returns an
+	// error
+	GetMany(ctx context.Context, paths []string) ([]File, error)
+	// DeleteMany removes each path
+	DeleteMany(ctx context.Context, paths []string) error
+}
+
+// GetMany opens a set of paths, using fs's GetMany method if fs implements
+// BatchFS, and falling back to calling Get on each path if not. Files are
+// returned in the same order as paths. Errors are wrapped with the path that
+// failed, any files opened before the error are closed
+func GetMany(ctx context.Context, fs Filesystem, paths []string) ([]File, error) {
+	if bfs, ok := fs.(BatchFS); ok {
+		return bfs.GetMany(ctx, paths)
+	}
+
+	res := make([]File, 0, len(paths))
+	for _, p := range paths {
+		f, err := fs.Get(ctx, p)
+		if err != nil {
+			CloseAll(res)
+			return nil, fmt.Errorf("getting %q: %w", p, err)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// DeleteMany removes a set of paths, using fs's DeleteMany method if fs
+// implements BatchFS, and falling back to calling Delete on each path if not.
+// The fallback stops at the first error, wrapping it with the path that failed
+func DeleteMany(ctx context.Context, fs Filesystem, paths []string) error {
+	if bfs, ok := fs.(BatchFS); ok {
+		return bfs.DeleteMany(ctx, paths)
+	}
+
+	for _, p := range paths {
+		if err := fs.Delete(ctx, p); err != nil {
+			return fmt.Errorf("deleting %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// CloseAll closes every non-nil file in files, ignoring errors. It's meant
+// for releasing files after an error
+func CloseAll(files []File) {
+	for _, f := range files {
+		if f != nil {
+			f.Close()
+		}
+	}
+}
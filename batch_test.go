@@ -0,0 +1,52 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGetManyDeleteMany(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	var paths []string
+	for _, data := range []string{"a", "b", "c"} {
+		p, err := fs.Put(ctx, NewMemfileBytes(data+".txt", []byte(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, p)
+	}
+
+	files, err := GetMany(ctx, fs, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, f := range files {
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		if want := string('a' + rune(i)); string(data) != want {
+			t.Errorf("file %d contents mismatch. want: %q got: %q", i, want, string(data))
+		}
+	}
+
+	if _, err := GetMany(ctx, fs, []string{paths[0], "/mem/QmMissing"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a missing path to return ErrNotFound, got: %v", err)
+	}
+
+	if err := DeleteMany(ctx, fs, paths[:2]); err != nil {
+		t.Fatal(err)
+	}
+	exist, err := HasMany(ctx, fs, paths)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exist[paths[0]] || exist[paths[1]] || !exist[paths[2]] {
+		t.Errorf("expected only the last path to remain, got: %v", exist)
+	}
+}
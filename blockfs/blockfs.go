@@ -0,0 +1,350 @@
+// Package blockfs is a content-addressed filesystem built directly on an IPFS
+// blockstore, for single-process use where running a full IPFS node is too
+// heavy. Files are imported with the same chunking & DAG layout as
+// "ipfs add", so content gets identical CIDs, but there's no networking, repo
+// lock, migrations or garbage collection: every block written is kept
+package blockfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	blockservice "github.com/ipfs/go-blockservice"
+	cid "github.com/ipfs/go-cid"
+	ds "github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/mount"
+	dssync "github.com/ipfs/go-datastore/sync"
+	badger "github.com/ipfs/go-ds-badger"
+	flatfs "github.com/ipfs/go-ds-flatfs"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfs "github.com/ipfs/go-unixfs"
+	"github.com/ipfs/go-unixfs/importer/balanced"
+	"github.com/ipfs/go-unixfs/importer/helpers"
+	"github.com/mitchellh/mapstructure"
+	"github.com/qri-io/qfs"
+)
+
+// FilestoreType uniquely identifies this filestore. blockfs shares the "ipfs"
+// prefix with qipfs, so paths written by either resolve in the other
+const FilestoreType = "ipfs"
+
+var log = logging.Logger("blockfs")
+
+const (
+	// DatastoreFlatfs stores each block as a file, sharded into directories
+	// the same way an IPFS repo does
+	DatastoreFlatfs = "flatfs"
+	// DatastoreBadger stores blocks in a badger database
+	DatastoreBadger = "badger"
+)
+
+// Config adjusts the behaviour of an FS instance
+type Config struct {
+	// Path is the directory blocks are stored in. An empty path keeps blocks
+	// in memory
+	Path string
+	// Datastore is the on-disk format of Path, DatastoreFlatfs or
+	// DatastoreBadger. Defaults to DatastoreFlatfs
+	Datastore string
+	// ChunkSize is the size in bytes of the blocks files are split into. Zero
+	// uses the IPFS default of 256KiB. Changing the chunk size changes the
+	// hashes of files larger than one chunk
+	ChunkSize int
+}
+
+// Validate returns an error if the configuration fields conflict
+func (cfg *Config) Validate() error {
+	switch cfg.Datastore {
+	case "", DatastoreFlatfs, DatastoreBadger:
+	default:
+		return fmt.Errorf("unknown datastore %q, expected %q or %q", cfg.Datastore, DatastoreFlatfs, DatastoreBadger)
+	}
+	if cfg.ChunkSize < 0 || cfg.ChunkSize > chunker.ChunkSizeLimit {
+		return fmt.Errorf("chunk size must be between 0 and %d bytes, got: %d", chunker.ChunkSizeLimit, cfg.ChunkSize)
+	}
+	return nil
+}
+
+// FS is a qfs.Filesystem of unixfs content stored in a blockstore
+type FS struct {
+	cfg   Config
+	store ds.Batching
+	dag   format.DAGService
+
+	doneCh  chan struct{}
+	doneErr error
+	once    sync.Once
+}
+
+// compile-time assertions
+var (
+	_ qfs.Filesystem          = (*FS)(nil)
+	_ qfs.CAFS                = (*FS)(nil)
+	_ qfs.ReleasingFilesystem = (*FS)(nil)
+	_ qfs.CapabilitiesFS      = (*FS)(nil)
+)
+
+// NewFilesystem creates a blockfs from a configuration map, satisfying the
+// qfs.Constructor type. The store is closed when ctx is cancelled
+func NewFilesystem(ctx context.Context, cfgMap map[string]interface{}) (qfs.Filesystem, error) {
+	cfg := Config{}
+	if cfgMap != nil {
+		if err := mapstructure.Decode(cfgMap, &cfg); err != nil {
+			return nil, err
+		}
+	}
+	return New(ctx, cfg)
+}
+
+// New opens the blockstore described by cfg, creating it if it doesn't exist.
+// The store is closed when ctx is cancelled
+func New(ctx context.Context, cfg Config) (*FS, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	store, err := openDatastore(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	bs := blockstore.NewBlockstore(store)
+	fs := &FS{
+		cfg:    cfg,
+		store:  store,
+		dag:    merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs))),
+		doneCh: make(chan struct{}),
+	}
+	go func() {
+		<-ctx.Done()
+		fs.close()
+	}()
+	return fs, nil
+}
+
+// openDatastore opens the datastore blocks are kept in. flatfs is mounted at
+// /blocks, where the blockstore writes, matching the layout of an IPFS repo
+func openDatastore(cfg Config) (ds.Batching, error) {
+	if cfg.Path == "" {
+		return dssync.MutexWrap(ds.NewMapDatastore()), nil
+	}
+	if cfg.Datastore == DatastoreBadger {
+		return badger.NewDatastore(cfg.Path, &badger.DefaultOptions)
+	}
+	flat, err := flatfs.CreateOrOpen(cfg.Path, flatfs.NextToLast(2), true)
+	if err != nil {
+		return nil, err
+	}
+	return mount.New([]mount.Mount{{Prefix: blockstore.BlockPrefix, Datastore: flat}}), nil
+}
+
+func (fs *FS) close() {
+	fs.once.Do(func() {
+		fs.doneErr = fs.store.Close()
+		if fs.doneErr != nil {
+			log.Errorf("closing blockstore: %s", fs.doneErr)
+		}
+		close(fs.doneCh)
+	})
+}
+
+// closed returns qfs.ErrClosed once the store is closed
+func (fs *FS) closed() error {
+	select {
+	case <-fs.doneCh:
+		return qfs.ErrClosed
+	default:
+		return nil
+	}
+}
+
+// Done implements the qfs.ReleasingFilesystem interface, closing once the
+// underlying datastore is closed
+func (fs *FS) Done() <-chan struct{} {
+	return fs.doneCh
+}
+
+// DoneErr returns the error closing the datastore, if any
+func (fs *FS) DoneErr() error {
+	return fs.doneErr
+}
+
+// Type distinguishes this filesystem from others by a unique string prefix
+func (fs *FS) Type() string {
+	return FilestoreType
+}
+
+// IsContentAddressedFilesystem marks blockfs as a CAFS
+func (fs *FS) IsContentAddressedFilesystem() {}
+
+// Capabilities reports that blockfs can't delete content
+func (fs *FS) Capabilities() qfs.CapabilitySet {
+	caps := qfs.DeriveCapabilities(fs)
+	caps.CanDelete = false
+	return caps
+}
+
+// Has returns whether the object at key is stored locally
+func (fs *FS) Has(ctx context.Context, key string) (bool, error) {
+	if _, err := fs.resolve(ctx, key); err != nil {
+		if errors.Is(err, qfs.ErrNotFound) || errors.Is(err, qfs.ErrNotDirectory) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Get opens the file or directory at key. Directory contents are read from
+// the blockstore as they're iterated
+func (fs *FS) Get(ctx context.Context, key string) (qfs.File, error) {
+	node, err := fs.resolve(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return newFile(ctx, fs.dag, strings.TrimSuffix(key, "/"), node)
+}
+
+// resolve returns the node at key, walking the subpath from its root
+func (fs *FS) resolve(ctx context.Context, key string) (format.Node, error) {
+	if err := fs.closed(); err != nil {
+		return nil, err
+	}
+	if err := qfs.ValidatePath(FilestoreType, key); err != nil {
+		return nil, err
+	}
+	_, hash, subpath := qfs.SplitStorePath(key)
+	id, err := cid.Decode(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := fs.dag.Get(ctx, id)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if subpath == "" {
+		return node, nil
+	}
+	for _, name := range strings.Split(subpath, "/") {
+		pn, ok := node.(*merkledag.ProtoNode)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", qfs.ErrNotDirectory, key)
+		}
+		if fsn, err := unixfs.FSNodeFromBytes(pn.Data()); err != nil || !fsn.IsDir() {
+			return nil, fmt.Errorf("%w: %q", qfs.ErrNotDirectory, key)
+		}
+		if node, err = pn.GetLinkedNode(ctx, fs.dag, name); err != nil {
+			if errors.Is(err, merkledag.ErrLinkNotFound) {
+				return nil, fmt.Errorf("%w: %q", qfs.ErrNotFound, key)
+			}
+			return nil, notFound(err)
+		}
+	}
+	return node, nil
+}
+
+// notFound wraps the errors of missing blocks with qfs.ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, format.ErrNotFound) || errors.Is(err, blockstore.ErrNotFound) || errors.Is(err, blockservice.ErrNotFound) {
+		return fmt.Errorf("%w: %s", qfs.ErrNotFound, err)
+	}
+	return err
+}
+
+// Put imports a file, directory or qfs.SymlinkFile, returning its /ipfs/ path
+func (fs *FS) Put(ctx context.Context, file qfs.File) (string, error) {
+	if err := fs.closed(); err != nil {
+		return "", err
+	}
+	if err := qfs.CheckCAFSPutPath(FilestoreType, file.FullPath()); err != nil {
+		return "", err
+	}
+	node, err := fs.add(ctx, file)
+	if err != nil {
+		return "", err
+	}
+	return qfs.JoinPath(FilestoreType, node.Cid().String()), nil
+}
+
+// add imports file & any files within it, returning the root node
+func (fs *FS) add(ctx context.Context, file qfs.File) (format.Node, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if target, ok := qfs.IsSymlink(file); ok {
+		return fs.addSymlink(ctx, target)
+	}
+	if !file.IsDirectory() {
+		return fs.addFile(file)
+	}
+
+	dir := unixfs.EmptyDirNode()
+	dir.SetCidBuilder(merkledag.V0CidPrefix())
+	for {
+		child, err := file.NextFile()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		node, err := fs.add(ctx, child)
+		if err != nil {
+			return nil, err
+		}
+		if err := dir.AddNodeLink(child.FileName(), node); err != nil {
+			return nil, err
+		}
+	}
+	if err := fs.dag.Add(ctx, dir); err != nil {
+		return nil, err
+	}
+	return dir, nil
+}
+
+// addFile chunks file into a balanced DAG of CIDv0 nodes, the defaults of
+// "ipfs add"
+func (fs *FS) addFile(r io.Reader) (format.Node, error) {
+	size := chunker.DefaultBlockSize
+	if fs.cfg.ChunkSize > 0 {
+		size = int64(fs.cfg.ChunkSize)
+	}
+	params := helpers.DagBuilderParams{
+		Dagserv:    fs.dag,
+		Maxlinks:   helpers.DefaultLinksPerBlock,
+		CidBuilder: merkledag.V0CidPrefix(),
+	}
+	db, err := params.New(chunker.NewSizeSplitter(r, size))
+	if err != nil {
+		return nil, err
+	}
+	return balanced.Layout(db)
+}
+
+// addSymlink writes a unixfs symlink node, the node "ipfs add" creates for
+// symlinks
+func (fs *FS) addSymlink(ctx context.Context, target string) (format.Node, error) {
+	data, err := unixfs.SymlinkData(target)
+	if err != nil {
+		return nil, err
+	}
+	node := merkledag.NodeWithData(data)
+	node.SetCidBuilder(merkledag.V0CidPrefix())
+	if err := fs.dag.Add(ctx, node); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// Delete is unsupported, blockfs keeps every block it writes
+func (fs *FS) Delete(ctx context.Context, key string) error {
+	return fmt.Errorf("%w: blockfs doesn't delete content", qfs.ErrUnsupported)
+}
@@ -0,0 +1,237 @@
+package blockfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestIPFSCompatibleCids(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs, err := New(ctx, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// hashes "ipfs add" produces for the same content
+	cases := []struct {
+		description string
+		file        qfs.File
+		expect      string
+	}{
+		{"file", qfs.NewMemfileBytes("hello.txt", []byte("hello world\n")), "/ipfs/QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"},
+		{"empty file", qfs.NewMemfileBytes("empty.txt", []byte{}), "/ipfs/QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH"},
+		{"empty directory", qfs.NewMemdir("/empty"), "/ipfs/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"},
+	}
+	for _, c := range cases {
+		got, err := fs.Put(ctx, c.file)
+		if err != nil {
+			t.Fatalf("%s: %s", c.description, err)
+		}
+		if got != c.expect {
+			t.Errorf("%s: path mismatch. want: %q got: %q", c.description, c.expect, got)
+		}
+	}
+}
+
+func TestGetPaths(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs, err := New(ctx, Config{ChunkSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	long := strings.Repeat("chunked across many blocks ", 20)
+	key, err := fs.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("long.txt", []byte(long)),
+		qfs.NewMemdir("b",
+			qfs.NewMemfileBytes("c.txt", []byte("c")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Get(ctx, key+"/long.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf, ok := f.(qfs.SizeFile); !ok || sf.Size() != int64(len(long)) {
+		t.Errorf("expected file to report its size of %d", len(long))
+	}
+	if _, s := qfs.FileString(f); s != long {
+		t.Errorf("multi-block file contents mismatch")
+	}
+
+	f, err = fs.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{}
+	if err := qfs.Walk(f, func(f qfs.File) error {
+		paths = append(paths, strings.TrimPrefix(f.FullPath(), key))
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/long.txt", "/b/c.txt"} {
+		found := false
+		for _, got := range paths {
+			found = found || got == p
+		}
+		if !found {
+			t.Errorf("expected walk to visit %q. got: %v", p, paths)
+		}
+	}
+
+	if exists, err := fs.Has(ctx, key+"/b/c.txt"); err != nil || !exists {
+		t.Errorf("expected nested file to exist. got: %t, %v", exists, err)
+	}
+	if _, err := fs.Get(ctx, key+"/missing"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected missing path to return ErrNotFound. got: %v", err)
+	}
+	if _, err := fs.Get(ctx, key+"/b/c.txt/d"); !errors.Is(err, qfs.ErrNotDirectory) {
+		t.Errorf("expected path through a file to return ErrNotDirectory. got: %v", err)
+	}
+	if exists, err := fs.Has(ctx, "/ipfs/QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"); err != nil || exists {
+		t.Errorf("expected unwritten content not to exist. got: %t, %v", exists, err)
+	}
+	if err := fs.Delete(ctx, key); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected delete to be unsupported. got: %v", err)
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	for _, store := range []string{DatastoreFlatfs, DatastoreBadger} {
+		t.Run(store, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "qfs_blockfs")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			cfg := Config{Path: dir, Datastore: store}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			fs, err := New(ctx, cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := fs.Put(ctx, qfs.NewMemfileBytes("hello.txt", []byte("hello world\n")))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cancel()
+			<-fs.Done()
+			if err := fs.DoneErr(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fs.Get(context.Background(), key); !errors.Is(err, qfs.ErrClosed) {
+				t.Errorf("expected closed store to return ErrClosed. got: %v", err)
+			}
+
+			ctx, cancel = context.WithCancel(context.Background())
+			defer cancel()
+			if fs, err = New(ctx, cfg); err != nil {
+				t.Fatal(err)
+			}
+			f, err := fs.Get(ctx, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, s := qfs.FileString(f); s != "hello world\n" {
+				t.Errorf("contents mismatch after reopening. got: %q", s)
+			}
+		})
+	}
+}
+
+func TestSymlinks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs, err := New(ctx, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := fs.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+		qfs.NewMemsymlink("link", "b.txt"),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Get(ctx, key+"/link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, ok := qfs.IsSymlink(f); !ok || target != "b.txt" {
+		t.Errorf("expected link to round-trip as a symlink to %q. got: %q, %t", "b.txt", target, ok)
+	}
+
+	f, err = fs.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := 0
+	if err := qfs.Walk(f, func(f qfs.File) error {
+		if _, ok := qfs.IsSymlink(f); ok {
+			links++
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if links != 1 {
+		t.Errorf("expected to walk 1 link. got: %d", links)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	src, err := New(ctx, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dst, err := New(ctx, Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := src.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := src.Put(ctx, qfs.NewMemdir("/d",
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+		qfs.NewMemdir("c", qfs.NewMemfileBytes("d.txt", []byte("d"))),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{file, dir} {
+		got, err := qfs.Copy(ctx, src, dst, key)
+		if err != nil {
+			t.Fatalf("copying %s: %s", key, err)
+		}
+		if got != key {
+			t.Errorf("expected copy to keep the path. want: %q got: %q", key, got)
+		}
+	}
+	f, err := dst.Get(ctx, dir+"/c/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(f); s != "d" {
+		t.Errorf("contents mismatch. got: %q", s)
+	}
+}
@@ -0,0 +1,133 @@
+package blockfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	files "github.com/ipfs/go-ipfs-files"
+	format "github.com/ipfs/go-ipld-format"
+	unixfile "github.com/ipfs/go-unixfs/file"
+	"github.com/qri-io/qfs"
+)
+
+// newFile opens node as a qfs.File at path
+func newFile(ctx context.Context, dag format.DAGService, path string, node format.Node) (qfs.File, error) {
+	n, err := unixfile.NewUnixfsFile(ctx, dag, node)
+	if err != nil {
+		return nil, err
+	}
+	return wrapNode(path, n)
+}
+
+func wrapNode(path string, n files.Node) (qfs.File, error) {
+	switch n := n.(type) {
+	case *files.Symlink:
+		return qfs.NewMemsymlink(path, n.Target), nil
+	case files.Directory:
+		return &dir{path: path, dir: n, it: n.Entries()}, nil
+	case files.File:
+		size, err := n.Size()
+		if err != nil {
+			size = -1
+		}
+		return &file{path: path, f: n, size: size}, nil
+	}
+	return nil, qfs.ErrUnsupported
+}
+
+// file is a unixfs file read from the blockstore
+type file struct {
+	path string
+	f    files.File
+	size int64
+}
+
+var (
+	_ qfs.File     = (*file)(nil)
+	_ qfs.SizeFile = (*file)(nil)
+	_ qfs.StatFile = (*file)(nil)
+	_ qfs.SeekFile = (*file)(nil)
+)
+
+func (f *file) Read(p []byte) (int, error) { return f.f.Read(p) }
+
+func (f *file) Close() error { return f.f.Close() }
+
+// Seek moves the read position within the file
+func (f *file) Seek(offset int64, whence int) (int64, error) {
+	return f.f.Seek(offset, whence)
+}
+
+// Size returns the size of the file in bytes, -1 if unknown
+func (f *file) Size() int64 { return f.size }
+
+// IsDirectory satisfies the qfs.File interface
+func (f *file) IsDirectory() bool { return false }
+
+// NextFile satisfies the qfs.File interface
+func (f *file) NextFile() (qfs.File, error) { return nil, qfs.ErrNotDirectory }
+
+// FileName returns the base name of the file's path
+func (f *file) FileName() string { return filepath.Base(f.path) }
+
+// FullPath returns the path used to fetch this file
+func (f *file) FullPath() string { return f.path }
+
+// MediaType is unknown, unixfs doesn't record media types
+func (f *file) MediaType() string { return "" }
+
+// ModTime is always zero, unixfs files are immutable
+func (f *file) ModTime() time.Time { return time.Time{} }
+
+// Stat returns info describing the file
+func (f *file) Stat() (fs.FileInfo, error) { return qfs.FileInfo(f), nil }
+
+// dir is a unixfs directory. Children are read as they're iterated
+type dir struct {
+	path string
+	dir  files.Directory
+	it   files.DirIterator
+}
+
+var (
+	_ qfs.File     = (*dir)(nil)
+	_ qfs.StatFile = (*dir)(nil)
+)
+
+// Read errors, dir is a directory
+func (d *dir) Read(p []byte) (int, error) { return 0, qfs.ErrNotFile }
+
+func (d *dir) Close() error { return d.dir.Close() }
+
+// IsDirectory satisfies the qfs.File interface
+func (d *dir) IsDirectory() bool { return true }
+
+// NextFile returns the next child of the directory, or io.EOF when all
+// children have been read
+func (d *dir) NextFile() (qfs.File, error) {
+	if !d.it.Next() {
+		if err := d.it.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return wrapNode(d.path+"/"+d.it.Name(), d.it.Node())
+}
+
+// FileName returns the base name of the directory
+func (d *dir) FileName() string { return filepath.Base(d.path) }
+
+// FullPath returns the path used to fetch this directory
+func (d *dir) FullPath() string { return d.path }
+
+// MediaType is a directory mime-type stand-in
+func (d *dir) MediaType() string { return "application/x-directory" }
+
+// ModTime is always zero, unixfs directories are immutable
+func (d *dir) ModTime() time.Time { return time.Time{} }
+
+// Stat returns info describing the directory
+func (d *dir) Stat() (fs.FileInfo, error) { return qfs.FileInfo(d), nil }
@@ -0,0 +1,68 @@
+package qfs
+
+// CapabilitySet describes the operations a filesystem supports
+type CapabilitySet struct {
+	// CanWrite is false for read-only filesystems, where Put always fails
+	CanWrite bool
+	// CanDelete is false for read-only filesystems & filesystems that never
+	// remove content
+	CanDelete bool
+	// CanPin is true for filesystems that implement PinningFS
+	CanPin bool
+	// CanAppend is true for filesystems that implement AppendFS
+	CanAppend bool
+	// CanList is true for filesystems that implement ListingFS
+	CanList bool
+	// ContentAddressed is true for filesystems that implement CAFS
+	ContentAddressed bool
+	// SupportsAdder is true for filesystems that implement MerkleDagStore,
+	// which muxfs prefers as its default write destination
+	SupportsAdder bool
+}
+
+// ReadOnlyFS marks a filesystem whose Put & Delete methods always return
+// ErrReadOnly
+type ReadOnlyFS interface {
+	IsReadOnlyFilesystem()
+}
+
+// CapabilitiesFS is an opt-in interface for filesystems that support fewer
+// operations than their method set suggests, eg: filesystems with a Put
+// method that always returns ErrReadOnly
+type CapabilitiesFS interface {
+	Filesystem
+	// Capabilities returns the operations the filesystem supports
+	Capabilities() CapabilitySet
+}
+
+// Capabilities describes the operations fs supports, using fs's Capabilities
+// method if fs implements CapabilitiesFS, and deriving them from the
+// interfaces fs implements if not
+func Capabilities(fs Filesystem) CapabilitySet {
+	if cfs, ok := fs.(CapabilitiesFS); ok {
+		return cfs.Capabilities()
+	}
+	return DeriveCapabilities(fs)
+}
+
+// DeriveCapabilities describes the operations fs supports based only on the
+// interfaces it implements. Writing & deleting are supported unless fs
+// implements ReadOnlyFS. Implementations of CapabilitiesFS can use
+// DeriveCapabilities as a starting point
+func DeriveCapabilities(fs Filesystem) CapabilitySet {
+	_, readOnly := fs.(ReadOnlyFS)
+	_, pins := fs.(PinningFS)
+	_, appends := fs.(AppendFS)
+	_, lists := fs.(ListingFS)
+	_, cafs := fs.(CAFS)
+	_, dag := fs.(MerkleDagStore)
+	return CapabilitySet{
+		CanWrite:         !readOnly,
+		CanDelete:        !readOnly,
+		CanPin:           pins,
+		CanAppend:        appends,
+		CanList:          lists,
+		ContentAddressed: cafs,
+		SupportsAdder:    dag,
+	}
+}
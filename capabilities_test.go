@@ -0,0 +1,42 @@
+package qfs
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCapabilities(t *testing.T) {
+	mem := NewMemFS()
+	cases := []struct {
+		description string
+		fs          Filesystem
+		expect      CapabilitySet
+	}{
+		{"memfs", mem, CapabilitySet{
+			CanWrite:         true,
+			CanDelete:        true,
+			CanList:          true,
+			ContentAddressed: true,
+			SupportsAdder:    true,
+		}},
+		{"subtree", Subtree(mem, "/mem/QmFoo"), CapabilitySet{
+			ContentAddressed: true,
+		}},
+		{"plain filesystem", struct{ Filesystem }{mem}, CapabilitySet{
+			CanWrite:  true,
+			CanDelete: true,
+		}},
+		{"read-only filesystem", readOnlyFS{mem}, CapabilitySet{}},
+	}
+
+	for _, c := range cases {
+		if diff := cmp.Diff(c.expect, Capabilities(c.fs)); diff != "" {
+			t.Errorf("%s capabilities mismatch (-want +got):\n%s", c.description, diff)
+		}
+	}
+}
+
+type readOnlyFS struct{ Filesystem }
+
+func (readOnlyFS) IsReadOnlyFilesystem() {}
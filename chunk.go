@@ -0,0 +1,151 @@
+package qfs
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// DefaultChunkSize is the size of the chunks MemFS stores file contents in
+// unless configured with SetChunkSize
+const DefaultChunkSize = 256 * 1024
+
+// chunkedData is file content held as a list of chunks. Every chunk but the
+// last is the same size
+type chunkedData struct {
+	chunks [][]byte
+	size   int64
+}
+
+// bytesData wraps a single byte slice as chunked data
+func bytesData(data []byte) chunkedData {
+	if len(data) == 0 {
+		return chunkedData{}
+	}
+	return chunkedData{chunks: [][]byte{data}, size: int64(len(data))}
+}
+
+// chunkBufs holds scratch buffers for reading chunks. Full chunks keep the
+// buffer they were read into, short chunks are copied out & the buffer reused
+var chunkBufs sync.Pool
+
+// getChunkBuf returns a buffer of size bytes, reusing a pooled one of the same
+// size. Full chunks are stored as read, so larger buffers aren't reused
+func getChunkBuf(size int) []byte {
+	if b, ok := chunkBufs.Get().([]byte); ok && cap(b) == size {
+		return b[:size]
+	}
+	return make([]byte, size)
+}
+
+// readChunked reads r to EOF in chunks of chunkSize bytes, writing each chunk
+// to w as it's read so content can be hashed incrementally. Content is never
+// copied into a single buffer, peak memory is the size of the content plus one
+// chunk
+func readChunked(r io.Reader, chunkSize int, w io.Writer) (chunkedData, error) {
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	d := chunkedData{}
+	for {
+		buf := getChunkBuf(chunkSize)
+		n, err := io.ReadFull(r, buf)
+		if n < chunkSize {
+			// don't hold on to the unused capacity of a short final chunk
+			short := append([]byte(nil), buf[:n]...)
+			chunkBufs.Put(buf)
+			buf = short
+		}
+		if n > 0 {
+			if _, err := w.Write(buf); err != nil {
+				return d, err
+			}
+			d.chunks = append(d.chunks, buf)
+			d.size += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return d, nil
+		} else if err != nil {
+			return d, err
+		}
+	}
+}
+
+// chunkReader reads & seeks chunked data
+type chunkReader struct {
+	data chunkedData
+	off  int64
+	// chunk & chunkOff cache the index & starting offset of the chunk the last
+	// read ended in, so sequential reads don't rescan the chunk list
+	chunk    int
+	chunkOff int64
+}
+
+var (
+	_ io.ReadSeeker = (*chunkReader)(nil)
+	_ io.WriterTo   = (*chunkReader)(nil)
+)
+
+func newChunkReader(data chunkedData) *chunkReader {
+	return &chunkReader{data: data}
+}
+
+// locate returns the chunk containing off & the position of off within it.
+// off must be less than the data size
+func (r *chunkReader) locate(off int64) (int, int64) {
+	if off < r.chunkOff {
+		r.chunk, r.chunkOff = 0, 0
+	}
+	for off >= r.chunkOff+int64(len(r.data.chunks[r.chunk])) {
+		r.chunkOff += int64(len(r.data.chunks[r.chunk]))
+		r.chunk++
+	}
+	return r.chunk, off - r.chunkOff
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.off >= r.data.size {
+		return 0, io.EOF
+	}
+	n := 0
+	for n < len(p) && r.off < r.data.size {
+		i, pos := r.locate(r.off)
+		m := copy(p[n:], r.data.chunks[i][pos:])
+		n += m
+		r.off += int64(m)
+	}
+	return n, nil
+}
+
+// WriteTo writes the remaining content to w a chunk at a time
+func (r *chunkReader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for r.off < r.data.size {
+		i, pos := r.locate(r.off)
+		n, err := w.Write(r.data.chunks[i][pos:])
+		total += int64(n)
+		r.off += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (r *chunkReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.off
+	case io.SeekEnd:
+		offset += r.data.size
+	default:
+		return 0, errors.New("chunkReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("chunkReader.Seek: negative position")
+	}
+	r.off = offset
+	return offset, nil
+}
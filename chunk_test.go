@@ -0,0 +1,113 @@
+package qfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"runtime"
+	"testing"
+)
+
+func TestChunkReader(t *testing.T) {
+	content := []byte("the quick brown fox jumps over the lazy dog")
+	data, err := readChunked(bytes.NewReader(content), 4, ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.size != int64(len(content)) {
+		t.Fatalf("size mismatch. want: %d got: %d", len(content), data.size)
+	}
+	if len(data.chunks) != 11 {
+		t.Errorf("expected 11 chunks, got: %d", len(data.chunks))
+	}
+
+	r := newChunkReader(data)
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("read mismatch. want: %q got: %q", content, got)
+	}
+
+	if _, err := r.Seek(10, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	p := make([]byte, 5)
+	if _, err := io.ReadFull(r, p); err != nil {
+		t.Fatal(err)
+	}
+	if string(p) != "brown" {
+		t.Errorf("read after seek mismatch. want: %q got: %q", "brown", p)
+	}
+
+	if _, err := r.Seek(-3, io.SeekEnd); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	if _, err := r.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "dog" {
+		t.Errorf("WriteTo mismatch. want: %q got: %q", "dog", buf.String())
+	}
+}
+
+func TestMemFSChunkSizeKeepsHashes(t *testing.T) {
+	ctx := context.Background()
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	a := NewMemFS()
+	pathA, err := a.Put(ctx, NewMemfileBytes("a.txt", content))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b := NewMemFS()
+	b.SetChunkSize(7)
+	pathB, err := b.Put(ctx, NewMemfileReader("a.txt", bytes.NewReader(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pathA != pathB {
+		t.Errorf("expected chunk size not to change hashes. %q != %q", pathA, pathB)
+	}
+
+	f, err := b.Get(ctx, pathB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := f.(SizeFile).Size(); size != int64(len(content)) {
+		t.Errorf("size mismatch. want: %d got: %d", len(content), size)
+	}
+	got, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("contents mismatch after a chunked put")
+	}
+}
+
+func TestReadChunkedReusesBuffers(t *testing.T) {
+	data := []byte("small file")
+	const runs = 100
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	for i := 0; i < runs; i++ {
+		d, err := readChunked(bytes.NewReader(data), DefaultChunkSize, ioutil.Discard)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.size != int64(len(data)) {
+			t.Fatalf("size mismatch. want: %d got: %d", len(data), d.size)
+		}
+	}
+	runtime.ReadMemStats(&after)
+	// pools may drop buffers, notably under the race detector, so only check
+	// the average stays well under a chunk per read
+	if perRun := (after.TotalAlloc - before.TotalAlloc) / runs; perRun > DefaultChunkSize/2 {
+		t.Errorf("expected reading a small file not to allocate a whole chunk. allocated %d bytes per read", perRun)
+	}
+}
@@ -0,0 +1,58 @@
+package qfs
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time. Filesystems & files read the current time through a
+// Clock so tests and golden fixtures can control it
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to the Clock interface
+type ClockFunc func() time.Time
+
+// Now calls f
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// SystemClock reads the time from the operating system
+var SystemClock Clock = ClockFunc(time.Now)
+
+// ManualClock is a Clock that only changes when told to. It's safe for
+// concurrent use
+type ManualClock struct {
+	lk sync.Mutex
+	t  time.Time
+}
+
+var _ Clock = (*ManualClock)(nil)
+
+// NewManualClock creates a clock set to t
+func NewManualClock(t time.Time) *ManualClock {
+	return &ManualClock{t: t}
+}
+
+// Now returns the clock's current time
+func (c *ManualClock) Now() time.Time {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	return c.t
+}
+
+// Set changes the clock's current time to t
+func (c *ManualClock) Set(t time.Time) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.t = t
+}
+
+// Advance moves the clock forward by d
+func (c *ManualClock) Advance(d time.Duration) {
+	c.lk.Lock()
+	defer c.lk.Unlock()
+	c.t = c.t.Add(d)
+}
@@ -0,0 +1,37 @@
+package qfs
+
+import (
+	"testing"
+	"time"
+)
+
+func TestModTimeSetter(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+
+	files := []File{
+		NewMemfileBytes("a.txt", []byte("a")),
+		NewMemdir("/dir"),
+		NewMemsymlink("/link", "a.txt"),
+		NewReaderAtFile("/b.txt", nil, 0),
+		NewFuncDir("/funcdir", nil),
+	}
+	for _, f := range files {
+		clock.Advance(time.Hour)
+		f.(ModTimeSetter).SetModTime(clock.Now())
+		if !f.ModTime().Equal(clock.Now()) {
+			t.Errorf("%s modtime mismatch. want: %s got: %s", f.FullPath(), clock.Now(), f.ModTime())
+		}
+	}
+}
+
+func TestEventBusClock(t *testing.T) {
+	start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	bus := &EventBus{Clock: NewManualClock(start)}
+	var got Event
+	bus.Subscribe(func(e Event) { got = e })
+	bus.Publish(Event{Type: EventFilePut, Path: "/a"})
+	if !got.Time.Equal(start) {
+		t.Errorf("event time mismatch. want: %s got: %s", start, got.Time)
+	}
+}
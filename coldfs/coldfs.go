@@ -0,0 +1,340 @@
+// Package coldfs offloads rarely-used content from a primary "hot"
+// filesystem to a cheaper "cold" one, like a local archive directory.
+// Offloaded paths are remembered as stubs in an Index, so Get continues to
+// resolve them
+package coldfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	logger "github.com/ipfs/go-log"
+	"github.com/qri-io/qfs"
+)
+
+var log = logger.Logger("coldfs")
+
+// Config adjusts the behaviour of an FS instance
+type Config struct {
+	// Window is how long a path can go without being accessed before Offload
+	// moves it to cold storage
+	Window time.Duration
+	// Promote moves offloaded files back to hot storage when they're read
+	Promote bool
+	// ColdRoot is the directory offloaded files are written beneath when cold
+	// storage isn't content-addressed, eg: "/mnt/archive" for a localfs
+	// archive. Content-addressed cold stores ignore ColdRoot
+	ColdRoot string
+	// Clock reads the time when recording accesses & computing the window
+	// cutoff, defaults to qfs.SystemClock
+	Clock qfs.Clock
+	// Index records offloaded paths. Defaults to an FSIndex written to
+	// StubIndexFilename beneath ColdRoot if cold storage isn't
+	// content-addressed, and a MemIndex otherwise
+	Index Index
+}
+
+// Option is a function type for passing to New
+type Option func(cfg *Config)
+
+// OptionWindow sets the access window
+func OptionWindow(d time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Window = d
+	}
+}
+
+// OptionPromote enables re-promoting offloaded files on read
+func OptionPromote(promote bool) Option {
+	return func(cfg *Config) {
+		cfg.Promote = promote
+	}
+}
+
+// OptionColdRoot sets the directory offloaded files are written beneath
+func OptionColdRoot(dir string) Option {
+	return func(cfg *Config) {
+		cfg.ColdRoot = dir
+	}
+}
+
+// OptionClock sets the clock used to track access times. A nil clock keeps
+// the default
+func OptionClock(c qfs.Clock) Option {
+	return func(cfg *Config) {
+		if c != nil {
+			cfg.Clock = c
+		}
+	}
+}
+
+// OptionIndex sets the index offloaded paths are recorded in
+func OptionIndex(idx Index) Option {
+	return func(cfg *Config) {
+		cfg.Index = idx
+	}
+}
+
+// DefaultConfig offloads content that hasn't been accessed in 30 days, and
+// doesn't promote offloaded files
+func DefaultConfig() *Config {
+	return &Config{
+		Window: time.Hour * 24 * 30,
+		Clock:  qfs.SystemClock,
+	}
+}
+
+// FS is a qfs.Filesystem that writes to hot storage, tracking when each path
+// was last accessed. Offload moves paths that fall outside the access window
+// to cold storage. Access times are held in memory, stubs are kept in the
+// configured Index
+type FS struct {
+	cfg  *Config
+	hot  qfs.Filesystem
+	cold qfs.Filesystem
+
+	lk       sync.Mutex
+	accessed map[string]time.Time
+}
+
+var _ qfs.Filesystem = (*FS)(nil)
+
+// New creates a tiered filesystem from hot & cold filesystems
+func New(hot, cold qfs.Filesystem, opts ...Option) *FS {
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = qfs.SystemClock
+	}
+	if cfg.Index == nil {
+		if _, ok := cold.(qfs.CAFS); ok {
+			cfg.Index = NewMemIndex()
+		} else {
+			cfg.Index = NewFSIndex(cold, filepath.Join(cfg.ColdRoot, StubIndexFilename))
+		}
+	}
+
+	return &FS{
+		cfg:      cfg,
+		hot:      hot,
+		cold:     cold,
+		accessed: map[string]time.Time{},
+	}
+}
+
+// Type returns the type of the hot filesystem, paths are always addressed
+// using hot filesystem paths
+func (cfs *FS) Type() string {
+	return cfs.hot.Type()
+}
+
+// Has returns true if path is in hot storage, or has been offloaded
+func (cfs *FS) Has(ctx context.Context, path string) (bool, error) {
+	if coldPath, err := cfs.cfg.Index.Stub(ctx, path); err != nil {
+		return false, err
+	} else if coldPath != "" {
+		return true, nil
+	}
+	return cfs.hot.Has(ctx, path)
+}
+
+// Get fetches path from hot storage, falling back to cold storage for
+// offloaded paths. If promotion is enabled, offloaded files are written back
+// to hot storage
+func (cfs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
+	coldPath, err := cfs.cfg.Index.Stub(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if coldPath == "" {
+		f, err := cfs.hot.Get(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		cfs.touch(path)
+		return f, nil
+	}
+
+	f, err := cfs.cold.Get(ctx, coldPath)
+	if err != nil {
+		return nil, err
+	}
+	if !cfs.cfg.Promote || f.IsDirectory() {
+		return f, nil
+	}
+	return cfs.promote(ctx, path, f)
+}
+
+// Put writes file to hot storage
+func (cfs *FS) Put(ctx context.Context, file qfs.File) (string, error) {
+	path, err := cfs.hot.Put(ctx, file)
+	if err != nil {
+		return "", err
+	}
+	if err := cfs.cfg.Index.DeleteStub(ctx, path); err != nil {
+		return "", err
+	}
+	cfs.touch(path)
+	return path, nil
+}
+
+// Delete removes path from whichever storage tier holds it
+func (cfs *FS) Delete(ctx context.Context, path string) error {
+	coldPath, err := cfs.cfg.Index.Stub(ctx, path)
+	if err != nil {
+		return err
+	}
+	cfs.lk.Lock()
+	delete(cfs.accessed, path)
+	cfs.lk.Unlock()
+
+	if coldPath != "" {
+		if err := cfs.cfg.Index.DeleteStub(ctx, path); err != nil {
+			return err
+		}
+		return cfs.cold.Delete(ctx, coldPath)
+	}
+	return cfs.hot.Delete(ctx, path)
+}
+
+// Offload moves every file in hot storage that hasn't been accessed within
+// the configured window to cold storage, returning the offloaded paths.
+// Offload stops at the first path that fails to move. Only paths accessed
+// through this FS are tracked, and directories are never offloaded
+func (cfs *FS) Offload(ctx context.Context) ([]string, error) {
+	cutoff := cfs.cfg.Clock.Now().Add(-cfs.cfg.Window)
+	cfs.lk.Lock()
+	var stale []string
+	for path, t := range cfs.accessed {
+		if t.Before(cutoff) {
+			stale = append(stale, path)
+		}
+	}
+	cfs.lk.Unlock()
+
+	moved := make([]string, 0, len(stale))
+	for _, path := range stale {
+		if err := ctx.Err(); err != nil {
+			return moved, err
+		}
+		if err := cfs.offload(ctx, path); err != nil {
+			return moved, err
+		}
+		moved = append(moved, path)
+	}
+	return moved, nil
+}
+
+// Run calls Offload every interval until ctx is done. Offload errors are
+// logged, not returned
+func (cfs *FS) Run(ctx context.Context, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if _, err := cfs.Offload(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Errorw("offloading", qfs.LogFields(ctx, "err", err)...)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// offload copies path to cold storage, records a stub & removes path from
+// hot storage. The hot copy is kept if the stub can't be recorded
+func (cfs *FS) offload(ctx context.Context, path string) error {
+	f, err := cfs.hot.Get(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if f.IsDirectory() {
+		cfs.lk.Lock()
+		delete(cfs.accessed, path)
+		cfs.lk.Unlock()
+		return nil
+	}
+
+	coldPath, err := cfs.cold.Put(ctx, pathFile{File: f, path: putPath(cfs.cold, cfs.cfg.ColdRoot, path)})
+	if err != nil {
+		return err
+	}
+
+	if err := cfs.cfg.Index.SetStub(ctx, path, coldPath); err != nil {
+		return err
+	}
+	cfs.lk.Lock()
+	delete(cfs.accessed, path)
+	cfs.lk.Unlock()
+
+	return cfs.hot.Delete(ctx, path)
+}
+
+// promote writes an offloaded file back to hot storage, returning a copy of
+// the file for the caller to read
+func (cfs *FS) promote(ctx context.Context, path string, f qfs.File) (qfs.File, error) {
+	data, err := ioutil.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	hotPath, err := cfs.hot.Put(ctx, qfs.NewMemfileBytes(putPath(cfs.hot, "", path), data))
+	if err != nil {
+		return nil, err
+	}
+	if hotPath != path {
+		// keep the stub, path can't be resolved from hot storage
+		log.Debugw("promoted path differs from offloaded path", qfs.LogFields(ctx, "path", path, "promoted", hotPath)...)
+		return qfs.NewMemfileBytes(path, data), nil
+	}
+
+	coldPath, err := cfs.cfg.Index.Stub(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfs.cfg.Index.DeleteStub(ctx, path); err != nil {
+		return nil, err
+	}
+	cfs.touch(path)
+
+	if err := cfs.cold.Delete(ctx, coldPath); err != nil {
+		log.Debugw("removing promoted file from cold storage", qfs.LogFields(ctx, "path", coldPath, "err", err)...)
+	}
+
+	return qfs.NewMemfileBytes(path, data), nil
+}
+
+// touch records an access of path
+func (cfs *FS) touch(path string) {
+	cfs.lk.Lock()
+	cfs.accessed[path] = cfs.cfg.Clock.Now()
+	cfs.lk.Unlock()
+}
+
+// putPath gives the path to write a file that was stored at path to fs.
+// content-addressed filesystems reject paths within existing content, so
+// only the file name is kept
+func putPath(fs qfs.Filesystem, root, path string) string {
+	if _, ok := fs.(qfs.CAFS); ok {
+		return filepath.Base(path)
+	}
+	return filepath.Join(root, path)
+}
+
+// pathFile overrides the path of a file
+type pathFile struct {
+	qfs.File
+	path string
+}
+
+func (f pathFile) FileName() string { return filepath.Base(f.path) }
+func (f pathFile) FullPath() string { return f.path }
@@ -0,0 +1,127 @@
+package coldfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/localfs"
+)
+
+func TestOffload(t *testing.T) {
+	ctx := context.Background()
+	hot, cold := qfs.NewMemFS(), qfs.NewMemFS()
+	clock := qfs.NewManualClock(time.Now())
+	fs := New(hot, cold, OptionWindow(time.Hour), OptionPromote(true), OptionClock(clock))
+
+	a, err := fs.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour * 2)
+	b, err := fs.Put(ctx, qfs.NewMemfileBytes("b.txt", []byte("b")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	moved, err := fs.Offload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(moved) != 1 || moved[0] != a {
+		t.Fatalf("expected only %q to be offloaded. got: %v", a, moved)
+	}
+	if exists, _ := hot.Has(ctx, a); exists {
+		t.Errorf("expected %q to be removed from hot storage", a)
+	}
+	if cold.ObjectCount() != 1 {
+		t.Errorf("expected cold storage to hold 1 object. got: %d", cold.ObjectCount())
+	}
+	if exists, _ := fs.Has(ctx, a); !exists {
+		t.Errorf("expected offloaded path %q to exist", a)
+	}
+	if exists, _ := hot.Has(ctx, b); !exists {
+		t.Errorf("expected %q to remain in hot storage", b)
+	}
+
+	// reading an offloaded file promotes it
+	f, err := fs.Get(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a" {
+		t.Errorf("data mismatch. want: %q got: %q", "a", string(data))
+	}
+	if exists, _ := hot.Has(ctx, a); !exists {
+		t.Errorf("expected %q to be promoted to hot storage", a)
+	}
+	if cold.ObjectCount() != 0 {
+		t.Errorf("expected promoted file to be removed from cold storage")
+	}
+}
+
+func TestOffloadPersistsStubs(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "qfs_coldfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hot := qfs.NewMemFS()
+	cold, err := localfs.NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	coldRoot := filepath.Join(dir, "cold")
+	clock := qfs.NewManualClock(time.Now())
+	fs := New(hot, cold, OptionWindow(time.Hour), OptionClock(clock), OptionColdRoot(coldRoot))
+
+	a, err := fs.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Hour * 2)
+	if moved, err := fs.Offload(ctx); err != nil {
+		t.Fatal(err)
+	} else if len(moved) != 1 {
+		t.Fatalf("expected 1 offloaded path. got: %v", moved)
+	}
+	if _, err := os.Stat(filepath.Join(coldRoot, StubIndexFilename)); err != nil {
+		t.Errorf("expected stub index to be written to cold storage: %s", err)
+	}
+
+	// a new FS over the same stores resolves the offloaded path
+	fs = New(hot, cold, OptionColdRoot(coldRoot))
+	f, err := fs.Get(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a" {
+		t.Errorf("data mismatch. want: %q got: %q", "a", string(data))
+	}
+}
+
+func TestNilClock(t *testing.T) {
+	ctx := context.Background()
+	fs := New(qfs.NewMemFS(), qfs.NewMemFS(), OptionClock(nil))
+	if _, err := fs.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Offload(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package coldfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sync"
+
+	"github.com/qri-io/qfs"
+)
+
+// StubIndexFilename is the name of the file the default index is written to,
+// beneath the cold root
+const StubIndexFilename = ".qfs_coldfs_stubs.json"
+
+// Index records the cold storage path of each offloaded path. Offloaded
+// content can only be found through the index, so Offload removes a path from
+// hot storage only after the index has recorded its stub
+type Index interface {
+	// Stub returns the cold storage path of an offloaded path, or the empty
+	// string if path hasn't been offloaded
+	Stub(ctx context.Context, path string) (coldPath string, err error)
+	// SetStub records path as offloaded to coldPath
+	SetStub(ctx context.Context, path, coldPath string) error
+	// DeleteStub forgets the stub for path, if any
+	DeleteStub(ctx context.Context, path string) error
+}
+
+// MemIndex is an Index held in memory. Content offloaded by an FS using a
+// MemIndex can't be found once the process exits
+type MemIndex struct {
+	lk    sync.Mutex
+	stubs map[string]string
+}
+
+var (
+	_ Index = (*MemIndex)(nil)
+	_ Index = (*FSIndex)(nil)
+)
+
+// NewMemIndex creates an empty in-memory index
+func NewMemIndex() *MemIndex {
+	return &MemIndex{stubs: map[string]string{}}
+}
+
+// Stub implements the Index interface
+func (idx *MemIndex) Stub(_ context.Context, path string) (string, error) {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+	return idx.stubs[path], nil
+}
+
+// SetStub implements the Index interface
+func (idx *MemIndex) SetStub(_ context.Context, path, coldPath string) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+	idx.stubs[path] = coldPath
+	return nil
+}
+
+// DeleteStub implements the Index interface
+func (idx *MemIndex) DeleteStub(_ context.Context, path string) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+	delete(idx.stubs, path)
+	return nil
+}
+
+// FSIndex is an Index persisted as a JSON file on a filesystem that writes
+// files to the paths they're given, like localfs. The file is read on first
+// use & rewritten on every change
+type FSIndex struct {
+	fs   qfs.Filesystem
+	path string
+
+	lk    sync.Mutex
+	stubs map[string]string
+}
+
+// NewFSIndex creates an index stored at path on fs
+func NewFSIndex(fs qfs.Filesystem, path string) *FSIndex {
+	return &FSIndex{fs: fs, path: path}
+}
+
+// Stub implements the Index interface
+func (idx *FSIndex) Stub(ctx context.Context, path string) (string, error) {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+	if err := idx.load(ctx); err != nil {
+		return "", err
+	}
+	return idx.stubs[path], nil
+}
+
+// SetStub implements the Index interface
+func (idx *FSIndex) SetStub(ctx context.Context, path, coldPath string) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+	if err := idx.load(ctx); err != nil {
+		return err
+	}
+	prev, existed := idx.stubs[path]
+	idx.stubs[path] = coldPath
+	if err := idx.save(ctx); err != nil {
+		if existed {
+			idx.stubs[path] = prev
+		} else {
+			delete(idx.stubs, path)
+		}
+		return err
+	}
+	return nil
+}
+
+// DeleteStub implements the Index interface
+func (idx *FSIndex) DeleteStub(ctx context.Context, path string) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+	if err := idx.load(ctx); err != nil {
+		return err
+	}
+	prev, ok := idx.stubs[path]
+	if !ok {
+		return nil
+	}
+	delete(idx.stubs, path)
+	if err := idx.save(ctx); err != nil {
+		idx.stubs[path] = prev
+		return err
+	}
+	return nil
+}
+
+// load reads the index file if it hasn't been read yet. A missing file is an
+// empty index. idx.lk must be held
+func (idx *FSIndex) load(ctx context.Context) error {
+	if idx.stubs != nil {
+		return nil
+	}
+	f, err := idx.fs.Get(ctx, idx.path)
+	if errors.Is(err, qfs.ErrNotFound) {
+		idx.stubs = map[string]string{}
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	stubs := map[string]string{}
+	if err := json.Unmarshal(data, &stubs); err != nil {
+		return err
+	}
+	idx.stubs = stubs
+	return nil
+}
+
+// save writes the index file. idx.lk must be held
+func (idx *FSIndex) save(ctx context.Context) error {
+	data, err := json.Marshal(idx.stubs)
+	if err != nil {
+		return err
+	}
+	_, err = idx.fs.Put(ctx, qfs.NewMemfileBytes(idx.path, data))
+	return err
+}
@@ -0,0 +1,112 @@
+package qfs
+
+import (
+	"context"
+	"io"
+)
+
+// CopyProgress describes how much of a Copy has completed
+type CopyProgress struct {
+	// Path is the source path of the file being copied
+	Path string
+	// Files is the number of files that have been fully copied
+	Files int
+	// Bytes is the number of bytes copied so far, across all files
+	Bytes int64
+	// Total is the size of the source in bytes, -1 if it isn't known up front
+	Total int64
+}
+
+// CopyOptions configures a call to Copy
+type CopyOptions struct {
+	// Progress is called each time data is read from the source filesystem.
+	// Progress is called from whichever goroutine the destination filesystem
+	// reads from
+	Progress func(p CopyProgress)
+}
+
+// CopyOption is a function type for passing to Copy
+type CopyOption func(o *CopyOptions)
+
+// OptCopyProgress sets a function that receives progress updates
+func OptCopyProgress(fn func(p CopyProgress)) CopyOption {
+	return func(o *CopyOptions) {
+		o.Progress = fn
+	}
+}
+
+// Copy streams the file or directory tree at path from src to dst, returning
+// the path dst wrote to. Files are read from src as dst consumes them, so
+// trees are never buffered in memory. Directory structure is preserved; the
+// file paths dst receives are the ones src reports, which content-addressed
+// destinations ignore
+func Copy(ctx context.Context, src, dst Filesystem, path string, opts ...CopyOption) (newPath string, err error) {
+	o := &CopyOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	f, err := src.Get(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	total := sizeOf(f)
+	if o.Progress != nil && f.IsDirectory() && total < 0 {
+		// directory sizes come from listings, so only list if they're reported
+		total = listedSize(ctx, src, path)
+	}
+	progress := newCopyProgress(o.Progress, total)
+	newPath, err = dst.Put(ctx, progress.wrap(f))
+	if err != nil {
+		return "", err
+	}
+	log.Debugw("copied", LogFields(ctx, "src", src.Type(), "dst", dst.Type(), "path", path, "newPath", newPath, "bytes", progress.state.Bytes)...)
+	return newPath, nil
+}
+
+// copyProgress accumulates progress across every file in a copy
+type copyProgress struct {
+	fn    func(p CopyProgress)
+	state CopyProgress
+}
+
+func newCopyProgress(fn func(p CopyProgress), total int64) *copyProgress {
+	return &copyProgress{fn: fn, state: CopyProgress{Total: total}}
+}
+
+func (p *copyProgress) wrap(f File) File {
+	return &copyFile{File: f, progress: p}
+}
+
+// copyFile reports progress as it's read, wrapping children of directories
+type copyFile struct {
+	File
+	progress *copyProgress
+	done     bool
+}
+
+func (f *copyFile) Read(b []byte) (int, error) {
+	n, err := f.File.Read(b)
+	p := f.progress
+	p.state.Path = f.FullPath()
+	p.state.Bytes += int64(n)
+	finished := err == io.EOF && !f.done
+	if finished {
+		f.done = true
+		p.state.Files++
+	}
+	if (n > 0 || finished) && p.fn != nil {
+		p.fn(p.state)
+	}
+	return n, err
+}
+
+func (f *copyFile) NextFile() (File, error) {
+	next, err := f.File.NextFile()
+	if err != nil {
+		return nil, err
+	}
+	return f.progress.wrap(next), nil
+}
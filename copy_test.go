@@ -0,0 +1,50 @@
+package qfs
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+	src, dst := NewMemFS(), NewMemFS()
+
+	path, err := src.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("bbb")),
+		NewMemdir("c",
+			NewMemfileBytes("d.txt", []byte("dd")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last CopyProgress
+	newPath, err := Copy(ctx, src, dst, path, OptCopyProgress(func(p CopyProgress) { last = p }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newPath != path {
+		t.Errorf("expected copy to a filesystem of the same type to produce the same hash. want: %q got: %q", path, newPath)
+	}
+	if last.Files != 2 || last.Bytes != 5 {
+		t.Errorf("progress mismatch. want 2 files & 5 bytes. got: %#v", last)
+	}
+
+	f, err := dst.Get(ctx, newPath+"/c/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "dd" {
+		t.Errorf("data mismatch. want: %q got: %q", "dd", string(data))
+	}
+
+	if _, err := Copy(ctx, src, dst, "/mem/QmWyotUE3FXAaYe4tYmT7GwFEoPNiRZiLJQQ5j1vbwCfmN"); err == nil {
+		t.Error("expected copying a missing path to error")
+	}
+}
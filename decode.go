@@ -0,0 +1,117 @@
+package qfs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/polydawn/refmt/cbor"
+)
+
+// DefaultDecodeLimit is the largest file in bytes DecodeJSON and DecodeCBOR
+// will read
+const DefaultDecodeLimit = 256 << 20 // 256MiB
+
+// ErrFileTooLarge is returned when a file exceeds a decode size limit
+var ErrFileTooLarge = errors.New("file too large")
+
+// DecodeJSON stream-decodes the JSON contents of f into v, closing f when
+// finished. Files larger than DefaultDecodeLimit return ErrFileTooLarge
+func DecodeJSON(f File, v interface{}) error {
+	return DecodeJSONLimit(f, v, DefaultDecodeLimit)
+}
+
+// DecodeJSONLimit is DecodeJSON with a caller-supplied size limit in bytes
+func DecodeJSONLimit(f File, v interface{}, limit int64) error {
+	r, err := limitDecodeReader(f, limit)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := json.NewDecoder(r).Decode(v); err != nil {
+		return r.wrap(err)
+	}
+	return nil
+}
+
+// DecodeCBOR stream-decodes the CBOR contents of f into v, closing f when
+// finished. CBOR is first decoded into generic maps & slices, then
+// copied into v with mapstructure, so struct fields are matched by
+// `mapstructure` tags. Files larger than DefaultDecodeLimit return
+// ErrFileTooLarge
+func DecodeCBOR(f File, v interface{}) error {
+	return DecodeCBORLimit(f, v, DefaultDecodeLimit)
+}
+
+// DecodeCBORLimit is DecodeCBOR with a caller-supplied size limit in bytes
+func DecodeCBORLimit(f File, v interface{}, limit int64) error {
+	r, err := limitDecodeReader(f, limit)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var raw interface{}
+	if err := cbor.NewUnmarshaller(cbor.DecodeOptions{}, r).Unmarshal(&raw); err != nil {
+		return r.wrap(err)
+	}
+	return mapstructure.Decode(raw, v)
+}
+
+// limitDecodeReader checks f can be decoded, returning a reader that fails
+// once more than limit bytes are read. Files that report their size are
+// rejected before reading if they exceed limit. f is closed on error
+func limitDecodeReader(f File, limit int64) (*limitedReader, error) {
+	if f.IsDirectory() {
+		f.Close()
+		return nil, ErrNotFile
+	}
+	if sf, ok := f.(SizeFile); ok && sf.Size() > limit {
+		f.Close()
+		return nil, fmt.Errorf("%w: %s is %d bytes, limit is %d", ErrFileTooLarge, f.FullPath(), sf.Size(), limit)
+	}
+	return &limitedReader{r: f, n: limit, path: f.FullPath(), limit: limit}, nil
+}
+
+// limitedReader is an io.LimitedReader that errors instead of returning io.EOF
+// when the limit is exceeded
+type limitedReader struct {
+	r        io.Reader
+	n        int64
+	limit    int64
+	path     string
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n < 0 {
+		l.exceeded = true
+		return 0, l.err()
+	}
+	// read one byte past the limit to distinguish a file of exactly limit
+	// bytes from one that's too large
+	if int64(len(p)) > l.n+1 {
+		p = p[:l.n+1]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	if l.n < 0 {
+		l.exceeded = true
+		return n, l.err()
+	}
+	return n, err
+}
+
+func (l *limitedReader) err() error {
+	return fmt.Errorf("%w: %s exceeds limit of %d bytes", ErrFileTooLarge, l.path, l.limit)
+}
+
+// wrap replaces decoder errors caused by hitting the size limit with
+// ErrFileTooLarge
+func (l *limitedReader) wrap(err error) error {
+	if l.exceeded && !errors.Is(err, ErrFileTooLarge) {
+		return l.err()
+	}
+	return err
+}
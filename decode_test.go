@@ -0,0 +1,75 @@
+package qfs
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/polydawn/refmt/cbor"
+)
+
+type decodeTestStruct struct {
+	Title string `json:"title" mapstructure:"title"`
+	Count int    `json:"count" mapstructure:"count"`
+}
+
+func TestDecodeJSON(t *testing.T) {
+	expect := decodeTestStruct{Title: "foo", Count: 3}
+
+	got := decodeTestStruct{}
+	if err := DecodeJSON(NewMemfileBytes("a.json", []byte(`{"title":"foo","count":3}`)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("result mismatch. (-want +got):\n%s", diff)
+	}
+
+	if err := DecodeJSON(NewMemdir("/a"), &got); !errors.Is(err, ErrNotFile) {
+		t.Errorf("expected decoding a directory to return ErrNotFile. got: %v", err)
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	data, err := cbor.Marshal(map[string]interface{}{"title": "foo", "count": 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := decodeTestStruct{Title: "foo", Count: 3}
+	got := decodeTestStruct{}
+	if err := DecodeCBOR(NewMemfileBytes("a.cbor", data), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("result mismatch. (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeLimit(t *testing.T) {
+	data := []byte(`{"title":"a long title that goes over the limit"}`)
+	v := decodeTestStruct{}
+
+	// sized files are rejected before reading
+	if err := DecodeJSONLimit(NewMemfileBytes("a.json", data), &v, 10); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("expected ErrFileTooLarge for sized file. got: %v", err)
+	}
+	// unsized files fail once the limit is read
+	f := NewMemfileReader("a.json", bytes.NewReader(data))
+	if err := DecodeJSONLimit(f, &v, 10); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("expected ErrFileTooLarge for unsized file. got: %v", err)
+	}
+	cborData, err := cbor.Marshal(map[string]interface{}{"title": "a long title that goes over the limit"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = NewMemfileReader("a.cbor", bytes.NewReader(cborData))
+	if err := DecodeCBORLimit(f, &v, 10); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("expected ErrFileTooLarge for unsized cbor file. got: %v", err)
+	}
+	// files of exactly the limit are fine
+	f = NewMemfileReader("a.json", bytes.NewReader(data))
+	if err := DecodeJSONLimit(f, &v, int64(len(data))); err != nil {
+		t.Errorf("unexpected error decoding file at limit: %s", err)
+	}
+}
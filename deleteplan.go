@@ -0,0 +1,144 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// Impact describes the effect a Delete would have on a content-addressed
+// filesystem
+type Impact struct {
+	// Path is the path that would be deleted
+	Path string
+	// Unreferenced lists blocks that would no longer be referenced by any
+	// remaining root or pin, making them eligible for garbage collection
+	Unreferenced []cid.Cid
+	// ReclaimableBytes is the total size of unreferenced blocks
+	ReclaimableBytes int64
+	// SharedWith lists the keys of remaining roots or pins that reference
+	// content also reachable from Path. Shared content is kept after a delete
+	SharedWith []string
+}
+
+// DeletePlannerFS is an opt-in interface for content-addressed filesystems
+// that can preview the impact of a delete without removing anything
+type DeletePlannerFS interface {
+	Filesystem
+	PlanDelete(ctx context.Context, path string) (Impact, error)
+}
+
+// PlanDeleteDAG computes the impact of removing the root at target from a
+// MerkleDagStore, where refs are the roots that remain. Recursive refs protect
+// every block they reach, non-recursive refs only protect their own block.
+// PlanDeleteDAG reads every block reachable from target & refs, it's meant
+// for previewing destructive operations, not hot paths
+func PlanDeleteDAG(ctx context.Context, store MerkleDagStore, target string, refs []Pin) (Impact, error) {
+	impact := Impact{Path: target}
+	targetID, err := cidFromPath(target)
+	if err != nil {
+		return impact, err
+	}
+
+	// sizes of every block reachable from target
+	sizes := map[cid.Cid]int64{}
+	if err := walkDAG(ctx, store, targetID, true, func(id cid.Cid) (bool, error) {
+		data, err := GetBlockBytes(store, id)
+		if err != nil {
+			if isNotFound(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		sizes[id] = int64(len(data))
+		return true, nil
+	}); err != nil {
+		return impact, err
+	}
+
+	protected := map[cid.Cid]struct{}{}
+	for _, ref := range refs {
+		refID, err := cidFromPath(ref.Key)
+		if err != nil {
+			return impact, err
+		}
+		if refID.Equals(targetID) {
+			continue
+		}
+		shared := false
+		if err := walkDAG(ctx, store, refID, ref.Recursive, func(id cid.Cid) (bool, error) {
+			if _, ok := sizes[id]; ok {
+				protected[id] = struct{}{}
+				shared = true
+			}
+			return true, nil
+		}); err != nil {
+			return impact, err
+		}
+		if shared {
+			impact.SharedWith = append(impact.SharedWith, ref.Key)
+		}
+	}
+
+	for id, size := range sizes {
+		if _, ok := protected[id]; !ok {
+			impact.Unreferenced = append(impact.Unreferenced, id)
+			impact.ReclaimableBytes += size
+		}
+	}
+	sort.Slice(impact.Unreferenced, func(i, j int) bool {
+		return impact.Unreferenced[i].String() < impact.Unreferenced[j].String()
+	})
+	sort.Strings(impact.SharedWith)
+	return impact, nil
+}
+
+// walkDAG calls visit on root and, if recursive, every block reachable from
+// root, visiting each block once. visit returns false to skip a block's links.
+// Missing blocks are skipped
+func walkDAG(ctx context.Context, store MerkleDagStore, root cid.Cid, recursive bool, visit func(id cid.Cid) (bool, error)) error {
+	visited := map[cid.Cid]struct{}{}
+	queue := []cid.Cid{root}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+
+		descend, err := visit(id)
+		if err != nil {
+			return err
+		}
+		if !recursive || !descend || id.Type() == cid.Raw {
+			continue
+		}
+		node, err := store.GetNode(id)
+		if err != nil {
+			if isNotFound(err) {
+				continue
+			}
+			return err
+		}
+		for _, lnk := range node.Links().SortedSlice() {
+			queue = append(queue, lnk.Cid)
+		}
+	}
+	return nil
+}
+
+// cidFromPath parses the CID from a content-addressed path like /ipfs/QmFoo,
+// or a bare CID string
+func cidFromPath(p string) (cid.Cid, error) {
+	_, hash, subpath := SplitStorePath(p)
+	if subpath != "" {
+		return cid.Cid{}, fmt.Errorf("can only plan deletes of an entire hash, not individual paths: %q", p)
+	}
+	return cid.Decode(hash)
+}
@@ -0,0 +1,89 @@
+package qfs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemFSPlanDelete(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	a, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("shared.txt", []byte("shared")),
+		NewMemfileBytes("only_a.txt", []byte("only in a")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := fs.Put(ctx, NewMemdir("/b",
+		NewMemfileBytes("shared.txt", []byte("shared")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	objects := fs.ObjectCount()
+
+	impact, err := fs.PlanDelete(ctx, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.ObjectCount() != objects {
+		t.Errorf("expected PlanDelete not to remove anything")
+	}
+
+	// the directory block & only_a.txt become unreferenced
+	if len(impact.Unreferenced) != 2 {
+		t.Errorf("expected 2 unreferenced blocks. got: %d", len(impact.Unreferenced))
+	}
+	if impact.ReclaimableBytes <= int64(len("only in a")) {
+		t.Errorf("expected reclaimable bytes to include the directory & only_a.txt. got: %d", impact.ReclaimableBytes)
+	}
+	if len(impact.SharedWith) != 1 || impact.SharedWith[0] != b {
+		t.Errorf("expected content to be shared with %q. got: %v", b, impact.SharedWith)
+	}
+
+	if _, err := fs.PlanDelete(ctx, a+"/shared.txt"); err == nil {
+		t.Error("expected planning a delete of a subpath to error")
+	}
+}
+
+func TestMemFSDeleteKeepsSharedContent(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	x, err := fs.Put(ctx, NewMemfileBytes("x.txt", []byte("x")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := fs.Put(ctx, NewMemdir("/d", NewMemfileBytes("x.txt", []byte("x"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	impact, err := fs.PlanDelete(ctx, x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(impact.Unreferenced) != 0 || len(impact.SharedWith) != 1 || impact.SharedWith[0] != d {
+		t.Errorf("expected x.txt to be shared with %q & nothing unreferenced. got: %#v", d, impact)
+	}
+
+	if err := fs.Delete(ctx, x); err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.Get(ctx, d+"/x.txt")
+	if err != nil {
+		t.Fatalf("expected content shared with another root to be kept. got: %s", err)
+	}
+	if _, s := FileString(f); s != "x" {
+		t.Errorf("contents mismatch. got: %q", s)
+	}
+
+	if err := fs.Delete(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := fs.Has(ctx, x); exists {
+		t.Errorf("expected content to be removed once no root references it")
+	}
+}
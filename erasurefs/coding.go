@@ -0,0 +1,190 @@
+package erasurefs
+
+import "fmt"
+
+// GF(2^8) arithmetic with the polynomial x^8 + x^4 + x^3 + x^2 + 1, the field
+// Reed-Solomon codes are commonly built over
+var (
+	gfExp [510]byte
+	gfLog [256]byte
+)
+
+func init() {
+	x := 1
+	for i := 0; i < 255; i++ {
+		gfExp[i] = byte(x)
+		gfLog[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < len(gfExp); i++ {
+		gfExp[i] = gfExp[i-255]
+	}
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfInv(a byte) byte {
+	return gfExp[255-int(gfLog[a])]
+}
+
+// coder is a systematic Reed-Solomon code: the first k fragments are the data
+// split in k, the rest are parity. Any k fragments recover the data
+type coder struct {
+	k, n int
+	// matrix has a row for each fragment, the identity for data fragments
+	// followed by rows of a cauchy matrix for parity. every k×k submatrix is
+	// invertible
+	matrix [][]byte
+}
+
+func newCoder(k, n int) (*coder, error) {
+	if k < 1 || n < k || n > 256 {
+		return nil, fmt.Errorf("invalid erasure code %d-of-%d", k, n)
+	}
+	c := &coder{k: k, n: n, matrix: make([][]byte, n)}
+	for r := 0; r < n; r++ {
+		c.matrix[r] = make([]byte, k)
+		if r < k {
+			c.matrix[r][r] = 1
+			continue
+		}
+		for col := 0; col < k; col++ {
+			// r & col are distinct field elements, so their sum is never zero
+			c.matrix[r][col] = gfInv(byte(r) ^ byte(col))
+		}
+	}
+	return c, nil
+}
+
+// fragmentSize is the length of each fragment of size bytes of data
+func (c *coder) fragmentSize(size int) int {
+	if size == 0 {
+		return 1
+	}
+	return (size + c.k - 1) / c.k
+}
+
+// encode splits data into n fragments
+func (c *coder) encode(data []byte) [][]byte {
+	size := c.fragmentSize(len(data))
+	padded := make([]byte, size*c.k)
+	copy(padded, data)
+
+	frags := make([][]byte, c.n)
+	for i := 0; i < c.k; i++ {
+		frags[i] = padded[i*size : (i+1)*size]
+	}
+	for r := c.k; r < c.n; r++ {
+		frags[r] = make([]byte, size)
+		mulRow(frags[r], c.matrix[r], frags[:c.k])
+	}
+	return frags
+}
+
+// decode reconstructs size bytes of data from fragments. nil fragments are
+// missing, at least k must be present
+func (c *coder) decode(frags [][]byte, size int) ([]byte, error) {
+	rows := make([]int, 0, c.k)
+	for i, f := range frags {
+		if f != nil && len(rows) < c.k {
+			rows = append(rows, i)
+		}
+	}
+	if len(rows) < c.k {
+		return nil, fmt.Errorf("%d of %d fragments are needed to decode, have %d", c.k, c.n, len(rows))
+	}
+
+	data := make([][]byte, c.k)
+	if rows[c.k-1] == c.k-1 {
+		// every data fragment is present
+		copy(data, frags[:c.k])
+	} else {
+		m := make([][]byte, c.k)
+		for i, r := range rows {
+			m[i] = c.matrix[r]
+		}
+		inv, err := invert(m)
+		if err != nil {
+			return nil, err
+		}
+		have := make([][]byte, c.k)
+		for i, r := range rows {
+			have[i] = frags[r]
+		}
+		for i := range data {
+			data[i] = make([]byte, len(have[0]))
+			mulRow(data[i], inv[i], have)
+		}
+	}
+
+	out := make([]byte, 0, len(data[0])*c.k)
+	for _, d := range data {
+		out = append(out, d...)
+	}
+	if size > len(out) {
+		return nil, fmt.Errorf("fragments hold %d bytes, expected %d", len(out), size)
+	}
+	return out[:size], nil
+}
+
+// mulRow sets dst to the sum of srcs weighted by coefficients
+func mulRow(dst, coefficients []byte, srcs [][]byte) {
+	for i, coef := range coefficients {
+		if coef == 0 {
+			continue
+		}
+		for j, b := range srcs[i] {
+			dst[j] ^= gfMul(coef, b)
+		}
+	}
+}
+
+// invert returns the inverse of square matrix m by gauss-jordan elimination
+func invert(m [][]byte) ([][]byte, error) {
+	n := len(m)
+	work := make([][]byte, n)
+	for i := range m {
+		work[i] = make([]byte, 2*n)
+		copy(work[i], m[i])
+		work[i][n+i] = 1
+	}
+
+	for col := 0; col < n; col++ {
+		pivot := col
+		for pivot < n && work[pivot][col] == 0 {
+			pivot++
+		}
+		if pivot == n {
+			return nil, fmt.Errorf("erasure matrix is singular")
+		}
+		work[col], work[pivot] = work[pivot], work[col]
+
+		scale := gfInv(work[col][col])
+		for j := range work[col] {
+			work[col][j] = gfMul(work[col][j], scale)
+		}
+		for r := 0; r < n; r++ {
+			if r == col || work[r][col] == 0 {
+				continue
+			}
+			f := work[r][col]
+			for j := range work[r] {
+				work[r][j] ^= gfMul(f, work[col][j])
+			}
+		}
+	}
+
+	inv := make([][]byte, n)
+	for i := range work {
+		inv[i] = work[i][n:]
+	}
+	return inv, nil
+}
@@ -0,0 +1,327 @@
+// Package erasurefs stores blocks across several content-addressed stores
+// with erasure coding. Each block is split into fragments, one per store, any
+// K of which rebuild the block, so content survives the loss of all but K
+// stores for a fraction of the space mirroring would take. erasurefs is
+// experimental
+package erasurefs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"io/ioutil"
+	"strings"
+	"sync"
+
+	cid "github.com/ipfs/go-cid"
+	multihash "github.com/multiformats/go-multihash"
+	"github.com/qri-io/qfs"
+)
+
+// FilestoreType uniquely identifies this filestore
+const FilestoreType = "erasure"
+
+// Stripe records the fragments a block is stored as
+type Stripe struct {
+	// Size is the length of the block in bytes
+	Size int64 `json:"size"`
+	// Fragments lists the identifier of each fragment, in the order of the
+	// stores they're written to. Fragments that weren't written are cid.Undef
+	Fragments []cid.Cid `json:"fragments"`
+}
+
+// Index maps block identifiers to their stripes. Fragments can't be found
+// without the index, so indexes should be kept at least as durably as the
+// stores
+type Index interface {
+	// GetStripe returns the stripe for id, or an error wrapping
+	// qfs.ErrNotFound
+	GetStripe(id cid.Cid) (Stripe, error)
+	PutStripe(id cid.Cid, s Stripe) error
+}
+
+// MemIndex is an Index held in memory. Content written to an FS using a
+// MemIndex can't be found once the process exits
+type MemIndex struct {
+	lk      sync.RWMutex
+	stripes map[string]Stripe
+}
+
+var _ Index = (*MemIndex)(nil)
+
+// NewMemIndex creates an empty in-memory index
+func NewMemIndex() *MemIndex {
+	return &MemIndex{stripes: map[string]Stripe{}}
+}
+
+// GetStripe implements the Index interface
+func (idx *MemIndex) GetStripe(id cid.Cid) (Stripe, error) {
+	idx.lk.RLock()
+	defer idx.lk.RUnlock()
+	s, ok := idx.stripes[string(id.Hash())]
+	if !ok {
+		return s, qfs.ErrNotFound
+	}
+	return s, nil
+}
+
+// PutStripe implements the Index interface
+func (idx *MemIndex) PutStripe(id cid.Cid, s Stripe) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+	idx.stripes[string(id.Hash())] = s
+	return nil
+}
+
+// Config adjusts the behaviour of an FS instance
+type Config struct {
+	// Index records the fragments of each block. Defaults to a ManifestIndex
+	// persisted to the stores, loaded from ManifestRoot
+	Index Index
+	// ManifestRoot is the root of the manifest the default index is loaded
+	// from. cid.Undef starts an empty index
+	ManifestRoot cid.Cid
+	// Prefix describes how block identifiers are derived. No single store
+	// holds a whole block, so the FS hashes blocks itself
+	Prefix cid.Prefix
+}
+
+// Option is a function type for passing to New
+type Option func(cfg *Config)
+
+// OptionIndex sets the index stripes are recorded in
+func OptionIndex(idx Index) Option {
+	return func(cfg *Config) {
+		cfg.Index = idx
+	}
+}
+
+// OptionManifestRoot loads the default index from the manifest at root, as
+// returned by ManifestIndex.Root
+func OptionManifestRoot(root cid.Cid) Option {
+	return func(cfg *Config) {
+		cfg.ManifestRoot = root
+	}
+}
+
+// OptionPrefix sets the prefix blocks are hashed with
+func OptionPrefix(p cid.Prefix) Option {
+	return func(cfg *Config) {
+		cfg.Prefix = p
+	}
+}
+
+// DefaultConfig hashes blocks with sha2-256, recording stripes in a new
+// manifest index
+func DefaultConfig() *Config {
+	return &Config{
+		Prefix: cid.Prefix{
+			Version:  1,
+			Codec:    cid.Raw,
+			MhType:   multihash.SHA2_256,
+			MhLength: -1,
+		},
+	}
+}
+
+// FS is a qfs.MerkleDagStore that erasure codes blocks & files across stores.
+// Writes succeed if at least K stores accept their fragment. Reads fetch
+// fragments until K arrive, skipping stores that fail. Nodes are small, so
+// they're mirrored to every store instead
+type FS struct {
+	cfg    *Config
+	stores []qfs.MerkleDagStore
+	coder  *coder
+}
+
+var _ qfs.MerkleDagStore = (*FS)(nil)
+
+// New creates a store that splits blocks across stores, any k of which can
+// rebuild them. Stores must stay in the same order for fragments to be found
+func New(stores []qfs.MerkleDagStore, k int, opts ...Option) (*FS, error) {
+	c, err := newCoder(k, len(stores))
+	if err != nil {
+		return nil, err
+	}
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.Index == nil {
+		if cfg.Index, err = LoadManifestIndex(stores, k, cfg.ManifestRoot); err != nil {
+			return nil, err
+		}
+	}
+	return &FS{cfg: cfg, stores: append([]qfs.MerkleDagStore(nil), stores...), coder: c}, nil
+}
+
+// Index returns the index stripes are recorded in
+func (efs *FS) Index() Index {
+	return efs.cfg.Index
+}
+
+// Type distinguishes this filesystem from others by a unique string prefix
+func (efs *FS) Type() string {
+	return FilestoreType
+}
+
+// PutBlock erasure codes a block across the stores
+func (efs *FS) PutBlock(d []byte) (cid.Cid, error) {
+	id, err := efs.cfg.Prefix.Sum(d)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err := efs.putStripe(id, d); err != nil {
+		return cid.Undef, err
+	}
+	return id, nil
+}
+
+// putStripe writes the fragments of data, recording them in the index
+func (efs *FS) putStripe(id cid.Cid, data []byte) error {
+	s := Stripe{Size: int64(len(data)), Fragments: make([]cid.Cid, len(efs.stores))}
+	var errs []string
+	written := 0
+	for i, frag := range efs.coder.encode(data) {
+		fid, err := efs.stores[i].PutBlock(frag)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("store %d: %s", i, err))
+			continue
+		}
+		s.Fragments[i] = fid
+		written++
+	}
+	if written < efs.coder.k {
+		return fmt.Errorf("writing %s: %d of %d fragments are needed, wrote %d: %s", id, efs.coder.k, len(efs.stores), written, strings.Join(errs, ", "))
+	}
+	return efs.cfg.Index.PutStripe(id, s)
+}
+
+// GetBlock rebuilds a block from its fragments
+func (efs *FS) GetBlock(id cid.Cid) (io.Reader, error) {
+	data, err := efs.getStripe(id)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
+
+// getStripe reads fragments of id until there are enough to decode it
+func (efs *FS) getStripe(id cid.Cid) ([]byte, error) {
+	s, err := efs.cfg.Index.GetStripe(id)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Fragments) != len(efs.stores) {
+		return nil, fmt.Errorf("stripe for %s has %d fragments, expected %d", id, len(s.Fragments), len(efs.stores))
+	}
+
+	frags := make([][]byte, len(efs.stores))
+	var errs []string
+	have := 0
+	for i, fid := range s.Fragments {
+		if have == efs.coder.k {
+			break
+		}
+		if !fid.Defined() {
+			continue
+		}
+		frag, err := qfs.GetBlockBytes(efs.stores[i], fid)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("store %d: %s", i, err))
+			continue
+		}
+		frags[i] = frag
+		have++
+	}
+	if have < efs.coder.k {
+		return nil, fmt.Errorf("%w: reading %s: %d of %d fragments are needed, read %d: %s", qfs.ErrUnavailable, id, efs.coder.k, len(efs.stores), have, strings.Join(errs, ", "))
+	}
+
+	data, err := efs.coder.decode(frags, int(s.Size))
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", id, err)
+	}
+	got, err := id.Prefix().Sum(data)
+	if err != nil {
+		return nil, err
+	}
+	if string(got.Hash()) != string(id.Hash()) {
+		return nil, fmt.Errorf("decoded %s doesn't match its hash %s", id, got)
+	}
+	return data, nil
+}
+
+// PutNode mirrors a node to every store
+func (efs *FS) PutNode(links qfs.Links) (res qfs.PutResult, err error) {
+	var errs []string
+	written := 0
+	for i, s := range efs.stores {
+		r, err := s.PutNode(links)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("store %d: %s", i, err))
+			continue
+		}
+		if written > 0 && !r.Cid.Equals(res.Cid) {
+			return res, fmt.Errorf("store %d identified node as %s, expected %s", i, r.Cid, res.Cid)
+		}
+		res = r
+		written++
+	}
+	if written < efs.coder.k {
+		return res, fmt.Errorf("writing node: %d of %d copies are needed, wrote %d: %s", efs.coder.k, len(efs.stores), written, strings.Join(errs, ", "))
+	}
+	return res, nil
+}
+
+// GetNode fetches a node from the first store that has it. Nodes beneath id
+// can be reached by path, files can't
+func (efs *FS) GetNode(id cid.Cid, path ...string) (node qfs.DagNode, err error) {
+	for _, s := range efs.stores {
+		if node, err = s.GetNode(id, path...); err == nil {
+			return node, nil
+		}
+	}
+	return nil, err
+}
+
+// PutFile reads a file into memory & erasure codes it as a single block
+func (efs *FS) PutFile(f iofs.File) (qfs.PutResult, error) {
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	if err := f.Close(); err != nil {
+		return qfs.PutResult{}, err
+	}
+	id, err := efs.PutBlock(data)
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	return qfs.PutResult{Cid: id, Size: int64(len(data))}, nil
+}
+
+// GetFile rebuilds a file. path follows links of mirrored nodes from root to
+// the file
+func (efs *FS) GetFile(root cid.Cid, path ...string) (io.ReadCloser, error) {
+	id := root
+	if len(path) > 0 {
+		parts := strings.Split(strings.Trim(strings.Join(path, "/"), "/"), "/")
+		node, err := efs.GetNode(root, parts[:len(parts)-1]...)
+		if err != nil {
+			return nil, err
+		}
+		l := node.Links().Get(parts[len(parts)-1])
+		if l == nil {
+			return nil, qfs.ErrNotFound
+		}
+		id = l.Cid
+	}
+
+	data, err := efs.getStripe(id)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
@@ -0,0 +1,201 @@
+package erasurefs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"io/ioutil"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/qri-io/qfs"
+)
+
+func TestCoder(t *testing.T) {
+	c, err := newCoder(3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 2, 3, 100, 1001} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		frags := c.encode(data)
+
+		// drop every pair of fragments
+		for a := 0; a < c.n; a++ {
+			for b := a + 1; b < c.n; b++ {
+				have := make([][]byte, c.n)
+				copy(have, frags)
+				have[a], have[b] = nil, nil
+				got, err := c.decode(have, size)
+				if err != nil {
+					t.Fatalf("size %d missing %d & %d: %s", size, a, b, err)
+				}
+				if !bytes.Equal(data, got) {
+					t.Errorf("size %d missing %d & %d: decoded data mismatch", size, a, b)
+				}
+			}
+		}
+	}
+
+	frags := c.encode([]byte("too few"))
+	if _, err := c.decode([][]byte{frags[0], nil, nil, nil, frags[4]}, 7); err == nil {
+		t.Error("expected decoding from fewer than k fragments to fail")
+	}
+}
+
+func TestErasureBlocks(t *testing.T) {
+	stores := []qfs.MerkleDagStore{qfs.NewMemFS(), qfs.NewMemFS(), qfs.NewMemFS(), qfs.NewMemFS()}
+	efs, err := New(stores, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := bytes.Repeat([]byte("erasure coded "), 100)
+	id, err := efs.PutBlock(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each store holds a fragment & a copy of the index manifest
+	for i, s := range stores {
+		if n := s.(*qfs.MemFS).ObjectCount(); n != 2 {
+			t.Errorf("expected store %d to hold a fragment & the index. got %d objects", i, n)
+		}
+	}
+
+	// lose two stores, including one holding data fragments
+	stores[0] = downStore{}
+	stores[3] = downStore{}
+	efs, err = New(stores, 2, OptionIndex(efs.cfg.Index))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data2, err := qfs.GetBlockBytes(efs, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, data2) {
+		t.Error("rebuilt block mismatch")
+	}
+
+	// writes need k stores
+	if _, err := efs.PutBlock([]byte("two of four")); err != nil {
+		t.Errorf("expected write to two of four stores to succeed. got: %v", err)
+	}
+	stores[1] = downStore{}
+	if efs, err = New(stores, 2, OptionIndex(efs.cfg.Index)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := efs.GetBlock(id); !errors.Is(err, qfs.ErrUnavailable) {
+		t.Errorf("expected reading with fewer than k stores to return ErrUnavailable. got: %v", err)
+	}
+	if _, err := efs.PutBlock([]byte("one of four")); err == nil {
+		t.Error("expected write to fewer than k stores to fail")
+	}
+
+	if _, err := efs.GetBlock(mustCid(t, "missing")); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected missing block to return ErrNotFound. got: %v", err)
+	}
+}
+
+func TestErasureFiles(t *testing.T) {
+	stores := []qfs.MerkleDagStore{qfs.NewMemFS(), qfs.NewMemFS(), qfs.NewMemFS()}
+	efs, err := New(stores, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := efs.PutFile(qfs.NewMemfileBytes("a.txt", []byte("file a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := efs.PutNode(qfs.NewLinks(file.ToLink("a.txt", true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores[0] = downStore{}
+	if efs, err = New(stores, 2, OptionIndex(efs.cfg.Index)); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range [][]string{nil, {"a.txt"}} {
+		root := file.Cid
+		if path != nil {
+			root = dir.Cid
+		}
+		rc, err := efs.GetFile(root, path...)
+		if err != nil {
+			t.Fatalf("path %v: %s", path, err)
+		}
+		data, _ := ioutil.ReadAll(rc)
+		if string(data) != "file a" {
+			t.Errorf("path %v: contents mismatch. got: %q", path, string(data))
+		}
+	}
+	if _, err := efs.GetFile(dir.Cid, "b.txt"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected missing file to return ErrNotFound. got: %v", err)
+	}
+}
+
+func TestManifestIndex(t *testing.T) {
+	stores := []qfs.MerkleDagStore{qfs.NewMemFS(), qfs.NewMemFS(), qfs.NewMemFS()}
+	efs, err := New(stores, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte("indexed in the stores")
+	id, err := efs.PutBlock(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := efs.Index().(*ManifestIndex).Root()
+	if !root.Defined() {
+		t.Fatal("expected writing a block to write an index manifest")
+	}
+
+	// reopen the index from the surviving stores
+	stores[0] = downStore{}
+	efs, err = New(stores, 2, OptionManifestRoot(root))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := qfs.GetBlockBytes(efs, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, got) {
+		t.Error("rebuilt block mismatch")
+	}
+
+	if _, err := New(stores, 2, OptionManifestRoot(mustCid(t, "missing"))); err == nil {
+		t.Error("expected loading a missing manifest to fail")
+	}
+}
+
+func mustCid(t *testing.T, s string) cid.Cid {
+	t.Helper()
+	id, err := DefaultConfig().Prefix.Sum([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// downStore is a store that can't be reached
+type downStore struct{}
+
+var errDown = fmt.Errorf("%w: store is down", qfs.ErrUnavailable)
+
+func (downStore) Type() string { return "down" }
+func (downStore) GetNode(cid.Cid, ...string) (qfs.DagNode, error) {
+	return nil, errDown
+}
+func (downStore) PutNode(qfs.Links) (qfs.PutResult, error)          { return qfs.PutResult{}, errDown }
+func (downStore) GetBlock(cid.Cid) (io.Reader, error)               { return nil, errDown }
+func (downStore) PutBlock([]byte) (cid.Cid, error)                  { return cid.Undef, errDown }
+func (downStore) PutFile(iofs.File) (qfs.PutResult, error)          { return qfs.PutResult{}, errDown }
+func (downStore) GetFile(cid.Cid, ...string) (io.ReadCloser, error) { return nil, errDown }
@@ -0,0 +1,153 @@
+package erasurefs
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/qri-io/qfs"
+)
+
+// ManifestIndex is an Index persisted to the stores it indexes. Stripes are
+// written as a manifest block mirrored to every store, rewritten on every
+// PutStripe, so the index survives the loss of all but K stores like the
+// blocks it indexes. Root returns the identifier of the latest manifest,
+// which must be recorded to reopen the index with LoadManifestIndex or
+// OptionManifestRoot. Earlier manifests are left in the stores
+type ManifestIndex struct {
+	stores []qfs.MerkleDagStore
+	k      int
+
+	lk      sync.RWMutex
+	root    cid.Cid
+	stripes map[string]manifestEntry
+}
+
+// manifestEntry is the manifest record of a block's stripe
+type manifestEntry struct {
+	Cid    cid.Cid `json:"cid"`
+	Stripe Stripe  `json:"stripe"`
+}
+
+var _ Index = (*ManifestIndex)(nil)
+
+// NewManifestIndex creates an empty index persisted to stores. Manifests must
+// be written to at least k stores
+func NewManifestIndex(stores []qfs.MerkleDagStore, k int) *ManifestIndex {
+	return &ManifestIndex{
+		stores:  append([]qfs.MerkleDagStore(nil), stores...),
+		k:       k,
+		stripes: map[string]manifestEntry{},
+	}
+}
+
+// LoadManifestIndex reads the manifest root from the first store that has it.
+// A root of cid.Undef creates an empty index
+func LoadManifestIndex(stores []qfs.MerkleDagStore, k int, root cid.Cid) (*ManifestIndex, error) {
+	idx := NewManifestIndex(stores, k)
+	if !root.Defined() {
+		return idx, nil
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	for _, s := range idx.stores {
+		if data, err = qfs.GetBlockBytes(s, root); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading index manifest %s: %w", root, err)
+	}
+	var entries []manifestEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("decoding index manifest %s: %w", root, err)
+	}
+	for _, e := range entries {
+		idx.stripes[string(e.Cid.Hash())] = e
+	}
+	idx.root = root
+	return idx, nil
+}
+
+// Root returns the identifier of the latest manifest, cid.Undef if nothing has
+// been written
+func (idx *ManifestIndex) Root() cid.Cid {
+	idx.lk.RLock()
+	defer idx.lk.RUnlock()
+	return idx.root
+}
+
+// GetStripe implements the Index interface
+func (idx *ManifestIndex) GetStripe(id cid.Cid) (Stripe, error) {
+	idx.lk.RLock()
+	defer idx.lk.RUnlock()
+	e, ok := idx.stripes[string(id.Hash())]
+	if !ok {
+		return Stripe{}, qfs.ErrNotFound
+	}
+	return e.Stripe, nil
+}
+
+// PutStripe implements the Index interface, writing a new manifest
+func (idx *ManifestIndex) PutStripe(id cid.Cid, s Stripe) error {
+	idx.lk.Lock()
+	defer idx.lk.Unlock()
+
+	key := string(id.Hash())
+	prev, existed := idx.stripes[key]
+	idx.stripes[key] = manifestEntry{Cid: id, Stripe: s}
+	if err := idx.write(); err != nil {
+		if existed {
+			idx.stripes[key] = prev
+		} else {
+			delete(idx.stripes, key)
+		}
+		return err
+	}
+	return nil
+}
+
+// write mirrors the manifest to every store. idx.lk must be held
+func (idx *ManifestIndex) write() error {
+	entries := make([]manifestEntry, 0, len(idx.stripes))
+	for _, e := range idx.stripes {
+		entries = append(entries, e)
+	}
+	// sort for a stable manifest identifier
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Cid.KeyString() < entries[j].Cid.KeyString()
+	})
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+
+	var (
+		root    cid.Cid
+		errs    []string
+		written int
+	)
+	for i, s := range idx.stores {
+		id, err := s.PutBlock(data)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("store %d: %s", i, err))
+			continue
+		}
+		if written > 0 && !id.Equals(root) {
+			return fmt.Errorf("store %d identified index manifest as %s, expected %s", i, id, root)
+		}
+		root = id
+		written++
+	}
+	if written < idx.k {
+		return fmt.Errorf("writing index manifest: %d of %d copies are needed, wrote %d: %s", idx.k, len(idx.stores), written, strings.Join(errs, ", "))
+	}
+	idx.root = root
+	return nil
+}
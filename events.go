@@ -0,0 +1,130 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// EventType names a kind of filesystem mutation
+type EventType string
+
+// Event types emitted by filesystems that implement EventPublisher
+const (
+	EventFilePut     EventType = "FilePut"
+	EventFileDeleted EventType = "FileDeleted"
+	EventPinned      EventType = "Pinned"
+	EventUnpinned    EventType = "Unpinned"
+)
+
+// Event describes a completed mutation of a filesystem
+type Event struct {
+	Type EventType
+	// FSType is the Type of the filesystem that was mutated
+	FSType string
+	// Path is the path the mutation affected. For puts it's the path Put
+	// returned
+	Path string
+	Time time.Time
+}
+
+// EventHandler is called with each event a filesystem emits
+type EventHandler func(e Event)
+
+// EventPublisher is an opt-in interface for filesystems that emit an Event
+// after each successful mutation
+type EventPublisher interface {
+	// Subscribe calls fn with every event emitted until the returned
+	// unsubscribe function is called. Handlers are called synchronously by the
+	// goroutine that performed the mutation, so they should return quickly
+	Subscribe(fn EventHandler) (unsubscribe func())
+}
+
+// EventBus delivers events to subscribers, filesystems use an EventBus to
+// implement EventPublisher. The zero value is ready to use. EventBus is safe
+// for concurrent use
+type EventBus struct {
+	// Clock stamps events published without a time, defaults to SystemClock
+	Clock Clock
+
+	lk   sync.Mutex
+	id   int
+	subs map[int]EventHandler
+}
+
+var _ EventPublisher = (*EventBus)(nil)
+
+// Subscribe adds a handler, implementing EventPublisher
+func (b *EventBus) Subscribe(fn EventHandler) func() {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+	if b.subs == nil {
+		b.subs = map[int]EventHandler{}
+	}
+	b.id++
+	id := b.id
+	b.subs[id] = fn
+	return func() {
+		b.lk.Lock()
+		defer b.lk.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish calls every subscribed handler with e. Events without a time are
+// stamped with the current time from the bus Clock
+func (b *EventBus) Publish(e Event) {
+	if e.Time.IsZero() {
+		if b.Clock != nil {
+			e.Time = b.Clock.Now()
+		} else {
+			e.Time = time.Now()
+		}
+	}
+	b.lk.Lock()
+	handlers := make([]EventHandler, 0, len(b.subs))
+	for _, fn := range b.subs {
+		handlers = append(handlers, fn)
+	}
+	b.lk.Unlock()
+
+	for _, fn := range handlers {
+		fn(e)
+	}
+}
+
+// Events delivers events emitted by fs on a channel, which is closed once ctx
+// is cancelled. Sends block until the event is received, so a slow consumer
+// slows the mutations it's watching. Filesystems that don't implement
+// EventPublisher return an error that wraps ErrUnsupported
+func Events(ctx context.Context, fs Filesystem, buffer int) (<-chan Event, error) {
+	pub, ok := fs.(EventPublisher)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s filesystem doesn't publish events", ErrUnsupported, fs.Type())
+	}
+
+	// handlers hold a read lock while sending, so the channel can't be closed
+	// mid-send
+	var lk sync.RWMutex
+	ch := make(chan Event, buffer)
+	unsubscribe := pub.Subscribe(func(e Event) {
+		lk.RLock()
+		defer lk.RUnlock()
+		if ctx.Err() != nil {
+			return
+		}
+		select {
+		case ch <- e:
+		case <-ctx.Done():
+		}
+	})
+	go func() {
+		<-ctx.Done()
+		unsubscribe()
+		lk.Lock()
+		close(ch)
+		lk.Unlock()
+	}()
+	return ch, nil
+}
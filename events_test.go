@@ -0,0 +1,82 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	fs := NewMemFS()
+
+	var got []Event
+	unsubscribe := fs.Subscribe(func(e Event) {
+		got = append(got, e)
+	})
+
+	path, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Delete(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []Event{
+		{Type: EventFilePut, FSType: MemFilestoreType, Path: path},
+		{Type: EventFileDeleted, FSType: MemFilestoreType, Path: path},
+	}
+	if len(got) != len(expect) {
+		t.Fatalf("event count mismatch. want: %d got: %d", len(expect), len(got))
+	}
+	for i, e := range expect {
+		if got[i].Type != e.Type || got[i].FSType != e.FSType || got[i].Path != e.Path {
+			t.Errorf("event %d mismatch. want: %v got: %v", i, e, got[i])
+		}
+		if got[i].Time.IsZero() {
+			t.Errorf("event %d: expected time to be set", i)
+		}
+	}
+
+	unsubscribe()
+	if _, err := fs.Put(ctx, NewMemfileBytes("b.txt", []byte("world"))); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(expect) {
+		t.Errorf("expected no events after unsubscribing. got: %d", len(got)-len(expect))
+	}
+
+	// a failed put emits nothing
+	fs.SetFaults(MemFaults{FailPutEvery: 1})
+	fs.Put(ctx, NewMemfileBytes("c.txt", []byte("!")))
+	if len(got) != len(expect) {
+		t.Errorf("expected failed puts to emit no events")
+	}
+}
+
+func TestEventsChan(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fs := NewMemFS()
+	events, err := Events(ctx, fs, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := <-events; e.Type != EventFilePut || e.Path != path {
+		t.Errorf("event mismatch. got: %v", e)
+	}
+
+	cancel()
+	for range events {
+	}
+
+	if _, err := Events(ctx, Subtree(fs, path), 0); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported for a filesystem that doesn't publish events. got: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package qfs
+
+import (
+	"context"
+	"time"
+)
+
+// FetchHints suggest where & how a fetch should find content
+type FetchHints struct {
+	// Peers are multiaddrs of peers likely to have the content, eg:
+	// "/ip4/1.2.3.4/tcp/4001/p2p/QmPeer"
+	Peers []string
+	// Gateways are base URLs of HTTP gateways likely to have the content
+	Gateways []string
+	// Deadline bounds the fetch. The zero time leaves the context deadline
+	// unchanged
+	Deadline time.Time
+}
+
+// FetchHint is a function type for passing to Fetch
+type FetchHint func(h *FetchHints)
+
+// HintPeer suggests a peer, by multiaddr, that has the content
+func HintPeer(addr string) FetchHint {
+	return func(h *FetchHints) {
+		h.Peers = append(h.Peers, addr)
+	}
+}
+
+// HintGateway suggests an HTTP gateway, by base URL, that has the content
+func HintGateway(url string) FetchHint {
+	return func(h *FetchHints) {
+		h.Gateways = append(h.Gateways, url)
+	}
+}
+
+// HintDeadline bounds how long a fetch may take
+func HintDeadline(t time.Time) FetchHint {
+	return func(h *FetchHints) {
+		h.Deadline = t
+	}
+}
+
+// ApplyFetchHints collects hints into a FetchHints
+func ApplyFetchHints(hints ...FetchHint) FetchHints {
+	h := FetchHints{}
+	for _, hint := range hints {
+		hint(&h)
+	}
+	return h
+}
+
+// FetcherFS is an opt-in interface for filesystems that retrieve content from
+// other sources, like peers on a network, and can use hints about where to
+// find it. Hints are advisory, filesystems ignore hints they can't use
+type FetcherFS interface {
+	Filesystem
+	Fetch(ctx context.Context, path string, hints ...FetchHint) (File, error)
+}
+
+// Fetch gets path from fs using hints. Filesystems that don't implement
+// FetcherFS honor the deadline hint only, which bounds the call to Get &
+// reads of the returned file
+func Fetch(ctx context.Context, fs Filesystem, path string, hints ...FetchHint) (File, error) {
+	if ffs, ok := fs.(FetcherFS); ok {
+		return ffs.Fetch(ctx, path, hints...)
+	}
+	h := ApplyFetchHints(hints...)
+	if h.Deadline.IsZero() {
+		return fs.Get(ctx, path)
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Until(h.Deadline))
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return FileWithCancel(f, cancel), nil
+}
+
+// FileWithCancel wraps f so closing it calls cancel, for files that read
+// with a context that must stay alive until the file is closed
+func FileWithCancel(f File, cancel context.CancelFunc) File {
+	return &cancelFile{File: f, cancel: cancel}
+}
+
+// cancelFile cancels a context when it's closed
+type cancelFile struct {
+	File
+	cancel context.CancelFunc
+}
+
+var _ SizeFile = (*cancelFile)(nil)
+
+// Close closes the file, then cancels its context
+func (f *cancelFile) Close() error {
+	defer f.cancel()
+	return f.File.Close()
+}
+
+// Size returns the size of the wrapped file, -1 if it's unknown
+func (f *cancelFile) Size() int64 {
+	if sf, ok := f.File.(SizeFile); ok {
+		return sf.Size()
+	}
+	return -1
+}
@@ -0,0 +1,95 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFetch(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	path, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Fetch(ctx, fs, path, HintPeer("/ip4/127.0.0.1/tcp/4001"), HintDeadline(time.Now().Add(time.Second)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "hello" {
+		t.Errorf("contents mismatch. want: %q got: %q", "hello", s)
+	}
+
+	hfs := &hintFS{Filesystem: fs}
+	_, err = Fetch(ctx, hfs, path, HintPeer("/ip4/1.2.3.4/tcp/4001/p2p/QmPeer"), HintGateway("https://ipfs.io"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hfs.hints.Peers) != 1 || len(hfs.hints.Gateways) != 1 {
+		t.Errorf("expected hints to be passed to FetcherFS implementations. got: %#v", hfs.hints)
+	}
+
+	deadline := time.Now().Add(-time.Second)
+	if _, err := Fetch(ctx, blockingFS{fs}, path, HintDeadline(deadline)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a passed deadline to end the fetch. got: %v", err)
+	}
+}
+
+// hintFS records the hints passed to Fetch
+type hintFS struct {
+	Filesystem
+	hints FetchHints
+}
+
+func (h *hintFS) Fetch(ctx context.Context, path string, hints ...FetchHint) (File, error) {
+	h.hints = ApplyFetchHints(hints...)
+	return h.Get(ctx, path)
+}
+
+// blockingFS waits for the context to be done on Get
+type blockingFS struct {
+	Filesystem
+}
+
+func (blockingFS) Get(ctx context.Context, path string) (File, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestFetchDeadlineOutlivesGet(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	path, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfs := &ctxFS{Filesystem: fs}
+	f, err := Fetch(ctx, cfs, path, HintDeadline(time.Now().Add(time.Minute)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfs.ctx.Err(); err != nil {
+		t.Errorf("expected the fetch context to stay alive until the file is closed. got: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfs.ctx.Err(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected closing the file to release the fetch context. got: %v", err)
+	}
+}
+
+// ctxFS records the context passed to Get
+type ctxFS struct {
+	Filesystem
+	ctx context.Context
+}
+
+func (c *ctxFS) Get(ctx context.Context, path string) (File, error) {
+	c.ctx = ctx
+	return c.Filesystem.Get(ctx, path)
+}
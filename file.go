@@ -5,10 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"io/ioutil"
 	"mime"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -17,6 +19,11 @@ var (
 	ErrNotDirectory = errors.New("file is not a directory")
 	// ErrNotFile is the result of attempting to perform "file like" operations on a directory
 	ErrNotFile = errors.New("file is a directory")
+	// ErrIsDirectory is an alias of ErrNotFile, errors.Is matches either
+	ErrIsDirectory = ErrNotFile
+	// ErrNotSeekable is returned by Seek when a file's backing reader doesn't
+	// support seeking
+	ErrNotSeekable = errors.New("file is not seekable")
 )
 
 // File is an interface that provides functionality for handling
@@ -65,31 +72,201 @@ type SizeFile interface {
 	Size() int64
 }
 
+// SeekFile is an opt-in interface for files that support random access.
+// Implementations backed by readers that may or may not be seekable must
+// return ErrNotSeekable from Seek when seeking isn't possible
+type SeekFile interface {
+	File
+	io.Seeker
+}
+
+// StatFile is an opt-in interface for files that can describe themselves
+// with an fs.FileInfo. Use the Stat function to get info for any File
+type StatFile interface {
+	File
+	Stat() (fs.FileInfo, error)
+}
+
+// MetadataFile is an opt-in interface for files that carry a permission mode
+// and user-defined attributes, like extended attributes on a local file.
+// Filesystems that can store metadata persist it on Put
+type MetadataFile interface {
+	File
+	// Mode returns the file's mode & permission bits
+	Mode() fs.FileMode
+	// Metadata returns user-defined attributes, nil if there are none. Callers
+	// must not modify the returned map
+	Metadata() map[string]string
+}
+
+// MetadataSetter adds the capacity to modify the mode & attributes of a file
+type MetadataSetter interface {
+	SetMode(mode fs.FileMode)
+	SetMetadata(meta map[string]string)
+}
+
+// Stat returns info describing f, calling f.Stat if f implements StatFile and
+// deriving info from the File interface with FileInfo if not
+func Stat(f File) (fs.FileInfo, error) {
+	if sf, ok := f.(StatFile); ok {
+		return sf.Stat()
+	}
+	return FileInfo(f), nil
+}
+
+// FileInfo builds an fs.FileInfo from methods on the File interface. Size is
+// taken from SizeFile if implemented, and is -1 if the size is unknown.
+// Directories always have a size of 0
+func FileInfo(f File) fs.FileInfo {
+	fi := &fileInfo{
+		name:    f.FileName(),
+		size:    -1,
+		mode:    0644,
+		modTime: f.ModTime(),
+	}
+	if f.IsDirectory() {
+		fi.size = 0
+		fi.mode = fs.ModeDir | 0755
+	} else if sf, ok := f.(SizeFile); ok {
+		fi.size = sf.Size()
+	}
+	if mf, ok := f.(MetadataFile); ok {
+		fi.mode = mf.Mode()
+	}
+	return fi
+}
+
+// fileInfo implements fs.FileInfo
+type fileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+var _ fs.FileInfo = (*fileInfo)(nil)
+
+func (fi *fileInfo) Name() string       { return fi.name }
+func (fi *fileInfo) Size() int64        { return fi.size }
+func (fi *fileInfo) Mode() fs.FileMode  { return fi.mode }
+func (fi *fileInfo) ModTime() time.Time { return fi.modTime }
+func (fi *fileInfo) IsDir() bool        { return fi.mode.IsDir() }
+func (fi *fileInfo) Sys() interface{}   { return nil }
+
 // PathSetter adds the capacity to modify a path property
 type PathSetter interface {
 	SetPath(path string)
 }
 
+// ModTimeSetter adds the capacity to modify a file's modification time. In
+// memory files are stamped with the time they're created, callers that need
+// deterministic timestamps set them from their own Clock
+type ModTimeSetter interface {
+	SetModTime(t time.Time)
+}
+
+// SkipDir can be returned by a WalkOpts visit function to skip the children of
+// a directory visited in pre-order. Returned when visiting a file, or a
+// directory visited in post-order, SkipDir skips the remaining entries of the
+// parent directory
+var SkipDir = errors.New("skip this directory")
+
+// WalkOptions configures WalkOpts
+type WalkOptions struct {
+	// MaxDepth limits how deep WalkOpts descends. The root has depth 0, its
+	// children depth 1 & so on. Directories at MaxDepth are visited, but
+	// their children are not. Zero means no limit
+	MaxDepth int
+	// PreOrder visits directories before their children. The default is
+	// post-order, visiting directories after all of their children
+	PreOrder bool
+}
+
 // Walk traverses a file tree from the bottom-up calling visit on each file
-// and directory within the tree
+// and directory within the tree. Symlinks are visited like files, they're
+// never followed
 func Walk(root File, visit func(f File) error) (err error) {
-	if root.IsDirectory() {
-		for {
-			f, err := root.NextFile()
-			if err != nil {
-				if err.Error() == "EOF" {
-					return visit(root)
-				} else {
-					return err
-				}
+	return WalkOpts(root, WalkOptions{}, func(f File, _ int) error {
+		return visit(f)
+	})
+}
+
+// WalkOpts traverses a file tree, calling visit on each file and directory
+// with its depth in the tree. Any error visit returns other than SkipDir
+// stops the walk and is returned by WalkOpts
+func WalkOpts(root File, opts WalkOptions, visit func(f File, depth int) error) error {
+	type frame struct {
+		dir   File
+		depth int
+		skip  bool // skip the remaining children of dir
+	}
+	// keep open directories on an explicit stack instead of recursing, so very
+	// deep trees can't exhaust the goroutine stack
+	var stack []*frame
+
+	// handle deals with a visit error, returning errors that end the walk.
+	// SkipDir marks the enclosing directory's remaining children as skipped
+	handle := func(err error) error {
+		if err == SkipDir {
+			if len(stack) > 0 {
+				stack[len(stack)-1].skip = true
 			}
+			return nil
+		}
+		return err
+	}
 
-			if err := Walk(f, visit); err != nil {
+	// enter visits files and pre-order directories, pushing directories that
+	// should be descended into onto the stack
+	enter := func(f File, depth int) error {
+		if _, link := IsSymlink(f); link || !f.IsDirectory() {
+			return handle(visit(f, depth))
+		}
+		descend := opts.MaxDepth == 0 || depth < opts.MaxDepth
+		if opts.PreOrder {
+			if err := visit(f, depth); err != nil {
+				if err == SkipDir {
+					return nil
+				}
 				return err
 			}
+		} else if !descend {
+			return handle(visit(f, depth))
+		}
+		if descend {
+			stack = append(stack, &frame{dir: f, depth: depth})
+		}
+		return nil
+	}
+
+	if err := enter(root, 0); err != nil {
+		return err
+	}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		var (
+			ch  File
+			err error
+		)
+		if top.skip {
+			err = io.EOF
+		} else {
+			ch, err = top.dir.NextFile()
+		}
+
+		if err == io.EOF {
+			stack = stack[:len(stack)-1]
+			if !opts.PreOrder {
+				if err := handle(visit(top.dir, top.depth)); err != nil {
+					return err
+				}
+			}
+			continue
+		} else if err != nil {
+			return err
 		}
-	} else {
-		if err := visit(root); err != nil {
+
+		if err := enter(ch, top.depth+1); err != nil {
 			return err
 		}
 	}
@@ -103,11 +280,19 @@ type Memfile struct {
 	buf     io.Reader
 	path    string
 	modTime time.Time
+	mode    fs.FileMode
+	meta    map[string]string
 }
 
 var (
-	_ File     = (*Memfile)(nil)
-	_ SizeFile = (*Memfile)(nil)
+	_ File           = (*Memfile)(nil)
+	_ SizeFile       = (*Memfile)(nil)
+	_ StatFile       = (*Memfile)(nil)
+	_ SeekFile       = (*Memfile)(nil)
+	_ MetadataFile   = (*Memfile)(nil)
+	_ MetadataSetter = (*Memfile)(nil)
+	_ ModTimeSetter  = (*Memfile)(nil)
+	_ io.WriterTo    = (*Memfile)(nil)
 )
 
 // NewMemfileReader creates a file from an io.Reader
@@ -125,11 +310,11 @@ func NewMemfileReaderSize(path string, r io.Reader, size int64) *Memfile {
 	}
 }
 
-// NewMemfileBytes creates a file from a byte slice
+// NewMemfileBytes creates a seekable file from a byte slice
 func NewMemfileBytes(path string, data []byte) *Memfile {
 	return &Memfile{
 		size:    int64(len(data)),
-		buf:     bytes.NewBuffer(data),
+		buf:     bytes.NewReader(data),
 		path:    path,
 		modTime: time.Now(),
 	}
@@ -140,6 +325,24 @@ func (m Memfile) Read(p []byte) (int, error) {
 	return m.buf.Read(p)
 }
 
+// WriteTo implements the io.WriterTo interface, writing directly from the
+// backing reader when it supports io.WriterTo
+func (m Memfile) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := m.buf.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, m.buf)
+}
+
+// Seek implements the io.Seeker interface, returning ErrNotSeekable if the
+// backing reader isn't an io.Seeker
+func (m Memfile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := m.buf.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, ErrNotSeekable
+}
+
 // Close closes the file, if the backing reader implements the io.Closer interface
 // it will call close on the backing Reader
 func (m Memfile) Close() error {
@@ -184,21 +387,65 @@ func (m Memfile) ModTime() time.Time {
 	return m.modTime
 }
 
+// SetModTime implements the ModTimeSetter interface
+func (m *Memfile) SetModTime(t time.Time) {
+	m.modTime = t
+}
+
 func (m Memfile) Size() int64 {
 	return m.size
 }
 
+// Mode returns the file's mode, 0644 unless set with SetMode
+func (m Memfile) Mode() fs.FileMode {
+	if m.mode == 0 {
+		return 0644
+	}
+	return m.mode
+}
+
+// SetMode implements the MetadataSetter interface
+func (m *Memfile) SetMode(mode fs.FileMode) {
+	m.mode = mode
+}
+
+// Metadata returns attributes set with SetMetadata
+func (m Memfile) Metadata() map[string]string {
+	return m.meta
+}
+
+// SetMetadata implements the MetadataSetter interface
+func (m *Memfile) SetMetadata(meta map[string]string) {
+	m.meta = meta
+}
+
+// Stat returns info describing the file
+func (m Memfile) Stat() (fs.FileInfo, error) {
+	return FileInfo(m), nil
+}
+
 // Memdir is an in-memory directory
 // Currently it only supports either Memfile & Memdir as links
+// Memdir is safe for concurrent use. Children added while a directory is
+// being read with NextFile are returned before io.EOF
 type Memdir struct {
+	lk      sync.Mutex
 	path    string
 	fi      int // file index for reading
 	links   []File
 	modTime time.Time
+	mode    fs.FileMode
+	meta    map[string]string
 }
 
-// Confirm that Memdir satisfies the File interface
-var _ = (File)(&Memdir{})
+// Confirm that Memdir satisfies the File, StatFile & metadata interfaces
+var (
+	_ File           = (*Memdir)(nil)
+	_ StatFile       = (*Memdir)(nil)
+	_ MetadataFile   = (*Memdir)(nil)
+	_ MetadataSetter = (*Memdir)(nil)
+	_ ModTimeSetter  = (*Memdir)(nil)
+)
 
 // NewMemdir creates a new Memdir, supplying zero or more links
 func NewMemdir(path string, links ...File) *Memdir {
@@ -212,33 +459,37 @@ func NewMemdir(path string, links ...File) *Memdir {
 }
 
 // Read does nothing, exists so MemDir implements the File interface
-func (Memdir) Read([]byte) (int, error) {
+func (*Memdir) Read([]byte) (int, error) {
 	return 0, ErrNotFile
 }
 
 // Close does nothing, exists so MemDir implements the File interface
-func (Memdir) Close() error {
+func (*Memdir) Close() error {
 	return ErrNotFile
 }
 
 // FileName returns the base of File's internal path
-func (m Memdir) FileName() string {
-	return filepath.Base(m.path)
+func (m *Memdir) FileName() string {
+	return filepath.Base(m.FullPath())
 }
 
 // FullPath returns the entire path string
-func (m Memdir) FullPath() string {
+func (m *Memdir) FullPath() string {
+	m.lk.Lock()
+	defer m.lk.Unlock()
 	return m.path
 }
 
 // IsDirectory returns true to indicate MemDir is a Directory
-func (Memdir) IsDirectory() bool {
+func (*Memdir) IsDirectory() bool {
 	return true
 }
 
 // NextFile iterates through each File in the directory on successive calls to File
 // Returning io.EOF when no files remain
 func (m *Memdir) NextFile() (File, error) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
 	if m.fi >= len(m.links) {
 		return nil, io.EOF
 	}
@@ -254,15 +505,81 @@ func (m *Memdir) MediaType() string {
 // ModTime returns the last-modified time for this directory
 // TODO (b5) - should modifying children affect this timestamp?
 func (m *Memdir) ModTime() time.Time {
+	m.lk.Lock()
+	defer m.lk.Unlock()
 	return m.modTime
 }
 
+// SetModTime implements the ModTimeSetter interface
+func (m *Memdir) SetModTime(t time.Time) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.modTime = t
+}
+
+// Stat returns info describing the directory
+func (m *Memdir) Stat() (fs.FileInfo, error) {
+	return FileInfo(m), nil
+}
+
+// Mode returns the directory's mode, which always includes fs.ModeDir.
+// Permissions are 0755 unless set with SetMode
+func (m *Memdir) Mode() fs.FileMode {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if m.mode.Perm() == 0 {
+		return fs.ModeDir | 0755
+	}
+	return m.mode | fs.ModeDir
+}
+
+// SetMode implements the MetadataSetter interface
+func (m *Memdir) SetMode(mode fs.FileMode) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.mode = mode
+}
+
+// Metadata returns attributes set with SetMetadata
+func (m *Memdir) Metadata() map[string]string {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.meta
+}
+
+// SetMetadata implements the MetadataSetter interface
+func (m *Memdir) SetMetadata(meta map[string]string) {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	m.meta = meta
+}
+
 // SetPath implements the PathSetter interface
 func (m *Memdir) SetPath(path string) {
-	m.path = path
-	for _, f := range m.links {
-		if fps, ok := f.(PathSetter); ok {
-			fps.SetPath(filepath.Join(path, f.FileName()))
+	// descend through child directories with an explicit stack rather than
+	// recursive SetPath calls, which could exhaust the stack on deep trees.
+	// only one directory is locked at a time
+	type frame struct {
+		dir  *Memdir
+		path string
+	}
+	stack := []frame{{dir: m, path: path}}
+	for len(stack) > 0 {
+		fr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		fr.dir.lk.Lock()
+		fr.dir.path = fr.path
+		links := make([]File, len(fr.dir.links))
+		copy(links, fr.dir.links)
+		fr.dir.lk.Unlock()
+
+		for _, f := range links {
+			if ch, ok := f.(*Memdir); ok {
+				stack = append(stack, frame{dir: ch, path: filepath.Join(fr.path, ch.FileName())})
+			} else if fps, ok := f.(PathSetter); ok {
+				fps.SetPath(filepath.Join(fr.path, f.FileName()))
+			}
 		}
 	}
 }
@@ -276,18 +593,85 @@ func (m *Memdir) AddChildren(fs ...File) {
 			fps.SetPath(filepath.Join(m.FullPath(), f.FileName()))
 		}
 		dir := m.MakeDirP(f)
+		dir.lk.Lock()
 		dir.links = append(dir.links, f)
+		dir.lk.Unlock()
+	}
+}
+
+// Child returns the direct child with the given name, nil if no such child
+// exists
+func (m *Memdir) Child(name string) File {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if i := m.childIndex(name); i >= 0 {
+		return m.links[i]
 	}
+	return nil
+}
+
+// RemoveChild removes the direct child with the given name, returning true if
+// a child was removed
+func (m *Memdir) RemoveChild(name string) bool {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	i := m.childIndex(name)
+	if i < 0 {
+		return false
+	}
+	m.links = append(m.links[:i], m.links[i+1:]...)
+	// keep NextFile iteration in step if the removed child was already read
+	if i < m.fi {
+		m.fi--
+	}
+	return true
+}
+
+// ReplaceChild swaps the direct child whose name matches f.FileName() for f,
+// adding f as a new child if no match exists. As with AddChildren, f's path is
+// updated to sit within this directory if f implements PathSetter. ReplaceChild
+// returns true if an existing child was replaced
+func (m *Memdir) ReplaceChild(f File) bool {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	if fps, ok := f.(PathSetter); ok {
+		fps.SetPath(filepath.Join(m.path, f.FileName()))
+	}
+	i := m.childIndex(f.FileName())
+	if i < 0 {
+		m.links = append(m.links, f)
+		return false
+	}
+	m.links[i] = f
+	return true
+}
+
+// childIndex returns the position of the named child in links, -1 if no such
+// child exists. callers must hold the lock
+func (m *Memdir) childIndex(name string) int {
+	for i, f := range m.links {
+		if f.FileName() == name {
+			return i
+		}
+	}
+	return -1
 }
 
 // ChildDir returns a child directory at dirname
 func (m *Memdir) ChildDir(dirname string) *Memdir {
+	m.lk.Lock()
+	defer m.lk.Unlock()
+	return m.childDir(dirname)
+}
+
+// childDir returns a child directory at dirname. callers must hold the lock
+func (m *Memdir) childDir(dirname string) *Memdir {
 	if dirname == "" || dirname == "." || dirname == "/" {
 		return m
 	}
 	for _, f := range m.links {
 		if dir, ok := f.(*Memdir); ok {
-			if filepath.Base(dir.path) == dirname {
+			if dir.FileName() == dirname {
 				return dir
 			}
 		}
@@ -298,7 +682,13 @@ func (m *Memdir) ChildDir(dirname string) *Memdir {
 // MakeDirP ensures all directories specified by the given file exist, returning
 // the deepest directory specified
 func (m *Memdir) MakeDirP(f File) *Memdir {
-	dirpath, _ := filepath.Split(f.FullPath())
+	p := f.FullPath()
+	mpath := m.FullPath()
+	// paths within this directory are created relative to it
+	if mpath != "" && mpath != "/" && strings.HasPrefix(p, mpath+"/") {
+		p = "." + strings.TrimPrefix(p, mpath)
+	}
+	dirpath, _ := filepath.Split(p)
 	if dirpath == "" || dirpath == "/" {
 		return m
 	}
@@ -309,12 +699,14 @@ func (m *Memdir) MakeDirP(f File) *Memdir {
 
 	dir := m
 	for _, dirname := range dirs {
-		if ch := dir.ChildDir(dirname); ch != nil {
-			dir = ch
-			continue
+		// check & create under the lock so concurrent callers agree on children
+		dir.lk.Lock()
+		ch := dir.childDir(dirname)
+		if ch == nil {
+			ch = NewMemdir(filepath.Join(dir.path, dirname))
+			dir.links = append(dir.links, ch)
 		}
-		ch := NewMemdir(filepath.Join(dir.FullPath(), dirname))
-		dir.links = append(dir.links, ch)
+		dir.lk.Unlock()
 		dir = ch
 	}
 	return dir
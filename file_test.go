@@ -2,7 +2,11 @@ package qfs
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -109,3 +113,327 @@ func TestMemdirMakeDirP(t *testing.T) {
 		}
 	}
 }
+
+func TestWalkDeepTree(t *testing.T) {
+	depth := 10000
+	root := deepMemdir(depth, NewMemfileBytes("file.txt", []byte("deep")))
+
+	visited := 0
+	err := Walk(root, func(f File) error {
+		visited++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every directory plus the file at the bottom
+	if visited != depth+2 {
+		t.Errorf("visit count mismatch. want: %d got: %d", depth+2, visited)
+	}
+}
+
+func TestMemdirNestedPaths(t *testing.T) {
+	dir := NewMemdir("/a/b")
+	dir.AddChildren(NewMemfileBytes("c.txt", []byte("c")))
+	dir.MakeDirP(NewMemfileBytes("/a/b/d/e.txt", []byte("e")))
+
+	expectPaths := []string{
+		"/a/b/c.txt",
+		"/a/b/d",
+		"/a/b",
+	}
+
+	paths := []string{}
+	if err := Walk(dir, func(f File) error {
+		paths = append(paths, f.FullPath())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expectPaths, paths); diff != "" {
+		t.Errorf("visited paths mismatch. (-want +got):\n%s", diff)
+	}
+}
+
+// deepMemdir creates a tree of nested directories depth levels deep, with
+// leaf in the deepest directory
+func deepMemdir(depth int, leaf File) *Memdir {
+	root := NewMemdir("/")
+	dir := root
+	for i := 0; i < depth; i++ {
+		ch := NewMemdir("d")
+		dir.AddChildren(ch)
+		dir = ch
+	}
+	dir.AddChildren(leaf)
+	return root
+}
+
+func TestMemdirChildEditing(t *testing.T) {
+	dir := NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("b")),
+		NewMemfileBytes("c.txt", []byte("c")),
+		NewMemdir("d",
+			NewMemfileBytes("e.txt", []byte("e")),
+		),
+	)
+
+	if ch := dir.Child("c.txt"); ch == nil || ch.FullPath() != "/a/c.txt" {
+		t.Errorf("expected to find child c.txt at /a/c.txt. got: %v", ch)
+	}
+	if ch := dir.Child("nope.txt"); ch != nil {
+		t.Errorf("expected missing child to be nil. got: %v", ch)
+	}
+
+	if !dir.RemoveChild("b.txt") {
+		t.Errorf("expected removing b.txt to return true")
+	}
+	if dir.RemoveChild("b.txt") {
+		t.Errorf("expected removing b.txt a second time to return false")
+	}
+
+	if !dir.ReplaceChild(NewMemdir("/somewhere/else/d", NewMemfileBytes("f.txt", []byte("f")))) {
+		t.Errorf("expected replacing d to return true")
+	}
+	if dir.ReplaceChild(NewMemfileBytes("g.txt", []byte("g"))) {
+		t.Errorf("expected replacing non-existent g.txt to return false")
+	}
+
+	expectPaths := []string{
+		"/a/c.txt",
+		"/a/d/f.txt",
+		"/a/d",
+		"/a/g.txt",
+		"/a",
+	}
+	paths := []string{}
+	if err := Walk(dir, func(f File) error {
+		paths = append(paths, f.FullPath())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expectPaths, paths); diff != "" {
+		t.Errorf("visited paths mismatch. (-want +got):\n%s", diff)
+	}
+}
+
+func TestMemdirRemoveChildDuringIteration(t *testing.T) {
+	dir := NewMemdir("/",
+		NewMemfileBytes("a.txt", []byte("a")),
+		NewMemfileBytes("b.txt", []byte("b")),
+		NewMemfileBytes("c.txt", []byte("c")),
+	)
+
+	f, err := dir.NextFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir.RemoveChild(f.FileName())
+
+	f, err = dir.NextFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.FileName() != "b.txt" {
+		t.Errorf("expected iteration to continue with b.txt. got: %s", f.FileName())
+	}
+}
+
+func TestMemdirConcurrentAddChildren(t *testing.T) {
+	dir := NewMemdir("/a")
+	const writers, files = 4, 50
+
+	done := make(chan struct{})
+	for w := 0; w < writers; w++ {
+		go func(w int) {
+			for i := 0; i < files; i++ {
+				dir.AddChildren(NewMemfileBytes(fmt.Sprintf("%d-%d.txt", w, i), []byte("x")))
+			}
+			done <- struct{}{}
+		}(w)
+	}
+
+	// read concurrently with writes, no child may be returned twice
+	seen := map[string]bool{}
+	read := func() error {
+		f, err := dir.NextFile()
+		if err != nil {
+			return err
+		}
+		if seen[f.FullPath()] {
+			t.Fatalf("child %q returned twice", f.FullPath())
+		}
+		seen[f.FullPath()] = true
+		return nil
+	}
+	for finished := 0; finished < writers; {
+		select {
+		case <-done:
+			finished++
+		default:
+			read()
+		}
+	}
+	// children added mid-iteration are returned before io.EOF
+	for read() == nil {
+	}
+
+	if len(seen) != writers*files {
+		t.Errorf("child count mismatch. want: %d got: %d", writers*files, len(seen))
+	}
+}
+
+func TestStat(t *testing.T) {
+	cases := []struct {
+		file  File
+		name  string
+		size  int64
+		isDir bool
+	}{
+		{NewMemfileBytes("/a/b.txt", []byte("foo")), "b.txt", 3, false},
+		{NewMemfileReader("/a/c.txt", &bytes.Buffer{}), "c.txt", -1, false},
+		{NewMemdir("/a"), "a", 0, true},
+		{NewTextFile("/a/d.txt", "hello"), "d.txt", 5, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fi, err := Stat(c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.name != fi.Name() {
+				t.Errorf("name mismatch. want: %q got: %q", c.name, fi.Name())
+			}
+			if c.size != fi.Size() {
+				t.Errorf("size mismatch. want: %d got: %d", c.size, fi.Size())
+			}
+			if c.isDir != fi.IsDir() {
+				t.Errorf("isDir mismatch. want: %t got: %t", c.isDir, fi.IsDir())
+			}
+			if c.isDir != fi.Mode().IsDir() {
+				t.Errorf("mode mismatch. expected directory mode: %t, got mode: %s", c.isDir, fi.Mode())
+			}
+			if !c.file.ModTime().Equal(fi.ModTime()) {
+				t.Errorf("modTime mismatch. want: %s got: %s", c.file.ModTime(), fi.ModTime())
+			}
+		})
+	}
+}
+
+func TestMetadataFile(t *testing.T) {
+	f := NewMemfileBytes("/a/b.txt", []byte("foo"))
+	if f.Mode() != 0644 {
+		t.Errorf("expected default file mode 0644. got: %s", f.Mode())
+	}
+	f.SetMode(0600)
+	f.SetMetadata(map[string]string{"tag": "draft"})
+	fi, err := Stat(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0600 {
+		t.Errorf("expected stat mode to match set mode. got: %s", fi.Mode())
+	}
+	if f.Metadata()["tag"] != "draft" {
+		t.Errorf("expected metadata to be preserved. got: %v", f.Metadata())
+	}
+
+	d := NewMemdir("/a", f)
+	d.SetMode(0700)
+	if d.Mode() != fs.ModeDir|0700 {
+		t.Errorf("expected directory mode to keep the directory bit. got: %s", d.Mode())
+	}
+	child, err := d.NextFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mf, ok := child.(MetadataFile); !ok || mf.Mode() != 0600 {
+		t.Errorf("expected child to preserve its metadata")
+	}
+}
+
+func TestMemfileSeek(t *testing.T) {
+	f := NewMemfileBytes("a.txt", []byte("foobar"))
+	if _, err := f.Seek(3, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "bar" {
+		t.Errorf("contents after seek mismatch. want: %q got: %q", "bar", s)
+	}
+
+	f = NewMemfileReader("b.txt", strings.NewReader("foo"))
+	if _, err := f.Seek(1, io.SeekStart); err != nil {
+		t.Errorf("expected seeking a seekable reader to succeed. got: %s", err)
+	}
+	f = NewMemfileReader("c.txt", &bytes.Buffer{})
+	if _, err := f.Seek(1, io.SeekStart); !errors.Is(err, ErrNotSeekable) {
+		t.Errorf("expected ErrNotSeekable seeking an unseekable reader. got: %v", err)
+	}
+}
+
+func TestWalkOpts(t *testing.T) {
+	newTree := func() File {
+		return NewMemdir("/a",
+			NewMemfileBytes("b.txt", []byte("foo")),
+			NewMemdir("/c",
+				NewMemfileBytes("d.txt", []byte("bar")),
+				NewMemdir("/e",
+					NewMemfileBytes("f.txt", []byte("baz")),
+				),
+			),
+			NewMemdir("/g",
+				NewMemfileBytes("h.txt", []byte("bat")),
+			),
+		)
+	}
+
+	cases := []struct {
+		description string
+		opts        WalkOptions
+		skip        string
+		expect      []string
+	}{
+		{"post-order", WalkOptions{}, "", []string{
+			"/a/b.txt:1", "/a/c/d.txt:2", "/a/c/e/f.txt:3", "/a/c/e:2", "/a/c:1", "/a/g/h.txt:2", "/a/g:1", "/a:0",
+		}},
+		{"pre-order", WalkOptions{PreOrder: true}, "", []string{
+			"/a:0", "/a/b.txt:1", "/a/c:1", "/a/c/d.txt:2", "/a/c/e:2", "/a/c/e/f.txt:3", "/a/g:1", "/a/g/h.txt:2",
+		}},
+		{"max depth", WalkOptions{MaxDepth: 1}, "", []string{
+			"/a/b.txt:1", "/a/c:1", "/a/g:1", "/a:0",
+		}},
+		{"pre-order skip dir", WalkOptions{PreOrder: true}, "/a/c", []string{
+			"/a:0", "/a/b.txt:1", "/a/c:1", "/a/g:1", "/a/g/h.txt:2",
+		}},
+		{"skip remaining siblings", WalkOptions{}, "/a/c/d.txt", []string{
+			"/a/b.txt:1", "/a/c/d.txt:2", "/a/c:1", "/a/g/h.txt:2", "/a/g:1", "/a:0",
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			got := []string{}
+			err := WalkOpts(newTree(), c.opts, func(f File, depth int) error {
+				got = append(got, fmt.Sprintf("%s:%d", f.FullPath(), depth))
+				if f.FullPath() == c.skip {
+					return SkipDir
+				}
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(c.expect, got); diff != "" {
+				t.Errorf("visited paths mismatch. (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	expectErr := errors.New("stop")
+	err := WalkOpts(newTree(), WalkOptions{}, func(f File, depth int) error { return expectErr })
+	if err != expectErr {
+		t.Errorf("expected visit error to stop the walk. got: %v", err)
+	}
+}
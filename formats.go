@@ -0,0 +1,130 @@
+package qfs
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// JSONMediaType is the media type of files created with NewJSONFile
+	JSONMediaType = "application/json"
+	// CSVMediaType is the media type of files created with NewCSVFile
+	CSVMediaType = "text/csv"
+	// TextMediaType is the media type of files created with NewTextFile
+	TextMediaType = "text/plain; charset=utf-8"
+)
+
+// NewJSONFile creates a file that reads as the JSON encoding of v. Encoding is
+// deferred until the file is first read, encoding errors are returned by Read
+func NewJSONFile(path string, v interface{}) File {
+	return newEncodedFile(path, JSONMediaType, func() ([]byte, error) {
+		return json.Marshal(v)
+	})
+}
+
+// NewCSVFile creates a file that reads as rows encoded as CSV. Encoding is
+// deferred until the file is first read, encoding errors are returned by Read
+func NewCSVFile(path string, rows [][]string) File {
+	return newEncodedFile(path, CSVMediaType, func() ([]byte, error) {
+		buf := &bytes.Buffer{}
+		w := csv.NewWriter(buf)
+		if err := w.WriteAll(rows); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	})
+}
+
+// NewTextFile creates a plain text file with the given contents
+func NewTextFile(path, text string) File {
+	return newEncodedFile(path, TextMediaType, func() ([]byte, error) {
+		return []byte(text), nil
+	})
+}
+
+// encodedFile is an in-memory file with a fixed media type whose contents
+// are produced by encode the first time they're needed
+type encodedFile struct {
+	path      string
+	mediaType string
+	modTime   time.Time
+
+	once   sync.Once
+	encode func() ([]byte, error)
+	buf    *bytes.Reader
+	err    error
+}
+
+var (
+	_ File          = (*encodedFile)(nil)
+	_ SizeFile      = (*encodedFile)(nil)
+	_ PathSetter    = (*encodedFile)(nil)
+	_ ModTimeSetter = (*encodedFile)(nil)
+)
+
+func newEncodedFile(path, mediaType string, encode func() ([]byte, error)) *encodedFile {
+	return &encodedFile{
+		path:      path,
+		mediaType: mediaType,
+		modTime:   time.Now(),
+		encode:    encode,
+	}
+}
+
+// load runs the encoder exactly once
+func (f *encodedFile) load() error {
+	f.once.Do(func() {
+		data, err := f.encode()
+		f.buf = bytes.NewReader(data)
+		f.err = err
+	})
+	return f.err
+}
+
+// Read encodes the file on first call, then reads encoded bytes
+func (f *encodedFile) Read(p []byte) (int, error) {
+	if err := f.load(); err != nil {
+		return 0, err
+	}
+	return f.buf.Read(p)
+}
+
+// Close does nothing, encoded files hold no resources
+func (f *encodedFile) Close() error { return nil }
+
+// FileName returns the base of the file's path
+func (f *encodedFile) FileName() string { return filepath.Base(f.path) }
+
+// FullPath returns the entire path string
+func (f *encodedFile) FullPath() string { return f.path }
+
+// SetPath implements the PathSetter interface
+func (f *encodedFile) SetPath(path string) { f.path = path }
+
+// IsDirectory always returns false
+func (f *encodedFile) IsDirectory() bool { return false }
+
+// NextFile always errors, encodedFile isn't a directory
+func (f *encodedFile) NextFile() (File, error) { return nil, ErrNotDirectory }
+
+// ModTime returns the time the file was created
+func (f *encodedFile) ModTime() time.Time { return f.modTime }
+
+// SetModTime implements the ModTimeSetter interface
+func (f *encodedFile) SetModTime(t time.Time) { f.modTime = t }
+
+// MediaType returns the media type of the encoding, regardless of extension
+func (f *encodedFile) MediaType() string { return f.mediaType }
+
+// Size returns the length of the encoded file, encoding if necessary. Size
+// returns -1 if encoding fails
+func (f *encodedFile) Size() int64 {
+	if err := f.load(); err != nil {
+		return -1
+	}
+	return f.buf.Size()
+}
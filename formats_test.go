@@ -0,0 +1,59 @@
+package qfs
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestFormatFiles(t *testing.T) {
+	cases := []struct {
+		file      File
+		path      string
+		mediaType string
+		data      string
+	}{
+		{NewJSONFile("/a/data.json", map[string]interface{}{"a": 1}), "/a/data.json", JSONMediaType, `{"a":1}`},
+		{NewCSVFile("/a/rows.csv", [][]string{{"a", "b"}, {"1", "2"}}), "/a/rows.csv", CSVMediaType, "a,b\n1,2\n"},
+		{NewTextFile("/a/notes", "hello"), "/a/notes", TextMediaType, "hello"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			if c.path != c.file.FullPath() {
+				t.Errorf("path mismatch. want: %q got: %q", c.path, c.file.FullPath())
+			}
+			if c.mediaType != c.file.MediaType() {
+				t.Errorf("media type mismatch. want: %q got: %q", c.mediaType, c.file.MediaType())
+			}
+			if size := c.file.(SizeFile).Size(); size != int64(len(c.data)) {
+				t.Errorf("size mismatch. want: %d got: %d", len(c.data), size)
+			}
+			data, err := ioutil.ReadAll(c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.data != string(data) {
+				t.Errorf("data mismatch. want: %q got: %q", c.data, string(data))
+			}
+		})
+	}
+}
+
+func TestJSONFileLazyEncode(t *testing.T) {
+	v := map[string]string{"a": "before"}
+	f := NewJSONFile("data.json", v)
+	v["a"] = "after"
+
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := `{"a":"after"}`; expect != string(data) {
+		t.Errorf("data mismatch. want: %q got: %q", expect, string(data))
+	}
+
+	f = NewJSONFile("bad.json", make(chan int))
+	if _, err := ioutil.ReadAll(f); err == nil {
+		t.Error("expected encoding error reading unencodable value")
+	}
+}
@@ -3,6 +3,8 @@ package qfs
 import (
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 
@@ -15,6 +17,31 @@ var (
 	ErrNotFound = errors.New("path not found")
 	// ErrReadOnly is a sentinel value for Filesystems that aren't writable
 	ErrReadOnly = errors.New("readonly filesystem")
+	// ErrPathIgnored is returned by content-addressed filesystems when asked to
+	// put a file at a path within existing content. CAFS paths are derived from
+	// content, and can't be chosen by the caller
+	ErrPathIgnored = errors.New("content-addressed filesystems cannot write to a given path")
+	// ErrNotLocked is returned when releasing a lock that isn't held
+	ErrNotLocked = errors.New("lock is not held")
+	// ErrNotPinned is returned when unpinning content that isn't pinned
+	ErrNotPinned = errors.New("not pinned")
+	// ErrUnsupported is returned when a filesystem can't perform an operation
+	// at all, as opposed to failing to perform it
+	ErrUnsupported = errors.New("operation not supported")
+	// ErrUnavailable is returned when storage can't be reached. Operations
+	// that fail with ErrUnavailable may succeed if retried
+	ErrUnavailable = errors.New("storage unavailable")
+	// ErrClosed is returned by filesystems used after they've been closed or
+	// their context has been cancelled
+	ErrClosed = errors.New("filesystem is closed")
+	// ErrInvalidPath is returned for paths that don't fit the form a
+	// filesystem expects
+	ErrInvalidPath = errors.New("invalid path")
+	// ErrPreconditionFailed is returned when a write's precondition doesn't
+	// hold, eg: the file being overwritten changed since it was last read
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrNoSpace is returned when a write would exceed a store's size limit
+	ErrNoSpace = errors.New("no space left in store")
 )
 
 // PathResolver is the "get" portion of a Filesystem
@@ -22,7 +49,48 @@ type PathResolver interface {
 	Get(ctx context.Context, path string) (File, error)
 }
 
+// GetTo fetches a file from a PathResolver and copies its contents into w,
+// closing the file when finished. Files that implement io.WriterTo write
+// directly to w, skipping the intermediate buffer io.Copy would allocate.
+// GetTo returns ErrNotFile if path resolves to a directory
+func GetTo(ctx context.Context, r PathResolver, path string, w io.Writer) (int64, error) {
+	f, err := r.Get(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	if f.IsDirectory() {
+		return 0, ErrNotFile
+	}
+	return io.Copy(w, f)
+}
+
+// GetBackedHas checks for the existence of path by fetching it from r, for
+// filesystems that can't check more cheaply. Errors that wrap ErrNotFound
+// report path as missing, other errors are returned
+func GetBackedHas(ctx context.Context, r PathResolver, path string) (bool, error) {
+	f, err := r.Get(ctx, path)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	f.Close()
+	return true, nil
+}
+
 // Filesystem abstracts & unifies filesystem-like behaviour
+//
+// Paths given to Has, Get & Delete follow the same rules on every filesystem:
+//   - trailing & repeated slashes are ignored, "/mem/QmFoo/a.txt/" names the
+//     same file as "/mem/QmFoo/a.txt". URLs are the exception, they're passed
+//     to servers as given
+//   - the empty path names nothing: Has returns false, Get returns an error
+//     wrapping ErrNotFound & Delete fails. Filesystems that can delete return
+//     an error wrapping ErrInvalidPath
+//   - on content-addressed filesystems the store root ("/", "/mem",
+//     "/mem/") is treated like the empty path. Use IsRootPath to check
 type Filesystem interface {
 	// Type returns a string identifier that distinguishes a filesystem from
 	// all other implementations, example identifiers include: "local", "ipfs",
@@ -48,10 +116,51 @@ type Filesystem interface {
 	Delete(ctx context.Context, path string) (err error)
 }
 
+// OpenWriteFS is a Filesystem that names its read & write operations
+// OpenFile and WriteFile. Every Filesystem can be used as an OpenWriteFS with
+// NewOpenWriteFS
+type OpenWriteFS interface {
+	Filesystem
+	// OpenFile fetches the file or directory at name. OpenFile must return an
+	// error that wraps ErrNotFound if name doesn't exist
+	OpenFile(ctx context.Context, name string) (File, error)
+	// WriteFile writes a file or directory, returning the path it can be
+	// opened with. Read-only filesystems must return ErrReadOnly
+	WriteFile(ctx context.Context, file File) (path string, err error)
+}
+
+// NewOpenWriteFS returns fs as an OpenWriteFS. Filesystems that don't
+// implement OpenWriteFS are wrapped, forwarding OpenFile to Get and
+// WriteFile to Put
+func NewOpenWriteFS(fs Filesystem) OpenWriteFS {
+	if owfs, ok := fs.(OpenWriteFS); ok {
+		return owfs
+	}
+	return openWriteFS{fs}
+}
+
+// openWriteFS adapts a Filesystem to the OpenWriteFS interface
+type openWriteFS struct {
+	Filesystem
+}
+
+// OpenFile gets name from the underlying filesystem
+func (fs openWriteFS) OpenFile(ctx context.Context, name string) (File, error) {
+	return fs.Get(ctx, name)
+}
+
+// WriteFile puts file on the underlying filesystem
+func (fs openWriteFS) WriteFile(ctx context.Context, file File) (string, error) {
+	return fs.Put(ctx, file)
+}
+
 // Config binds a filesystem type to a configuration map
 type Config struct {
 	Type   string                 `json:"type"`
 	Config map[string]interface{} `json:"config,omitempty"`
+	// MaxConcurrency caps the calls a mux makes to the filesystem at once,
+	// queueing the rest. Zero is unlimited
+	MaxConcurrency int `json:"maxConcurrency,omitempty"`
 }
 
 // Constructor is a function that creates a filesystem from a config map
@@ -67,6 +176,18 @@ type ReleasingFilesystem interface {
 	DoneErr() error
 }
 
+// LockFS is an opt-in interface for filesystems that support named advisory
+// locks, letting cooperating writers serialize access to a shared store.
+// Locks are advisory: holding a lock does not prevent writes from callers
+// that don't ask for it
+type LockFS interface {
+	Filesystem
+	// Lock blocks until the named lock is acquired, or ctx is done
+	Lock(ctx context.Context, name string) error
+	// Unlock releases a lock acquired with Lock
+	Unlock(name string) error
+}
+
 // Destroyer is an optional interface to tear down a filesystem, removing all
 // persisted resources
 type Destroyer interface {
@@ -79,14 +200,57 @@ type PinningFS interface {
 	Unpin(ctx context.Context, key string, recursive bool) error
 }
 
+// HasManyFS is an opt-in interface for filesystems that can check for the
+// existence of many paths more efficiently than repeated calls to Has
+type HasManyFS interface {
+	Filesystem
+	// HasMany returns a map of each given path to whether it exists
+	HasMany(ctx context.Context, paths []string) (map[string]bool, error)
+}
+
+// HasMany checks for the existence of a set of paths, using fs's HasMany
+// method if fs implements HasManyFS, and falling back to calling Has on each
+// path if not
+func HasMany(ctx context.Context, fs Filesystem, paths []string) (map[string]bool, error) {
+	if hmfs, ok := fs.(HasManyFS); ok {
+		return hmfs.HasMany(ctx, paths)
+	}
+
+	res := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		exists, err := fs.Has(ctx, p)
+		if err != nil {
+			return nil, err
+		}
+		res[p] = exists
+	}
+	return res, nil
+}
+
 // CAFS stands for "content-addressed filesystem". Filesystem that implement
 // this interface declare that  all paths to persisted content are reference-by
 // -hash.
+// Calling Put on a CAFS with a file whose path points inside existing content
+// (eg: "/ipfs/QmFoo/body.json") must return an error that wraps ErrPathIgnored
 // TODO (b5) - write up a spec test suite for CAFS conformance
 type CAFS interface {
 	IsContentAddressedFilesystem()
 }
 
+// CheckCAFSPutPath returns an error wrapping ErrPathIgnored if path addresses
+// content within a content-addressed filesystem of type fsType, which is any
+// path of the form /[fsType]/[hash]/[subpath]. A bare /[fsType]/[hash] is
+// allowed, so files read from a store can be put again
+func CheckCAFSPutPath(fsType, path string) error {
+	if ValidatePath(fsType, path) != nil {
+		return nil
+	}
+	if _, _, subpath := SplitStorePath(path); subpath == "" {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrPathIgnored, path)
+}
+
 // AbsPath adjusts the provided string to a path lib functions can work with
 // because paths for Qri can come from the local filesystem, an http url, or
 // the distributed web, Absolutizing is a little tricky
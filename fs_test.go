@@ -1,6 +1,8 @@
 package qfs
 
 import (
+	"context"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
@@ -67,3 +69,113 @@ func TestPathKind(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckCAFSPutPath(t *testing.T) {
+	cases := []struct {
+		fsType, path string
+		ignored      bool
+	}{
+		{"ipfs", "", false},
+		{"ipfs", "/ipfs/body.json", false},
+		{"ipfs", "/ipfs/QmQPeNsJPyVWPFDVHb77w8G42Fvo15z4bG2X8D2GhfbSXc", false},
+		{"ipfs", "/ipfs/QmQPeNsJPyVWPFDVHb77w8G42Fvo15z4bG2X8D2GhfbSXc/", false},
+		{"ipfs", "/ipfs/QmQPeNsJPyVWPFDVHb77w8G42Fvo15z4bG2X8D2GhfbSXc/body.json", true},
+		{"mem", "/ipfs/QmQPeNsJPyVWPFDVHb77w8G42Fvo15z4bG2X8D2GhfbSXc/body.json", false},
+		{"mem", "/mem/QmQPeNsJPyVWPFDVHb77w8G42Fvo15z4bG2X8D2GhfbSXc/body.json", true},
+	}
+
+	for i, c := range cases {
+		err := CheckCAFSPutPath(c.fsType, c.path)
+		if c.ignored != errors.Is(err, ErrPathIgnored) {
+			t.Errorf("case %d: expected ignored: %t, got error: %v", i, c.ignored, err)
+		}
+	}
+}
+
+func TestOpenWriteFS(t *testing.T) {
+	ctx := context.Background()
+	fs := NewOpenWriteFS(NewMemFS())
+	if NewOpenWriteFS(fs) != fs {
+		t.Error("expected NewOpenWriteFS to return OpenWriteFS values unchanged")
+	}
+
+	path, err := fs.WriteFile(ctx, NewMemfileBytes("a.txt", []byte("foo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.OpenFile(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "foo" {
+		t.Errorf("contents mismatch. want: %q got: %q", "foo", s)
+	}
+
+	if _, err := fs.OpenFile(ctx, "/mem/QmNotFound"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected opening missing file to return ErrNotFound. got: %v", err)
+	}
+
+	ro := NewOpenWriteFS(Subtree(NewMemFS(), "/mem"))
+	if _, err := ro.WriteFile(ctx, NewMemfileBytes("a.txt", []byte("foo"))); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("expected writing to read-only filesystem to return ErrReadOnly. got: %v", err)
+	}
+}
+
+func TestGetBackedHas(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	path, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if has, err := GetBackedHas(ctx, fs, path); err != nil || !has {
+		t.Errorf("expected stored path to exist. has: %t err: %v", has, err)
+	}
+	if has, err := GetBackedHas(ctx, fs, "/mem/QmMissing"); err != nil || has {
+		t.Errorf("expected missing path not to exist. has: %t err: %v", has, err)
+	}
+
+	fs.SetFaults(MemFaults{Unavailable: []string{path}})
+	if _, err := GetBackedHas(ctx, fs, path); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected errors other than ErrNotFound to be returned. got: %v", err)
+	}
+}
+
+func TestErrorsIs(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	dir, err := fs.Put(ctx, NewMemdir("/a", NewMemfileBytes("b.txt", []byte("b"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirFile, err := fs.Get(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := cidFromPath(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		description string
+		err         error
+		expect      error
+	}{
+		{"get missing", func() error { _, err := fs.Get(ctx, "/mem/QmNotFound"); return err }(), ErrNotFound},
+		{"read directory", func() error { _, err := dirFile.Read(nil); return err }(), ErrIsDirectory},
+		{"put directory at key", fs.PutFileAtKey(ctx, "key", dirFile), ErrIsDirectory},
+		{"list file", func() error { _, err := fs.List(ctx, dir+"/b.txt"); return err }(), ErrNotDirectory},
+		{"path through file", func() error { _, err := fs.GetFile(id, "b.txt", "c"); return err }(), ErrNotDirectory},
+	}
+
+	for _, c := range cases {
+		if !errors.Is(c.err, c.expect) {
+			t.Errorf("%s: expected errors.Is(%v, %v)", c.description, c.err, c.expect)
+		}
+	}
+	if !errors.Is(ErrIsDirectory, ErrNotFile) {
+		t.Error("expected ErrIsDirectory to match ErrNotFile")
+	}
+}
@@ -0,0 +1,179 @@
+package qfs
+
+import (
+	"io"
+	"io/fs"
+	"mime"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// NewFileFromFS opens the named file or directory from a standard library
+// fs.FS, adapting it to the File interface. Directories are read lazily, each
+// call to NextFile opens the next child. The returned file uses name as its
+// full path, child paths are joined onto that name
+func NewFileFromFS(fsys fs.FS, name string) (File, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if info.IsDir() {
+		// directory entries are read through fsys, the handle isn't needed
+		f.Close()
+		return &fsDirFile{
+			fsys: fsys,
+			name: name,
+			path: name,
+			info: info,
+		}, nil
+	}
+
+	return &fsFileFile{
+		f:    f,
+		info: info,
+		path: name,
+	}, nil
+}
+
+// NewFSFile wraps an open fs.File in the File interface, using path as the
+// file's full path. NewFSFile only accepts regular files, use NewFileFromFS to
+// adapt directories
+func NewFSFile(path string, f fs.File) (File, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, ErrNotFile
+	}
+	return &fsFileFile{
+		f:    f,
+		info: info,
+		path: path,
+	}, nil
+}
+
+// fsFileFile adapts an fs.File to the File interface
+type fsFileFile struct {
+	f    fs.File
+	info fs.FileInfo
+	path string
+}
+
+var (
+	_ File       = (*fsFileFile)(nil)
+	_ SizeFile   = (*fsFileFile)(nil)
+	_ StatFile   = (*fsFileFile)(nil)
+	_ PathSetter = (*fsFileFile)(nil)
+)
+
+// Read proxies to the underlying fs.File
+func (f *fsFileFile) Read(p []byte) (int, error) { return f.f.Read(p) }
+
+// Close proxies to the underlying fs.File
+func (f *fsFileFile) Close() error { return f.f.Close() }
+
+// FileName returns the base of the file's path
+func (f *fsFileFile) FileName() string { return filepath.Base(f.path) }
+
+// FullPath returns the entire path string
+func (f *fsFileFile) FullPath() string { return f.path }
+
+// SetPath implements the PathSetter interface
+func (f *fsFileFile) SetPath(path string) { f.path = path }
+
+// IsDirectory always returns false
+func (f *fsFileFile) IsDirectory() bool { return false }
+
+// NextFile always errors, fsFileFile isn't a directory
+func (f *fsFileFile) NextFile() (File, error) { return nil, ErrNotDirectory }
+
+// ModTime returns the modification time reported by the source FS
+func (f *fsFileFile) ModTime() time.Time { return f.info.ModTime() }
+
+// MediaType returns a mime type based on file extension
+func (f *fsFileFile) MediaType() string { return mime.TypeByExtension(filepath.Ext(f.path)) }
+
+// Size returns the length of the file reported by the source FS
+func (f *fsFileFile) Size() int64 { return f.info.Size() }
+
+// Stat returns the info reported by the source FS
+func (f *fsFileFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+// fsDirFile adapts a directory within an fs.FS to the File interface
+type fsDirFile struct {
+	fsys    fs.FS
+	name    string // name of the directory within fsys
+	path    string
+	info    fs.FileInfo
+	entries []fs.DirEntry
+	read    bool // have entries been read
+	i       int
+}
+
+var (
+	_ File       = (*fsDirFile)(nil)
+	_ StatFile   = (*fsDirFile)(nil)
+	_ PathSetter = (*fsDirFile)(nil)
+)
+
+// Read does nothing, exists so fsDirFile implements the File interface
+func (d *fsDirFile) Read([]byte) (int, error) { return 0, ErrNotFile }
+
+// Close does nothing, exists so fsDirFile implements the File interface
+func (d *fsDirFile) Close() error { return ErrNotFile }
+
+// FileName returns the base of the directory's path
+func (d *fsDirFile) FileName() string { return filepath.Base(d.path) }
+
+// FullPath returns the entire path string
+func (d *fsDirFile) FullPath() string { return d.path }
+
+// SetPath implements the PathSetter interface. children that have yet to be
+// read will be assigned paths relative to the new path
+func (d *fsDirFile) SetPath(path string) { d.path = path }
+
+// IsDirectory always returns true
+func (d *fsDirFile) IsDirectory() bool { return true }
+
+// NextFile opens the next entry in the directory, returning io.EOF when no
+// entries remain. Entries are returned in lexical order
+func (d *fsDirFile) NextFile() (File, error) {
+	if !d.read {
+		entries, err := fs.ReadDir(d.fsys, d.name)
+		if err != nil {
+			return nil, err
+		}
+		d.entries = entries
+		d.read = true
+	}
+
+	if d.i >= len(d.entries) {
+		return nil, io.EOF
+	}
+	entry := d.entries[d.i]
+	d.i++
+
+	f, err := NewFileFromFS(d.fsys, path.Join(d.name, entry.Name()))
+	if err != nil {
+		return nil, err
+	}
+	f.(PathSetter).SetPath(filepath.Join(d.path, entry.Name()))
+	return f, nil
+}
+
+// ModTime returns the modification time reported by the source FS
+func (d *fsDirFile) ModTime() time.Time { return d.info.ModTime() }
+
+// MediaType is a directory mime-type stand-in
+func (d *fsDirFile) MediaType() string { return "application/x-directory" }
+
+// Stat returns the info reported by the source FS
+func (d *fsDirFile) Stat() (fs.FileInfo, error) { return d.info, nil }
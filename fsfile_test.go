@@ -0,0 +1,106 @@
+package qfs
+
+import (
+	"context"
+	"io/fs"
+	"io/ioutil"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewFileFromFS(t *testing.T) {
+	modTime := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	fsys := fstest.MapFS{
+		"data/a.txt":       {Data: []byte("foo"), ModTime: modTime},
+		"data/b/c.txt":     {Data: []byte("bar")},
+		"data/b/d/e.json":  {Data: []byte(`{}`)},
+		"data/z/index.csv": {Data: []byte("a,b,c")},
+	}
+
+	f, err := NewFileFromFS(fsys, "data/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.IsDirectory() {
+		t.Errorf("expected file, got directory")
+	}
+	if got := f.(SizeFile).Size(); got != 3 {
+		t.Errorf("size mismatch. want: 3 got: %d", got)
+	}
+	if !f.ModTime().Equal(modTime) {
+		t.Errorf("modtime mismatch. want: %s got: %s", modTime, f.ModTime())
+	}
+
+	dir, err := NewFileFromFS(fsys, "data")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{}
+	if err := Walk(dir, func(f File) error {
+		paths = append(paths, f.FullPath())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{
+		"data/a.txt",
+		"data/b/c.txt",
+		"data/b/d/e.json",
+		"data/b/d",
+		"data/b",
+		"data/z/index.csv",
+		"data/z",
+		"data",
+	}
+	if diff := cmp.Diff(expect, paths); diff != "" {
+		t.Errorf("visited paths mismatch. (-want +got):\n%s", diff)
+	}
+
+	if _, err := NewFSFile("data", mustOpen(t, fsys, "data")); err != ErrNotFile {
+		t.Errorf("expected NewFSFile on a directory to return ErrNotFile, got: %v", err)
+	}
+}
+
+func TestPutFileFromFS(t *testing.T) {
+	ctx := context.Background()
+	fsys := fstest.MapFS{
+		"a/b.txt": {Data: []byte("this is file b")},
+	}
+
+	dir, err := NewFileFromFS(fsys, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mfs := NewMemFS()
+	key, err := mfs.Put(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := mfs.Get(ctx, key+"/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "this is file b" {
+		t.Errorf("data mismatch. want: %q got: %q", "this is file b", string(data))
+	}
+}
+
+func mustOpen(t *testing.T, fsys fs.FS, name string) fs.File {
+	t.Helper()
+	f, err := fsys.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
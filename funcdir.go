@@ -0,0 +1,125 @@
+package qfs
+
+import (
+	"io"
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FuncDir is a directory whose children are produced on demand by a
+// generator function, so directories can be built from streams like database
+// cursors or paginated API responses without holding every child in memory.
+// FuncDir is safe for concurrent use. Calls to the generator are serialized,
+// so it's never called concurrently
+type FuncDir struct {
+	lk      sync.Mutex
+	path    string
+	next    func() (File, error)
+	err     error
+	modTime time.Time
+}
+
+var (
+	_ File          = (*FuncDir)(nil)
+	_ StatFile      = (*FuncDir)(nil)
+	_ PathSetter    = (*FuncDir)(nil)
+	_ ModTimeSetter = (*FuncDir)(nil)
+)
+
+// NewFuncDir creates a directory that calls next for each child. next
+// returns io.EOF once there are no more children, a nil file with a nil error
+// is treated the same way. Once next returns an
+// error, NextFile returns that error without calling next again. Children
+// that implement PathSetter have their paths set beneath the directory as
+// they're produced
+func NewFuncDir(path string, next func() (File, error)) *FuncDir {
+	return &FuncDir{
+		path:    path,
+		next:    next,
+		modTime: time.Now(),
+	}
+}
+
+// Read returns ErrNotFile, directories can't be read
+func (*FuncDir) Read([]byte) (int, error) {
+	return 0, ErrNotFile
+}
+
+// Close does nothing, exists so FuncDir implements the File interface
+func (*FuncDir) Close() error {
+	return nil
+}
+
+// FileName returns the base of the directory path
+func (d *FuncDir) FileName() string {
+	return filepath.Base(d.FullPath())
+}
+
+// FullPath returns the directory path
+func (d *FuncDir) FullPath() string {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	return d.path
+}
+
+// SetPath implements the PathSetter interface. Children produced before
+// SetPath is called keep the paths they were given
+func (d *FuncDir) SetPath(path string) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.path = path
+}
+
+// IsDirectory returns true
+func (*FuncDir) IsDirectory() bool {
+	return true
+}
+
+// NextFile produces the next child by calling the generator function
+func (d *FuncDir) NextFile() (File, error) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	f, err := d.next()
+	if err != nil {
+		d.err = err
+		return nil, err
+	}
+	if f == nil {
+		d.err = io.EOF
+		return nil, io.EOF
+	}
+	if fps, ok := f.(PathSetter); ok {
+		fps.SetPath(filepath.Join(d.path, f.FileName()))
+	}
+	return f, nil
+}
+
+// MediaType is a directory mime-type stand-in
+func (*FuncDir) MediaType() string {
+	return "application/x-directory"
+}
+
+// ModTime returns the time the directory was created
+func (d *FuncDir) ModTime() time.Time {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	return d.modTime
+}
+
+// SetModTime implements the ModTimeSetter interface
+func (d *FuncDir) SetModTime(t time.Time) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	d.modTime = t
+}
+
+// Stat returns info describing the directory
+func (d *FuncDir) Stat() (fs.FileInfo, error) {
+	return FileInfo(d), nil
+}
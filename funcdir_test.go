@@ -0,0 +1,81 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestFuncDir(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	i := 0
+	dir := NewFuncDir("/dir", func() (File, error) {
+		if i == 3 {
+			return nil, io.EOF
+		}
+		i++
+		return NewMemfileBytes(fmt.Sprintf("%d.txt", i), []byte{byte(i)}), nil
+	})
+	got, err := fs.Put(ctx, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect, err := fs.Put(ctx, NewMemdir("/dir",
+		NewMemfileBytes("1.txt", []byte{1}),
+		NewMemfileBytes("2.txt", []byte{2}),
+		NewMemfileBytes("3.txt", []byte{3}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expect {
+		t.Errorf("expected generated directory to hash like an equivalent Memdir. want: %q got: %q", expect, got)
+	}
+	if _, err := dir.NextFile(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected exhausted directory to keep returning io.EOF. got: %v", err)
+	}
+}
+
+func TestFuncDirConcurrentReads(t *testing.T) {
+	const n = 100
+	produced := 0
+	dir := NewFuncDir("/dir", func() (File, error) {
+		if produced == n {
+			return nil, io.EOF
+		}
+		produced++
+		return NewMemfileBytes(fmt.Sprintf("%d.txt", produced), nil), nil
+	})
+
+	var (
+		wg   sync.WaitGroup
+		lk   sync.Mutex
+		seen = map[string]bool{}
+	)
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				f, err := dir.NextFile()
+				if err != nil {
+					return
+				}
+				lk.Lock()
+				seen[f.FullPath()] = true
+				lk.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != n {
+		t.Errorf("expected %d distinct children, got: %d", n, len(seen))
+	}
+}
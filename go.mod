@@ -3,12 +3,17 @@ module github.com/qri-io/qfs
 go 1.15
 
 require (
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/gabriel-vasile/mimetype v1.2.0 // indirect
 	github.com/google/go-cmp v0.5.5
+	github.com/ipfs/go-bitswap v0.3.4
 	github.com/ipfs/go-block-format v0.0.3
 	github.com/ipfs/go-blockservice v0.1.4
 	github.com/ipfs/go-cid v0.0.7
 	github.com/ipfs/go-datastore v0.4.5
+	github.com/ipfs/go-ds-badger v0.2.6
+	github.com/ipfs/go-ds-flatfs v0.4.5
+	github.com/ipfs/go-fs-lock v0.0.6
 	github.com/ipfs/go-ipfs v0.9.1
 	github.com/ipfs/go-ipfs-blockstore v0.1.6
 	github.com/ipfs/go-ipfs-chunker v0.0.5
@@ -22,14 +27,22 @@ require (
 	github.com/ipfs/go-log v1.0.5
 	github.com/ipfs/go-merkledag v0.3.2
 	github.com/ipfs/go-mfs v0.1.2
+	github.com/ipfs/go-path v0.0.9
 	github.com/ipfs/go-unixfs v0.2.5
 	github.com/ipfs/interface-go-ipfs-core v0.4.0
+	github.com/ipld/go-car v0.3.1
+	github.com/libp2p/go-libp2p-core v0.8.5
 	github.com/mattn/go-isatty v0.0.12 // indirect
 	github.com/mattn/go-runewidth v0.0.9 // indirect
 	github.com/mitchellh/mapstructure v1.1.2
 	github.com/mr-tron/base58 v1.2.0
+	github.com/multiformats/go-multiaddr v0.3.3
+	github.com/multiformats/go-multibase v0.0.3
 	github.com/multiformats/go-multihash v0.0.15
 	github.com/otiai10/copy v1.2.0
+	github.com/polydawn/refmt v0.0.0-20201211092308-30ac6d18308e
 	github.com/qri-io/go-ipfs-http-client v0.0.6-0.20200623125303-7a2eee881baa
+	github.com/spf13/afero v1.1.2
 	golang.org/x/sys v0.0.0-20210511113859-b0526f3d8744
+	gopkg.in/yaml.v2 v2.4.0
 )
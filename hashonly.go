@@ -0,0 +1,27 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+)
+
+// HashOnlyFS is an opt-in interface for content-addressed filesystems that
+// can compute the path Put would return for a file without storing it
+type HashOnlyFS interface {
+	Filesystem
+	// HashOnly reads file, returning the path Put would return for it.
+	// Nothing is written to the store
+	HashOnly(ctx context.Context, file File) (string, error)
+}
+
+// HashOnly computes the path putting file to fs would return without storing
+// it, so callers can detect duplicates or check an expected CID before
+// committing a write. Filesystems that don't implement HashOnlyFS return an
+// error that wraps ErrUnsupported
+func HashOnly(ctx context.Context, fs Filesystem, file File) (string, error) {
+	hfs, ok := fs.(HashOnlyFS)
+	if !ok {
+		return "", fmt.Errorf("%w: %s filesystem can't hash without storing", ErrUnsupported, fs.Type())
+	}
+	return hfs.HashOnly(ctx, file)
+}
@@ -0,0 +1,89 @@
+package qfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestHashOnly(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	newDir := func() File {
+		return NewMemdir("/a",
+			NewMemfileBytes("b.txt", []byte("hello")),
+			NewMemdir("c", NewMemfileBytes("d.txt", []byte("world"))),
+		)
+	}
+
+	hashed, err := HashOnly(ctx, fs, newDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs.ObjectCount() != 0 {
+		t.Errorf("expected HashOnly to store nothing. got %d objects", fs.ObjectCount())
+	}
+
+	put, err := fs.Put(ctx, newDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashed != put {
+		t.Errorf("path mismatch. HashOnly: %s Put: %s", hashed, put)
+	}
+
+	if _, err := HashOnly(ctx, fs, NewMemfileBytes(put+"/a.txt", nil)); !errors.Is(err, ErrPathIgnored) {
+		t.Errorf("expected HashOnly to reject paths inside content. got: %v", err)
+	}
+
+	if _, err := HashOnly(ctx, Subtree(fs, put), newDir()); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported from a filesystem that can't hash. got: %v", err)
+	}
+}
+
+func TestHashOnlyMatchesPut(t *testing.T) {
+	ctx := context.Background()
+	data := bytes.Repeat([]byte("0123456789"), 100)
+
+	cases := []struct {
+		description string
+		hash        MemHash
+	}{
+		{"default", MemHash{}},
+		{"unixfs", MemHash{UnixFS: true}},
+		{"unixfs cidv1", MemHash{UnixFS: true, CidVersion: 1}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			fs := NewMemFS()
+			fs.SetChunkSize(64)
+			if err := fs.SetHash(c.hash); err != nil {
+				t.Fatal(err)
+			}
+			newDir := func() File {
+				return NewMemdir("/a",
+					NewMemfileBytes("big.txt", data),
+					NewMemdir("c", NewMemfileBytes("d.txt", []byte("world"))),
+				)
+			}
+
+			hashed, err := HashOnly(ctx, fs, newDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fs.ObjectCount() != 0 {
+				t.Errorf("expected HashOnly to store nothing. got %d objects", fs.ObjectCount())
+			}
+			put, err := fs.Put(ctx, newDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if hashed != put {
+				t.Errorf("path mismatch. HashOnly: %s Put: %s", hashed, put)
+			}
+		})
+	}
+}
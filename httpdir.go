@@ -0,0 +1,115 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+)
+
+// HTTPDir exposes the tree beneath root in fs as an http.FileSystem, for use
+// with http.FileServer & other net/http tooling. Directory indexes are read
+// with List, which is cheap for filesystems that implement ListingFS.
+// http.File must be seekable, files that aren't seekable or don't report
+// their size are read into memory when opened
+func HTTPDir(fs Filesystem, root string) http.FileSystem {
+	return &httpDir{fs: fs, root: root}
+}
+
+type httpDir struct {
+	fs   Filesystem
+	root string
+}
+
+var _ http.FileSystem = (*httpDir)(nil)
+
+// Open implements the http.FileSystem interface
+func (d *httpDir) Open(name string) (http.File, error) {
+	ctx := context.Background()
+	p := path.Join(d.root, path.Clean("/"+name))
+	f, err := d.fs.Get(ctx, p)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, &fs.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+		}
+		return nil, err
+	}
+
+	if f.IsDirectory() {
+		return &httpDirFile{File: f, dir: NewDirReader(ctx, d.fs, p, f.ModTime())}, nil
+	}
+
+	info, err := Stat(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if sf, ok := f.(SeekFile); ok && info.Size() >= 0 {
+		return &httpFile{SeekFile: sf, info: info}, nil
+	}
+
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return &httpFile{
+		SeekFile: NewMemfileBytes(f.FullPath(), data),
+		info: &fileInfo{
+			name:    info.Name(),
+			size:    int64(len(data)),
+			mode:    info.Mode(),
+			modTime: info.ModTime(),
+		},
+	}, nil
+}
+
+// httpFile adapts a seekable file to the http.File interface
+type httpFile struct {
+	SeekFile
+	info fs.FileInfo
+}
+
+var _ http.File = (*httpFile)(nil)
+
+func (f *httpFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, ErrNotDirectory
+}
+
+func (f *httpFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
+
+// httpDirFile adapts a directory to the http.File interface, listing entries
+// on the first call to Readdir
+type httpDirFile struct {
+	File
+	dir *DirReader
+}
+
+var _ http.File = (*httpDirFile)(nil)
+
+func (f *httpDirFile) Read(p []byte) (int, error) {
+	return 0, ErrNotFile
+}
+
+func (f *httpDirFile) Seek(offset int64, whence int) (int64, error) {
+	if offset == 0 && whence == io.SeekStart {
+		f.dir.Rewind()
+		return 0, nil
+	}
+	return 0, ErrNotFile
+}
+
+func (f *httpDirFile) Stat() (fs.FileInfo, error) {
+	return Stat(f.File)
+}
+
+// Readdir implements http.File, following the semantics of os.File.Readdir
+func (f *httpDirFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return f.dir.Readdir(count)
+}
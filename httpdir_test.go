@@ -0,0 +1,62 @@
+package qfs
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTPDir(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	root, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("bbb")),
+		NewMemdir("c",
+			NewMemfileReader("d.txt", strings.NewReader("dd")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := httptest.NewServer(http.FileServer(HTTPDir(fs, root)))
+	defer s.Close()
+
+	cases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/b.txt", http.StatusOK, "bbb"},
+		{"/c/d.txt", http.StatusOK, "dd"},
+		{"/c/", http.StatusOK, `<a href="d.txt">d.txt</a>`},
+		{"/", http.StatusOK, `<a href="c/">c/</a>`},
+		{"/missing.txt", http.StatusNotFound, ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			res, err := http.Get(s.URL + c.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != c.status {
+				t.Fatalf("status mismatch. want: %d got: %d", c.status, res.StatusCode)
+			}
+			if c.body == "" {
+				return
+			}
+			data, err := ioutil.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(data), c.body) {
+				t.Errorf("expected body to contain %q. got: %q", c.body, string(data))
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package httpfs
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/qri-io/qfs"
+)
+
+// ContentDecoder decodes a response body sent with a Content-Encoding
+type ContentDecoder func(r io.Reader) (io.ReadCloser, error)
+
+// encodingMediaTypes maps content encodings to the media type of bytes left
+// in that encoding
+var encodingMediaTypes = map[string]string{
+	"gzip": "application/gzip",
+	"br":   "application/x-brotli",
+}
+
+func decodeGzip(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// OptionContentDecoder negotiates encoding in Accept-Encoding headers,
+// decoding responses with dec. gzip is supported by default, use this to add
+// encodings like brotli ("br")
+func OptionContentDecoder(encoding string, dec ContentDecoder) Option {
+	return func(cfg *FSConfig) {
+		if cfg.decoders == nil {
+			cfg.decoders = map[string]ContentDecoder{}
+		}
+		cfg.decoders[strings.ToLower(encoding)] = dec
+	}
+}
+
+// OptionKeepEncoding returns response bodies in the content encoding the
+// server sent them in. Files report the media type of the encoding, eg:
+// "application/gzip", and the encoding itself from ContentEncoding
+func OptionKeepEncoding() Option {
+	return func(cfg *FSConfig) {
+		cfg.KeepEncoding = true
+	}
+}
+
+// decoder returns the decoder for encoding, if one is configured
+func (cfg *FSConfig) decoder(encoding string) (ContentDecoder, bool) {
+	if dec, ok := cfg.decoders[encoding]; ok {
+		return dec, true
+	}
+	if encoding == "gzip" {
+		return decodeGzip, true
+	}
+	return nil, false
+}
+
+// acceptEncoding lists the encodings requests accept, gzip first
+func (cfg *FSConfig) acceptEncoding() string {
+	encs := []string{"gzip"}
+	for enc := range cfg.decoders {
+		if enc != "gzip" {
+			encs = append(encs, enc)
+		}
+	}
+	sort.Strings(encs[1:])
+	return strings.Join(encs, ", ")
+}
+
+// contentEncodings lists the encodings applied to a response body, in the
+// order they were applied. identity encodings are omitted
+func contentEncodings(h http.Header) []string {
+	var encs []string
+	for _, v := range h.Values("Content-Encoding") {
+		for _, enc := range strings.Split(v, ",") {
+			enc = strings.ToLower(strings.TrimSpace(enc))
+			if enc != "" && enc != "identity" {
+				encs = append(encs, enc)
+			}
+		}
+	}
+	return encs
+}
+
+// decodeBody returns a reader of the decoded response body. Encodings without
+// a configured decoder return an error wrapping qfs.ErrUnsupported rather than
+// passing encoded bytes off as the resource
+func (cfg *FSConfig) decodeBody(res *http.Response) (io.ReadCloser, error) {
+	encs := contentEncodings(res.Header)
+	var body io.ReadCloser = res.Body
+	for i := len(encs) - 1; i >= 0; i-- {
+		dec, ok := cfg.decoder(encs[i])
+		if !ok {
+			return nil, fmt.Errorf("%w: content encoding %q", qfs.ErrUnsupported, encs[i])
+		}
+		r, err := dec(body)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s response: %w", encs[i], err)
+		}
+		body = r
+	}
+	return body, nil
+}
@@ -0,0 +1,62 @@
+package httpfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/qri-io/qfs"
+)
+
+// Gateway fetches raw blocks from an IPFS HTTP gateway, implementing
+// qfs.BlockGetter so gateways can be used as qfs.Repair sources
+type Gateway struct {
+	url string
+	cfg *FSConfig
+}
+
+var _ qfs.BlockGetter = (*Gateway)(nil)
+
+// NewGateway creates a Gateway for the gateway at url, eg:
+// "https://ipfs.io"
+func NewGateway(url string, opts ...Option) *Gateway {
+	cfg := DefaultFSConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return &Gateway{
+		url: strings.TrimSuffix(url, "/"),
+		cfg: cfg,
+	}
+}
+
+// GetBlock requests the raw block for id from the gateway
+func (g *Gateway) GetBlock(id cid.Cid) (io.Reader, error) {
+	req, err := http.NewRequest("GET", fmt.Sprintf("%s/ipfs/%s?format=raw", g.url, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.ipld.raw")
+	res, err := g.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, qfs.ErrNotFound
+	}
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("gateway responded with status %d", res.StatusCode)
+	}
+
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(data), nil
+}
@@ -2,6 +2,9 @@ package httpfs
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
 	"path/filepath"
 	"strings"
@@ -14,6 +17,12 @@ import (
 // FSConfig adjusts the behaviour of an FS instance
 type FSConfig struct {
 	Client *http.Client // client to use to make requests
+	// KeepEncoding returns response bodies without removing their content
+	// encoding, see OptionKeepEncoding
+	KeepEncoding bool
+
+	// decoders are content decoders added with OptionContentDecoder
+	decoders map[string]ContentDecoder
 }
 
 // Option is a function type for passing to NewFS
@@ -57,7 +66,12 @@ type FS struct {
 }
 
 // compile-time assertion that MapStore satisfies the Filesystem interface
-var _ qfs.Filesystem = (*FS)(nil)
+var (
+	_ qfs.Filesystem  = (*FS)(nil)
+	_ qfs.RangeGetter = (*FS)(nil)
+	_ qfs.StatPathFS  = (*FS)(nil)
+	_ qfs.ReadOnlyFS  = (*FS)(nil)
+)
 
 // NewFS creates a new local filesytem PathResolver
 func NewFS(cfgMap map[string]interface{}, opts ...Option) (qfs.Filesystem, error) {
@@ -81,34 +95,157 @@ func (httpfs *FS) Type() string {
 	return FilestoreType
 }
 
-// Has returns whether the store has a File with the key
-// https has no caching strategy, so it'll always return false
-func (https *FS) Has(ctx context.Context, path string) (bool, error) {
-	return false, nil
+// Has checks for the resource at path with a HEAD request, falling back to a
+// GET for servers that don't allow HEAD
+func (httpfs *FS) Has(ctx context.Context, path string) (bool, error) {
+	if path == "" {
+		return false, nil
+	}
+	resp, err := httpfs.head(ctx, path)
+	if err != nil {
+		return false, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented:
+		return qfs.GetBackedHas(ctx, httpfs, path)
+	case resp.StatusCode == http.StatusNotFound:
+		return false, nil
+	case resp.StatusCode >= 400:
+		return false, fmt.Errorf("HEAD %s: unexpected status: %s", path, resp.Status)
+	}
+	return true, nil
+}
+
+// head makes a HEAD request for path, closing the response body
+func (httpfs *FS) head(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequest("HEAD", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	resp, err := httpfs.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// StatPath describes the resource at path with a HEAD request. Size is read
+// from the Content-Length header, and is -1 if the server doesn't send one
+func (httpfs *FS) StatPath(ctx context.Context, path string) (qfs.PathInfo, error) {
+	info := qfs.PathInfo{Size: -1}
+	if path == "" {
+		return info, nil
+	}
+	resp, err := httpfs.head(ctx, path)
+	if err != nil {
+		return info, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return info, nil
+	case resp.StatusCode >= 400:
+		return info, fmt.Errorf("HEAD %s: unexpected status: %s", path, resp.Status)
+	}
+	info.Exists = true
+	info.Size = resp.ContentLength
+	return info, nil
 }
 
-// Get implements qfs.PathResolver
+// Get implements qfs.PathResolver. Get negotiates compressed responses with
+// the server, decoding them unless the filesystem is configured with
+// OptionKeepEncoding. Decoded files have an unknown size
 func (httpfs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
+	if path == "" {
+		return nil, qfs.ErrNotFound
+	}
 	req, err := http.NewRequest("GET", path, nil)
 	if err != nil {
 		return nil, err
 	}
 	req = req.WithContext(ctx)
+	req.Header.Set("Accept-Encoding", httpfs.cfg.acceptEncoding())
 	resp, err := httpfs.cfg.Client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 
 	if resp.StatusCode == http.StatusNotFound {
+		resp.Body.Close()
 		return nil, qfs.ErrNotFound
 	}
 
-	return &HTTPResFile{
+	f := &HTTPResFile{
 		path: path,
 		res:  resp,
-	}, nil
+		body: resp.Body,
+	}
+	if encs := contentEncodings(resp.Header); len(encs) > 0 {
+		if httpfs.cfg.KeepEncoding {
+			f.encoding = encs[len(encs)-1]
+			return f, nil
+		}
+		if f.body, err = httpfs.cfg.decodeBody(resp); err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+		f.decoded = true
+	}
+	return f, nil
 }
 
+// GetRange requests part of the resource at path with a Range header. Servers
+// that ignore the Range header and respond with the full resource have
+// the response body trimmed to the requested range. Ranges are always
+// requested without a content encoding, so offsets address the resource's
+// bytes
+func (httpfs *FS) GetRange(ctx context.Context, path string, offset, length int64) (qfs.File, error) {
+	if length == 0 {
+		// a zero-length Range header isn't expressible
+		return qfs.NewMemfileBytes(path, nil), nil
+	}
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	if length >= 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	req.Header.Set("Accept-Encoding", "identity")
+	resp, err := httpfs.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, qfs.ErrNotFound
+	case http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return qfs.NewMemfileBytes(path, nil), nil
+	}
+
+	f := &HTTPResFile{
+		path: path,
+		res:  resp,
+		body: resp.Body,
+	}
+	if resp.StatusCode == http.StatusPartialContent {
+		return f, nil
+	}
+	return qfs.NewRangeFile(f, offset, length)
+}
+
+// IsReadOnlyFilesystem marks the filesystem as read-only
+func (httpfs *FS) IsReadOnlyFilesystem() {}
+
 // Put places a file or directory on the filesystem, returning the root path.
 // The returned path may or may not honor the path of the given file
 func (httpfs *FS) Put(ctx context.Context, file qfs.File) (resultPath string, err error) {
@@ -124,20 +261,45 @@ func (httpfs *FS) Delete(ctx context.Context, path string) (err error) {
 type HTTPResFile struct {
 	res  *http.Response
 	path string
+	// body reads the response body, decoded if decoded is true
+	body    io.ReadCloser
+	decoded bool
+	// encoding is the content encoding of the bytes body reads, if any
+	encoding string
 }
 
-var _ qfs.File = (*HTTPResFile)(nil)
+var (
+	_ qfs.File     = (*HTTPResFile)(nil)
+	_ qfs.SizeFile = (*HTTPResFile)(nil)
+	_ qfs.StatFile = (*HTTPResFile)(nil)
+)
 
 // Read proxies to the response body reader
 func (rf *HTTPResFile) Read(p []byte) (int, error) {
-	return rf.res.Body.Read(p)
+	return rf.reader().Read(p)
 }
 
 // Close proxies to the response body reader
 func (rf *HTTPResFile) Close() error {
+	if rf.body != nil && rf.body != rf.res.Body {
+		rf.body.Close()
+	}
 	return rf.res.Body.Close()
 }
 
+func (rf *HTTPResFile) reader() io.Reader {
+	if rf.body != nil {
+		return rf.body
+	}
+	return rf.res.Body
+}
+
+// ContentEncoding returns the content encoding of the bytes Read returns, ""
+// if they're unencoded
+func (rf *HTTPResFile) ContentEncoding() string {
+	return rf.encoding
+}
+
 // IsDirectory satisfies the qfs.File interface
 func (rf *HTTPResFile) IsDirectory() bool {
 	return false
@@ -158,8 +320,15 @@ func (rf *HTTPResFile) FullPath() string {
 	return rf.path
 }
 
-// MediaType gets the value of the Content-Type response header
+// MediaType gets the value of the Content-Type response header. Files left
+// in a content encoding report the media type of the encoding
 func (rf *HTTPResFile) MediaType() string {
+	if rf.encoding != "" {
+		if mt, ok := encodingMediaTypes[rf.encoding]; ok {
+			return mt
+		}
+		return "application/octet-stream"
+	}
 	// TODO (b5) - this is super hacky
 	return strings.Split(rf.res.Header.Get("Content-Type"), ";")[0]
 }
@@ -170,3 +339,17 @@ func (rf *HTTPResFile) MediaType() string {
 func (rf *HTTPResFile) ModTime() time.Time {
 	return time.Time{}
 }
+
+// Size returns the Content-Length of the response, -1 if unknown. The
+// size of decoded responses is unknown
+func (rf *HTTPResFile) Size() int64 {
+	if rf.decoded {
+		return -1
+	}
+	return rf.res.ContentLength
+}
+
+// Stat returns info describing the response body
+func (rf *HTTPResFile) Stat() (fs.FileInfo, error) {
+	return qfs.FileInfo(rf), nil
+}
@@ -0,0 +1,263 @@
+package httpfs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/qfstest"
+)
+
+func TestPathSemantics(t *testing.T) {
+	s := httptest.NewServer(http.FileServer(http.FS(fstest.MapFS{
+		"a/b.txt": &fstest.MapFile{Data: []byte("b")},
+	})))
+	defer s.Close()
+
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	qfstest.AssertPathSemantics(t, fs, s.URL+"/a/b.txt")
+}
+
+func TestGetContentEncoding(t *testing.T) {
+	ctx := context.Background()
+	body := "hello, compressed world"
+	gzipped := gzipBytes(t, body)
+
+	var accepted string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted = r.Header.Get("Accept-Encoding")
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		switch r.URL.Path {
+		case "/plain":
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			w.Write([]byte(body))
+		case "/gzip", "/br", "/zstd":
+			w.Header().Set("Content-Encoding", strings.TrimPrefix(r.URL.Path, "/"))
+			w.Header().Set("Content-Length", strconv.Itoa(len(gzipped)))
+			w.Write(gzipped)
+		}
+	}))
+	defer s.Close()
+
+	// the br decoder stands in for a brotli implementation, reading the gzip
+	// bytes the server sends
+	br := OptionContentDecoder("br", func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	})
+
+	cases := []struct {
+		description string
+		opts        []Option
+		path        string
+		accept      string
+		body        string
+		mediaType   string
+		size        int64
+		encoding    string
+	}{
+		{"identity", nil, "/plain", "gzip", body, "text/plain", int64(len(body)), ""},
+		{"gzip", nil, "/gzip", "gzip", body, "text/plain", -1, ""},
+		{"br", []Option{br}, "/br", "gzip, br", body, "text/plain", -1, ""},
+		{"keep encoding", []Option{OptionKeepEncoding()}, "/gzip", "gzip", string(gzipped), "application/gzip", int64(len(gzipped)), "gzip"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			fs, err := NewFS(nil, c.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := fs.Get(ctx, s.URL+c.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			if accepted != c.accept {
+				t.Errorf("Accept-Encoding mismatch. want: %q got: %q", c.accept, accepted)
+			}
+			rf := f.(*HTTPResFile)
+			if rf.MediaType() != c.mediaType {
+				t.Errorf("media type mismatch. want: %q got: %q", c.mediaType, rf.MediaType())
+			}
+			if rf.Size() != c.size {
+				t.Errorf("size mismatch. want: %d got: %d", c.size, rf.Size())
+			}
+			if rf.ContentEncoding() != c.encoding {
+				t.Errorf("content encoding mismatch. want: %q got: %q", c.encoding, rf.ContentEncoding())
+			}
+			data, err := ioutil.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != c.body {
+				t.Errorf("body mismatch. want: %q got: %q", c.body, string(data))
+			}
+		})
+	}
+
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Get(ctx, s.URL+"/zstd"); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected an encoding without a decoder to return ErrUnsupported. got: %v", err)
+	}
+}
+
+func gzipBytes(t *testing.T, s string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w := gzip.NewWriter(buf)
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestHas(t *testing.T) {
+	ctx := context.Background()
+
+	var methods []string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		switch r.URL.Path {
+		case "/exists":
+			w.Write([]byte("a"))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/no-head/exists", "/no-head/missing":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			} else if r.URL.Path == "/no-head/missing" {
+				w.WriteHeader(http.StatusNotFound)
+			} else {
+				w.Write([]byte("a"))
+			}
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer s.Close()
+
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		path    string
+		exists  bool
+		methods []string
+	}{
+		{"/exists", true, []string{"HEAD"}},
+		{"/missing", false, []string{"HEAD"}},
+		{"/no-head/exists", true, []string{"HEAD", "GET"}},
+		{"/no-head/missing", false, []string{"HEAD", "GET"}},
+	}
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			methods = nil
+			exists, err := fs.Has(ctx, s.URL+c.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exists != c.exists {
+				t.Errorf("exists mismatch. want: %t got: %t", c.exists, exists)
+			}
+			if strings.Join(methods, ",") != strings.Join(c.methods, ",") {
+				t.Errorf("request methods mismatch. want: %v got: %v", c.methods, methods)
+			}
+		})
+	}
+
+	if _, err := fs.Has(ctx, s.URL+"/error"); err == nil {
+		t.Error("expected an error status to return an error")
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	ctx := context.Background()
+	body := "hello, ranged world"
+
+	var accepted, ranged string
+	s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		accepted, ranged = r.Header.Get("Accept-Encoding"), r.Header.Get("Range")
+		switch r.URL.Path {
+		case "/ranges":
+			// a server that would compress responses if asked to
+			if strings.Contains(accepted, "gzip") {
+				w.Header().Set("Content-Encoding", "gzip")
+				w.Write(gzipBytes(t, body))
+				return
+			}
+			http.ServeContent(w, r, "a.txt", time.Time{}, strings.NewReader(body))
+		case "/no-ranges":
+			w.Write([]byte(body))
+		}
+	}))
+	defer s.Close()
+
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rg := fs.(qfs.RangeGetter)
+
+	cases := []struct {
+		path           string
+		offset, length int64
+		expect         string
+	}{
+		{"/ranges", 7, 6, "ranged"},
+		{"/ranges", 7, -1, "ranged world"},
+		{"/ranges", 100, 5, ""},
+		{"/ranges", 0, 0, ""},
+		{"/no-ranges", 7, 6, "ranged"},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s %d:%d", c.path, c.offset, c.length), func(t *testing.T) {
+			accepted = ""
+			f, err := rg.GetRange(ctx, s.URL+c.path, c.offset, c.length)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			data, err := ioutil.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != c.expect {
+				t.Errorf("contents mismatch. want: %q got: %q", c.expect, string(data))
+			}
+			if c.length != 0 && accepted != "identity" {
+				t.Errorf("expected ranges to be requested without a content encoding. got Accept-Encoding: %q", accepted)
+			}
+		})
+	}
+
+	if _, err := rg.GetRange(ctx, s.URL+"/ranges", 2, 3); err != nil {
+		t.Fatal(err)
+	}
+	if ranged != "bytes=2-4" {
+		t.Errorf("range header mismatch. want: %q got: %q", "bytes=2-4", ranged)
+	}
+}
@@ -0,0 +1,153 @@
+// Package layout encodes the conventional layout of dataset components
+// stored beneath a root path, giving typed accessors in place of path
+// arithmetic. A dataset written to "/ipfs/QmFoo" keeps its metadata at
+// "/ipfs/QmFoo/meta.json" and its body at "/ipfs/QmFoo/body.csv"
+package layout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qri-io/qfs"
+)
+
+// Component names a part of a dataset
+type Component string
+
+// Dataset components
+const (
+	Dataset   Component = "dataset"
+	Body      Component = "body"
+	Meta      Component = "meta"
+	Structure Component = "structure"
+	Transform Component = "transform"
+	Readme    Component = "readme"
+)
+
+// Components lists every component other than the body, which is stored
+// under a name that depends on its format
+var Components = []Component{Dataset, Meta, Structure, Transform, Readme}
+
+// Filename returns the name a component is stored under. Components other
+// than the body are JSON files. Body files are named by their format with
+// BodyFilename
+func (c Component) Filename() string {
+	return string(c) + ".json"
+}
+
+// BodyFilename returns the name of a body file in the given format, eg:
+// "body.csv"
+func BodyFilename(format string) string {
+	return string(Body) + "." + format
+}
+
+// Layout addresses the components of a dataset stored at a root path on a
+// filesystem
+type Layout struct {
+	fs   qfs.Filesystem
+	root string
+}
+
+// New creates a Layout for the dataset stored at root on fs. Component paths
+// are built by appending to root, so root may be a URL
+func New(fs qfs.Filesystem, root string) *Layout {
+	return &Layout{fs: fs, root: strings.TrimSuffix(root, "/")}
+}
+
+// Root returns the path the dataset is stored at
+func (l *Layout) Root() string {
+	return l.root
+}
+
+// Path returns the path of a component. The body's path depends on its
+// format, use BodyPath to get it
+func (l *Layout) Path(c Component) string {
+	return l.root + "/" + c.Filename()
+}
+
+// Has reports whether component c is stored
+func (l *Layout) Has(ctx context.Context, c Component) (bool, error) {
+	if c == Body {
+		_, err := l.BodyPath(ctx)
+		if err != nil {
+			if errors.Is(err, qfs.ErrNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	return l.fs.Has(ctx, l.Path(c))
+}
+
+// Get fetches component c. A missing component returns an error wrapping
+// qfs.ErrNotFound
+func (l *Layout) Get(ctx context.Context, c Component) (qfs.File, error) {
+	if c == Body {
+		return l.Body(ctx)
+	}
+	return l.fs.Get(ctx, l.Path(c))
+}
+
+// Decode fetches component c and decodes its JSON contents into v
+func (l *Layout) Decode(ctx context.Context, c Component, v interface{}) error {
+	if c == Body {
+		return fmt.Errorf("%w: decoding dataset bodies", qfs.ErrUnsupported)
+	}
+	f, err := l.Get(ctx, c)
+	if err != nil {
+		return err
+	}
+	return qfs.DecodeJSON(f, v)
+}
+
+// BodyPath finds the path of the body file by listing the dataset root.
+// Datasets without a body return qfs.ErrNotFound
+func (l *Layout) BodyPath(ctx context.Context) (string, error) {
+	entries, err := qfs.List(ctx, l.fs, l.root)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir && strings.HasPrefix(entry.Name, string(Body)+".") {
+			return l.root + "/" + entry.Name, nil
+		}
+	}
+	return "", qfs.ErrNotFound
+}
+
+// Body fetches the body file
+func (l *Layout) Body(ctx context.Context) (qfs.File, error) {
+	p, err := l.BodyPath(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return l.fs.Get(ctx, p)
+}
+
+// Dataset fetches the dataset component
+func (l *Layout) Dataset(ctx context.Context) (qfs.File, error) {
+	return l.Get(ctx, Dataset)
+}
+
+// Meta fetches the meta component
+func (l *Layout) Meta(ctx context.Context) (qfs.File, error) {
+	return l.Get(ctx, Meta)
+}
+
+// Structure fetches the structure component
+func (l *Layout) Structure(ctx context.Context) (qfs.File, error) {
+	return l.Get(ctx, Structure)
+}
+
+// Transform fetches the transform component
+func (l *Layout) Transform(ctx context.Context) (qfs.File, error) {
+	return l.Get(ctx, Transform)
+}
+
+// Readme fetches the readme component
+func (l *Layout) Readme(ctx context.Context) (qfs.File, error) {
+	return l.Get(ctx, Readme)
+}
@@ -0,0 +1,61 @@
+package layout
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestLayout(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewMemFS()
+	root, err := fs.Put(ctx, qfs.NewMemdir("/ds",
+		qfs.NewMemfileBytes(Meta.Filename(), []byte(`{"title":"example"}`)),
+		qfs.NewMemfileBytes(BodyFilename("csv"), []byte("a,b\n1,2\n")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := New(fs, root+"/")
+	if got := l.Path(Meta); got != root+"/meta.json" {
+		t.Errorf("meta path mismatch. want: %q got: %q", root+"/meta.json", got)
+	}
+
+	meta := struct {
+		Title string `json:"title"`
+	}{}
+	if err := l.Decode(ctx, Meta, &meta); err != nil {
+		t.Fatal(err)
+	}
+	if meta.Title != "example" {
+		t.Errorf("expected decoded title. got: %q", meta.Title)
+	}
+
+	bodyPath, err := l.BodyPath(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bodyPath != root+"/body.csv" {
+		t.Errorf("body path mismatch. want: %q got: %q", root+"/body.csv", bodyPath)
+	}
+	body, err := l.Body(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(body); s != "a,b\n1,2\n" {
+		t.Errorf("body mismatch. got: %q", s)
+	}
+
+	if has, err := l.Has(ctx, Body); err != nil || !has {
+		t.Errorf("expected body to exist. has: %t err: %v", has, err)
+	}
+	if has, err := l.Has(ctx, Readme); err != nil || has {
+		t.Errorf("expected readme not to exist. has: %t err: %v", has, err)
+	}
+	if _, err := l.Structure(ctx); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected missing component to return ErrNotFound. got: %v", err)
+	}
+}
@@ -0,0 +1,194 @@
+package qfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/polydawn/refmt/cbor"
+	"github.com/polydawn/refmt/obj/atlas"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// CBORMediaType is the media type of files created with NewLinkedDataFile
+	CBORMediaType = "application/cbor"
+	// YAMLMediaType is the media type NewLinkedDataFileReader decodes as YAML
+	YAMLMediaType = "application/yaml"
+)
+
+// LinkedDataDecoder decodes a document into a generic value, the way
+// json.Unmarshal decodes into an interface{}. Values are normalized after
+// decoding, so decoders may return any map, slice & number types
+// NormalizeLinkedData accepts
+type LinkedDataDecoder func(r io.Reader) (interface{}, error)
+
+var (
+	linkedDataDecodersLk sync.RWMutex
+	linkedDataDecoders   = map[string]LinkedDataDecoder{
+		JSONMediaType: decodeJSONValue,
+		CBORMediaType: decodeCBORValue,
+		YAMLMediaType: decodeYAMLValue,
+	}
+)
+
+// RegisterLinkedDataDecoder adds or replaces the decoder for mediaType, for
+// ingesting formats like TOML or CUE with NewLinkedDataFileReader. JSON, CBOR
+// and YAML decoders are registered by default
+func RegisterLinkedDataDecoder(mediaType string, dec LinkedDataDecoder) {
+	linkedDataDecodersLk.Lock()
+	defer linkedDataDecodersLk.Unlock()
+	linkedDataDecoders[mediaType] = dec
+}
+
+// NewLinkedDataFile normalizes v and creates a file containing its canonical
+// dag-cbor encoding: map keys are sorted length-first, and the same value
+// always encodes to the same bytes, regardless of the format it was authored
+// in. See NormalizeLinkedData for the values v may hold
+func NewLinkedDataFile(path string, v interface{}) (File, error) {
+	norm, err := NormalizeLinkedData(v)
+	if err != nil {
+		return nil, err
+	}
+	data, err := cbor.MarshalAtlased(norm, canonicalCBORAtlas)
+	if err != nil {
+		return nil, err
+	}
+	return newEncodedFile(path, CBORMediaType, func() ([]byte, error) {
+		return data, nil
+	}), nil
+}
+
+// NewLinkedDataFileReader decodes r with the decoder registered for
+// mediaType, creating a linked data file from the result
+func NewLinkedDataFileReader(path, mediaType string, r io.Reader) (File, error) {
+	linkedDataDecodersLk.RLock()
+	dec, ok := linkedDataDecoders[mediaType]
+	linkedDataDecodersLk.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no linked data decoder registered for media type %q", mediaType)
+	}
+
+	v, err := dec(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", mediaType, err)
+	}
+	return NewLinkedDataFile(path, v)
+}
+
+// canonicalCBORAtlas sorts map keys the way dag-cbor requires
+var canonicalCBORAtlas = atlas.MustBuild().WithMapMorphism(atlas.MapMorphism{KeySortMode: atlas.KeySortMode_RFC7049})
+
+// NormalizeLinkedData converts v to the linked data value model: nil, bool,
+// int64, float64, string, []byte, []interface{} and map[string]interface{}.
+// Any integer type becomes int64 and float32 becomes float64. Maps must have
+// string keys, json.Number must be a valid number, and floats must be finite
+func NormalizeLinkedData(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case nil, bool, string, int64, []byte:
+		return x, nil
+	case int:
+		return int64(x), nil
+	case int8:
+		return int64(x), nil
+	case int16:
+		return int64(x), nil
+	case int32:
+		return int64(x), nil
+	case uint:
+		return uintToInt64(uint64(x))
+	case uint8:
+		return int64(x), nil
+	case uint16:
+		return int64(x), nil
+	case uint32:
+		return int64(x), nil
+	case uint64:
+		return uintToInt64(x)
+	case float32:
+		return normalizeFloat(float64(x))
+	case float64:
+		return normalizeFloat(x)
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := x.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return normalizeFloat(f)
+	case []interface{}:
+		list := make([]interface{}, len(x))
+		for i, el := range x {
+			n, err := NormalizeLinkedData(el)
+			if err != nil {
+				return nil, fmt.Errorf("index %d: %w", i, err)
+			}
+			list[i] = n
+		}
+		return list, nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, el := range x {
+			n, err := NormalizeLinkedData(el)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", k, err)
+			}
+			m[k] = n
+		}
+		return m, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, el := range x {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("map keys must be strings, got %T: %v", k, k)
+			}
+			n, err := NormalizeLinkedData(el)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: %w", key, err)
+			}
+			m[key] = n
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unsupported linked data type %T", v)
+	}
+}
+
+func uintToInt64(u uint64) (interface{}, error) {
+	if u > math.MaxInt64 {
+		return nil, fmt.Errorf("integer %d overflows int64", u)
+	}
+	return int64(u), nil
+}
+
+func normalizeFloat(f float64) (interface{}, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("float %v isn't finite", f)
+	}
+	return f, nil
+}
+
+func decodeJSONValue(r io.Reader) (interface{}, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var v interface{}
+	err := dec.Decode(&v)
+	return v, err
+}
+
+func decodeCBORValue(r io.Reader) (interface{}, error) {
+	var v interface{}
+	err := cbor.NewUnmarshaller(cbor.DecodeOptions{}, r).Unmarshal(&v)
+	return v, err
+}
+
+func decodeYAMLValue(r io.Reader) (interface{}, error) {
+	var v interface{}
+	err := yaml.NewDecoder(r).Decode(&v)
+	return v, err
+}
@@ -0,0 +1,96 @@
+package qfs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNewLinkedDataFileReader(t *testing.T) {
+	docs := map[string]string{
+		JSONMediaType: `{"title":"cities","meta":{"rows":3,"score":1.5},"tags":["a","b"]}`,
+		YAMLMediaType: "tags: [a, b]\nmeta:\n  score: 1.5\n  rows: 3\ntitle: cities\n",
+	}
+
+	var expect []byte
+	for mediaType, doc := range docs {
+		f, err := NewLinkedDataFileReader("data", mediaType, strings.NewReader(doc))
+		if err != nil {
+			t.Fatalf("%s: %s", mediaType, err)
+		}
+		if f.MediaType() != CBORMediaType {
+			t.Errorf("%s: media type mismatch. want: %q got: %q", mediaType, CBORMediaType, f.MediaType())
+		}
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expect == nil {
+			expect = data
+		} else if !bytes.Equal(expect, data) {
+			t.Errorf("expected JSON & YAML documents to produce the same bytes")
+		}
+	}
+
+	var got map[string]interface{}
+	if err := DecodeCBOR(NewMemfileBytes("data", expect), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["title"] != "cities" {
+		t.Errorf("title mismatch. got: %v", got["title"])
+	}
+
+	if _, err := NewLinkedDataFileReader("data", "application/toml", strings.NewReader("")); err == nil {
+		t.Error("expected an unregistered media type to error")
+	}
+	if _, err := NewLinkedDataFileReader("data", YAMLMediaType, strings.NewReader("1: one\n")); err == nil {
+		t.Error("expected a non-string map key to error")
+	}
+}
+
+func TestRegisterLinkedDataDecoder(t *testing.T) {
+	mediaType := "application/x-test"
+	RegisterLinkedDataDecoder(mediaType, func(r io.Reader) (interface{}, error) {
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, errors.New("empty")
+		}
+		return map[string]interface{}{"text": string(data)}, nil
+	})
+
+	if _, err := NewLinkedDataFileReader("data", mediaType, strings.NewReader("hello")); err != nil {
+		t.Error(err)
+	}
+	if _, err := NewLinkedDataFileReader("data", mediaType, strings.NewReader("")); err == nil {
+		t.Error("expected decoder errors to be returned")
+	}
+}
+
+func TestNormalizeLinkedData(t *testing.T) {
+	v, err := NormalizeLinkedData(map[string]interface{}{"a": []interface{}{1, uint8(2), float32(0.5)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := v.(map[string]interface{})["a"].([]interface{})
+	if list[0] != int64(1) || list[1] != int64(2) || list[2] != float64(0.5) {
+		t.Errorf("unexpected normalized values: %#v", list)
+	}
+
+	bad := []interface{}{
+		math.NaN(),
+		uint64(math.MaxUint64),
+		struct{}{},
+	}
+	for _, b := range bad {
+		if _, err := NormalizeLinkedData(b); err == nil {
+			t.Errorf("expected %#v to error", b)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package qfs
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"sort"
+	"time"
+)
+
+// DirEntry describes a single entry in a directory listing
+type DirEntry struct {
+	// Name is the base name of the entry
+	Name string
+	// Path is the path the entry can be fetched with. On content-addressed
+	// filesystems this is the content-addressed path of the entry itself,
+	// eg: /ipfs/QmFoo
+	Path string
+	// Size is the length of the entry in bytes, -1 if unknown. Directories
+	// always have a size of -1
+	Size int64
+	// IsDir is true if the entry is a directory
+	IsDir bool
+}
+
+// ListingFS is an opt-in interface for filesystems that can list the entries
+// of a directory without opening each child
+type ListingFS interface {
+	Filesystem
+	// List returns the entries of the directory at path sorted by name. List
+	// must return ErrNotDirectory if path isn't a directory
+	List(ctx context.Context, path string) ([]DirEntry, error)
+}
+
+// List returns the entries of the directory at path, using fs's List method
+// if fs implements ListingFS, and falling back to reading each child of the
+// directory with NextFile if not
+func List(ctx context.Context, fs Filesystem, path string) ([]DirEntry, error) {
+	if lfs, ok := fs.(ListingFS); ok {
+		return lfs.List(ctx, path)
+	}
+
+	dir, err := fs.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+	if !dir.IsDirectory() {
+		return nil, ErrNotDirectory
+	}
+
+	entries := []DirEntry{}
+	for {
+		f, err := dir.NextFile()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		entry := DirEntry{
+			Name:  f.FileName(),
+			Path:  f.FullPath(),
+			Size:  -1,
+			IsDir: f.IsDirectory(),
+		}
+		if sf, ok := f.(SizeFile); ok && !entry.IsDir {
+			entry.Size = sf.Size()
+		}
+		f.Close()
+		entries = append(entries, entry)
+	}
+	SortDirEntries(entries)
+	return entries, nil
+}
+
+// SortDirEntries sorts entries by name
+func SortDirEntries(entries []DirEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+}
+
+// DirReader pages through the entries of a directory with the semantics of
+// os.File.Readdir, listing the directory with List on the first call to
+// Readdir. Adapters that expose qfs directories as os-style files share it
+type DirReader struct {
+	ctx     context.Context
+	fs      Filesystem
+	path    string
+	modTime time.Time
+
+	entries []fs.FileInfo
+	listed  bool
+	offset  int
+}
+
+// NewDirReader creates a DirReader for the directory at path. Entries are
+// given modTime, listings don't carry modification times
+func NewDirReader(ctx context.Context, fsys Filesystem, path string, modTime time.Time) *DirReader {
+	return &DirReader{ctx: ctx, fs: fsys, path: path, modTime: modTime}
+}
+
+// Readdir returns up to count entries, or all remaining entries if count is
+// zero or less, following the semantics of os.File.Readdir
+func (r *DirReader) Readdir(count int) ([]fs.FileInfo, error) {
+	if !r.listed {
+		entries, err := List(r.ctx, r.fs, r.path)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			r.entries = append(r.entries, DirEntryInfo(e, r.modTime))
+		}
+		r.listed = true
+	}
+
+	rest := r.entries[r.offset:]
+	if count <= 0 {
+		r.offset = len(r.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(rest) {
+		count = len(rest)
+	}
+	r.offset += count
+	return rest[:count], nil
+}
+
+// Rewind makes the next call to Readdir start from the first entry
+func (r *DirReader) Rewind() {
+	r.offset = 0
+}
+
+// DirEntryInfo builds file info from a listing entry. Entries don't carry
+// modification times, so they're given modTime, usually the modification time
+// of their parent
+func DirEntryInfo(e DirEntry, modTime time.Time) fs.FileInfo {
+	fi := &fileInfo{
+		name:    e.Name,
+		size:    e.Size,
+		mode:    0644,
+		modTime: modTime,
+	}
+	if e.IsDir {
+		fi.size = 0
+		fi.mode = fs.ModeDir | 0755
+	}
+	return fi
+}
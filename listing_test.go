@@ -0,0 +1,76 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	root, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("foo")),
+		NewMemdir("/c",
+			NewMemfileBytes("d.txt", []byte("bar")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := List(ctx, fs, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries. got: %d", len(entries))
+	}
+	expect := []DirEntry{
+		{Name: "b.txt", Path: entries[0].Path, Size: 3},
+		{Name: "c", Path: entries[1].Path, Size: -1, IsDir: true},
+	}
+	if diff := cmp.Diff(expect, entries); diff != "" {
+		t.Errorf("entries mismatch. (-want +got):\n%s", diff)
+	}
+	if exists, _ := fs.Has(ctx, entries[0].Path); !exists {
+		t.Errorf("expected entry path %q to exist", entries[0].Path)
+	}
+
+	// fall back to reading children when List isn't implemented
+	fallback, err := List(ctx, Subtree(fs, root), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fallback) != 2 || fallback[0].Name != "b.txt" || fallback[0].Size != 3 || !fallback[1].IsDir {
+		t.Errorf("unexpected fallback listing: %#v", fallback)
+	}
+
+	if _, err := List(ctx, fs, entries[0].Path); !errors.Is(err, ErrNotDirectory) {
+		t.Errorf("expected listing a file to return ErrNotDirectory. got: %v", err)
+	}
+}
+
+func TestListFallbackClosesDirectory(t *testing.T) {
+	ctx := context.Background()
+	dir := &closeTrackingFile{File: NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("foo")),
+	)}
+	fs := getFS{Filesystem: NewMemFS(), get: func(context.Context, string) (File, error) { return dir, nil }}
+	if _, err := List(ctx, fs, "/a"); err != nil {
+		t.Fatal(err)
+	}
+	if !dir.closed {
+		t.Error("expected List to close the directory")
+	}
+}
+
+// getFS overrides Get, hiding opt-in interfaces of the wrapped filesystem
+type getFS struct {
+	Filesystem
+	get func(ctx context.Context, path string) (File, error)
+}
+
+func (fs getFS) Get(ctx context.Context, path string) (File, error) { return fs.get(ctx, path) }
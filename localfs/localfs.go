@@ -2,13 +2,17 @@ package localfs
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"mime"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	logging "github.com/ipfs/go-log"
 	"github.com/mitchellh/mapstructure"
 	"github.com/qri-io/qfs"
 )
@@ -16,6 +20,8 @@ import (
 // FilestoreType uniquely identifies this filestore
 const FilestoreType = "local"
 
+var log = logging.Logger("localfs")
+
 // FSConfig adjusts the behaviour of an FS instance
 type FSConfig struct {
 	PWD string // working directory. defaults to system root
@@ -54,10 +60,31 @@ func mapToConfig(cfgMap map[string]interface{}) (*FSConfig, error) {
 // FS is a implementation of qfs.PathResolver that uses the local filesystem
 type FS struct {
 	cfg *FSConfig
+
+	locksLk sync.Mutex
+	locks   map[string]io.Closer
+
+	// putLk serializes conditional puts. tokens maps the idempotency tokens of
+	// completed puts to their results, tokenOrder holds tokens oldest-first
+	putLk      sync.Mutex
+	tokens     map[string]string
+	tokenOrder []string
+
+	events qfs.EventBus
 }
 
 // compile-time assertion that MapStore satisfies the Filesystem interface
-var _ qfs.Filesystem = (*FS)(nil)
+var (
+	_ qfs.Filesystem     = (*FS)(nil)
+	_ qfs.HasManyFS      = (*FS)(nil)
+	_ qfs.LockFS         = (*FS)(nil)
+	_ qfs.RangeGetter    = (*FS)(nil)
+	_ qfs.ListingFS      = (*FS)(nil)
+	_ qfs.StatPathFS     = (*FS)(nil)
+	_ qfs.CapabilitiesFS = (*FS)(nil)
+	_ qfs.AppendFS       = (*FS)(nil)
+	_ qfs.EventPublisher = (*FS)(nil)
+)
 
 // NewFilesystem creates a new local filesystem Pathresolver
 // with no options
@@ -79,6 +106,15 @@ func NewFS(cfgMap map[string]interface{}, opts ...Option) (qfs.Filesystem, error
 	return &FS{cfg: cfg}, nil
 }
 
+// cleanPath drops trailing & repeated slashes from a local path. The empty
+// path is left empty, so it doesn't name the working directory
+func cleanPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return filepath.Clean(path)
+}
+
 // Type distinguishes this filesystem from others by a unique string prefix
 func (lfs *FS) Type() string {
 	return FilestoreType
@@ -86,6 +122,7 @@ func (lfs *FS) Type() string {
 
 // Has returns whether the store has a File with the key
 func (lfs *FS) Has(ctx context.Context, path string) (bool, error) {
+	path = cleanPath(path)
 	_, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -97,8 +134,42 @@ func (lfs *FS) Has(ctx context.Context, path string) (bool, error) {
 	return true, nil
 }
 
+// StatPath describes path with os.Stat
+func (lfs *FS) StatPath(ctx context.Context, path string) (qfs.PathInfo, error) {
+	path = cleanPath(path)
+	info := qfs.PathInfo{Size: -1}
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return info, nil
+		}
+		return info, err
+	}
+	info.Exists = true
+	info.IsDir = fi.IsDir()
+	if !info.IsDir {
+		info.Size = fi.Size()
+		info.ETag = etag(fi)
+	}
+	return info, nil
+}
+
+// HasMany stats each of the given paths
+func (lfs *FS) HasMany(ctx context.Context, paths []string) (map[string]bool, error) {
+	res := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		exists, err := lfs.Has(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		res[path] = exists
+	}
+	return res, nil
+}
+
 // Get implements qfs.PathResolver
 func (lfs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
+	path = cleanPath(path)
 	fi, err := os.Stat(path)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -109,12 +180,12 @@ func (lfs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
 
 	if fi.IsDir() {
 		// TODO (b5): implement local directory support
-		return nil, fmt.Errorf("local directory is not supported")
+		return nil, fmt.Errorf("%w: getting local directories", qfs.ErrUnsupported)
 	}
 
 	f, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("opening local file: %s", err.Error())
+		return nil, fmt.Errorf("opening local file: %w", err)
 	}
 
 	return &LocalFile{
@@ -124,46 +195,248 @@ func (lfs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
 	}, nil
 }
 
+// GetRange opens the file at path, seeking to offset and limiting reads to
+// length bytes
+func (lfs *FS) GetRange(ctx context.Context, path string, offset, length int64) (qfs.File, error) {
+	f, err := lfs.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return qfs.NewRangeFile(f, offset, length)
+}
+
+// List reads the entries of a local directory
+func (lfs *FS) List(ctx context.Context, path string) ([]qfs.DirEntry, error) {
+	path = cleanPath(path)
+	fi, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, qfs.ErrNotFound
+		}
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, qfs.ErrNotDirectory
+	}
+
+	des, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]qfs.DirEntry, 0, len(des))
+	for _, de := range des {
+		entry := qfs.DirEntry{
+			Name:  de.Name(),
+			Path:  filepath.Join(path, de.Name()),
+			Size:  -1,
+			IsDir: de.IsDir(),
+		}
+		if !entry.IsDir {
+			if info, err := de.Info(); err == nil {
+				entry.Size = info.Size()
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
 // Put places a file or directory on the filesystem, returning the root path.
-// The returned path may or may not honor the path of the given file
+// The returned path may or may not honor the path of the given file. Put
+// checks any qfs.Precondition set on ctx before writing
 func (lfs *FS) Put(ctx context.Context, file qfs.File) (resultPath string, err error) {
+	resultPath, _, err = lfs.putTree(ctx, file)
+	return resultPath, err
+}
+
+// PutTree writes file & any files within it, returning every path written in
+// the order they were written, directories before their contents. If a write
+// fails, the files & directories the call created are removed. Files that
+// existed before the call & were overwritten aren't restored. Files that
+// implement qfs.SymlinkFile are written as symlinks
+func (lfs *FS) PutTree(ctx context.Context, file qfs.File) (written []string, err error) {
+	_, written, err = lfs.putTree(ctx, file)
+	return written, err
+}
+
+func (lfs *FS) putTree(ctx context.Context, file qfs.File) (resultPath string, written []string, err error) {
+	if pre, ok := qfs.PreconditionFrom(ctx); ok {
+		resultPath, written, err = lfs.conditionalPut(ctx, file, pre)
+	} else {
+		resultPath = file.FullPath()
+		written, err = lfs.put(ctx, file)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	if len(written) > 0 {
+		lfs.events.Publish(qfs.Event{Type: qfs.EventFilePut, FSType: FilestoreType, Path: resultPath})
+	}
+	return resultPath, written, nil
+}
+
+// Subscribe calls fn after each successful Put, implementing the
+// qfs.EventPublisher interface
+func (lfs *FS) Subscribe(fn qfs.EventHandler) func() {
+	return lfs.events.Subscribe(fn)
+}
+
+// put writes file, returning the paths written. Paths created by a failed put
+// are removed
+func (lfs *FS) put(ctx context.Context, file qfs.File) (written []string, err error) {
+	var created []string
+	if err = lfs.putFile(ctx, file, &written, &created); err != nil {
+		for i := len(created) - 1; i >= 0; i-- {
+			if rerr := os.Remove(created[i]); rerr != nil && !os.IsNotExist(rerr) {
+				log.Errorf("removing %q after failed put: %s", created[i], rerr)
+			}
+		}
+		return nil, err
+	}
+	return written, nil
+}
+
+// putFile writes file & its contents, adding paths written & paths that
+// didn't exist before to written & created
+func (lfs *FS) putFile(ctx context.Context, file qfs.File, written, created *[]string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	path := file.FullPath()
-	// ensure directory exists
-	if err := os.MkdirAll(filepath.Dir(path), 0666); err != nil {
-		return "", err
+	if err := mkdirAll(filepath.Dir(path), created); err != nil {
+		return err
 	}
 
 	if file.IsDirectory() {
+		if err := mkdirAll(path, created); err != nil {
+			return err
+		}
+		*written = append(*written, path)
 		for {
 			childFile, err := file.NextFile()
-			if err != nil {
-				if err.Error() == "EOF" {
-					return path, err
-				}
-
-				return "", err
+			if errors.Is(err, io.EOF) {
+				return writeMetadata(path, file)
+			} else if err != nil {
+				return err
 			}
-
-			if _, err = lfs.Put(ctx, childFile); err != nil {
-				return "", err
+			if err := lfs.putFile(ctx, childFile, written, created); err != nil {
+				return err
 			}
 		}
 	}
 
+	_, statErr := os.Lstat(path)
+	if target, ok := qfs.IsSymlink(file); ok {
+		return putSymlink(path, target, statErr, written, created)
+	}
 	f, err := os.Create(path)
 	if err != nil {
-		return path, err
+		return err
+	}
+	if os.IsNotExist(statErr) {
+		*created = append(*created, path)
 	}
-	defer f.Close()
+	*written = append(*written, path)
 
 	_, err = io.Copy(f, file)
-	return path, err
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return writeMetadata(path, file)
+}
+
+// putSymlink creates a symlink at path, replacing any file already there.
+// statErr is the result of calling os.Lstat on path
+func putSymlink(path, target string, statErr error, written, created *[]string) error {
+	if statErr == nil {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+	if err := os.Symlink(target, path); err != nil {
+		return err
+	}
+	if os.IsNotExist(statErr) {
+		*created = append(*created, path)
+	}
+	*written = append(*written, path)
+	return nil
+}
+
+// mkdirAll creates dir & any missing parents, adding the directories it
+// creates to created, parents first
+func mkdirAll(dir string, created *[]string) error {
+	var missing []string
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			break
+		}
+		missing = append(missing, d)
+		if parent := filepath.Dir(d); parent == d {
+			break
+		}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		*created = append(*created, missing[i])
+	}
+	return nil
+}
+
+// Append writes the contents of r to the end of the file at path, creating the
+// file & any parent directories if they don't exist
+func (lfs *FS) Append(ctx context.Context, path string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// errXattrsUnsupported is returned when file attributes can't be persisted
+var errXattrsUnsupported = fmt.Errorf("%w: extended attributes", qfs.ErrUnsupported)
+
+// writeMetadata persists the permissions & attributes of file to path if file
+// implements qfs.MetadataFile. Attributes are stored as extended attributes
+func writeMetadata(path string, file qfs.File) error {
+	mf, ok := file.(qfs.MetadataFile)
+	if !ok {
+		return nil
+	}
+	if perm := mf.Mode().Perm(); perm != 0 {
+		if err := os.Chmod(path, perm); err != nil {
+			return err
+		}
+	}
+	return writeXattrs(path, mf.Metadata())
+}
+
+// Capabilities reports that local files can't be deleted
+func (lfs *FS) Capabilities() qfs.CapabilitySet {
+	caps := qfs.DeriveCapabilities(lfs)
+	caps.CanDelete = false
+	return caps
 }
 
 // Delete removes a file or directory from the filesystem
 func (lfs *FS) Delete(ctx context.Context, path string) (err error) {
+	if path == "" {
+		return fmt.Errorf("%w: empty path", qfs.ErrInvalidPath)
+	}
 	// TODO (b5):
-	return fmt.Errorf("deleting local files via qfs.Localfs is not finished")
+	return fmt.Errorf("%w: deleting local files via qfs.Localfs is not finished", qfs.ErrUnsupported)
 }
 
 // LocalFile implements qfs.File with a filesystem file
@@ -174,10 +447,20 @@ type LocalFile struct {
 }
 
 var (
-	_ qfs.File     = (*LocalFile)(nil)
-	_ qfs.SizeFile = (*LocalFile)(nil)
+	_ qfs.File         = (*LocalFile)(nil)
+	_ qfs.SizeFile     = (*LocalFile)(nil)
+	_ qfs.StatFile     = (*LocalFile)(nil) // Stat is provided by the embedded os.File
+	_ qfs.SeekFile     = (*LocalFile)(nil) // as is Seek
+	_ qfs.MetadataFile = (*LocalFile)(nil)
+	_ io.WriterTo      = (*LocalFile)(nil)
 )
 
+// WriteTo implements the io.WriterTo interface by copying straight from the
+// underlying os.File, letting the OS use sendfile & friends where available
+func (lf *LocalFile) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, &lf.File)
+}
+
 // IsDirectory satisfies the qfs.File interface
 func (lf *LocalFile) IsDirectory() bool {
 	return false
@@ -215,3 +498,19 @@ func (lf *LocalFile) ModTime() time.Time {
 func (lf *LocalFile) Size() int64 {
 	return lf.info.Size()
 }
+
+// Mode returns the file's mode & permission bits
+func (lf *LocalFile) Mode() fs.FileMode {
+	return lf.info.Mode()
+}
+
+// Metadata returns the file's user extended attributes, nil if there are none
+// or they can't be read
+func (lf *LocalFile) Metadata() map[string]string {
+	meta, err := readXattrs(lf.path)
+	if err != nil {
+		log.Debugw("reading extended attributes", "path", lf.path, "err", err)
+		return nil
+	}
+	return meta
+}
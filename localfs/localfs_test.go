@@ -1,10 +1,18 @@
 package localfs
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/iotest"
+	"time"
 
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/qfstest"
 )
 
 func TestMapToConfig(t *testing.T) {
@@ -41,3 +49,365 @@ func TestSizeFile(t *testing.T) {
 		t.Errorf("size mismatch. want: %d got: %d", expect, got)
 	}
 }
+
+func TestHasMany(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := qfs.HasMany(ctx, fs, []string{"testdata/text.txt", "testdata/missing.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got["testdata/text.txt"] {
+		t.Errorf("expected testdata/text.txt to exist")
+	}
+	if got["testdata/missing.txt"] {
+		t.Errorf("expected testdata/missing.txt not to exist")
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := qfs.GetTo(ctx, fs, "testdata/text.txt", buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 12 {
+		t.Errorf("written length mismatch. want: 12 got: %d", n)
+	}
+}
+
+func TestLock(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "qfs_localfs_lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fs, err := NewFS(map[string]interface{}{"PWD": dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lfs := fs.(*FS)
+
+	if err := lfs.Lock(ctx, "write.lock"); err != nil {
+		t.Fatal(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*50)
+	defer cancel()
+	if err := lfs.Lock(timeoutCtx, "write.lock"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected locking a held lock to wait until context deadline. got: %v", err)
+	}
+
+	if err := lfs.Unlock("write.lock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := lfs.Lock(ctx, "write.lock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := lfs.Unlock("write.lock"); err != nil {
+		t.Fatal(err)
+	}
+	if err := lfs.Unlock("write.lock"); !errors.Is(err, qfs.ErrNotLocked) {
+		t.Errorf("expected unlocking an unheld lock to return ErrNotLocked. got: %v", err)
+	}
+}
+
+func TestGetRange(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := qfs.GetRange(ctx, fs, "testdata/text.txt", 6, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "world"; expect != string(data) {
+		t.Errorf("range mismatch. want: %q got: %q", expect, string(data))
+	}
+	if size := f.(qfs.SizeFile).Size(); size != 5 {
+		t.Errorf("size mismatch. want: 5 got: %d", size)
+	}
+}
+
+func TestAppend(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "qfs_localfs_append")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "logs", "run.log")
+	for _, line := range []string{"one\n", "two\n"} {
+		if err := qfs.Append(ctx, fs, path, bytes.NewBufferString(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "one\ntwo\n"; expect != string(data) {
+		t.Errorf("appended contents mismatch. want: %q got: %q", expect, string(data))
+	}
+}
+
+func TestList(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := qfs.List(ctx, fs, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry. got: %d", len(entries))
+	}
+	expect := qfs.DirEntry{Name: "text.txt", Path: "testdata/text.txt", Size: 12}
+	if entries[0] != expect {
+		t.Errorf("entry mismatch. want: %#v got: %#v", expect, entries[0])
+	}
+
+	if _, err := fs.(qfs.ListingFS).List(ctx, "testdata/text.txt"); !errors.Is(err, qfs.ErrNotDirectory) {
+		t.Errorf("expected listing a file to return ErrNotDirectory. got: %v", err)
+	}
+}
+
+func TestUnsupportedErrors(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Get(ctx, "testdata"); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected getting a directory to return ErrUnsupported. got: %v", err)
+	}
+	if err := fs.Delete(ctx, "testdata/text.txt"); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected delete to return ErrUnsupported. got: %v", err)
+	}
+}
+
+func TestMetadataRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "qfs_localfs_metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := qfs.NewMemfileBytes(filepath.Join(dir, "a.txt"), []byte("a"))
+	f.SetMode(0600)
+	f.SetMetadata(map[string]string{"tag": "draft"})
+	path, err := fs.Put(ctx, f)
+	if errors.Is(err, qfs.ErrUnsupported) {
+		t.Skipf("extended attributes aren't supported here: %s", err)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := fs.Get(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer got.Close()
+	mf, ok := got.(qfs.MetadataFile)
+	if !ok {
+		t.Fatal("expected local files to implement qfs.MetadataFile")
+	}
+	if mf.Mode().Perm() != 0600 {
+		t.Errorf("mode mismatch. want: %s got: %s", os.FileMode(0600), mf.Mode().Perm())
+	}
+	if mf.Metadata()["tag"] != "draft" {
+		t.Errorf("expected metadata to survive a round trip. got: %v", mf.Metadata())
+	}
+}
+
+func TestStatPath(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat("testdata/text.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := qfs.StatPath(ctx, fs, "testdata/text.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Exists || info.IsDir || info.Size != fi.Size() {
+		t.Errorf("unexpected file info: %#v", info)
+	}
+
+	info, err = qfs.StatPath(ctx, fs, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Exists || !info.IsDir || info.Size != -1 {
+		t.Errorf("unexpected directory info: %#v", info)
+	}
+
+	info, err = qfs.StatPath(ctx, fs, "testdata/missing.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Exists {
+		t.Errorf("expected missing path not to exist: %#v", info)
+	}
+}
+
+func TestPathSemantics(t *testing.T) {
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "qfs_localfs_paths")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.txt")
+	if err := ioutil.WriteFile(path, []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	qfstest.AssertPathSemantics(t, fs, path)
+}
+
+func TestPutTree(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "qfs_localfs_put_tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "tree")
+	written, err := fs.(*FS).PutTree(ctx, qfs.NewMemdir(root,
+		qfs.NewMemfileBytes("a.txt", []byte("a")),
+		qfs.NewMemdir("b",
+			qfs.NewMemdir("c",
+				qfs.NewMemfileBytes("d.txt", []byte("d")),
+			),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []string{
+		root,
+		filepath.Join(root, "a.txt"),
+		filepath.Join(root, "b"),
+		filepath.Join(root, "b/c"),
+		filepath.Join(root, "b/c/d.txt"),
+	}
+	if len(written) != len(expect) {
+		t.Fatalf("written paths mismatch. want: %v got: %v", expect, written)
+	}
+	for i := range expect {
+		if written[i] != expect[i] {
+			t.Errorf("written path %d mismatch. want: %q got: %q", i, expect[i], written[i])
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(root, "b/c/d.txt")); err != nil || string(data) != "d" {
+		t.Errorf("expected nested file to be written. got: %q, %v", string(data), err)
+	}
+
+	// a failure part way through removes what the put created, leaving
+	// existing content in place
+	failing := qfs.NewMemdir(root,
+		qfs.NewMemdir("new",
+			qfs.NewMemfileBytes("e.txt", []byte("e")),
+		),
+		qfs.NewMemfileReader("f.txt", iotest.ErrReader(errors.New("read failed"))),
+	)
+	if _, err := fs.Put(ctx, failing); err == nil {
+		t.Fatal("expected put with a failing file to error")
+	}
+	for _, p := range []string{"new", "new/e.txt", "f.txt"} {
+		if _, err := os.Stat(filepath.Join(root, p)); !os.IsNotExist(err) {
+			t.Errorf("expected %q to be removed after failed put. got: %v", p, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.txt")); err != nil {
+		t.Errorf("expected existing file to remain after failed put. got: %v", err)
+	}
+}
+
+func TestPutSymlink(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "qfs_localfs_symlink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	root := filepath.Join(dir, "tree")
+	if err := os.MkdirAll(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(root, "replaced"), []byte("file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Put(ctx, qfs.NewMemdir(root,
+		qfs.NewMemfileBytes("a.txt", []byte("a")),
+		qfs.NewMemsymlink("link", "a.txt"),
+		qfs.NewMemsymlink("replaced", "../elsewhere"),
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string]string{"link": "a.txt", "replaced": "../elsewhere"} {
+		got, err := os.Readlink(filepath.Join(root, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: target mismatch. want: %q got: %q", name, want, got)
+		}
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(root, "link")); err != nil || string(data) != "a" {
+		t.Errorf("expected link to resolve to a.txt. got: %q, %v", string(data), err)
+	}
+}
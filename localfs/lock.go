@@ -0,0 +1,68 @@
+package localfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	lockfile "github.com/ipfs/go-fs-lock"
+	"github.com/qri-io/qfs"
+)
+
+// lockPollInterval is the time to wait between attempts to acquire a lock
+// held by someone else
+const lockPollInterval = time.Millisecond * 10
+
+// Lock acquires an advisory lock on the file at path name, creating it if
+// necessary. Locks are held with flock (or the platform equivalent), so they
+// serialize access across processes as well as goroutines. Lock polls until
+// the lock is acquired or ctx is done
+func (lfs *FS) Lock(ctx context.Context, name string) error {
+	name = lfs.lockPath(name)
+	for {
+		lk, err := lockfile.Lock(filepath.Dir(name), filepath.Base(name))
+		if err == nil {
+			lfs.locksLk.Lock()
+			if lfs.locks == nil {
+				lfs.locks = map[string]io.Closer{}
+			}
+			lfs.locks[name] = lk
+			lfs.locksLk.Unlock()
+			return nil
+		}
+		if !errors.As(err, new(lockfile.LockedError)) {
+			return err
+		}
+
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Unlock releases a lock acquired with Lock
+func (lfs *FS) Unlock(name string) error {
+	name = lfs.lockPath(name)
+	lfs.locksLk.Lock()
+	defer lfs.locksLk.Unlock()
+	lk, ok := lfs.locks[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", qfs.ErrNotLocked, name)
+	}
+	delete(lfs.locks, name)
+	return lk.Close()
+}
+
+// lockPath resolves relative lock names against the configured working
+// directory
+func (lfs *FS) lockPath(name string) string {
+	if !filepath.IsAbs(name) && lfs.cfg.PWD != "" {
+		return filepath.Join(lfs.cfg.PWD, name)
+	}
+	return name
+}
@@ -0,0 +1,293 @@
+package localfs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+	"github.com/multiformats/go-multihash"
+	"github.com/qri-io/qfs"
+)
+
+// MirrorStateFilename is the name of the file Mirror keeps in the root of a
+// mirrored directory to track what was written
+const MirrorStateFilename = ".qfs_mirror.json"
+
+// MirrorState records the root a directory was last mirrored from, the
+// source CID of each file written, & a checksum of the contents written to
+// disk. Files from sources that don't report CIDs record their checksum in
+// place of a CID. Checksums are sha2-256 multihashes of file contents,
+// encoded as CIDs
+type MirrorState struct {
+	Root      string            `json:"root"`
+	Files     map[string]string `json:"files"`
+	Checksums map[string]string `json:"checksums"`
+}
+
+// MirrorResult lists paths relative to the mirror directory that were
+// affected by a call to Mirror
+type MirrorResult struct {
+	Written   []string
+	Removed   []string
+	Unchanged []string
+}
+
+// ReadMirrorState loads mirror state from a directory. A directory that has
+// never been mirrored to returns an empty state
+func ReadMirrorState(dir string) (*MirrorState, error) {
+	state := &MirrorState{Files: map[string]string{}, Checksums: map[string]string{}}
+	data, err := ioutil.ReadFile(filepath.Join(dir, MirrorStateFilename))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("reading mirror state: %w", err)
+	}
+	if state.Files == nil {
+		state.Files = map[string]string{}
+	}
+	if state.Checksums == nil {
+		state.Checksums = map[string]string{}
+	}
+	return state, nil
+}
+
+// Mirror materializes the file or directory at root into dir on the local
+// filesystem, recording the state of the mirror in dir. Subsequent calls only
+// fetch files whose source CIDs differ from the recorded state, or that have
+// been modified on disk since they were written. Sources that implement
+// qfs.HashOnlyFS have fetched content checked against its CID. Files written
+// by a previous mirror that no longer exist in root are removed. Files in dir
+// that Mirror didn't write are left alone
+func Mirror(ctx context.Context, src qfs.Filesystem, root, dir string) (*MirrorResult, error) {
+	prev, err := ReadMirrorState(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := qfs.StatPath(ctx, src, root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Exists {
+		return nil, fmt.Errorf("%w: %q", qfs.ErrNotFound, root)
+	}
+
+	next := &MirrorState{Root: root, Files: map[string]string{}, Checksums: map[string]string{}}
+	res := &MirrorResult{}
+
+	mirrorFile := func(p, rel string, id cid.Cid) error {
+		target, err := mirrorPath(dir, rel)
+		if err != nil {
+			return err
+		}
+		if id.Defined() {
+			next.Files[rel] = id.String()
+			if sum := prev.Checksums[rel]; prev.Files[rel] == id.String() && sum != "" && localChecksum(target) == sum {
+				next.Checksums[rel] = sum
+				res.Unchanged = append(res.Unchanged, rel)
+				return nil
+			}
+		}
+
+		f, err := src.Get(ctx, p)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+			f.Close()
+			return err
+		}
+		// stream to a temp file beside the target, hashing as it's written, so
+		// files of any size are never held in memory
+		tmp, sum, err := writeTemp(filepath.Dir(target), f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+		if err := checkMirrored(ctx, src, tmp, id); err != nil {
+			os.Remove(tmp)
+			return fmt.Errorf("mirroring %q: %w", rel, err)
+		}
+		if !id.Defined() {
+			next.Files[rel] = sum
+		}
+		next.Checksums[rel] = sum
+
+		if prev.Checksums[rel] == sum && localChecksum(target) == sum {
+			res.Unchanged = append(res.Unchanged, rel)
+			return os.Remove(tmp)
+		}
+		if err := os.Rename(tmp, target); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		res.Written = append(res.Written, rel)
+		return nil
+	}
+
+	var visit func(p, rel string) error
+	visit = func(p, rel string) error {
+		entries, err := qfs.List(ctx, src, p)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			chRel := filepath.Join(rel, e.Name)
+			if e.IsDir {
+				err = visit(e.Path, chRel)
+			} else {
+				var info qfs.PathInfo
+				if info, err = qfs.StatPath(ctx, src, e.Path); err == nil {
+					err = mirrorFile(e.Path, chRel, info.Cid)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if info.IsDir {
+		err = visit(root, ".")
+	} else {
+		err = mirrorFile(root, path.Base(root), info.Cid)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for rel := range prev.Files {
+		if _, ok := next.Files[rel]; ok {
+			continue
+		}
+		target, err := mirrorPath(dir, rel)
+		if err != nil {
+			return nil, fmt.Errorf("reading mirror state: %w", err)
+		}
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		res.Removed = append(res.Removed, rel)
+	}
+
+	data, err := json.MarshalIndent(next, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, MirrorStateFilename), data, 0644); err != nil {
+		return nil, err
+	}
+
+	sort.Strings(res.Written)
+	sort.Strings(res.Removed)
+	sort.Strings(res.Unchanged)
+	return res, nil
+}
+
+// mirrorPath joins rel to dir, returning an error that wraps
+// qfs.ErrInvalidPath if rel names a path outside dir, dir itself or the
+// mirror state file. Names come from the source & the state file, neither of
+// which can be trusted to stay within dir
+func mirrorPath(dir, rel string) (string, error) {
+	clean := filepath.Clean(rel)
+	if filepath.IsAbs(clean) || clean == "." || clean == ".." || clean == MirrorStateFilename ||
+		strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%w: %q isn't within the mirror directory", qfs.ErrInvalidPath, rel)
+	}
+	return filepath.Join(dir, clean), nil
+}
+
+// writeTemp copies r to a new temp file in dir, returning the temp file's
+// path & the checksum of its contents
+func writeTemp(dir string, r io.Reader) (path, sum string, err error) {
+	tmp, err := ioutil.TempFile(dir, ".qfs_mirror_*")
+	if err != nil {
+		return "", "", err
+	}
+	h := sha256.New()
+	_, err = io.Copy(io.MultiWriter(tmp, h), r)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		sum, err = checksumOf(h)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	return tmp.Name(), sum, nil
+}
+
+// checkMirrored returns an error if the file at path doesn't hash to id.
+// Files without a source CID & sources that can't hash without storing
+// aren't checked
+func checkMirrored(ctx context.Context, src qfs.Filesystem, path string, id cid.Cid) error {
+	hfs, ok := src.(qfs.HashOnlyFS)
+	if !ok || !id.Defined() {
+		return nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	got, err := hfs.HashOnly(ctx, qfs.NewMemfileReader(filepath.Base(path), f))
+	if err != nil {
+		return err
+	}
+	_, hash, _ := qfs.SplitStorePath(got)
+	if gotID, err := cid.Decode(hash); err != nil || !gotID.Equals(id) {
+		return fmt.Errorf("fetched content hashes to %s, expected %s", got, id)
+	}
+	return nil
+}
+
+// checksumOf encodes the digest of a sha2-256 hash as a CID
+func checksumOf(h hash.Hash) (string, error) {
+	mh, err := multihash.Encode(h.Sum(nil), multihash.SHA2_256)
+	if err != nil {
+		return "", err
+	}
+	return cid.NewCidV0(mh).String(), nil
+}
+
+// localChecksum returns the checksum of a file on disk, or the empty string if
+// the file can't be read
+func localChecksum(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return ""
+	}
+	sum, err := checksumOf(h)
+	if err != nil {
+		return ""
+	}
+	return sum
+}
@@ -0,0 +1,238 @@
+package localfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/qfs"
+)
+
+func TestMirror(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "qfs_mirror_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := qfs.NewMemFS()
+	rootA, err := src.Put(ctx, qfs.NewMemdir("/",
+		qfs.NewMemfileBytes("body.json", []byte(`[1,2,3]`)),
+		qfs.NewMemfileBytes("meta.json", []byte(`{"title":"a"}`)),
+		qfs.NewMemdir("viz",
+			qfs.NewMemfileBytes("index.html", []byte(`<html></html>`)),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := Mirror(ctx, src, rootA, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &MirrorResult{
+		Written: []string{"body.json", "meta.json", "viz/index.html"},
+	}
+	if diff := cmp.Diff(expect, res); diff != "" {
+		t.Errorf("first mirror result mismatch (-want +got):\n%s", diff)
+	}
+
+	// mirror the same root with a locally modified file
+	if err := ioutil.WriteFile(filepath.Join(dir, "meta.json"), []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res, err = Mirror(ctx, src, rootA, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = &MirrorResult{
+		Written:   []string{"meta.json"},
+		Unchanged: []string{"body.json", "viz/index.html"},
+	}
+	if diff := cmp.Diff(expect, res); diff != "" {
+		t.Errorf("corrected mirror result mismatch (-want +got):\n%s", diff)
+	}
+
+	rootB, err := src.Put(ctx, qfs.NewMemdir("/",
+		qfs.NewMemfileBytes("body.json", []byte(`[1,2,3,4]`)),
+		qfs.NewMemfileBytes("meta.json", []byte(`{"title":"a"}`)),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = Mirror(ctx, src, rootB, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect = &MirrorResult{
+		Written:   []string{"body.json"},
+		Removed:   []string{"viz/index.html"},
+		Unchanged: []string{"meta.json"},
+	}
+	if diff := cmp.Diff(expect, res); diff != "" {
+		t.Errorf("updated mirror result mismatch (-want +got):\n%s", diff)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "body.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[1,2,3,4]` {
+		t.Errorf("body.json data mismatch. want: %q got: %q", `[1,2,3,4]`, string(data))
+	}
+
+	state, err := ReadMirrorState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Root != rootB {
+		t.Errorf("state root mismatch. want: %q got: %q", rootB, state.Root)
+	}
+}
+
+func TestMirrorSourceCIDs(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "qfs_mirror_cids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	src := &countingFS{MemFS: qfs.NewMemFS()}
+	root, err := src.Put(ctx, qfs.NewMemdir("/",
+		qfs.NewMemfileBytes("a.txt", []byte("a")),
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Mirror(ctx, src, root, dir); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := ReadMirrorState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		info, err := qfs.StatPath(ctx, src, root+"/"+name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if state.Files[name] != info.Cid.String() {
+			t.Errorf("expected state to record the source CID of %s. want: %s got: %s", name, info.Cid, state.Files[name])
+		}
+	}
+
+	// unchanged entries aren't fetched again
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.txt"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	src.gets = nil
+	res, err := Mirror(ctx, src, root, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := &MirrorResult{Written: []string{"b.txt"}, Unchanged: []string{"a.txt"}}
+	if diff := cmp.Diff(expect, res); diff != "" {
+		t.Errorf("result mismatch (-want +got):\n%s", diff)
+	}
+	if len(src.gets) != 1 {
+		t.Errorf("expected only the locally modified file to be fetched. got: %v", src.gets)
+	}
+
+	// fetched content must match its source CID
+	os.Remove(filepath.Join(dir, "a.txt"))
+	src.tamper = true
+	if _, err := Mirror(ctx, src, root, dir); err == nil {
+		t.Error("expected content that doesn't match its CID to fail")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected mismatched content not to be written. got: %v", err)
+	}
+}
+
+// countingFS records the paths fetched with Get, optionally returning the
+// wrong content for them
+type countingFS struct {
+	*qfs.MemFS
+	gets   []string
+	tamper bool
+}
+
+func (fs *countingFS) Get(ctx context.Context, path string) (qfs.File, error) {
+	fs.gets = append(fs.gets, path)
+	if fs.tamper {
+		return qfs.NewMemfileBytes("tampered.txt", []byte("tampered")), nil
+	}
+	return fs.MemFS.Get(ctx, path)
+}
+
+func TestMirrorContainment(t *testing.T) {
+	ctx := context.Background()
+	base, err := ioutil.TempDir("", "qfs_mirror_containment")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(base)
+	dir := filepath.Join(base, "mirror")
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+
+	src := resolver{f: qfs.NewMemdir("/",
+		renamed{qfs.NewMemfileBytes("escape.txt", []byte("escaped")), "../escape.txt"},
+	)}
+	if _, err := Mirror(ctx, src, "/", dir); !errors.Is(err, qfs.ErrInvalidPath) {
+		t.Errorf("expected a name outside the mirror to return ErrInvalidPath. got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(base, "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be written outside the mirror. got: %v", err)
+	}
+
+	// a tampered state file mustn't be able to remove files outside the mirror
+	victim := filepath.Join(base, "victim.txt")
+	if err := ioutil.WriteFile(victim, []byte("keep"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	state := []byte(`{"root":"/","files":{"../victim.txt":"QmFoo"}}`)
+	if err := ioutil.WriteFile(filepath.Join(dir, MirrorStateFilename), state, 0644); err != nil {
+		t.Fatal(err)
+	}
+	mem := qfs.NewMemFS()
+	root, err := mem.Put(ctx, qfs.NewMemdir("/", qfs.NewMemfileBytes("a.txt", []byte("a"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Mirror(ctx, mem, root, dir); !errors.Is(err, qfs.ErrInvalidPath) {
+		t.Errorf("expected a state entry outside the mirror to return ErrInvalidPath. got: %v", err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("expected file outside the mirror to be left alone. got: %v", err)
+	}
+}
+
+// resolver returns f for any path
+type resolver struct {
+	qfs.Filesystem
+	f qfs.File
+}
+
+func (r resolver) Get(ctx context.Context, path string) (qfs.File, error) {
+	return r.f, nil
+}
+
+// renamed overrides the name of a file
+type renamed struct {
+	qfs.File
+	name string
+}
+
+func (f renamed) FileName() string { return f.name }
@@ -0,0 +1,67 @@
+package localfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/qri-io/qfs"
+)
+
+// maxIdempotencyTokens caps the number of completed write tokens remembered.
+// Once full, the oldest token is forgotten
+const maxIdempotencyTokens = 1024
+
+// etag identifies the version of a local file by its modification time &
+// size
+func etag(fi os.FileInfo) string {
+	return fmt.Sprintf("%x-%x", fi.ModTime().UnixNano(), fi.Size())
+}
+
+// conditionalPut puts file if the precondition holds, returning the paths
+// written. Nothing is written when an idempotency token repeats. Conditional
+// puts are serialized, so preconditions are
+// only guaranteed against writes made through this FS
+func (lfs *FS) conditionalPut(ctx context.Context, file qfs.File, pre qfs.Precondition) (res string, written []string, err error) {
+	lfs.putLk.Lock()
+	defer lfs.putLk.Unlock()
+
+	if pre.IdempotencyToken != "" {
+		if res, ok := lfs.tokens[pre.IdempotencyToken]; ok {
+			return res, nil, nil
+		}
+	}
+
+	path := file.FullPath()
+	if pre.IfMatch != "" {
+		fi, err := os.Stat(path)
+		if err != nil && !os.IsNotExist(err) {
+			return "", nil, err
+		}
+		if err != nil || fi.IsDir() || etag(fi) != pre.IfMatch {
+			return "", nil, fmt.Errorf("%w: %q doesn't match ETag %q", qfs.ErrPreconditionFailed, path, pre.IfMatch)
+		}
+	}
+
+	if written, err = lfs.put(ctx, file); err != nil {
+		return "", nil, err
+	}
+	if pre.IdempotencyToken != "" {
+		lfs.rememberToken(pre.IdempotencyToken, path)
+	}
+	return path, written, nil
+}
+
+// rememberToken records the result of a completed write. callers must hold
+// putLk
+func (lfs *FS) rememberToken(token, res string) {
+	if lfs.tokens == nil {
+		lfs.tokens = map[string]string{}
+	}
+	if len(lfs.tokenOrder) >= maxIdempotencyTokens {
+		delete(lfs.tokens, lfs.tokenOrder[0])
+		lfs.tokenOrder = lfs.tokenOrder[1:]
+	}
+	lfs.tokens[token] = res
+	lfs.tokenOrder = append(lfs.tokenOrder, token)
+}
@@ -0,0 +1,79 @@
+package localfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestPutPrecondition(t *testing.T) {
+	ctx := context.Background()
+	fs, err := NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "qfs_localfs_precondition")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "a.txt")
+
+	if _, err := fs.Put(ctx, qfs.NewMemfileBytes(path, []byte("one"))); err != nil {
+		t.Fatal(err)
+	}
+	info, err := qfs.StatPath(ctx, fs, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ETag == "" {
+		t.Fatal("expected StatPath to report an ETag")
+	}
+
+	// a concurrent update changes the ETag
+	if _, err := fs.Put(ctx, qfs.NewMemfileBytes(path, []byte("three!"))); err != nil {
+		t.Fatal(err)
+	}
+	matchCtx := qfs.WithPrecondition(ctx, qfs.Precondition{IfMatch: info.ETag})
+	_, err = fs.Put(matchCtx, qfs.NewMemfileBytes(path, []byte("two")))
+	if !errors.Is(err, qfs.ErrPreconditionFailed) {
+		t.Errorf("expected a stale ETag to return ErrPreconditionFailed. got: %v", err)
+	}
+
+	if info, err = qfs.StatPath(ctx, fs, path); err != nil {
+		t.Fatal(err)
+	}
+	matchCtx = qfs.WithPrecondition(ctx, qfs.Precondition{IfMatch: info.ETag})
+	if _, err := fs.Put(matchCtx, qfs.NewMemfileBytes(path, []byte("two"))); err != nil {
+		t.Errorf("expected a current ETag to allow the write. got: %v", err)
+	}
+
+	// retrying a write with the same token doesn't write again
+	tokenCtx := qfs.WithPrecondition(ctx, qfs.Precondition{IdempotencyToken: "write-1"})
+	if _, err := fs.Put(tokenCtx, qfs.NewMemfileBytes(path, []byte("four"))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Put(ctx, qfs.NewMemfileBytes(path, []byte("five"))); err != nil {
+		t.Fatal(err)
+	}
+	res, err := fs.Put(tokenCtx, qfs.NewMemfileBytes(path, []byte("four")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != path {
+		t.Errorf("expected a retry to return the first write's result. want: %q got: %q", path, res)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "five" {
+		t.Errorf("expected a retried write not to clobber a later update. got: %q", string(data))
+	}
+}
@@ -0,0 +1,132 @@
+package localfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/qri-io/qfs"
+)
+
+// WatchOptions configures the behaviour of WatchAndSync
+type WatchOptions struct {
+	// Debounce is the quiet period to wait after a change before writing, so a
+	// burst of changes results in a single write. defaults to 100ms
+	Debounce time.Duration
+}
+
+// SyncEvent is sent by WatchAndSync after each attempt to write the watched
+// directory. Root holds the path returned by the destination Put, Err is
+// non-nil if the write failed
+type SyncEvent struct {
+	Root string
+	Err  error
+}
+
+// WatchAndSync writes the directory at localPath to dst, then watches
+// localPath for changes, writing it again each time files within it change.
+// The root path of every write that differs from the previous one is sent on
+// the returned channel. Watching stops & the channel closes when ctx is
+// cancelled
+func WatchAndSync(ctx context.Context, localPath string, dst qfs.Filesystem, opts *WatchOptions) (<-chan SyncEvent, error) {
+	if opts == nil {
+		opts = &WatchOptions{}
+	}
+	if opts.Debounce == 0 {
+		opts.Debounce = time.Millisecond * 100
+	}
+
+	localPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watchDirs(watcher, localPath); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	events := make(chan SyncEvent)
+	go func() {
+		defer close(events)
+		defer watcher.Close()
+
+		prevRoot := ""
+		sync := func() {
+			root, err := putDir(ctx, localPath, dst)
+			if err == nil && root == prevRoot {
+				return
+			}
+			if err == nil {
+				prevRoot = root
+			}
+			select {
+			case events <- SyncEvent{Root: root, Err: err}:
+			case <-ctx.Done():
+			}
+		}
+
+		sync()
+		timer := time.NewTimer(opts.Debounce)
+		timer.Stop()
+		for {
+			select {
+			case evt, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				// fsnotify doesn't watch recursively, pick up newly created directories
+				if evt.Op&fsnotify.Create != 0 {
+					if fi, err := os.Stat(evt.Name); err == nil && fi.IsDir() {
+						if err := watchDirs(watcher, evt.Name); err != nil {
+							log.Debugw("watching new directory", "path", evt.Name, "err", err)
+						}
+					}
+				}
+				timer.Reset(opts.Debounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Debugw("watching directory", "path", localPath, "err", err)
+			case <-timer.C:
+				sync()
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			}
+		}
+	}()
+
+	return events, nil
+}
+
+// watchDirs adds a watch for dir and every directory beneath it
+func watchDirs(watcher *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// putDir reads the local directory at path into a file tree and writes it to
+// dst
+func putDir(ctx context.Context, path string, dst qfs.Filesystem) (string, error) {
+	dir, err := qfs.NewFileFromFS(os.DirFS(path), ".")
+	if err != nil {
+		return "", err
+	}
+	dir.(qfs.PathSetter).SetPath("/")
+	return dst.Put(ctx, dir)
+}
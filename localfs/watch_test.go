@@ -0,0 +1,79 @@
+package localfs
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestWatchAndSync(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dir, err := ioutil.TempDir("", "qfs_watch_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "body.json"), []byte(`[1,2,3]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := qfs.NewMemFS()
+	events, err := WatchAndSync(ctx, dir, dst, &WatchOptions{Debounce: time.Millisecond * 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() SyncEvent {
+		t.Helper()
+		select {
+		case evt := <-events:
+			if evt.Err != nil {
+				t.Fatal(evt.Err)
+			}
+			return evt
+		case <-time.After(time.Second * 5):
+			t.Fatal("timed out waiting for sync event")
+		}
+		return SyncEvent{}
+	}
+
+	first := next()
+
+	if err := os.MkdirAll(filepath.Join(dir, "viz"), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	// give the watcher a moment to pick up the new directory
+	time.Sleep(time.Millisecond * 50)
+	if err := ioutil.WriteFile(filepath.Join(dir, "viz", "index.html"), []byte(`<html></html>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var second SyncEvent
+	for {
+		second = next()
+		if has, _ := dst.Has(ctx, second.Root+"/viz/index.html"); has {
+			break
+		}
+	}
+	if first.Root == second.Root {
+		t.Errorf("expected root to change after writing a file")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		for ok {
+			_, ok = <-events
+		}
+	case <-time.After(time.Second):
+		t.Error("expected events channel to close after context cancellation")
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package localfs
+
+// readXattrs always returns nil, extended attributes are only supported on
+// linux & darwin
+func readXattrs(path string) (map[string]string, error) {
+	return nil, nil
+}
+
+// writeXattrs errors if meta has any attributes, extended attributes are only
+// supported on linux & darwin
+func writeXattrs(path string, meta map[string]string) error {
+	if len(meta) > 0 {
+		return errXattrsUnsupported
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package localfs
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// xattrPrefix namespaces file metadata within extended attributes. Linux only
+// permits unprivileged users to write attributes in the "user." namespace
+const xattrPrefix = "user."
+
+// readXattrs reads the user extended attributes of the file at path, returning
+// nil if the file has none or the filesystem doesn't support them
+func readXattrs(path string) (map[string]string, error) {
+	size, err := unix.Listxattr(path, nil)
+	if err != nil {
+		if errors.Is(err, unix.ENOTSUP) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	buf := make([]byte, size)
+	if size, err = unix.Listxattr(path, buf); err != nil {
+		return nil, err
+	}
+
+	var meta map[string]string
+	for _, name := range bytes.Split(buf[:size], []byte{0}) {
+		attr := string(name)
+		if !strings.HasPrefix(attr, xattrPrefix) {
+			continue
+		}
+		vsize, err := unix.Getxattr(path, attr, nil)
+		if err != nil {
+			return nil, err
+		}
+		val := make([]byte, vsize)
+		if vsize, err = unix.Getxattr(path, attr, val); err != nil {
+			return nil, err
+		}
+		if meta == nil {
+			meta = map[string]string{}
+		}
+		meta[strings.TrimPrefix(attr, xattrPrefix)] = string(val[:vsize])
+	}
+	return meta, nil
+}
+
+// writeXattrs sets meta as user extended attributes of the file at path
+func writeXattrs(path string, meta map[string]string) error {
+	for key, val := range meta {
+		if err := unix.Setxattr(path, xattrPrefix+key, []byte(val), 0); err != nil {
+			if errors.Is(err, unix.ENOTSUP) {
+				return errXattrsUnsupported
+			}
+			return err
+		}
+	}
+	return nil
+}
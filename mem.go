@@ -2,8 +2,8 @@ package qfs
 
 import (
 	"bytes"
+	"container/list"
 	"context"
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
@@ -12,12 +12,11 @@ import (
 	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	format "github.com/ipfs/go-ipld-format"
 	merkledag "github.com/ipfs/go-merkledag"
-	"github.com/mr-tron/base58"
-	"github.com/multiformats/go-multihash"
 )
 
 // MemFilestoreType uniquely identifies the mem filestore
@@ -40,18 +39,77 @@ const MemFilestoreType = "mem"
 // File is an interface that provides functionality for handling
 // cafs/directories as values that can be supplied to commands.
 type MemFS struct {
-	Pinned  bool
-	Network []*MemFS
-
-	filesLk sync.Mutex
+	Pinned bool
+	// Network lists connected peers Get asks for keys that aren't stored
+	// locally. Modify connections with AddConnection, which is safe to call
+	// while the store is in use
+	Network   []*MemFS
+	networkLk sync.RWMutex
+
+	// filesLk guards Files & the other fields describing stored content.
+	// Reads hold the lock shared, writes hold it exclusively
+	filesLk sync.RWMutex
 	Files   map[string]filer
+
+	locksLk sync.Mutex
+	locks   map[string]chan struct{}
+
+	faultsLk    sync.Mutex
+	faults      MemFaults
+	unavailable map[string]struct{}
+	puts        int
+	// links configures simulated connections to peers on the Network
+	links map[*MemFS]*memLink
+
+	// chunkSize is the size of the chunks file contents are read & stored in,
+	// DefaultChunkSize if zero
+	chunkSize int
+	// hash configures the content identifiers stored content is keyed by
+	hash MemHash
+	// events publishes puts & deletes
+	events EventBus
+
+	// maxBytes caps the bytes of file content stored, bytes is the current
+	// total. lru orders keys most recently used first when evicting, nil if
+	// eviction is disabled
+	maxBytes int64
+	bytes    int64
+	lru      *list.List
+	lruElems map[string]*list.Element
+	// lruLk guards lru & lruElems, which reads holding filesLk shared update
+	lruLk sync.Mutex
+
+	// ttl is how long objects live after they're written, zero if they don't
+	// expire. expires holds the time each object expires, read from clock.
+	// expiries orders expiry times soonest first, so sweeps only visit
+	// expired objects
+	ttl      time.Duration
+	clock    Clock
+	expires  map[string]time.Time
+	expiries expiryHeap
+
+	// roots are the keys of objects written by callers, which keep the objects
+	// they reference from being garbage collected
+	roots map[string]struct{}
+	// pending counts holds on objects written by puts in progress, which
+	// aren't reachable from a root until the put finishes
+	pending map[string]int
 }
 
 // compile-time assertions
 var (
-	_ Filesystem     = (*MemFS)(nil)
-	_ CAFS           = (*MemFS)(nil)
-	_ MerkleDagStore = (*MemFS)(nil)
+	_ Filesystem      = (*MemFS)(nil)
+	_ HasManyFS       = (*MemFS)(nil)
+	_ LockFS          = (*MemFS)(nil)
+	_ CAFS            = (*MemFS)(nil)
+	_ MerkleDagStore  = (*MemFS)(nil)
+	_ BlockLister     = (*MemFS)(nil)
+	_ ListingFS       = (*MemFS)(nil)
+	_ DeletePlannerFS = (*MemFS)(nil)
+	_ StatPathFS      = (*MemFS)(nil)
+	_ HashOnlyFS      = (*MemFS)(nil)
+	_ EventPublisher  = (*MemFS)(nil)
+	_ DiskUsageFS     = (*MemFS)(nil)
 )
 
 // NewMemFilesystem allocates an instace of a mapstore that
@@ -62,10 +120,25 @@ func NewMemFilesystem(_ context.Context, cfg map[string]interface{}) (Filesystem
 }
 
 // NewMemFS allocates an instance of a mapstore
-func NewMemFS() *MemFS {
-	return &MemFS{
+func NewMemFS(opts ...MemOption) *MemFS {
+	m := &MemFS{
 		Files: make(map[string]filer),
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// SetChunkSize sets the size of the chunks file contents are read & stored
+// in. Files are hashed as they're read, so writing a file holds at most its
+// content plus one chunk in memory. Chunk size doesn't affect hashes unless
+// hashing with MemHash.UnixFS, where it matches the ipfs add chunker size.
+// Zero uses DefaultChunkSize
+func (m *MemFS) SetChunkSize(size int) {
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	m.chunkSize = size
 }
 
 // Type distinguishes this filesystem from others by a unique string prefix
@@ -77,8 +150,8 @@ func (m *MemFS) IsContentAddressedFilesystem() {}
 
 // Print converts the store to a string
 func (m *MemFS) Print() (string, error) {
-	m.filesLk.Lock()
-	defer m.filesLk.Unlock()
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
 
 	buf := &bytes.Buffer{}
 	for key, file := range m.Files {
@@ -102,6 +175,8 @@ func (m *MemFS) Print() (string, error) {
 
 // ObjectCount returns the number of content-addressed objects in the store
 func (m *MemFS) ObjectCount() (objects int) {
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
 	return len(m.Files)
 }
 
@@ -109,98 +184,276 @@ func (m *MemFS) ObjectCount() (objects int) {
 // Deprecated - this method breaks CAFS interface assertions. Don't use it.
 func (m *MemFS) PutFileAtKey(ctx context.Context, key string, file File) error {
 	if file.IsDirectory() {
-		return fmt.Errorf("PutFileAtKey does not work with directories")
+		return fmt.Errorf("%w: PutFileAtKey does not work with directories", ErrIsDirectory)
 	}
-	data, err := ioutil.ReadAll(file)
+	if err := checkMemSymlink(file); err != nil {
+		return err
+	}
+	data, err := readChunked(file, m.getChunkSize(), ioutil.Discard)
 	if err != nil {
 		return err
 	}
-	m.Files[key] = fsFile{name: file.FileName(), path: file.FullPath(), data: data}
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	if err := m.setFile(key, fsFile{name: file.FileName(), path: file.FullPath(), data: data}); err != nil {
+		return err
+	}
+	m.addRoot(key)
 	return nil
 }
 
 // Put adds a file to the store
 func (m *MemFS) Put(ctx context.Context, file File) (key string, err error) {
-	key, err = m.put(ctx, file)
-	return fmt.Sprintf("/%s/%s", MemFilestoreType, key), err
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if err := m.putFault(); err != nil {
+		return "", err
+	}
+	if err := CheckCAFSPutPath(MemFilestoreType, file.FullPath()); err != nil {
+		return "", err
+	}
+	key, held, err := m.put(ctx, file, false)
+	path := fmt.Sprintf("/%s/%s", MemFilestoreType, key)
+	m.filesLk.Lock()
+	if err == nil {
+		m.addRoot(key)
+	}
+	m.release(held)
+	m.filesLk.Unlock()
+	if err == nil {
+		m.events.Publish(Event{Type: EventFilePut, FSType: MemFilestoreType, Path: path})
+	}
+	return path, err
 }
 
-func (m *MemFS) put(ctx context.Context, file File) (key string, err error) {
+// Subscribe calls fn after each successful Put & Delete, implementing the
+// EventPublisher interface
+func (m *MemFS) Subscribe(fn EventHandler) func() {
+	return m.events.Subscribe(fn)
+}
 
-	if file.IsDirectory() {
-		buf := bytes.NewBuffer(nil)
-		dir := fsDir{
-			fs:    m,
-			path:  file.FullPath(),
-			files: map[string]string{},
+// put stores file, returning its key & the keys of objects written, which are
+// held until the caller releases them. If hashOnly is set files are hashed as
+// they're read & nothing is stored or held
+func (m *MemFS) put(ctx context.Context, file File, hashOnly bool) (key string, held []string, err error) {
+	if !file.IsDirectory() {
+		if hashOnly {
+			id, _, err := m.hashFile(file)
+			if err != nil {
+				return "", nil, err
+			}
+			return m.getHash().key(id), nil, nil
+		}
+		id, _, err := m.putFile(file)
+		if err != nil {
+			return "", nil, err
 		}
+		key := m.getHash().key(id)
+		return key, []string{key}, nil
+	}
 
-		for {
-			f, e := file.NextFile()
-			if e != nil {
-				if e.Error() == "EOF" {
-					dirhash, e := hashBytes(buf.Bytes())
-					if err != nil {
-						err = fmt.Errorf("error hashing file data: %s", e.Error())
-						return
-					}
-
-					key = dirhash
-					m.filesLk.Lock()
-					m.Files[dirhash] = dir
-					m.filesLk.Unlock()
-					return
-				}
-				err = fmt.Errorf("error getting next file: %s", err.Error())
-				return
+	// directories are written depth-first using an explicit stack of open
+	// directories, so deep trees can't exhaust the goroutine stack. each frame
+	// accumulates the hashes of its children. once a directory is exhausted
+	// its block is hashed to produce the directory hash, so the hash doesn't
+	// depend on the order children are read in
+	type frame struct {
+		file File
+		dir  fsDir
+		// links & size track children for UnixFS directory nodes
+		links []*format.Link
+		size  uint64
+	}
+	newFrame := func(f File) *frame {
+		return &frame{
+			file: f,
+			dir: fsDir{
+				fs:    m,
+				path:  f.FullPath(),
+				files: map[string]string{},
+			},
+		}
+	}
+
+	hc := m.getHash()
+	stack := []*frame{newFrame(file)}
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return "", held, err
+		}
+		top := stack[len(stack)-1]
+		f, e := top.file.NextFile()
+		if e != nil {
+			if !errors.Is(e, io.EOF) {
+				return "", held, fmt.Errorf("error getting next file: %w", e)
 			}
 
-			hash, e := m.put(ctx, f)
+			var (
+				dirID   cid.Cid
+				dirSize uint64
+			)
+			if hc.UnixFS {
+				dirID, dirSize, e = hc.unixfsDir(top.links)
+			} else {
+				dirID, e = hc.sum(top.dir.blockData(), cid.DagProtobuf)
+			}
 			if e != nil {
-				err = fmt.Errorf("error putting file: %s", e.Error())
-				return
+				return "", held, fmt.Errorf("error hashing file data: %s", e.Error())
 			}
-			key = hash
-			m.filesLk.Lock()
-			dir.files[f.FileName()] = hash
-			m.filesLk.Unlock()
-			_, err = buf.WriteString(key + "\n")
-			if err != nil {
-				err = fmt.Errorf("error writing to buffer: %s", err.Error())
-				return
+			dirhash := hc.key(dirID)
+			if !hashOnly {
+				m.filesLk.Lock()
+				e = m.setFile(dirhash, top.dir)
+				if e == nil {
+					m.hold(dirhash)
+					held = append(held, dirhash)
+				}
+				m.filesLk.Unlock()
+				if e != nil {
+					return "", held, e
+				}
 			}
+
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				return dirhash, held, nil
+			}
+			parent := stack[len(stack)-1]
+			parent.dir.files[top.file.FileName()] = dirhash
+			parent.links = append(parent.links, &format.Link{Name: top.file.FileName(), Size: dirSize, Cid: dirID})
+			continue
 		}
-	} else {
-		data, e := ioutil.ReadAll(file)
-		if e != nil {
-			err = fmt.Errorf("error reading from file: %s", e.Error())
-			return
+
+		if f.IsDirectory() {
+			stack = append(stack, newFrame(f))
+			continue
 		}
-		hash, e := hashBytes(data)
+
+		if hashOnly {
+			id, size, e := m.hashFile(f)
+			if e != nil {
+				return "", held, fmt.Errorf("error hashing file: %w", e)
+			}
+			top.dir.files[f.FileName()] = hc.key(id)
+			top.links = append(top.links, &format.Link{Name: f.FileName(), Size: size, Cid: id})
+			continue
+		}
+		id, size, e := m.putFile(f)
 		if e != nil {
-			err = fmt.Errorf("error hashing file data: %s", e.Error())
-			return
+			return "", held, fmt.Errorf("error putting file: %w", e)
 		}
-		m.filesLk.Lock()
-		m.Files[hash] = fsFile{name: file.FileName(), path: file.FullPath(), data: data}
-		m.filesLk.Unlock()
-		key = hash
-		return
+		held = append(held, hc.key(id))
+		top.dir.files[f.FileName()] = hc.key(id)
+		top.links = append(top.links, &format.Link{Name: f.FileName(), Size: size, Cid: id})
+	}
+
+	return key, held, nil
+}
+
+// HashOnly returns the path Put would return for file without storing it.
+// Files are hashed with the same hash configuration & chunk size as Put
+func (m *MemFS) HashOnly(ctx context.Context, file File) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
 	}
+	if err := CheckCAFSPutPath(MemFilestoreType, file.FullPath()); err != nil {
+		return "", err
+	}
+	key, _, err := m.put(ctx, file, true)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("/%s/%s", MemFilestoreType, key), nil
 }
 
-// Get returns a File from the store
+// hashFile hashes file as it's read in the same way putFile does, without
+// buffering or storing its content
+func (m *MemFS) hashFile(file File) (id cid.Cid, size uint64, err error) {
+	if err := checkMemSymlink(file); err != nil {
+		return cid.Undef, 0, err
+	}
+	hc := m.getHash()
+	if hc.UnixFS {
+		return hc.unixfsFile(file, m.getChunkSize())
+	}
+	h, err := hc.newHasher()
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	if _, err := io.Copy(h, file); err != nil {
+		return cid.Undef, 0, fmt.Errorf("error reading from file: %s", err.Error())
+	}
+	id, err = hc.cid(h.Sum(nil), cid.Raw)
+	return id, 0, err
+}
+
+// checkMemSymlink returns an error that wraps ErrUnsupported if file is a
+// symbolic link. MemFS stores files as bytes, so a link would be read back as
+// a regular file holding its target
+func checkMemSymlink(file File) error {
+	if _, ok := IsSymlink(file); ok {
+		return fmt.Errorf("%w: MemFS can't store symlinks, %q", ErrUnsupported, file.FullPath())
+	}
+	return nil
+}
+
+// putFile stores a file, hashing content as it's read in chunks. size is the
+// cumulative size of the file's UnixFS DAG when hashing with UnixFS. The
+// stored file is held, the caller must release it
+func (m *MemFS) putFile(file File) (id cid.Cid, size uint64, err error) {
+	if err := checkMemSymlink(file); err != nil {
+		return cid.Undef, 0, err
+	}
+	hc := m.getHash()
+	h, e := hc.newHasher()
+	if e != nil {
+		return cid.Undef, 0, e
+	}
+	var w io.Writer = h
+	if hc.UnixFS {
+		w = ioutil.Discard
+	}
+	data, e := readChunked(file, m.getChunkSize(), w)
+	if e != nil {
+		return cid.Undef, 0, fmt.Errorf("error reading from file: %s", e.Error())
+	}
+	if hc.UnixFS {
+		id, size, e = hc.unixfsFile(newChunkReader(data), m.getChunkSize())
+	} else {
+		id, e = hc.cid(h.Sum(nil), cid.Raw)
+	}
+	if e != nil {
+		return cid.Undef, 0, fmt.Errorf("error hashing file data: %s", e.Error())
+	}
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	if e := m.setFile(hc.key(id), fsFile{name: file.FileName(), path: file.FullPath(), data: data}); e != nil {
+		return cid.Undef, 0, e
+	}
+	m.hold(hc.key(id))
+	return id, size, nil
+}
+
+// Get returns a File from the store, asking stores on the Network for keys
+// that aren't stored locally. ctx is checked before each network request
 func (m *MemFS) Get(ctx context.Context, key string) (File, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	// Check if the local MapStore has the file.
 	f, err := m.getLocal(key)
 	if err != nil {
 		if errors.Is(err, ErrNotFound) {
 			// Check if the anyone connected on the mock Network has the file.
-			for _, connect := range m.Network {
+			for _, connect := range m.Peers() {
+				if err := m.networkHop(ctx, connect); err != nil {
+					return nil, err
+				}
 				f, err := connect.getLocal(key)
 				if err == nil {
-					return f, nil
-				} else if err != ErrNotFound {
+					return m.throttle(ctx, connect, f), nil
+				} else if !errors.Is(err, ErrNotFound) {
 					return nil, err
 				}
 			}
@@ -209,87 +462,273 @@ func (m *MemFS) Get(ctx context.Context, key string) (File, error) {
 		return nil, err
 	}
 
-	return f, nil
+	return m.partialRead(f), nil
 }
 
 func (m *MemFS) getLocal(key string) (File, error) {
-	key = strings.TrimPrefix(key, fmt.Sprintf("/%s/", MemFilestoreType))
-	// key may be of the form /mem/QmFoo/file.json but MemFS indexes its maps
-	// using keys like /mem/QmFoo. Trim after the second part of the key.
-	parts := strings.Split(key, "/")
-	log.Debugw("MemFS getting", "key", key, "parts", parts)
+	if err := m.readFault(key); err != nil {
+		return nil, err
+	}
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
 
-	if len(parts) == 0 {
-		return nil, fmt.Errorf("key is required")
+	hash, f, err := m.resolveHash(key)
+	if err != nil {
+		return nil, err
 	}
+	m.touch(hash)
+	return f.File()
+}
 
-	m.filesLk.Lock()
-	defer m.filesLk.Unlock()
+// resolve finds the stored value for a key, walking directories for keys
+// with subpaths. callers must hold filesLk
+func (m *MemFS) resolve(key string) (filer, error) {
+	_, f, err := m.resolveHash(key)
+	return f, err
+}
+
+// resolveHash finds the stored value for a key & the hash it's stored under.
+// callers must hold filesLk
+func (m *MemFS) resolveHash(key string) (string, filer, error) {
+	// key may be of the form /mem/QmFoo/file.json but MemFS indexes its maps
+	// by hash. Split off the hash & walk the subpath
+	prefix, hash, subpath := SplitStorePath(key)
+	log.Debugw("MemFS getting", "key", key, "hash", hash, "subpath", subpath)
+	if (prefix != "" && prefix != "/"+MemFilestoreType) || hash == "" {
+		return "", nil, ErrNotFound
+	}
 
-	log.Debugw("get", "hash", parts[0])
 	// Check if the local MemFS has the file
-	f := m.Files[parts[0]]
-	if f == nil {
-		return nil, ErrNotFound
+	f, ok := m.object(hash)
+	if !ok {
+		return "", nil, ErrNotFound
 	}
 
-	parts = parts[1:]
+	parts, err := subpathParts(key, subpath)
+	if err != nil {
+		return "", nil, err
+	}
 	for len(parts) > 0 {
 		dir, ok := f.(fsDir)
 		if !ok {
-			return nil, ErrNotDirectory
+			return "", nil, ErrNotDirectory
 		}
 		log.Debugf("get part=%s files=%v", parts[0], dir.files)
-		f = m.Files[dir.files[parts[0]]]
-		if f == nil {
-			return nil, ErrNotFound
+		hash = dir.files[parts[0]]
+		if f, ok = m.object(hash); !ok {
+			return "", nil, ErrNotFound
 		}
 		parts = parts[1:]
 	}
 
-	return f.File()
+	return hash, f, nil
+}
+
+// subpathParts splits subpath into its elements. Empty elements are dropped
+// by SplitStorePath. MemFS doesn't resolve relative elements, so "." & ".."
+// return an error wrapping ErrInvalidPath rather than being looked up as names
+func subpathParts(key, subpath string) ([]string, error) {
+	if subpath == "" {
+		return nil, nil
+	}
+	parts := strings.Split(subpath, "/")
+	for _, part := range parts {
+		if part == "." || part == ".." {
+			return nil, fmt.Errorf("%w: %q has a relative path element %q", ErrInvalidPath, key, part)
+		}
+	}
+	return parts, nil
+}
+
+// StatPath describes the file or directory at key without opening it
+func (m *MemFS) StatPath(ctx context.Context, key string) (PathInfo, error) {
+	info := PathInfo{Size: -1}
+	if err := ctx.Err(); err != nil {
+		return info, err
+	}
+	if err := m.readFault(key); err != nil {
+		return info, err
+	}
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+
+	hash, f, err := m.resolveHash(key)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return info, nil
+		}
+		return info, err
+	}
+	info.Exists = true
+	switch f := f.(type) {
+	case fsDir:
+		info.IsDir = true
+	case fsFile:
+		info.Size = f.data.size
+	}
+	if id, err := cid.Decode(hash); err == nil {
+		info.Cid = id
+	}
+	return info, nil
 }
 
 // Has returns whether the store has a File with the key
 func (m *MemFS) Has(ctx context.Context, key string) (exists bool, err error) {
-	if _, err := m.getLocal(key); err == nil {
-		return true, nil
+	if err := ctx.Err(); err != nil {
+		return false, err
 	}
-	return false, nil
+	_, err = m.getLocal(key)
+	if errors.Is(err, ErrUnavailable) {
+		return false, err
+	}
+	return err == nil, nil
 }
 
-// Delete removes the file from the store with the key
+// HasMany checks for the existence of many keys while holding the store lock
+// once
+func (m *MemFS) HasMany(ctx context.Context, keys []string) (map[string]bool, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+
+	res := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if err := m.readFault(key); err != nil {
+			return nil, err
+		}
+		_, err := m.resolve(key)
+		res[key] = err == nil
+	}
+	return res, nil
+}
+
+// List returns the entries of a stored directory. Entry paths are the
+// content-addressed paths of each child
+func (m *MemFS) List(ctx context.Context, key string) ([]DirEntry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+
+	f, err := m.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := f.(fsDir)
+	if !ok {
+		return nil, ErrNotDirectory
+	}
+
+	entries := make([]DirEntry, 0, len(dir.files))
+	for name, hash := range dir.files {
+		entry := DirEntry{
+			Name: name,
+			Path: fmt.Sprintf("/%s/%s", MemFilestoreType, hash),
+			Size: -1,
+		}
+		ch, _ := m.object(hash)
+		switch ch := ch.(type) {
+		case fsDir:
+			entry.IsDir = true
+		case fsFile:
+			entry.Size = ch.data.size
+		}
+		entries = append(entries, entry)
+	}
+	SortDirEntries(entries)
+	return entries, nil
+}
+
+// Delete removes key as a root, removing the object & anything it references
+// that's no longer reachable from another root. Content shared with another
+// root is kept
 func (m *MemFS) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	key = strings.TrimPrefix(key, fmt.Sprintf("/%s/", MemFilestoreType))
-	// key may be of the form /mem/QmFoo/file.json but MemFS indexes its maps
-	// using keys like /mem/QmFoo. Trim after the second part of the key.
-	parts := strings.Split(key, "/")
-	log.Debugf("MemFS deleting key=%q parts=%q", key, parts)
+	prefix, hash, subpath := SplitStorePath(key)
+	log.Debugw("MemFS deleting", LogFields(ctx, "key", key, "hash", hash)...)
 
-	if len(parts) == 0 {
-		return fmt.Errorf("path is required")
-	} else if len(parts) > 1 {
-		return fmt.Errorf("can only delete entire hash, not individual paths")
+	if prefix != "" && prefix != "/"+MemFilestoreType {
+		return fmt.Errorf("%w: %q isn't a %s path", ErrInvalidPath, key, MemFilestoreType)
+	} else if hash == "" {
+		return fmt.Errorf("%w: a hash is required, got %q", ErrInvalidPath, key)
+	} else if subpath != "" {
+		return fmt.Errorf("%w: Delete removes entire hashes, use DeletePath to delete %q", ErrUnsupported, key)
 	}
 
-	// TODO (b5)
-	log.Debugf("deleting root hash=%q", parts[0])
+	// drop the root & collect whatever is no longer reachable. content another
+	// root references is kept
 	m.filesLk.Lock()
-	delete(m.Files, parts[0])
+	delete(m.roots, hash)
+	_, err := m.collectGarbage(ctx)
 	m.filesLk.Unlock()
+	if err != nil {
+		return err
+	}
+	m.events.Publish(Event{Type: EventFileDeleted, FSType: MemFilestoreType, Path: key})
 	return nil
-	// return m.walkRm(parts[0])
 }
 
+// PlanDelete reports the impact of deleting key without deleting anything.
+// Every other root is treated as keeping the content it references
+func (m *MemFS) PlanDelete(ctx context.Context, key string) (Impact, error) {
+	m.filesLk.RLock()
+	refs := []Pin{}
+	for hash := range m.roots {
+		if _, err := cid.Decode(hash); err != nil {
+			// keys set with PutFileAtKey needn't be CIDs
+			continue
+		}
+		refs = append(refs, Pin{Key: fmt.Sprintf("/%s/%s", MemFilestoreType, hash), Recursive: true})
+	}
+	m.filesLk.RUnlock()
+
+	return PlanDeleteDAG(ctx, m, key, refs)
+}
+
+// GetNode returns the node for id, or for the object at path beneath id when
+// path is given, eg: GetNode(id, "a", "b.txt")
 func (m *MemFS) GetNode(id cid.Cid, path ...string) (DagNode, error) {
-	m.filesLk.Lock()
-	defer m.filesLk.Unlock()
+	key := m.dagPathKey(id, path)
+	if err := m.readFault(key); err != nil {
+		return nil, err
+	}
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
 
-	log.Debugw("get node", "cid", id.String(), "files", m.Files)
-	f, ok := m.Files[id.String()]
-	if !ok {
-		return nil, ErrNotFound
+	log.Debugw("get node", "key", key)
+	hash, f, err := m.resolveHash(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(path) > 0 {
+		if id, err = cid.Decode(hash); err != nil {
+			return nil, err
+		}
+	}
+
+	if dir, ok := f.(fsDir); ok {
+		data := dir.blockData()
+		node := merkledag.NodeWithData(data)
+		for name, hash := range dir.files {
+			chID, err := cid.Decode(hash)
+			if err != nil {
+				return nil, err
+			}
+			if err := node.AddRawLink(name, &format.Link{Name: name, Cid: chID}); err != nil {
+				return nil, err
+			}
+		}
+		return &memDagNode{
+			id:   id,
+			size: int64(len(data)),
+			node: node,
+		}, nil
 	}
 
 	file, err := f.File()
@@ -317,20 +756,26 @@ func (m *MemFS) PutNode(links Links) (PutResult, error) {
 		files: map[string]string{},
 	}
 
+	hc := m.getHash()
 	for _, ch := range links.SortedSlice() {
-		dir.files[ch.Name] = ch.Cid.String()
-		if _, err := buf.WriteString(ch.Cid.String() + "\n"); err != nil {
+		key := hc.key(ch.Cid)
+		dir.files[ch.Name] = key
+		if _, err := buf.WriteString(key + "\n"); err != nil {
 			panic(err.Error())
 		}
 	}
 
-	mh, err := multihash.Sum(buf.Bytes(), multihash.SHA2_256, -1)
+	id, err := hc.sum(buf.Bytes(), cid.DagProtobuf)
 	if err != nil {
 		return PutResult{}, err
 	}
 
-	id := cid.NewCidV0(mh)
-	m.Files[id.String()] = dir
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	if err := m.setFile(hc.key(id), dir); err != nil {
+		return PutResult{}, err
+	}
+	m.addRoot(hc.key(id))
 	return PutResult{
 		Cid:  id,
 		Size: int64(buf.Len()),
@@ -338,16 +783,44 @@ func (m *MemFS) PutNode(links Links) (PutResult, error) {
 }
 
 func (m *MemFS) GetBlock(id cid.Cid) (io.Reader, error) {
-	m.filesLk.Lock()
-	defer m.filesLk.Unlock()
-	filer, ok := m.Files[id.String()]
+	key := m.getHash().key(id)
+	if err := m.readFault(key); err != nil {
+		return nil, err
+	}
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+	filer, ok := m.object(key)
 	if !ok {
 		return nil, ErrNotFound
 	}
+	if dir, ok := filer.(fsDir); ok {
+		return bytes.NewReader(dir.blockData()), nil
+	}
 
 	return filer.File()
 }
 
+// AllBlocks lists the ids of every block in the store, implementing the
+// BlockLister interface
+func (m *MemFS) AllBlocks(ctx context.Context) ([]cid.Cid, error) {
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+
+	ids := make([]cid.Cid, 0, len(m.Files))
+	for key := range m.Files {
+		if _, ok := m.object(key); !ok {
+			continue
+		}
+		id, err := cid.Decode(key)
+		if err != nil {
+			// keys set with PutFileAtKey needn't be CIDs
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
 func (m *MemFS) PutBlock(d []byte) (id cid.Cid, err error) {
 	res, err := m.putBlock("", d)
 	if err != nil {
@@ -360,21 +833,28 @@ func (m *MemFS) putBlock(name string, data []byte) (PutResult, error) {
 	m.filesLk.Lock()
 	defer m.filesLk.Unlock()
 
-	hash, err := multihash.Sum(data, multihash.SHA2_256, -1)
+	id, err := m.hash.sum(data, cid.Raw)
 	if err != nil {
 		return PutResult{}, err
 	}
+	return m.storeBlock(name, id, bytesData(data))
+}
 
-	id := cid.NewCidV0(hash)
-	m.Files[id.String()] = fsFile{
+// storeBlock stores data under id. the caller must hold the files lock
+func (m *MemFS) storeBlock(name string, id cid.Cid, data chunkedData) (PutResult, error) {
+	f := fsFile{
 		name: name,
 		path: "",
 		data: data,
 	}
+	if err := m.setFile(m.hash.key(id), f); err != nil {
+		return PutResult{}, err
+	}
+	m.addRoot(m.hash.key(id))
 
 	return PutResult{
 		Cid:  id,
-		Size: int64(len(data)),
+		Size: data.size,
 	}, nil
 }
 
@@ -384,33 +864,56 @@ func (m *MemFS) PutFile(f fs.File) (PutResult, error) {
 		return PutResult{}, err
 	}
 
-	data, err := ioutil.ReadAll(f)
+	// hash content as it's read instead of buffering it in full first
+	hc := m.getHash()
+	h, err := hc.newHasher()
+	if err != nil {
+		return PutResult{}, err
+	}
+	data, err := readChunked(f, m.getChunkSize(), h)
 	if err != nil {
 		return PutResult{}, err
 	}
 	if err := f.Close(); err != nil {
 		return PutResult{}, err
 	}
+	id, err := hc.cid(h.Sum(nil), cid.Raw)
+	if err != nil {
+		return PutResult{}, err
+	}
 
-	return m.putBlock(stat.Name(), data)
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	return m.storeBlock(stat.Name(), id, data)
 }
 
+// GetFile opens the file at root, or at path beneath root when path is given.
+// Path elements may contain slashes, like the subpath of a Get key
 func (m *MemFS) GetFile(root cid.Cid, path ...string) (io.ReadCloser, error) {
-	if len(path) > 0 {
-		return nil, fmt.Errorf("memfs does not support pathing beyond a root CID")
+	key := m.dagPathKey(root, path)
+	if err := m.readFault(key); err != nil {
+		return nil, err
 	}
 
-	m.filesLk.Lock()
-	defer m.filesLk.Unlock()
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
 
-	f, ok := m.Files[root.String()]
-	if !ok {
-		return nil, ErrNotFound
+	_, f, err := m.resolveHash(key)
+	if err != nil {
+		return nil, err
 	}
-
 	return f.File()
 }
 
+// dagPathKey builds the key of the object at path beneath root
+func (m *MemFS) dagPathKey(root cid.Cid, path []string) string {
+	key := fmt.Sprintf("/%s/%s", MemFilestoreType, m.getHash().key(root))
+	if len(path) > 0 {
+		key += "/" + strings.Join(path, "/")
+	}
+	return key
+}
+
 type memDagNode struct {
 	id   cid.Cid
 	size int64
@@ -434,24 +937,40 @@ func (n memDagNode) Links() Links {
 	return links
 }
 
-func (m *MemFS) walkRm(hash string) error {
-	f := m.Files[hash]
-	if f == nil {
-		return ErrNotFound
-	}
+// Lock acquires a named in-memory lock, blocking until the lock is released
+// by its current holder or ctx is done
+func (m *MemFS) Lock(ctx context.Context, name string) error {
+	for {
+		m.locksLk.Lock()
+		if m.locks == nil {
+			m.locks = map[string]chan struct{}{}
+		}
+		held, ok := m.locks[name]
+		if !ok {
+			m.locks[name] = make(chan struct{})
+			m.locksLk.Unlock()
+			return nil
+		}
+		m.locksLk.Unlock()
 
-	dir, ok := f.(fsDir)
-	if !ok {
-		delete(m.Files, hash)
-		return nil
+		select {
+		case <-held:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
+}
 
-	for _, chHash := range dir.files {
-		if err := m.walkRm(chHash); err != nil {
-			return err
-		}
+// Unlock releases a named lock, waking any callers waiting to acquire it
+func (m *MemFS) Unlock(name string) error {
+	m.locksLk.Lock()
+	defer m.locksLk.Unlock()
+	held, ok := m.locks[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrNotLocked, name)
 	}
-	delete(m.Files, hash)
+	delete(m.locks, name)
+	close(held)
 	return nil
 }
 
@@ -461,50 +980,72 @@ func (m *MemFS) AddConnection(other *MemFS) {
 		return
 	}
 	// Add pointer from that network to this one.
-	found := false
+	m.connect(other)
+	// Add pointer from this network to that one.
+	other.connect(m)
+}
+
+// connect adds peer to m's network if it isn't already connected. Each side
+// of a connection is added under its own lock, so stores connecting to each
+// other concurrently can't deadlock
+func (m *MemFS) connect(peer *MemFS) {
+	m.networkLk.Lock()
+	defer m.networkLk.Unlock()
 	for _, elem := range m.Network {
-		if other == elem {
-			found = true
+		if peer == elem {
+			return
 		}
 	}
-	if !found {
-		m.Network = append(m.Network, other)
-	}
-	// Add pointer from this network to that one.
-	found = false
-	for _, elem := range other.Network {
-		if m == elem {
-			found = true
+	// copy on write, so slices returned by peers are never modified
+	m.Network = append(m.Network[:len(m.Network):len(m.Network)], peer)
+}
+
+// RemoveConnection removes the pointers between this MapStore & that, the
+// inverse of AddConnection
+func (m *MemFS) RemoveConnection(other *MemFS) {
+	m.disconnect(other)
+	other.disconnect(m)
+}
+
+// disconnect removes peer from m's network
+func (m *MemFS) disconnect(peer *MemFS) {
+	m.networkLk.Lock()
+	defer m.networkLk.Unlock()
+	network := make([]*MemFS, 0, len(m.Network))
+	for _, elem := range m.Network {
+		if elem != peer {
+			network = append(network, elem)
 		}
 	}
-	if !found {
-		other.Network = append(other.Network, m)
-	}
+	m.Network = network
 }
 
-func hashBytes(data []byte) (hash string, err error) {
-	h := sha256.New()
-	if _, err = h.Write(data); err != nil {
-		err = fmt.Errorf("error writing hash data: %s", err.Error())
-		return
-	}
-	mhBuf, err := multihash.Encode(h.Sum(nil), multihash.SHA2_256)
-	if err != nil {
-		err = fmt.Errorf("error encoding hash: %s", err.Error())
-		return
-	}
-	hash = base58.Encode(mhBuf)
-	return
+// Peers returns the stores connected to m. Use Peers instead of reading
+// Network while connections may change. The returned slice isn't modified by
+// later connection changes
+func (m *MemFS) Peers() []*MemFS {
+	m.networkLk.RLock()
+	defer m.networkLk.RUnlock()
+	return m.Network
 }
 
 type fsFile struct {
 	name string
 	path string
-	data []byte
+	data chunkedData
 }
 
 func (f fsFile) File() (File, error) {
-	return NewMemfileBytes(f.path, f.data), nil
+	return NewMemfileReaderSize(f.path, newChunkReader(f.data), f.data.size), nil
+}
+
+func (m *MemFS) getChunkSize() int {
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+	if m.chunkSize <= 0 {
+		return DefaultChunkSize
+	}
+	return m.chunkSize
 }
 
 type fsDir struct {
@@ -513,22 +1054,56 @@ type fsDir struct {
 	files map[string]string
 }
 
-func (f fsDir) File() (File, error) {
-	files := make([]File, 0, len(f.files))
+// blockData encodes the directory as a block, a newline-delimited list of
+// child hashes sorted by child name. This matches the encoding PutNode hashes
+func (f fsDir) blockData() []byte {
+	names := make([]string, 0, len(f.files))
+	for name := range f.files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-	for fileName, hash := range f.files {
-		f := f.fs.Files[hash]
-		if f == nil {
-			return nil, fmt.Errorf("%w: fileName: %s hash: %s", ErrNotFound, fileName, hash)
-		}
-		file, err := f.File()
-		if err != nil {
-			return nil, err
+	buf := bytes.NewBuffer(nil)
+	for _, name := range names {
+		buf.WriteString(f.files[name] + "\n")
+	}
+	return buf.Bytes()
+}
+
+func (f fsDir) File() (File, error) {
+	// rebuild the tree top-down with an explicit stack, adding each directory
+	// to its parent before its children are added, so child paths are set
+	// once & deep trees can't exhaust the goroutine stack
+	type frame struct {
+		dir  fsDir
+		mdir *Memdir
+	}
+	root := NewMemdir(f.path)
+	stack := []frame{{dir: f, mdir: root}}
+	for len(stack) > 0 {
+		fr := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for fileName, hash := range fr.dir.files {
+			ch, ok := f.fs.object(hash)
+			if !ok {
+				return nil, fmt.Errorf("%w: fileName: %s hash: %s", ErrNotFound, fileName, hash)
+			}
+			if chDir, ok := ch.(fsDir); ok {
+				md := NewMemdir(chDir.path)
+				fr.mdir.AddChildren(md)
+				stack = append(stack, frame{dir: chDir, mdir: md})
+				continue
+			}
+			file, err := ch.File()
+			if err != nil {
+				return nil, err
+			}
+			fr.mdir.AddChildren(file)
 		}
-		files = append(files, file)
 	}
 
-	return NewMemdir(f.path, files...), nil
+	return root, nil
 }
 
 type filer interface {
@@ -567,9 +1142,10 @@ func (n *nd) toDir(fs *MemFS) (string, fsDir) {
 		}
 	}
 
-	hash, err := hashBytes(buf.Bytes())
+	hc := fs.getHash()
+	id, err := hc.sum(buf.Bytes(), cid.DagProtobuf)
 	if err != nil {
 		panic(err)
 	}
-	return hash, dir
+	return hc.key(id), dir
 }
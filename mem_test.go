@@ -3,9 +3,15 @@ package qfs
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"math/rand"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestMemFS(t *testing.T) {
@@ -66,3 +72,322 @@ func (t testStore) Get(ctx context.Context, path string) (File, error) {
 
 	return nil, ErrNotFound
 }
+
+func TestMemFSHasMany(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	fileKey, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte(`a`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dirKey, err := fs.Put(ctx, NewMemdir("/", NewMemfileBytes("b.txt", []byte(`b`))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := HasMany(ctx, fs, []string{
+		fileKey,
+		dirKey + "/b.txt",
+		dirKey + "/c.txt",
+		"/mem/QmNotAKey",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]bool{
+		fileKey:           true,
+		dirKey + "/b.txt": true,
+		dirKey + "/c.txt": false,
+		"/mem/QmNotAKey":  false,
+	}
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("result mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMemFSPutPathIgnored(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	key, err := fs.Put(ctx, NewMemfileBytes("/mem/a.txt", []byte(`a`)))
+	if err != nil {
+		t.Fatalf("putting a non-hash path shouldn't error. got: %s", err)
+	}
+
+	_, err = fs.Put(ctx, NewMemfileBytes(key+"/b.txt", []byte(`b`)))
+	if !errors.Is(err, ErrPathIgnored) {
+		t.Errorf("expected putting a path within existing content to return ErrPathIgnored. got: %v", err)
+	}
+}
+
+func TestGetTo(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	key, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte(`hello`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	n, err := GetTo(ctx, fs, key, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 || buf.String() != "hello" {
+		t.Errorf("result mismatch. want: 5 bytes %q, got: %d bytes %q", "hello", n, buf.String())
+	}
+
+	dirKey, err := fs.Put(ctx, NewMemdir("/", NewMemfileBytes("a.txt", []byte(`a`))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetTo(ctx, fs, dirKey, buf); err != ErrNotFile {
+		t.Errorf("expected GetTo on a directory to return ErrNotFile. got: %v", err)
+	}
+
+	dir := &closeTrackingFile{File: NewMemdir("/dir")}
+	if _, err := GetTo(ctx, resolverFunc(func(context.Context, string) (File, error) { return dir, nil }), "/dir", buf); err != ErrNotFile {
+		t.Errorf("expected GetTo on a directory to return ErrNotFile. got: %v", err)
+	}
+	if !dir.closed {
+		t.Error("expected GetTo to close the directory")
+	}
+}
+
+type resolverFunc func(ctx context.Context, path string) (File, error)
+
+func (fn resolverFunc) Get(ctx context.Context, path string) (File, error) { return fn(ctx, path) }
+
+type closeTrackingFile struct {
+	File
+	closed bool
+}
+
+func (f *closeTrackingFile) Close() error {
+	f.closed = true
+	return f.File.Close()
+}
+
+func TestMemFSLock(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	if err := fs.Lock(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Millisecond*20)
+	defer cancel()
+	if err := fs.Lock(timeoutCtx, "a"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected locking a held lock to wait until context deadline. got: %v", err)
+	}
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- fs.Lock(ctx, "a")
+	}()
+
+	if err := fs.Unlock("a"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected waiting lock to be acquired after unlock")
+	}
+
+	if err := fs.Unlock("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Unlock("a"); !errors.Is(err, ErrNotLocked) {
+		t.Errorf("expected unlocking an unheld lock to return ErrNotLocked. got: %v", err)
+	}
+}
+
+func TestMemFSDeepTree(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	depth := 10000
+	key, err := fs.Put(ctx, deepMemdir(depth, NewMemfileBytes("file.txt", []byte("deep"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var leaf File
+	if err := Walk(f, func(f File) error {
+		if !f.IsDirectory() {
+			leaf = f
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if leaf == nil {
+		t.Fatal("expected to find a file at the bottom of the tree")
+	}
+	expect := strings.Repeat("/d", depth) + "/file.txt"
+	if leaf.FullPath() != expect {
+		t.Errorf("leaf path mismatch. want %d chars, got: %d chars", len(expect), len(leaf.FullPath()))
+	}
+}
+
+func TestMemFSGetNestedPaths(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	key, err := fs.Put(ctx, NewMemdir("/",
+		NewMemdir("b",
+			NewMemdir("c",
+				NewMemfileBytes("x.txt", []byte("x")),
+			),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	paths := []string{}
+	if err := Walk(f, func(f File) error {
+		paths = append(paths, f.FullPath())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := []string{"/b/c/x.txt", "/b/c", "/b", "/"}
+	if diff := cmp.Diff(expect, paths); diff != "" {
+		t.Errorf("paths mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestMemFSDagPaths(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	key, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemdir("b",
+			NewMemfileBytes("c.txt", []byte("c")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := mustPathCid(t, key)
+
+	rc, err := fs.GetFile(root, "b", "c.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "c" {
+		t.Errorf("contents mismatch. want: %q got: %q", "c", string(data))
+	}
+	if _, err := fs.GetFile(root, "b/c.txt"); err != nil {
+		t.Errorf("expected path elements to accept slashes. got: %v", err)
+	}
+
+	node, err := fs.GetNode(root, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Cid().Equals(root) {
+		t.Error("expected node at a path to have the child's cid")
+	}
+	if node.Links().Get("c.txt") == nil {
+		t.Error("expected node at path to link to c.txt")
+	}
+
+	if _, err := fs.GetFile(root, "b", "missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected missing path to return ErrNotFound. got: %v", err)
+	}
+	if _, err := fs.GetNode(root, "b", "c.txt", "d"); !errors.Is(err, ErrNotDirectory) {
+		t.Errorf("expected path through a file to return ErrNotDirectory. got: %v", err)
+	}
+}
+
+// TestMemFSPathResolution resolves randomly generated paths against a known
+// tree, a stand-in for fuzzing that checks every path either resolves to the
+// expected file or fails with a typed error
+func TestMemFSPathResolution(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	root, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("x.txt", []byte("x")),
+		NewMemdir("b",
+			NewMemfileBytes("y.txt", []byte("y")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{"x.txt": "x", "b/y.txt": "y"}
+	dirs := map[string]bool{"": true, "b": true}
+
+	elements := []string{"b", "x.txt", "y.txt", ".", "..", "", "missing"}
+	rnd := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		n := rnd.Intn(5)
+		parts := make([]string, n)
+		for j := range parts {
+			parts[j] = elements[rnd.Intn(len(elements))]
+		}
+		path := strings.Join(parts, "/")
+
+		clean, relative := []string{}, false
+		for _, p := range parts {
+			if p == "." || p == ".." {
+				relative = true
+			} else if p != "" {
+				clean = append(clean, p)
+			}
+		}
+		want := strings.Join(clean, "/")
+
+		f, err := fs.Get(ctx, root+"/"+path)
+		switch {
+		case relative:
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Fatalf("%q: expected ErrInvalidPath. got: %v", path, err)
+			}
+		case dirs[want]:
+			if err != nil || !f.IsDirectory() {
+				t.Fatalf("%q: expected a directory. got: %v", path, err)
+			}
+		case files[want] != "":
+			if err != nil {
+				t.Fatalf("%q: %s", path, err)
+			}
+			if _, s := FileString(f); s != files[want] {
+				t.Fatalf("%q: contents mismatch. want: %q got: %q", path, files[want], s)
+			}
+		default:
+			if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrNotDirectory) {
+				t.Fatalf("%q: expected ErrNotFound or ErrNotDirectory. got: %v", path, err)
+			}
+		}
+	}
+
+	if _, err := fs.DeletePath(ctx, root+"/b/../x.txt"); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("expected DeletePath to reject relative elements. got: %v", err)
+	}
+}
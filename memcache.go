@@ -0,0 +1,122 @@
+package qfs
+
+import (
+	"container/list"
+	"fmt"
+)
+
+// MemOption is a function type for passing to NewMemFS
+type MemOption func(m *MemFS)
+
+// WithMaxBytes caps the bytes of file content a MemFS holds. Writes that
+// would exceed the cap fail with an error wrapping ErrNoSpace, unless LRU
+// eviction is enabled with WithLRUEviction & enough space can be freed.
+// Zero doesn't cap the store
+func WithMaxBytes(n int64) MemOption {
+	return func(m *MemFS) {
+		m.maxBytes = n
+	}
+}
+
+// WithLRUEviction makes a MemFS capped with WithMaxBytes evict the least
+// recently used files to make room for writes, so the store can be used as a
+// bounded cache. Files written by a put in progress aren't evicted until the
+// put finishes, so a put that doesn't fit fails with ErrNoSpace. Evicting a
+// file doesn't evict directories that link to it, fetching those directories
+// returns an error wrapping ErrNotFound
+func WithLRUEviction() MemOption {
+	return func(m *MemFS) {
+		m.lru = list.New()
+		m.lruElems = map[string]*list.Element{}
+	}
+}
+
+// StoredBytes returns the bytes of file content m holds
+func (m *MemFS) StoredBytes() int64 {
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+	return m.bytes
+}
+
+// objectSize is the number of bytes a stored object counts against the cap.
+// Directories are small enough not to count
+func objectSize(f filer) int64 {
+	if file, ok := f.(fsFile); ok {
+		return file.data.size
+	}
+	return 0
+}
+
+// setFile stores f under key, evicting least recently used files if needed.
+// callers must hold filesLk
+func (m *MemFS) setFile(key string, f filer) error {
+	size := objectSize(f)
+	delta := size - objectSize(m.Files[key])
+	if m.maxBytes > 0 && m.bytes+delta > m.maxBytes {
+		if size > m.maxBytes || m.lru == nil || !m.evict(key, m.bytes+delta-m.maxBytes) {
+			return fmt.Errorf("%w: storing %d bytes would exceed the %d byte limit", ErrNoSpace, size, m.maxBytes)
+		}
+	}
+
+	m.Files[key] = f
+	m.bytes += delta
+	m.touch(key)
+	m.setExpiry(key)
+	return nil
+}
+
+// removeFile drops the object stored under key. callers must hold filesLk
+// exclusively
+func (m *MemFS) removeFile(key string) {
+	m.bytes -= objectSize(m.Files[key])
+	delete(m.Files, key)
+	delete(m.roots, key)
+	delete(m.expires, key)
+	if m.lru != nil {
+		if el, ok := m.lruElems[key]; ok {
+			m.lru.Remove(el)
+			delete(m.lruElems, key)
+		}
+	}
+}
+
+// touch marks key as the most recently used object. callers must hold
+// filesLk, shared or exclusively
+func (m *MemFS) touch(key string) {
+	if m.lru == nil {
+		return
+	}
+	m.lruLk.Lock()
+	defer m.lruLk.Unlock()
+	if el, ok := m.lruElems[key]; ok {
+		m.lru.MoveToFront(el)
+		return
+	}
+	m.lruElems[key] = m.lru.PushFront(key)
+}
+
+// evict removes least recently used files other than keep until at least
+// need bytes are freed, returning false without removing anything if need
+// bytes can't be freed. Files held by puts in progress are never evicted.
+// callers must hold filesLk exclusively
+func (m *MemFS) evict(keep string, need int64) bool {
+	var victims []string
+	for el := m.lru.Back(); el != nil && need > 0; el = el.Prev() {
+		key := el.Value.(string)
+		if key == keep || m.pending[key] > 0 {
+			continue
+		}
+		if size := objectSize(m.Files[key]); size > 0 {
+			victims = append(victims, key)
+			need -= size
+		}
+	}
+	if need > 0 {
+		return false
+	}
+	for _, key := range victims {
+		log.Debugw("MemFS evicting", "key", key, "size", objectSize(m.Files[key]))
+		m.removeFile(key)
+	}
+	return true
+}
@@ -0,0 +1,108 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMemFSMaxBytes(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS(WithMaxBytes(10))
+
+	a, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("aaaaaa")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Put(ctx, NewMemfileBytes("b.txt", []byte("bbbbbb"))); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("expected exceeding the limit to return ErrNoSpace. got: %v", err)
+	}
+	if fs.StoredBytes() != 6 {
+		t.Errorf("stored bytes mismatch. want: 6 got: %d", fs.StoredBytes())
+	}
+
+	if err := fs.Delete(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if fs.StoredBytes() != 0 {
+		t.Errorf("expected deletes to free space. got: %d stored bytes", fs.StoredBytes())
+	}
+	if _, err := fs.Put(ctx, NewMemfileBytes("b.txt", []byte("bbbbbb"))); err != nil {
+		t.Errorf("expected put to succeed once space is freed. got: %v", err)
+	}
+}
+
+func TestMemFSLRUEviction(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS(WithMaxBytes(10), WithLRUEviction())
+
+	put := func(data string) string {
+		t.Helper()
+		p, err := fs.Put(ctx, NewMemfileBytes("f.txt", []byte(data)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	has := func(p string) bool {
+		t.Helper()
+		exists, err := fs.Has(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return exists
+	}
+
+	a := put("aaaa")
+	b := put("bbbb")
+	// fetching a makes b the least recently used
+	if _, err := fs.Get(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	c := put("cccc")
+
+	if !has(a) || has(b) || !has(c) {
+		t.Errorf("expected the least recently used file to be evicted. has a: %t b: %t c: %t", has(a), has(b), has(c))
+	}
+	if fs.StoredBytes() != 8 {
+		t.Errorf("stored bytes mismatch. want: 8 got: %d", fs.StoredBytes())
+	}
+
+	if _, err := fs.Put(ctx, NewMemfileBytes("big.txt", []byte(strings.Repeat("x", 11)))); !errors.Is(err, ErrNoSpace) {
+		t.Errorf("expected files larger than the limit to return ErrNoSpace. got: %v", err)
+	}
+	if !has(a) || !has(c) {
+		t.Error("expected a rejected put not to evict anything")
+	}
+}
+
+func TestMemFSLRUEvictionSkipsPendingPuts(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS(WithMaxBytes(10), WithLRUEviction())
+
+	_, err := fs.Put(ctx, NewMemdir("/dir",
+		NewMemfileBytes("a", []byte("aaaaaa")),
+		NewMemfileBytes("b", []byte("bbbbbb")),
+	))
+	if !errors.Is(err, ErrNoSpace) {
+		t.Errorf("expected a directory larger than the limit to return ErrNoSpace. got: %v", err)
+	}
+	if fs.StoredBytes() > 10 {
+		t.Errorf("expected the limit to hold. got: %d stored bytes", fs.StoredBytes())
+	}
+
+	// files from the failed put aren't held once it finishes
+	root, err := fs.Put(ctx, NewMemdir("/dir",
+		NewMemfileBytes("a", []byte("aaaa")),
+		NewMemfileBytes("b", []byte("bbbb")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := fs.Get(ctx, root+"/"+name); err != nil {
+			t.Errorf("expected %s to be stored. got: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// DeletePath removes the file or directory at a path within a stored
+// directory, eg: "/mem/QmFoo/a/b.txt". Stored content never changes, so
+// DeletePath stores a copy of each directory between the root & the deleted
+// path, returning the path of the new root. The new root replaces the old one,
+// content only the old root referenced is garbage collected. Paths without a
+// subpath are deleted like Delete, returning the empty string. Stores hashing
+// with MemHash.UnixFS don't support deleting paths within directories
+func (m *MemFS) DeletePath(ctx context.Context, key string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	prefix, hash, subpath := SplitStorePath(key)
+	if subpath == "" {
+		return "", m.Delete(ctx, key)
+	}
+	if prefix != "" && prefix != "/"+MemFilestoreType {
+		return "", fmt.Errorf("%w: %q isn't a %s path", ErrInvalidPath, key, MemFilestoreType)
+	}
+
+	parts, err := subpathParts(key, subpath)
+	if err != nil {
+		return "", err
+	}
+
+	m.filesLk.Lock()
+	newHash, err := m.deletePath(hash, parts)
+	if err == nil {
+		delete(m.roots, hash)
+		m.addRoot(newHash)
+		_, err = m.collectGarbage(ctx)
+	}
+	m.filesLk.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	m.events.Publish(Event{Type: EventFileDeleted, FSType: MemFilestoreType, Path: key})
+	return fmt.Sprintf("/%s/%s", MemFilestoreType, newHash), nil
+}
+
+// deletePath stores copies of the directories from the one stored under hash
+// down to the parent of the last of parts, with the last part removed,
+// returning the hash of the new root. callers must hold filesLk exclusively
+func (m *MemFS) deletePath(hash string, parts []string) (string, error) {
+	if m.hash.UnixFS {
+		return "", fmt.Errorf("%w: deleting paths within UnixFS directories", ErrUnsupported)
+	}
+
+	// dirs are the directories from the root to the parent of the deleted path
+	dirs := make([]fsDir, 0, len(parts))
+	for i, name := range parts {
+		obj, exists := m.object(hash)
+		if !exists {
+			return "", ErrNotFound
+		}
+		dir, ok := obj.(fsDir)
+		if !ok {
+			return "", ErrNotDirectory
+		}
+		dirs = append(dirs, dir)
+		if hash, ok = dir.files[name]; !ok {
+			return "", fmt.Errorf("%w: %s", ErrNotFound, strings.Join(parts[:i+1], "/"))
+		}
+	}
+
+	// rebuild from the deepest directory up, replacing each child with its copy
+	child := ""
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := fsDir{
+			fs:    m,
+			path:  dirs[i].path,
+			files: make(map[string]string, len(dirs[i].files)),
+		}
+		for name, h := range dirs[i].files {
+			dir.files[name] = h
+		}
+		if i == len(dirs)-1 {
+			delete(dir.files, parts[i])
+		} else {
+			dir.files[parts[i]] = child
+		}
+
+		id, err := m.hash.sum(dir.blockData(), cid.DagProtobuf)
+		if err != nil {
+			return "", err
+		}
+		child = m.hash.key(id)
+		if err := m.setFile(child, dir); err != nil {
+			return "", err
+		}
+	}
+	return child, nil
+}
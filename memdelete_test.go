@@ -0,0 +1,73 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestMemFSDeletePath(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	root, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("keep.txt", []byte("keep")),
+		NewMemdir("b",
+			NewMemfileBytes("drop.txt", []byte("drop")),
+			NewMemfileBytes("stay.txt", []byte("stay")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fs.Delete(ctx, root+"/b/drop.txt"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected Delete of a subpath to return ErrUnsupported. got: %v", err)
+	}
+
+	newRoot, err := fs.DeletePath(ctx, root+"/b/drop.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newRoot == root {
+		t.Fatal("expected deleting a path to produce a new root")
+	}
+
+	want, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("keep.txt", []byte("keep")),
+		NewMemdir("b",
+			NewMemfileBytes("stay.txt", []byte("stay")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newRoot != want {
+		t.Errorf("expected the new root to match putting the remaining content. want: %q got: %q", want, newRoot)
+	}
+
+	if exists, _ := fs.Has(ctx, newRoot+"/b/drop.txt"); exists {
+		t.Error("expected deleted path to be gone from the new root")
+	}
+	f, err := fs.Get(ctx, newRoot+"/b/stay.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "stay" {
+		t.Errorf("contents mismatch. want: %q got: %q", "stay", s)
+	}
+	if exists, _ := fs.Has(ctx, root); exists {
+		t.Error("expected the old root to be replaced")
+	}
+	// a/, a/b, keep.txt & stay.txt
+	if fs.ObjectCount() != 4 {
+		t.Errorf("expected content only the old root referenced to be collected. got %d objects", fs.ObjectCount())
+	}
+
+	if _, err := fs.DeletePath(ctx, newRoot+"/missing.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected deleting a missing path to return ErrNotFound. got: %v", err)
+	}
+	if _, err := fs.DeletePath(ctx, newRoot+"/keep.txt/x"); !errors.Is(err, ErrNotDirectory) {
+		t.Errorf("expected deleting beneath a file to return ErrNotDirectory. got: %v", err)
+	}
+}
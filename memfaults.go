@@ -0,0 +1,115 @@
+package qfs
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// MemFaults configures failures a MemFS simulates, for exercising retry,
+// rollback & circuit-breaking logic against storage that misbehaves. Faults
+// are deterministic: the same sequence of calls fails the same way every time
+type MemFaults struct {
+	// FailPutEvery makes every Nth call to Put return ErrUnavailable without
+	// writing anything. Zero disables Put failures
+	FailPutEvery int
+	// Unavailable lists content hashes, with or without the /mem/ prefix, that
+	// return ErrUnavailable from any read, including reads of paths within
+	// them & block-level reads
+	Unavailable []string
+	// PartialReadLimit cuts off each file returned by Get after this many
+	// bytes, returning io.ErrUnexpectedEOF in place of the rest. Applies to
+	// files within directories. Zero disables partial reads
+	PartialReadLimit int64
+	// NetworkLatency delays each request Get makes to a store on the MemFS
+	// Network, simulating slow peers. Waits end early if the request context
+	// is done. Zero disables latency. Use SetLink to configure individual
+	// peers
+	NetworkLatency time.Duration
+}
+
+// SetFaults replaces the faults m simulates & resets the Put counter. Pass
+// the zero value to stop simulating faults
+func (m *MemFS) SetFaults(faults MemFaults) {
+	unavailable := make(map[string]struct{}, len(faults.Unavailable))
+	for _, key := range faults.Unavailable {
+		unavailable[memHash(key)] = struct{}{}
+	}
+
+	m.faultsLk.Lock()
+	defer m.faultsLk.Unlock()
+	m.faults = faults
+	m.unavailable = unavailable
+	m.puts = 0
+}
+
+// putFault counts a call to Put, returning ErrUnavailable if it should fail
+func (m *MemFS) putFault() error {
+	m.faultsLk.Lock()
+	defer m.faultsLk.Unlock()
+	if m.faults.FailPutEvery <= 0 {
+		return nil
+	}
+	m.puts++
+	if m.puts%m.faults.FailPutEvery == 0 {
+		return fmt.Errorf("%w: simulated put failure %d", ErrUnavailable, m.puts)
+	}
+	return nil
+}
+
+// readFault returns ErrUnavailable if the hash key resolves to is unavailable
+func (m *MemFS) readFault(key string) error {
+	m.faultsLk.Lock()
+	defer m.faultsLk.Unlock()
+	if _, ok := m.unavailable[memHash(key)]; ok {
+		return fmt.Errorf("%w: %s", ErrUnavailable, key)
+	}
+	return nil
+}
+
+// partialRead wraps f to fail after the configured partial read limit
+func (m *MemFS) partialRead(f File) File {
+	m.faultsLk.Lock()
+	limit := m.faults.PartialReadLimit
+	m.faultsLk.Unlock()
+	if limit <= 0 {
+		return f
+	}
+	return &partialFile{File: f, remaining: limit, limit: limit}
+}
+
+// memHash trims a MemFS key to its root hash
+func memHash(key string) string {
+	_, hash, _ := SplitStorePath(key)
+	return hash
+}
+
+// partialFile returns io.ErrUnexpectedEOF once remaining bytes are read
+type partialFile struct {
+	File
+	remaining int64
+	limit     int64
+}
+
+func (f *partialFile) Read(p []byte) (int, error) {
+	if f.IsDirectory() {
+		return f.File.Read(p)
+	}
+	if f.remaining <= 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if int64(len(p)) > f.remaining {
+		p = p[:f.remaining]
+	}
+	n, err := f.File.Read(p)
+	f.remaining -= int64(n)
+	return n, err
+}
+
+func (f *partialFile) NextFile() (File, error) {
+	next, err := f.File.NextFile()
+	if err != nil {
+		return nil, err
+	}
+	return &partialFile{File: next, remaining: f.limit, limit: f.limit}, nil
+}
@@ -0,0 +1,96 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestMemFSFaults(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	fs.SetFaults(MemFaults{FailPutEvery: 2})
+	for i, expectErr := range []bool{false, true, false, true} {
+		_, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("a")))
+		if expectErr != errors.Is(err, ErrUnavailable) {
+			t.Errorf("put %d: expected failure: %t. got: %v", i+1, expectErr, err)
+		}
+	}
+
+	fs.SetFaults(MemFaults{})
+	path, err := fs.Put(ctx, NewMemdir("/dir", NewMemfileBytes("b.txt", []byte("hello world"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs.SetFaults(MemFaults{Unavailable: []string{path}})
+	if _, err := fs.Get(ctx, path+"/b.txt"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected get of unavailable content to return ErrUnavailable. got: %v", err)
+	}
+	if _, err := fs.Has(ctx, path); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected has of unavailable content to return ErrUnavailable. got: %v", err)
+	}
+	id, err := cidFromPath(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.GetBlock(id); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("expected block read of unavailable content to return ErrUnavailable. got: %v", err)
+	}
+
+	fs.SetFaults(MemFaults{PartialReadLimit: 5})
+	f, err := fs.Get(ctx, path+"/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected partial read to return io.ErrUnexpectedEOF. got: %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("partial read mismatch. want: %q got: %q", "hello", string(data))
+	}
+
+	fs.SetFaults(MemFaults{})
+	f, err = fs.Get(ctx, path+"/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "hello world" {
+		t.Errorf("expected clearing faults to restore reads. got: %q", s)
+	}
+}
+
+func TestMemFSNetworkLatency(t *testing.T) {
+	local, peer := NewMemFS(), NewMemFS()
+	local.AddConnection(peer)
+	path, err := peer.Put(context.Background(), NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local.SetFaults(MemFaults{NetworkLatency: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := local.Get(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected slow peer to exceed the deadline. got: %v", err)
+	}
+
+	local.SetFaults(MemFaults{NetworkLatency: time.Millisecond})
+	if _, err := local.Get(context.Background(), path); err != nil {
+		t.Errorf("expected get from peer to succeed. got: %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := local.Put(cancelled, NewMemfileBytes("b.txt", []byte("b"))); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected put with a cancelled context to fail. got: %v", err)
+	}
+	if _, err := peer.Has(cancelled, path); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected has with a cancelled context to fail. got: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package qfs
+
+import "context"
+
+// addRoot marks key as a root, keeping it & everything it references from
+// being collected. callers must hold filesLk
+func (m *MemFS) addRoot(key string) {
+	if m.roots == nil {
+		m.roots = map[string]struct{}{}
+	}
+	m.roots[key] = struct{}{}
+}
+
+// hold protects key from garbage collection until it's released, for objects
+// written by a put that hasn't finished. callers must hold filesLk
+func (m *MemFS) hold(key string) {
+	if m.pending == nil {
+		m.pending = map[string]int{}
+	}
+	m.pending[key]++
+}
+
+// release drops a hold on each of keys. callers must hold filesLk
+func (m *MemFS) release(keys []string) {
+	for _, key := range keys {
+		if m.pending[key]--; m.pending[key] <= 0 {
+			delete(m.pending, key)
+		}
+	}
+}
+
+// CollectGarbage removes stored objects that can't be reached from a root,
+// returning the number of objects removed. Roots are the objects written by
+// Put, PutFileAtKey & the block-level put methods that haven't been deleted.
+// Delete collects garbage after removing a root, so CollectGarbage is only
+// needed to clean up after objects are removed some other way, like LRU
+// eviction
+func (m *MemFS) CollectGarbage(ctx context.Context) (removed int, err error) {
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	return m.collectGarbage(ctx)
+}
+
+// collectGarbage marks every object reachable from a root or held by a put in
+// progress, then sweeps the rest. callers must hold filesLk
+func (m *MemFS) collectGarbage(ctx context.Context) (removed int, err error) {
+	marked := make(map[string]struct{}, len(m.Files))
+	stack := make([]string, 0, len(m.roots)+len(m.pending))
+	for key := range m.roots {
+		stack = append(stack, key)
+	}
+	for key := range m.pending {
+		stack = append(stack, key)
+	}
+	for len(stack) > 0 {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		key := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if _, ok := marked[key]; ok {
+			continue
+		}
+		marked[key] = struct{}{}
+		if dir, ok := m.Files[key].(fsDir); ok {
+			for _, child := range dir.files {
+				stack = append(stack, child)
+			}
+		}
+	}
+
+	for key := range m.Files {
+		if _, ok := marked[key]; !ok {
+			m.removeFile(key)
+			removed++
+		}
+	}
+	return removed, nil
+}
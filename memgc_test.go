@@ -0,0 +1,56 @@
+package qfs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemFSCollectGarbage(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	a, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("shared.txt", []byte("shared")),
+		NewMemdir("b",
+			NewMemfileBytes("only_a.txt", []byte("only in a")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := fs.Put(ctx, NewMemdir("/c",
+		NewMemfileBytes("shared.txt", []byte("shared")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// c & shared.txt
+	remaining := 2
+
+	if err := fs.Delete(ctx, a); err != nil {
+		t.Fatal(err)
+	}
+	if fs.ObjectCount() != remaining {
+		t.Errorf("expected deleting a to remove content only it references. want %d objects got: %d", remaining, fs.ObjectCount())
+	}
+	f, err := fs.Get(ctx, c+"/shared.txt")
+	if err != nil {
+		t.Fatalf("expected shared content to survive deleting a. got: %v", err)
+	}
+	if _, s := FileString(f); s != "shared" {
+		t.Errorf("contents mismatch. want: %q got: %q", "shared", s)
+	}
+
+	if removed, err := fs.CollectGarbage(ctx); err != nil {
+		t.Fatal(err)
+	} else if removed != 0 {
+		t.Errorf("expected nothing left to collect. removed: %d", removed)
+	}
+
+	if err := fs.Delete(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if fs.ObjectCount() != 0 {
+		t.Errorf("expected an empty store. got %d objects", fs.ObjectCount())
+	}
+}
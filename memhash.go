@@ -0,0 +1,116 @@
+package qfs
+
+import (
+	"fmt"
+	"hash"
+
+	cid "github.com/ipfs/go-cid"
+	multibase "github.com/multiformats/go-multibase"
+	multihash "github.com/multiformats/go-multihash"
+)
+
+// MemHash configures how MemFS derives content identifiers, so tests can
+// mirror the CIDs a production IPFS node is configured to produce. The zero
+// value produces base58 CIDv0 SHA2-256 identifiers
+type MemHash struct {
+	// Code is the multihash function code, eg: multihash.SHA3_256 or
+	// multihash.BLAKE2B_MIN+31 for blake2b-256. Any function with a hasher
+	// registered with go-multihash can be used. Zero uses SHA2-256
+	Code uint64
+	// CidVersion is 0 or 1. CIDv0 requires SHA2-256
+	CidVersion uint64
+	// Base is the multibase encoding of CIDv1 strings. Zero uses base32
+	Base multibase.Encoding
+	// UnixFS derives identifiers from the UnixFS DAG ipfs add builds for the
+	// same content, so files & directories put to MemFS get the same CIDs an
+	// IPFS node configured with the same CID version, hash function and chunk
+	// size would give them. Content is still stored in MemFS's own format, so
+	// the block-level methods (PutBlock, PutNode, GetBlock) are unaffected.
+	// Stored block data doesn't hash to UnixFS identifiers, so Audit & Repair
+	// return ErrUnsupported for a MemFS hashing with UnixFS
+	UnixFS bool
+}
+
+// SetHash configures the content identifiers m derives. Content stored
+// before SetHash is called keeps the identifiers it was stored under, so
+// SetHash should be called before storing content
+func (m *MemFS) SetHash(h MemHash) error {
+	if _, err := h.newHasher(); err != nil {
+		return err
+	}
+	if h.CidVersion > 1 {
+		return fmt.Errorf("%w: CID version %d", ErrUnsupported, h.CidVersion)
+	}
+	if h.CidVersion == 0 && h.code() != multihash.SHA2_256 {
+		return fmt.Errorf("%w: CIDv0 requires sha2-256", ErrUnsupported)
+	}
+	if h.Base != 0 {
+		if _, err := multibase.NewEncoder(h.Base); err != nil {
+			return fmt.Errorf("%w: %s", ErrUnsupported, err)
+		}
+	}
+
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	m.hash = h
+	return nil
+}
+
+func (m *MemFS) getHash() MemHash {
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+	return m.hash
+}
+
+func (h MemHash) code() uint64 {
+	if h.Code == 0 {
+		return multihash.SHA2_256
+	}
+	return h.Code
+}
+
+// newHasher returns a hash for streaming content into
+func (h MemHash) newHasher() (hash.Hash, error) {
+	hasher, err := multihash.GetHasher(h.code())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, err)
+	}
+	return hasher, nil
+}
+
+// cid builds the identifier of content with the given digest. codec is the
+// codec CIDv1 identifiers are tagged with
+func (h MemHash) cid(digest []byte, codec uint64) (cid.Cid, error) {
+	mh, err := multihash.Encode(digest, h.code())
+	if err != nil {
+		return cid.Undef, fmt.Errorf("error encoding hash: %s", err.Error())
+	}
+	if h.CidVersion == 0 {
+		return cid.NewCidV0(mh), nil
+	}
+	return cid.NewCidV1(codec, mh), nil
+}
+
+// sum hashes data, returning its identifier
+func (h MemHash) sum(data []byte, codec uint64) (cid.Cid, error) {
+	hasher, err := h.newHasher()
+	if err != nil {
+		return cid.Undef, err
+	}
+	if _, err := hasher.Write(data); err != nil {
+		return cid.Undef, fmt.Errorf("error writing hash data: %s", err.Error())
+	}
+	return h.cid(hasher.Sum(nil), codec)
+}
+
+// key returns the string MemFS stores content with identifier id under
+func (h MemHash) key(id cid.Cid) string {
+	if id.Version() == 0 || h.Base == 0 {
+		return id.String()
+	}
+	key, err := id.StringOfBase(h.Base)
+	if err != nil {
+		return id.String()
+	}
+	return key
+}
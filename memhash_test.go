@@ -0,0 +1,80 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	multibase "github.com/multiformats/go-multibase"
+	multihash "github.com/multiformats/go-multihash"
+)
+
+func TestMemFSSetHash(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	if err := fs.SetHash(MemHash{Code: multihash.SHA3_256, CidVersion: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("b")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hash, _ := SplitStorePath(path)
+	id, err := cid.Decode(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Version() != 1 || id.Prefix().MhType != multihash.SHA3_256 {
+		t.Errorf("expected a sha3-256 CIDv1. got: %v", id.Prefix())
+	}
+	if !strings.HasPrefix(hash, "b") {
+		t.Errorf("expected CIDv1 to default to base32. got: %q", hash)
+	}
+
+	f, err := fs.Get(ctx, path+"/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "b" {
+		t.Errorf("contents mismatch. want: %q got: %q", "b", s)
+	}
+
+	if err := fs.SetHash(MemHash{Code: multihash.BLAKE2B_MIN + 31, CidVersion: 1, Base: multibase.Base58BTC}); err != nil {
+		t.Fatal(err)
+	}
+	id, err = fs.PutBlock([]byte("block"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Prefix().MhType != multihash.BLAKE2B_MIN+31 {
+		t.Errorf("expected a blake2b-256 hash. got: %v", id.Prefix())
+	}
+	if _, err := fs.GetBlock(id); err != nil {
+		t.Errorf("expected block to be stored under its CID. got: %v", err)
+	}
+	if has, _ := fs.Has(ctx, "/mem/"+id.Encode(multibase.MustNewEncoder(multibase.Base58BTC))); !has {
+		t.Errorf("expected block to be keyed by its base58 encoding")
+	}
+}
+
+func TestMemFSSetHashErrors(t *testing.T) {
+	fs := NewMemFS()
+	cases := []struct {
+		description string
+		hash        MemHash
+	}{
+		{"CIDv0 with another hash function", MemHash{Code: multihash.SHA3_256}},
+		{"unregistered hash function", MemHash{Code: multihash.MURMUR3_128, CidVersion: 1}},
+		{"unknown CID version", MemHash{CidVersion: 2}},
+	}
+	for _, c := range cases {
+		if err := fs.SetHash(c.hash); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%s: expected ErrUnsupported. got: %v", c.description, err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package qfs
+
+import (
+	"context"
+	"sort"
+)
+
+// Keys sends the key of every object stored in m on a channel, in sorted
+// order. Keys are listed when Keys is called, objects stored afterward aren't
+// included. The channel is closed once every key is sent or ctx is done
+func (m *MemFS) Keys(ctx context.Context) (<-chan string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	keys := m.sortedKeys()
+	ch := make(chan string)
+	go func() {
+		defer close(ch)
+		for _, key := range keys {
+			select {
+			case ch <- key:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// ForEach calls fn with every object stored in m in sorted key order, opening
+// each as a File. Keys are listed when ForEach is called. Objects removed
+// before fn reaches them are skipped. An error returned by fn stops iteration
+// & is returned by ForEach
+func (m *MemFS) ForEach(ctx context.Context, fn func(key string, f File) error) error {
+	for _, key := range m.sortedKeys() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// directories read their children from Files while opening
+		m.filesLk.RLock()
+		obj, ok := m.object(key)
+		var (
+			f   File
+			err error
+		)
+		if ok {
+			f, err = obj.File()
+		}
+		m.filesLk.RUnlock()
+		if !ok {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := fn(key, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemFS) sortedKeys() []string {
+	m.filesLk.RLock()
+	keys := make([]string, 0, len(m.Files))
+	for key := range m.Files {
+		if _, ok := m.object(key); ok {
+			keys = append(keys, key)
+		}
+	}
+	m.filesLk.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestMemFSKeysForEach(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	if _, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("b")),
+		NewMemfileBytes("c.txt", []byte("c")),
+	)); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := fs.Keys(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for key := range keys {
+		got = append(got, key)
+	}
+	if len(got) != fs.ObjectCount() {
+		t.Errorf("expected a key for each object. want %d got: %d", fs.ObjectCount(), len(got))
+	}
+	if !sort.StringsAreSorted(got) {
+		t.Errorf("expected keys in sorted order. got: %v", got)
+	}
+
+	dirs, files := 0, 0
+	err = fs.ForEach(ctx, func(key string, f File) error {
+		if f.IsDirectory() {
+			dirs++
+		} else {
+			files++
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dirs != 1 || files != 2 {
+		t.Errorf("expected 1 directory & 2 files. got: %d, %d", dirs, files)
+	}
+
+	errStop := errors.New("stop")
+	calls := 0
+	err = fs.ForEach(ctx, func(key string, f File) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("expected an error to stop iteration. got: %v after %d calls", err, calls)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	keys, err = fs.Keys(cancelled)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-keys
+	cancel()
+	for range keys {
+	}
+	if _, err := fs.Keys(cancelled); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected a cancelled context to fail. got: %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// MemLink configures the simulated connection from a MemFS to a peer on its
+// Network. Like MemFaults, links are deterministic: the same sequence of
+// requests is delayed & fails the same way every time
+type MemLink struct {
+	// Latency delays each request to the peer, on top of the NetworkLatency
+	// fault. Zero adds no delay
+	Latency time.Duration
+	// FailEvery makes every Nth request to the peer return ErrUnavailable.
+	// Zero disables failures
+	FailEvery int
+	// Bandwidth caps the bytes per second read from files fetched from the
+	// peer, including files within directories. Zero is unlimited
+	Bandwidth int64
+}
+
+// memLink is a link & the count of requests made over it
+type memLink struct {
+	MemLink
+	requests int
+}
+
+// SetLink configures the simulated connection from m to peer, resetting its
+// request counter. peer is connected to m if it isn't already. Links only
+// affect requests m makes, use peer.SetLink to configure the other direction.
+// Pass the zero value to restore an ideal connection
+func (m *MemFS) SetLink(peer *MemFS, link MemLink) {
+	m.AddConnection(peer)
+
+	m.faultsLk.Lock()
+	defer m.faultsLk.Unlock()
+	if m.links == nil {
+		m.links = map[*MemFS]*memLink{}
+	}
+	m.links[peer] = &memLink{MemLink: link}
+}
+
+// link returns the configuration of the connection to peer
+func (m *MemFS) link(peer *MemFS) MemLink {
+	m.faultsLk.Lock()
+	defer m.faultsLk.Unlock()
+	if l, ok := m.links[peer]; ok {
+		return l.MemLink
+	}
+	return MemLink{}
+}
+
+// networkHop simulates a request to a connected store, waiting out the
+// configured latency & counting the request toward the link's failures.
+// returns ctx's error if ctx is done first
+func (m *MemFS) networkHop(ctx context.Context, peer *MemFS) error {
+	m.faultsLk.Lock()
+	latency := m.faults.NetworkLatency
+	var failure error
+	if l, ok := m.links[peer]; ok {
+		latency += l.Latency
+		l.requests++
+		if l.FailEvery > 0 && l.requests%l.FailEvery == 0 {
+			failure = fmt.Errorf("%w: simulated network failure %d", ErrUnavailable, l.requests)
+		}
+	}
+	m.faultsLk.Unlock()
+
+	if err := sleepContext(ctx, latency); err != nil {
+		return err
+	}
+	return failure
+}
+
+// throttle limits reads of a file fetched from peer to the link's bandwidth
+func (m *MemFS) throttle(ctx context.Context, peer *MemFS, f File) File {
+	bandwidth := m.link(peer).Bandwidth
+	if bandwidth <= 0 {
+		return f
+	}
+	return &throttledFile{File: f, ctx: ctx, bandwidth: bandwidth}
+}
+
+// sleepContext waits for d, returning ctx's error if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// throttledFile delays reads so bytes are delivered no faster than bandwidth
+// bytes per second
+type throttledFile struct {
+	File
+	ctx       context.Context
+	bandwidth int64
+}
+
+func (f *throttledFile) Read(p []byte) (int, error) {
+	if f.IsDirectory() {
+		return f.File.Read(p)
+	}
+	// cap reads at a tenth of a second's worth of bytes so waits are spread
+	// over the file instead of taken in one large pause
+	if max := f.bandwidth/10 + 1; int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := f.File.Read(p)
+	if n > 0 {
+		if werr := sleepContext(f.ctx, time.Duration(int64(n)*int64(time.Second)/f.bandwidth)); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (f *throttledFile) NextFile() (File, error) {
+	next, err := f.File.NextFile()
+	if err != nil {
+		return nil, err
+	}
+	return &throttledFile{File: next, ctx: f.ctx, bandwidth: f.bandwidth}, nil
+}
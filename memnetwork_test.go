@@ -0,0 +1,86 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemFSLinkLatency(t *testing.T) {
+	local, slow, fast := NewMemFS(), NewMemFS(), NewMemFS()
+	local.SetLink(slow, MemLink{Latency: time.Minute})
+	local.AddConnection(fast)
+
+	path, err := fast.Put(context.Background(), NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := local.Get(ctx, path); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a slow peer to exceed the deadline. got: %v", err)
+	}
+
+	local.SetLink(slow, MemLink{})
+	if _, err := local.Get(context.Background(), path); err != nil {
+		t.Errorf("expected clearing the link to restore gets. got: %v", err)
+	}
+}
+
+func TestMemFSLinkFailures(t *testing.T) {
+	ctx := context.Background()
+	local, peer := NewMemFS(), NewMemFS()
+	path, err := peer.Put(ctx, NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local.SetLink(peer, MemLink{FailEvery: 3})
+	for i, expectErr := range []bool{false, false, true, false, false, true} {
+		_, err := local.Get(ctx, path)
+		if expectErr != errors.Is(err, ErrUnavailable) {
+			t.Errorf("get %d: expected failure: %t. got: %v", i+1, expectErr, err)
+		}
+	}
+}
+
+func TestMemFSLinkBandwidth(t *testing.T) {
+	ctx := context.Background()
+	local, peer := NewMemFS(), NewMemFS()
+	content := strings.Repeat("a", 1000)
+	path, err := peer.Put(ctx, NewMemfileBytes("a.txt", []byte(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	local.SetLink(peer, MemLink{Bandwidth: 10000})
+	f, err := local.Get(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("contents mismatch")
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond*90 {
+		t.Errorf("expected reading 1000 bytes at 10000 bytes/s to take at least 100ms. took: %s", elapsed)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	f, err = local.Get(cancelled, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := ioutil.ReadAll(f); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected cancelling the get to stop throttled reads. got: %v", err)
+	}
+}
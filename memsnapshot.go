@@ -0,0 +1,127 @@
+package qfs
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+)
+
+// memSnapshotVersion is the version of the MemFS snapshot format. Bump it when
+// the format changes incompatibly
+const memSnapshotVersion = 1
+
+// memSnapshot is the gob-encoded form of a MemFS
+type memSnapshot struct {
+	Version   int
+	Pinned    bool
+	Hash      MemHash
+	ChunkSize int
+	Objects   map[string]memSnapshotObject
+	// Roots are the keys of objects that aren't garbage collected. Snapshots
+	// taken before roots were tracked have no roots, objects no directory
+	// links to are treated as roots
+	Roots []string
+}
+
+// memSnapshotObject is a stored file or directory. Directories have Links
+// from child name to child hash
+type memSnapshotObject struct {
+	IsDir  bool
+	Name   string
+	Path   string
+	Chunks [][]byte
+	Size   int64
+	Links  map[string]string
+}
+
+// Snapshot writes every object stored in m to w with encoding/gob, along with
+// m's hash & chunk size configuration, so stores can be restored with
+// LoadMemFS. Network peers, locks & faults aren't included. MemFS refuses
+// symlinks, so snapshots never hold them
+func (m *MemFS) Snapshot(w io.Writer) error {
+	m.filesLk.RLock()
+	snap := memSnapshot{
+		Version:   memSnapshotVersion,
+		Pinned:    m.Pinned,
+		Hash:      m.hash,
+		ChunkSize: m.chunkSize,
+		Objects:   make(map[string]memSnapshotObject, len(m.Files)),
+		Roots:     make([]string, 0, len(m.roots)),
+	}
+	for key := range m.roots {
+		snap.Roots = append(snap.Roots, key)
+	}
+	for key, f := range m.Files {
+		switch f := f.(type) {
+		case fsFile:
+			snap.Objects[key] = memSnapshotObject{
+				Name:   f.name,
+				Path:   f.path,
+				Chunks: f.data.chunks,
+				Size:   f.data.size,
+			}
+		case fsDir:
+			snap.Objects[key] = memSnapshotObject{
+				IsDir: true,
+				Path:  f.path,
+				Links: f.files,
+			}
+		default:
+			m.filesLk.RUnlock()
+			return fmt.Errorf("snapshotting %q: unexpected object type %T", key, f)
+		}
+	}
+	// stored objects are never modified in place, so they can be encoded
+	// without holding the lock
+	m.filesLk.RUnlock()
+
+	return gob.NewEncoder(w).Encode(snap)
+}
+
+// LoadMemFS restores a MemFS from a snapshot written by MemFS.Snapshot
+func LoadMemFS(r io.Reader) (*MemFS, error) {
+	snap := memSnapshot{}
+	if err := gob.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("decoding MemFS snapshot: %w", err)
+	}
+	if snap.Version != memSnapshotVersion {
+		return nil, fmt.Errorf("%w: MemFS snapshot version %d", ErrUnsupported, snap.Version)
+	}
+
+	m := NewMemFS()
+	m.Pinned = snap.Pinned
+	m.hash = snap.Hash
+	m.chunkSize = snap.ChunkSize
+	for key, obj := range snap.Objects {
+		if obj.IsDir {
+			links := obj.Links
+			if links == nil {
+				links = map[string]string{}
+			}
+			m.setFile(key, fsDir{fs: m, path: obj.Path, files: links})
+			continue
+		}
+		m.setFile(key, fsFile{
+			name: obj.Name,
+			path: obj.Path,
+			data: chunkedData{chunks: obj.Chunks, size: obj.Size},
+		})
+	}
+	for _, key := range snap.Roots {
+		m.addRoot(key)
+	}
+	if snap.Roots == nil {
+		linked := map[string]struct{}{}
+		for _, obj := range snap.Objects {
+			for _, child := range obj.Links {
+				linked[child] = struct{}{}
+			}
+		}
+		for key := range snap.Objects {
+			if _, ok := linked[key]; !ok {
+				m.addRoot(key)
+			}
+		}
+	}
+	return m, nil
+}
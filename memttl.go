@@ -0,0 +1,108 @@
+package qfs
+
+import (
+	"container/heap"
+	"time"
+)
+
+// OptMemTTL makes objects written to a MemFS expire ttl after they're
+// written, for using the store as a short-lived cache. Expired objects read
+// as missing. They're removed by the first write after they expire, or by
+// calling Expire. Rewriting an object restarts its TTL. Objects in a
+// directory are written before the directory, so they can expire just
+// before it, fetching a directory with expired children returns an error
+// wrapping ErrNotFound. Zero disables expiry
+func OptMemTTL(ttl time.Duration) MemOption {
+	return func(m *MemFS) {
+		m.ttl = ttl
+	}
+}
+
+// OptMemClock sets the clock a MemFS reads the time from when expiring
+// objects, defaults to SystemClock
+func OptMemClock(c Clock) MemOption {
+	return func(m *MemFS) {
+		m.clock = c
+	}
+}
+
+func (m *MemFS) now() time.Time {
+	if m.clock == nil {
+		return SystemClock.Now()
+	}
+	return m.clock.Now()
+}
+
+// object returns the unexpired object stored under key. callers must hold
+// filesLk, shared or exclusively
+func (m *MemFS) object(key string) (filer, bool) {
+	f, ok := m.Files[key]
+	if !ok {
+		return nil, false
+	}
+	if exp, ok := m.expires[key]; ok && !m.now().Before(exp) {
+		return nil, false
+	}
+	return f, true
+}
+
+// setExpiry starts the TTL of the object stored under key, removing objects
+// that have expired. callers must hold filesLk exclusively
+func (m *MemFS) setExpiry(key string) {
+	if m.ttl <= 0 {
+		return
+	}
+	now := m.now()
+	if m.expires == nil {
+		m.expires = map[string]time.Time{}
+	}
+	// restart key's TTL before sweeping, so a rewrite of an expired object
+	// isn't removed
+	exp := now.Add(m.ttl)
+	m.expires[key] = exp
+	heap.Push(&m.expiries, expiry{key: key, at: exp})
+	m.expire(now)
+}
+
+// Expire removes every expired object, returning the number removed
+func (m *MemFS) Expire() int {
+	m.filesLk.Lock()
+	defer m.filesLk.Unlock()
+	return m.expire(m.now())
+}
+
+// expire removes objects that expired by now. callers must hold filesLk
+// exclusively
+func (m *MemFS) expire(now time.Time) (removed int) {
+	for len(m.expiries) > 0 && !now.Before(m.expiries[0].at) {
+		e := heap.Pop(&m.expiries).(expiry)
+		// entries are left in the heap when objects are rewritten or removed,
+		// skip any that no longer match the object's expiry
+		if exp, ok := m.expires[e.key]; !ok || !exp.Equal(e.at) {
+			continue
+		}
+		m.removeFile(e.key)
+		removed++
+	}
+	return removed
+}
+
+// expiry is the time an object expires
+type expiry struct {
+	key string
+	at  time.Time
+}
+
+// expiryHeap is a min-heap of expiries, implementing heap.Interface
+type expiryHeap []expiry
+
+func (h expiryHeap) Len() int            { return len(h) }
+func (h expiryHeap) Less(i, j int) bool  { return h[i].at.Before(h[j].at) }
+func (h expiryHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expiryHeap) Push(x interface{}) { *h = append(*h, x.(expiry)) }
+func (h *expiryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
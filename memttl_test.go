@@ -0,0 +1,59 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemFSTTL(t *testing.T) {
+	ctx := context.Background()
+	clock := NewManualClock(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	fs := NewMemFS(OptMemTTL(time.Minute), OptMemClock(clock))
+
+	a, err := fs.Put(ctx, NewMemdir("/a", NewMemfileBytes("b.txt", []byte("b"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second * 30)
+	c, err := fs.Put(ctx, NewMemfileBytes("c.txt", []byte("c")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Second * 40)
+	if _, err := fs.Get(ctx, a+"/b.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected expired content to read as missing. got: %v", err)
+	}
+	if exists, _ := fs.Has(ctx, a); exists {
+		t.Error("expected expired directory to be missing")
+	}
+	if _, err := fs.Get(ctx, c); err != nil {
+		t.Errorf("expected unexpired content to be readable. got: %v", err)
+	}
+	// a/ & b.txt
+	if removed := fs.Expire(); removed != 2 {
+		t.Errorf("expected Expire to remove 2 objects. removed: %d", removed)
+	}
+	if fs.ObjectCount() != 1 {
+		t.Errorf("expected 1 object left. got: %d", fs.ObjectCount())
+	}
+
+	// rewriting restarts the TTL, and writes remove expired objects
+	clock.Advance(time.Second * 20)
+	if _, err := fs.Put(ctx, NewMemfileBytes("c.txt", []byte("c"))); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second * 50)
+	if _, err := fs.Get(ctx, c); err != nil {
+		t.Errorf("expected rewritten content to be readable. got: %v", err)
+	}
+	clock.Advance(time.Second * 10)
+	if _, err := fs.Put(ctx, NewMemfileBytes("d.txt", []byte("d"))); err != nil {
+		t.Fatal(err)
+	}
+	if fs.ObjectCount() != 1 {
+		t.Errorf("expected writes to remove expired objects. got %d objects", fs.ObjectCount())
+	}
+}
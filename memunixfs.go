@@ -0,0 +1,96 @@
+package qfs
+
+import (
+	"context"
+	"io"
+
+	cid "github.com/ipfs/go-cid"
+	chunker "github.com/ipfs/go-ipfs-chunker"
+	format "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfs "github.com/ipfs/go-unixfs"
+	"github.com/ipfs/go-unixfs/importer/balanced"
+	helpers "github.com/ipfs/go-unixfs/importer/helpers"
+)
+
+// cidBuilder returns the CID prefix UnixFS nodes are built with
+func (h MemHash) cidBuilder() (cid.Builder, error) {
+	prefix, err := merkledag.PrefixForCidVersion(int(h.CidVersion))
+	if err != nil {
+		return nil, err
+	}
+	prefix.MhType = h.code()
+	prefix.MhLength = -1
+	return prefix, nil
+}
+
+// unixfsFile builds the UnixFS DAG for r the way ipfs add does, returning
+// the root CID & the cumulative size of the DAG. CIDv1 DAGs use raw leaves,
+// matching ipfs add --cid-version=1
+func (h MemHash) unixfsFile(r io.Reader, chunkSize int) (cid.Cid, uint64, error) {
+	builder, err := h.cidBuilder()
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	params := helpers.DagBuilderParams{
+		Maxlinks:   helpers.DefaultLinksPerBlock,
+		RawLeaves:  h.CidVersion == 1,
+		CidBuilder: builder,
+		Dagserv:    discardDAG{},
+	}
+	db, err := params.New(chunker.NewSizeSplitter(r, int64(chunkSize)))
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	nd, err := balanced.Layout(db)
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	size, err := nd.Size()
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	return nd.Cid(), size, nil
+}
+
+// unixfsDir builds a UnixFS directory node linking to children, returning its
+// CID & the cumulative size of the directory DAG
+func (h MemHash) unixfsDir(links []*format.Link) (cid.Cid, uint64, error) {
+	builder, err := h.cidBuilder()
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	nd := unixfs.EmptyDirNode()
+	nd.SetCidBuilder(builder)
+	for _, lnk := range links {
+		if err := nd.AddRawLink(lnk.Name, lnk); err != nil {
+			return cid.Undef, 0, err
+		}
+	}
+	size, err := nd.Size()
+	if err != nil {
+		return cid.Undef, 0, err
+	}
+	return nd.Cid(), size, nil
+}
+
+// discardDAG is a DAGService that drops nodes added to it. MemFS builds UnixFS
+// DAGs only to derive their CIDs, content is stored in MemFS's own format
+type discardDAG struct{}
+
+var _ format.DAGService = discardDAG{}
+
+func (discardDAG) Get(context.Context, cid.Cid) (format.Node, error) {
+	return nil, format.ErrNotFound
+}
+
+func (discardDAG) GetMany(context.Context, []cid.Cid) <-chan *format.NodeOption {
+	ch := make(chan *format.NodeOption)
+	close(ch)
+	return ch
+}
+
+func (discardDAG) Add(context.Context, format.Node) error       { return nil }
+func (discardDAG) AddMany(context.Context, []format.Node) error { return nil }
+func (discardDAG) Remove(context.Context, cid.Cid) error        { return nil }
+func (discardDAG) RemoveMany(context.Context, []cid.Cid) error  { return nil }
@@ -0,0 +1,112 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+func TestMemFSUnixFS(t *testing.T) {
+	ctx := context.Background()
+
+	// expected CIDs are the output of ipfs add with default settings
+	cases := []struct {
+		description string
+		hash        MemHash
+		file        File
+		expect      string
+	}{
+		{"file", MemHash{UnixFS: true}, NewMemfileBytes("hello.txt", []byte("hello world\n")), "/mem/QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"},
+		{"empty file", MemHash{UnixFS: true}, NewMemfileBytes("empty.txt", nil), "/mem/QmbFMke1KXqnYyBBWxB74N4c5SBnJMVAiMNRcGu6x1AwQH"},
+		{"empty directory", MemHash{UnixFS: true}, NewMemdir("/a"), "/mem/QmUNLLsPACCz1vLxQVkXqqLX5R1X345qqfHbsf67hvA3Nn"},
+		{"cidv1 raw leaves", MemHash{UnixFS: true, CidVersion: 1}, NewMemfileBytes("hello.txt", []byte("hello world\n")), "/mem/bafkreifjjcie6lypi6ny7amxnfftagclbuxndqonfipmb64f2km2devei4"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			fs := NewMemFS()
+			if err := fs.SetHash(c.hash); err != nil {
+				t.Fatal(err)
+			}
+			got, err := fs.Put(ctx, c.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != c.expect {
+				t.Errorf("path mismatch. want: %s got: %s", c.expect, got)
+			}
+		})
+	}
+}
+
+func TestMemFSUnixFSDirectory(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	if err := fs.SetHash(MemHash{UnixFS: true}); err != nil {
+		t.Fatal(err)
+	}
+	fs.SetChunkSize(4)
+
+	root, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("hello world\n")),
+		NewMemdir("c",
+			NewMemfileBytes("d.txt", []byte("nested")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := fs.Get(ctx, root+"/c/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "nested" {
+		t.Errorf("contents mismatch. want: %q got: %q", "nested", s)
+	}
+
+	// the same content put in a different order must produce the same root
+	again := NewMemFS()
+	if err := again.SetHash(MemHash{UnixFS: true}); err != nil {
+		t.Fatal(err)
+	}
+	again.SetChunkSize(4)
+	got, err := again.Put(ctx, NewMemdir("/a",
+		NewMemdir("c",
+			NewMemfileBytes("d.txt", []byte("nested")),
+		),
+		NewMemfileBytes("b.txt", []byte("hello world\n")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != root {
+		t.Errorf("expected directory CID to be independent of child order. want: %s got: %s", root, got)
+	}
+}
+
+func TestMemFSUnixFSAudit(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	if err := fs.SetHash(MemHash{UnixFS: true}); err != nil {
+		t.Fatal(err)
+	}
+	path, err := fs.Put(ctx, NewMemfileBytes("hello.txt", []byte("hello world\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, hash, _ := SplitStorePath(path)
+	id, err := cid.Decode(hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Audit(ctx, fs, []cid.Cid{id}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected auditing a UnixFS-hashed MemFS to return ErrUnsupported. got: %v", err)
+	}
+	if _, err := Repair(ctx, fs, []cid.Cid{id}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected repairing a UnixFS-hashed MemFS to return ErrUnsupported. got: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package qfs
+
+import (
+	"context"
+	"sort"
+)
+
+// MemStatsLargest is the number of objects Stats lists as the largest
+const MemStatsLargest = 10
+
+// MemStats summarizes the objects stored in a MemFS
+type MemStats struct {
+	// Objects is the number of content-addressed objects, including
+	// directories
+	Objects int
+	// Directories is the number of objects that are directories
+	Directories int
+	// TotalBytes is the size of every object. Directories count the bytes of
+	// their encoded block
+	TotalBytes int64
+	// Largest lists up to MemStatsLargest of the biggest objects, largest
+	// first
+	Largest []MemObjectSize
+}
+
+// MemObjectSize is the size of a stored object
+type MemObjectSize struct {
+	Key  string
+	Size int64
+}
+
+// Stats summarizes the objects stored in m. Expired objects aren't counted
+func (m *MemFS) Stats() MemStats {
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+
+	stats := MemStats{}
+	for key := range m.Files {
+		f, ok := m.object(key)
+		if !ok {
+			continue
+		}
+		size := storedSize(f)
+		stats.Objects++
+		if _, ok := f.(fsDir); ok {
+			stats.Directories++
+		}
+		stats.TotalBytes += size
+		stats.Largest = append(stats.Largest, MemObjectSize{Key: key, Size: size})
+	}
+
+	sort.Slice(stats.Largest, func(i, j int) bool {
+		if stats.Largest[i].Size == stats.Largest[j].Size {
+			return stats.Largest[i].Key < stats.Largest[j].Key
+		}
+		return stats.Largest[i].Size > stats.Largest[j].Size
+	})
+	if len(stats.Largest) > MemStatsLargest {
+		stats.Largest = stats.Largest[:MemStatsLargest]
+	}
+	return stats
+}
+
+// DiskUsage returns the bytes of the objects reachable from path, counting
+// objects shared within the path once. An empty path returns the TotalBytes
+// of Stats, implementing the DiskUsageFS interface
+func (m *MemFS) DiskUsage(ctx context.Context, path string) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	if path == "" {
+		return m.Stats().TotalBytes, nil
+	}
+	if err := m.readFault(path); err != nil {
+		return 0, err
+	}
+
+	m.filesLk.RLock()
+	defer m.filesLk.RUnlock()
+
+	hash, f, err := m.resolveHash(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	seen := map[string]struct{}{hash: {}}
+	queue := []filer{f}
+	for len(queue) > 0 {
+		f, queue = queue[0], queue[1:]
+		total += storedSize(f)
+		dir, ok := f.(fsDir)
+		if !ok {
+			continue
+		}
+		for _, key := range dir.files {
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			ch, ok := m.object(key)
+			if !ok {
+				return 0, ErrNotFound
+			}
+			queue = append(queue, ch)
+		}
+	}
+	return total, nil
+}
+
+// storedSize is the number of bytes an object takes to store, including the
+// encoded block of directories
+func storedSize(f filer) int64 {
+	switch o := f.(type) {
+	case fsFile:
+		return o.data.size
+	case fsDir:
+		return int64(len(o.blockData()))
+	}
+	return 0
+}
@@ -0,0 +1,46 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMemFSStats(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	if stats := fs.Stats(); stats.Objects != 0 || stats.TotalBytes != 0 || len(stats.Largest) != 0 {
+		t.Errorf("expected empty stats for an empty store. got: %#v", stats)
+	}
+
+	files := []File{}
+	var fileBytes int64
+	for i := 1; i <= MemStatsLargest+2; i++ {
+		files = append(files, NewMemfileBytes(fmt.Sprintf("%d.txt", i), []byte(strings.Repeat("x", i))))
+		fileBytes += int64(i)
+	}
+	if _, err := fs.Put(ctx, NewMemdir("/a", NewMemdir("b", files...))); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := fs.Stats()
+	if stats.Objects != fs.ObjectCount() {
+		t.Errorf("object count mismatch. want: %d got: %d", fs.ObjectCount(), stats.Objects)
+	}
+	if stats.Directories != 2 {
+		t.Errorf("expected 2 directories. got: %d", stats.Directories)
+	}
+	if stats.TotalBytes <= fileBytes {
+		t.Errorf("expected total to include file & directory bytes. got: %d", stats.TotalBytes)
+	}
+	if len(stats.Largest) != MemStatsLargest {
+		t.Fatalf("expected %d largest objects. got: %d", MemStatsLargest, len(stats.Largest))
+	}
+	for i := 1; i < len(stats.Largest); i++ {
+		if stats.Largest[i].Size > stats.Largest[i-1].Size {
+			t.Errorf("expected largest objects sorted by size. got: %v", stats.Largest)
+			break
+		}
+	}
+}
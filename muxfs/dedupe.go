@@ -0,0 +1,291 @@
+package muxfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/qri-io/qfs"
+)
+
+// crossRefs is the JSON form of the cross-references saved to the refs path
+type crossRefs struct {
+	// Refs maps paths Put returned to the path holding their content
+	Refs map[string]string `json:"refs"`
+	// Pinned lists referenced paths the mux pinned, which are unpinned once
+	// nothing references them
+	Pinned []string `json:"pinned,omitempty"`
+}
+
+// SetDeduplicate turns cross-mount deduplication on, saving cross-references
+// as JSON to refsPath, a file on a filesystem the mux holds that writes to the
+// paths it's given. Cross-references already saved at refsPath are loaded. An
+// empty refsPath turns deduplication off, loaded cross-references still
+// resolve & are saved as they're removed.
+//
+// While on, putting a file to a content-addressed filesystem that implements
+// qfs.HashOnlyFS hashes it first, & if that filesystem doesn't have the
+// content but another content-addressed filesystem that implements
+// qfs.PinListerFS holds the same hash, the mux records a cross-reference
+// instead of writing the bytes again. The referenced content is pinned until
+// the last path referencing it is deleted, & deleting it through the mux
+// fails while it's referenced. Has & Get resolve cross-referenced paths to the
+// filesystem holding the content. Only the mux knows about cross-references,
+// the filesystem a cross-referenced path names doesn't hold its content.
+// Files that can't seek are read into memory to hash them before writing.
+// Directories are always written
+func (m *Mux) SetDeduplicate(ctx context.Context, refsPath string) error {
+	if refsPath == "" {
+		m.xrefsLk.Lock()
+		defer m.xrefsLk.Unlock()
+		m.dedupe = false
+		return nil
+	}
+
+	kind := qfs.PathKind(refsPath)
+	handler, ok := m.handlers[kind]
+	if !ok {
+		return noMuxerError(kind, refsPath)
+	}
+	if caps := qfs.Capabilities(handler); caps.ContentAddressed || !caps.CanWrite {
+		return fmt.Errorf("%w: %s filesystem can't save cross-references to a given path", qfs.ErrUnsupported, kind)
+	}
+	refs := crossRefs{Refs: map[string]string{}}
+	f, err := handler.Get(ctx, refsPath)
+	if err == nil {
+		err = json.NewDecoder(f).Decode(&refs)
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("decoding cross-references %q: %w", refsPath, err)
+		}
+	} else if !errors.Is(err, qfs.ErrNotFound) {
+		return fmt.Errorf("loading cross-references %q: %w", refsPath, err)
+	}
+
+	m.xrefsLk.Lock()
+	defer m.xrefsLk.Unlock()
+	m.dedupe = true
+	m.xrefsPath = refsPath
+	m.xrefs = refs.Refs
+	if m.xrefs == nil {
+		m.xrefs = map[string]string{}
+	}
+	m.xrefPins = map[string]bool{}
+	for _, ref := range refs.Pinned {
+		m.xrefPins[ref] = true
+	}
+	return nil
+}
+
+// CrossRefs returns a copy of the recorded cross-references, mapping each path
+// Put returned to the path of the filesystem holding its content
+func (m *Mux) CrossRefs() map[string]string {
+	m.xrefsLk.RLock()
+	defer m.xrefsLk.RUnlock()
+	refs := make(map[string]string, len(m.xrefs))
+	for k, v := range m.xrefs {
+		refs[k] = v
+	}
+	return refs
+}
+
+// resolve returns the path holding the content of path, following any
+// cross-reference
+func (m *Mux) resolve(path string) string {
+	m.xrefsLk.RLock()
+	defer m.xrefsLk.RUnlock()
+	if ref, ok := m.xrefs[path]; ok {
+		return ref
+	}
+	return path
+}
+
+// referenced returns the number of cross-references to ref. m.xrefsLk must be
+// held
+func (m *Mux) referenced(ref string) (n int) {
+	for _, r := range m.xrefs {
+		if r == ref {
+			n++
+		}
+	}
+	return n
+}
+
+// checkUnreferenced returns an error that wraps qfs.ErrPreconditionFailed if
+// any cross-reference resolves to path
+func (m *Mux) checkUnreferenced(path string) error {
+	m.xrefsLk.RLock()
+	defer m.xrefsLk.RUnlock()
+	if n := m.referenced(path); n > 0 {
+		return fmt.Errorf("%w: %q holds the content of %d cross-referenced paths", qfs.ErrPreconditionFailed, path, n)
+	}
+	return nil
+}
+
+// saveCrossRefs writes the cross-references to the refs path. m.xrefsLk must
+// be held for writing
+func (m *Mux) saveCrossRefs(ctx context.Context) error {
+	refs := crossRefs{Refs: m.xrefs}
+	for ref := range m.xrefPins {
+		refs.Pinned = append(refs.Pinned, ref)
+	}
+	sort.Strings(refs.Pinned)
+	data, err := json.Marshal(refs)
+	if err != nil {
+		return err
+	}
+	handler := m.handlers[qfs.PathKind(m.xrefsPath)]
+	if _, err := handler.Put(ctx, qfs.NewMemfileBytes(m.xrefsPath, data)); err != nil {
+		return fmt.Errorf("saving cross-references %q: %w", m.xrefsPath, err)
+	}
+	return nil
+}
+
+// addCrossRef records a cross-reference from path to ref, pinning ref in
+// holder if it isn't pinned already
+func (m *Mux) addCrossRef(ctx context.Context, path, ref string, holder qfs.PinListerFS) error {
+	m.xrefsLk.Lock()
+	defer m.xrefsLk.Unlock()
+
+	pinned := false
+	if m.referenced(ref) == 0 {
+		pins, err := holder.Pins(ctx)
+		if err != nil {
+			return err
+		}
+		for _, p := range pins {
+			if p.Key == ref && p.Recursive {
+				pinned = true
+				break
+			}
+		}
+		if !pinned {
+			if err := holder.Pin(ctx, ref, true); err != nil {
+				return err
+			}
+			m.xrefPins[ref] = true
+		}
+	}
+	m.xrefs[path] = ref
+	if err := m.saveCrossRefs(ctx); err != nil {
+		delete(m.xrefs, path)
+		if m.xrefPins[ref] && m.referenced(ref) == 0 {
+			delete(m.xrefPins, ref)
+			if unpinErr := holder.Unpin(ctx, ref, true); unpinErr != nil {
+				return fmt.Errorf("%w. unpinning %q: %s", err, ref, unpinErr)
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// removeCrossRef deletes the cross-reference for path, reporting whether one
+// existed. Content the mux pinned is unpinned once nothing references it
+func (m *Mux) removeCrossRef(ctx context.Context, path string) (bool, error) {
+	m.xrefsLk.Lock()
+	defer m.xrefsLk.Unlock()
+	ref, ok := m.xrefs[path]
+	if !ok {
+		return false, nil
+	}
+	delete(m.xrefs, path)
+	unpin := m.xrefPins[ref] && m.referenced(ref) == 0
+	if unpin {
+		delete(m.xrefPins, ref)
+	}
+	if err := m.saveCrossRefs(ctx); err != nil {
+		m.xrefs[path] = ref
+		if unpin {
+			m.xrefPins[ref] = true
+		}
+		return true, err
+	}
+	if unpin {
+		if holder, ok := m.handlers[qfs.PathKind(ref)].(qfs.PinningFS); ok {
+			if err := holder.Unpin(ctx, ref, true); err != nil {
+				return true, fmt.Errorf("unpinning %q: %w", ref, err)
+			}
+		}
+	}
+	return true, nil
+}
+
+// dedupePut records a cross-reference if file's content is already held by a
+// content-addressed filesystem other than the handler of kind. If it isn't,
+// dedupePut returns a file for the caller to write in place of the consumed
+// original
+func (m *Mux) dedupePut(ctx context.Context, kind string, file qfs.File) (path string, write qfs.File, err error) {
+	handler := m.handlers[kind]
+	m.xrefsLk.RLock()
+	enabled := m.dedupe
+	m.xrefsLk.RUnlock()
+	if !enabled || file.IsDirectory() || !qfs.Capabilities(handler).ContentAddressed {
+		return "", file, nil
+	}
+	if _, ok := handler.(qfs.HashOnlyFS); !ok {
+		return "", file, nil
+	}
+
+	// seekable files are hashed & rewound, others are read into memory once &
+	// written from there. hashing reads through a plain reader so the file
+	// can't be closed before it's written
+	var (
+		data []byte
+		hash qfs.File
+	)
+	seeker, seekable := file.(io.Seeker)
+	if seekable {
+		_, err := seeker.Seek(0, io.SeekCurrent)
+		seekable = err == nil
+	}
+	if seekable {
+		hash = qfs.NewMemfileReader(file.FullPath(), struct{ io.Reader }{file})
+	} else {
+		if data, err = ioutil.ReadAll(file); err != nil {
+			return "", nil, err
+		}
+		hash = qfs.NewMemfileBytes(file.FullPath(), data)
+	}
+	path, err = qfs.HashOnly(ctx, handler, hash)
+	if err != nil {
+		return "", nil, err
+	}
+	write = file
+	if seekable {
+		if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+			return "", nil, err
+		}
+	} else {
+		write = qfs.NewMemfileBytes(file.FullPath(), data)
+	}
+	if exists, err := handler.Has(ctx, path); err != nil || exists {
+		return "", write, err
+	}
+
+	_, id, _ := qfs.SplitStorePath(path)
+	kinds := make([]string, 0, len(m.handlers))
+	for k, fs := range m.handlers {
+		if _, ok := fs.(qfs.PinListerFS); ok && k != kind && qfs.Capabilities(fs).ContentAddressed {
+			kinds = append(kinds, k)
+		}
+	}
+	sort.Strings(kinds)
+	for _, k := range kinds {
+		ref := qfs.JoinPath(k, id)
+		// filesystems that can't answer are skipped, the content is written
+		// instead
+		if exists, err := m.Has(ctx, ref); err == nil && exists {
+			if err := m.addCrossRef(ctx, path, ref, m.handlers[k].(qfs.PinListerFS)); err != nil {
+				return "", nil, err
+			}
+			m.events.Publish(qfs.Event{Type: qfs.EventFilePut, FSType: kind, Path: path})
+			return path, nil, nil
+		}
+	}
+	return "", write, nil
+}
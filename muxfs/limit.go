@@ -0,0 +1,120 @@
+package muxfs
+
+import (
+	"context"
+	"sync"
+)
+
+// ConcurrencyStats describes the calls waiting on & running against a
+// filesystem with a concurrency limit
+type ConcurrencyStats struct {
+	// Limit is the maximum number of calls that run at once
+	Limit int
+	// Active is the number of calls running
+	Active int
+	// Queued is the number of calls waiting for a running call to finish
+	Queued int
+}
+
+// limiter is a semaphore that counts the calls holding & waiting on it
+type limiter struct {
+	sem chan struct{}
+
+	lk     sync.Mutex
+	active int
+	queued int
+}
+
+func newLimiter(n int) *limiter {
+	return &limiter{sem: make(chan struct{}, n)}
+}
+
+// acquire waits for a slot, returning ctx's error if ctx is done first
+func (l *limiter) acquire(ctx context.Context) error {
+	select {
+	case l.sem <- struct{}{}:
+		l.lk.Lock()
+		l.active++
+		l.lk.Unlock()
+		return nil
+	default:
+	}
+
+	l.lk.Lock()
+	l.queued++
+	l.lk.Unlock()
+	select {
+	case l.sem <- struct{}{}:
+		l.lk.Lock()
+		l.queued--
+		l.active++
+		l.lk.Unlock()
+		return nil
+	case <-ctx.Done():
+		l.lk.Lock()
+		l.queued--
+		l.lk.Unlock()
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release() {
+	l.lk.Lock()
+	l.active--
+	l.lk.Unlock()
+	<-l.sem
+}
+
+func (l *limiter) stats() ConcurrencyStats {
+	l.lk.Lock()
+	defer l.lk.Unlock()
+	return ConcurrencyStats{Limit: cap(l.sem), Active: l.active, Queued: l.queued}
+}
+
+// SetConcurrencyLimit caps the number of calls the mux makes to the
+// filesystem for fsType at once. Calls beyond the limit queue until a running
+// call returns or their context is done, so a flood of requests waits its turn
+// instead of overwhelming a backend like an embedded IPFS node or a
+// rate-limited HTTP API. Get & similar calls hold their slot until they
+// return, not until the returned file is closed. Limits can also be set with
+// qfs.Config.MaxConcurrency. A limit of zero removes the cap. Changing a limit
+// doesn't affect calls already running or queued
+func (m *Mux) SetConcurrencyLimit(fsType string, n int) {
+	m.limitsLk.Lock()
+	defer m.limitsLk.Unlock()
+	if m.limits == nil {
+		m.limits = map[string]*limiter{}
+	}
+	if n <= 0 {
+		delete(m.limits, fsType)
+		return
+	}
+	m.limits[fsType] = newLimiter(n)
+}
+
+// ConcurrencyStats reports the calls running & queued against the filesystem
+// for fsType. ok is false if the filesystem has no concurrency limit
+func (m *Mux) ConcurrencyStats(fsType string) (stats ConcurrencyStats, ok bool) {
+	m.limitsLk.RLock()
+	l, ok := m.limits[fsType]
+	m.limitsLk.RUnlock()
+	if !ok {
+		return stats, false
+	}
+	return l.stats(), true
+}
+
+// acquire waits for a slot to call the filesystem for kind, returning a func
+// that releases it
+func (m *Mux) acquire(ctx context.Context, kind string) (func(), error) {
+	m.limitsLk.RLock()
+	l, ok := m.limits[kind]
+	m.limitsLk.RUnlock()
+	if !ok {
+		return func() {}, nil
+	}
+	if err := l.acquire(ctx); err != nil {
+		return nil, err
+	}
+	return l.release, nil
+}
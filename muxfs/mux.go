@@ -2,8 +2,11 @@ package muxfs
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/qri-io/qfs"
 	"github.com/qri-io/qfs/httpfs"
@@ -19,22 +22,49 @@ const FilestoreType = "mux"
 type Mux struct {
 	handlers map[string]qfs.Filesystem
 	// sophisticated writes require the Adder interface for writing with hooks.
-	// the first configured filesystem that implements qfs.AddingFS
+	// the first configured filesystem that implements qfs.MerkleDagStore
 	// will be set to this string, and returned by the DefaultWriteFS method
 	defaultWriteDestination string
 
+	// per-filesystem time budgets for resolving paths, keyed by type
+	budgetsLk sync.RWMutex
+	budgets   map[string]time.Duration
+	// per-filesystem concurrency limits, keyed by type
+	limitsLk sync.RWMutex
+	limits   map[string]*limiter
+
+	// events forwards events published by handlers
+	events qfs.EventBus
+
+	// dedupe enables cross-references in place of writing duplicate content.
+	// xrefs maps paths Put returned to the path holding their content & is
+	// saved to xrefsPath. xrefPins holds the referenced paths the mux pinned
+	xrefsLk   sync.RWMutex
+	dedupe    bool
+	xrefsPath string
+	xrefs     map[string]string
+	xrefPins  map[string]bool
+
 	doneCh  chan struct{}
 	doneWg  sync.WaitGroup
 	doneErr error
 }
 
 // compile-time assertion that MapStore satisfies the Filesystem interface
-var _ qfs.Filesystem = (*Mux)(nil)
+var (
+	_ qfs.Filesystem     = (*Mux)(nil)
+	_ qfs.HasManyFS      = (*Mux)(nil)
+	_ qfs.StatPathFS     = (*Mux)(nil)
+	_ qfs.RangeGetter    = (*Mux)(nil)
+	_ qfs.AppendFS       = (*Mux)(nil)
+	_ qfs.EventPublisher = (*Mux)(nil)
+	_ qfs.FetcherFS      = (*Mux)(nil)
+)
 
 // New creates a new Mux Filesystem, if no Option funcs are provided,
 // New uses a default set of Option funcs. Any Option functions passed to this
 // function must check whether their fields are nil or not.
-// The first configured filesystem that implements the qfs.AddingFS interface
+// The first configured filesystem that implements the qfs.MerkleDagStore interface
 // becomes the default filesystem returned by DefaultWriteFS
 func New(ctx context.Context, cfgs []qfs.Config) (*Mux, error) {
 	mux := &Mux{
@@ -44,7 +74,7 @@ func New(ctx context.Context, cfgs []qfs.Config) (*Mux, error) {
 	for _, cfg := range cfgs {
 		constructor, ok := constructors[cfg.Type]
 		if !ok {
-			return nil, fmt.Errorf("unrecognized filesystem type: %q", cfg.Type)
+			return nil, fmt.Errorf("%w: unrecognized filesystem type: %q", qfs.ErrUnsupported, cfg.Type)
 		}
 		fs, err := constructor(ctx, cfg.Config)
 		if err != nil {
@@ -54,6 +84,7 @@ func New(ctx context.Context, cfgs []qfs.Config) (*Mux, error) {
 		if err := mux.SetFilesystem(fs); err != nil {
 			return nil, err
 		}
+		mux.SetConcurrencyLimit(fs.Type(), cfg.MaxConcurrency)
 	}
 
 	go func() {
@@ -82,8 +113,11 @@ func (m *Mux) SetFilesystem(fs qfs.Filesystem) error {
 			m.doneWg.Done()
 		}(releaser)
 	}
+	if pub, ok := fs.(qfs.EventPublisher); ok {
+		pub.Subscribe(m.events.Publish)
+	}
 	if m.defaultWriteDestination == "" {
-		if _, ok := fs.(qfs.MerkleDagStore); ok {
+		if qfs.Capabilities(fs).SupportsAdder {
 			m.defaultWriteDestination = fs.Type()
 		}
 	}
@@ -92,6 +126,31 @@ func (m *Mux) SetFilesystem(fs qfs.Filesystem) error {
 	return nil
 }
 
+// Subscribe calls fn with the events published by every filesystem the mux
+// holds that implements qfs.EventPublisher, giving a single change feed
+// across filesystems. Event.FSType identifies the source filesystem
+func (m *Mux) Subscribe(fn qfs.EventHandler) func() {
+	return m.events.Subscribe(fn)
+}
+
+// SetBudget caps the time the filesystem for fsType may spend answering a Has
+// or Get call, independent of any deadline on the request context. Budgets
+// keep one slow backend (eg: an IPFS DHT lookup) from consuming a request's
+// entire deadline before callers can try elsewhere. A budget of zero removes
+// the cap
+func (m *Mux) SetBudget(fsType string, budget time.Duration) {
+	m.budgetsLk.Lock()
+	defer m.budgetsLk.Unlock()
+	if m.budgets == nil {
+		m.budgets = map[string]time.Duration{}
+	}
+	if budget == 0 {
+		delete(m.budgets, fsType)
+		return
+	}
+	m.budgets[fsType] = budget
+}
+
 // Filesystem returns the filesystem for a given fs type string, nil if no
 // filesystem for fsType exists
 func (m *Mux) Filesystem(fsType string) qfs.Filesystem {
@@ -130,7 +189,7 @@ func (m *Mux) Done() <-chan struct{} {
 }
 
 func noMuxerError(kind, path string) error {
-	return fmt.Errorf("cannot resolve paths of kind '%s'. path: %s", kind, path)
+	return fmt.Errorf("%w: cannot resolve paths of kind '%s'. path: %s", qfs.ErrUnsupported, kind, path)
 }
 
 // Has returns whether the store has a File with the given path
@@ -138,14 +197,94 @@ func (m *Mux) Has(ctx context.Context, path string) (bool, error) {
 	if path == "" {
 		return false, nil
 	}
+	path = m.resolve(path)
 
 	kind := qfs.PathKind(path)
 	handler, ok := m.handlers[kind]
 	if !ok {
 		return false, noMuxerError(kind, path)
 	}
+	release, err := m.acquire(ctx, kind)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	var exists bool
+	err = m.budgeted(ctx, kind, func(ctx context.Context) (err error) {
+		exists, err = handler.Has(ctx, path)
+		return err
+	})
+	return exists, err
+}
+
+// StatPath describes path using the filesystem that handles its kind
+func (m *Mux) StatPath(ctx context.Context, path string) (qfs.PathInfo, error) {
+	if path == "" {
+		return qfs.PathInfo{Size: -1}, nil
+	}
+	path = m.resolve(path)
 
-	return handler.Has(ctx, path)
+	kind := qfs.PathKind(path)
+	handler, ok := m.handlers[kind]
+	if !ok {
+		return qfs.PathInfo{Size: -1}, noMuxerError(kind, path)
+	}
+	release, err := m.acquire(ctx, kind)
+	if err != nil {
+		return qfs.PathInfo{Size: -1}, err
+	}
+	defer release()
+
+	info := qfs.PathInfo{Size: -1}
+	err = m.budgeted(ctx, kind, func(ctx context.Context) (err error) {
+		info, err = qfs.StatPath(ctx, handler, path)
+		return err
+	})
+	return info, err
+}
+
+// HasMany checks for the existence of many paths, grouping paths by kind so
+// each filesystem is asked once
+func (m *Mux) HasMany(ctx context.Context, paths []string) (map[string]bool, error) {
+	byKind := map[string][]string{}
+	res := make(map[string]bool, len(paths))
+	// resolved maps cross-referenced paths back to the paths asked for
+	resolved := map[string][]string{}
+	for _, path := range paths {
+		if path == "" {
+			res[path] = false
+			continue
+		}
+		if ref := m.resolve(path); ref != path {
+			resolved[ref] = append(resolved[ref], path)
+			path = ref
+		}
+		kind := qfs.PathKind(path)
+		if _, ok := m.handlers[kind]; !ok {
+			return nil, noMuxerError(kind, path)
+		}
+		byKind[kind] = append(byKind[kind], path)
+	}
+
+	for kind, kindPaths := range byKind {
+		release, err := m.acquire(ctx, kind)
+		if err != nil {
+			return nil, err
+		}
+		got, err := qfs.HasMany(ctx, m.handlers[kind], kindPaths)
+		release()
+		if err != nil {
+			return nil, err
+		}
+		for path, exists := range got {
+			res[path] = exists
+			for _, orig := range resolved[path] {
+				res[orig] = exists
+			}
+		}
+	}
+	return res, nil
 }
 
 // Get a path
@@ -153,6 +292,7 @@ func (m *Mux) Get(ctx context.Context, path string) (qfs.File, error) {
 	if path == "" {
 		return nil, qfs.ErrNotFound
 	}
+	path = m.resolve(path)
 
 	kind := qfs.PathKind(path)
 	handler, ok := m.handlers[kind]
@@ -160,7 +300,120 @@ func (m *Mux) Get(ctx context.Context, path string) (qfs.File, error) {
 		return nil, noMuxerError(kind, path)
 	}
 
-	return handler.Get(ctx, path)
+	return m.getBudgeted(ctx, kind, func(ctx context.Context) (qfs.File, error) {
+		return handler.Get(ctx, path)
+	})
+}
+
+// Fetch gets path using the filesystem that handles its kind, passing hints
+// on to filesystems that implement qfs.FetcherFS
+func (m *Mux) Fetch(ctx context.Context, path string, hints ...qfs.FetchHint) (qfs.File, error) {
+	if path == "" {
+		return nil, qfs.ErrNotFound
+	}
+	path = m.resolve(path)
+
+	kind := qfs.PathKind(path)
+	handler, ok := m.handlers[kind]
+	if !ok {
+		return nil, noMuxerError(kind, path)
+	}
+
+	return m.getBudgeted(ctx, kind, func(ctx context.Context) (qfs.File, error) {
+		return qfs.Fetch(ctx, handler, path, hints...)
+	})
+}
+
+// GetRange fetches part of the file at path using the filesystem that handles
+// its kind, passing the range on to filesystems that implement
+// qfs.RangeGetter. A negative length reads to the end of the file
+func (m *Mux) GetRange(ctx context.Context, path string, offset, length int64) (qfs.File, error) {
+	if path == "" {
+		return nil, qfs.ErrNotFound
+	}
+	path = m.resolve(path)
+
+	kind := qfs.PathKind(path)
+	handler, ok := m.handlers[kind]
+	if !ok {
+		return nil, noMuxerError(kind, path)
+	}
+
+	return m.getBudgeted(ctx, kind, func(ctx context.Context) (qfs.File, error) {
+		return qfs.GetRange(ctx, handler, path, offset, length)
+	})
+}
+
+// getBudgeted calls get once the concurrency limit for kind allows, giving up
+// when the time budget for kind expires
+func (m *Mux) getBudgeted(ctx context.Context, kind string, get func(context.Context) (qfs.File, error)) (qfs.File, error) {
+	release, err := m.acquire(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	budget, ok := m.budget(kind)
+	if !ok {
+		defer release()
+		return get(ctx)
+	}
+
+	// reading a returned file may depend on the context passed to Get, so the
+	// budget can't be applied to ctx itself. Instead stop waiting on the handler
+	// when the budget expires, closing any file that resolves afterward
+	type result struct {
+		f   qfs.File
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		// the handler is busy until get returns, even if the budget expires
+		defer release()
+		f, err := get(ctx)
+		resCh <- result{f, err}
+	}()
+
+	timer := time.NewTimer(budget)
+	defer timer.Stop()
+	select {
+	case res := <-resCh:
+		return res.f, res.err
+	case <-timer.C:
+		go func() {
+			if res := <-resCh; res.err == nil && !res.f.IsDirectory() {
+				res.f.Close()
+			}
+		}()
+		return nil, budgetError(kind, budget, context.DeadlineExceeded)
+	}
+}
+
+// budget returns the time budget for kind, if one is set
+func (m *Mux) budget(kind string) (time.Duration, bool) {
+	m.budgetsLk.RLock()
+	defer m.budgetsLk.RUnlock()
+	budget, ok := m.budgets[kind]
+	return budget, ok
+}
+
+// budgeted calls fn with ctx bounded by the time budget for kind. Errors are
+// wrapped as budget errors only if the budget expired, not the caller's own
+// deadline
+func (m *Mux) budgeted(ctx context.Context, kind string, fn func(context.Context) error) error {
+	budget, ok := m.budget(kind)
+	if !ok {
+		return fn(ctx)
+	}
+	bctx, cancel := context.WithTimeout(ctx, budget)
+	defer cancel()
+	err := fn(bctx)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil && bctx.Err() != nil {
+		return budgetError(kind, budget, err)
+	}
+	return err
+}
+
+func budgetError(kind string, budget time.Duration, err error) error {
+	return fmt.Errorf("%s filesystem exceeded time budget of %s: %w", kind, budget, err)
 }
 
 // Put places a file or directory on the filesystem, returning the root path.
@@ -172,17 +425,63 @@ func (m *Mux) Put(ctx context.Context, file qfs.File) (resPath string, err error
 	if !ok {
 		return "", noMuxerError(kind, path)
 	}
+	release, err := m.acquire(ctx, kind)
+	if err != nil {
+		return "", err
+	}
+	defer release()
 
+	path, file, err = m.dedupePut(ctx, kind, file)
+	if err != nil || file == nil {
+		return path, err
+	}
 	return handler.Put(ctx, file)
 }
 
-// Delete removes a file or directory from the filesystem
+// Append writes the contents of r to the end of the file at path using the
+// filesystem that handles its kind. Appending to immutable filesystems returns
+// an error that wraps qfs.ErrUnsupported
+func (m *Mux) Append(ctx context.Context, path string, r io.Reader) error {
+	kind := qfs.PathKind(path)
+	handler, ok := m.handlers[kind]
+	if !ok {
+		return noMuxerError(kind, path)
+	}
+	release, err := m.acquire(ctx, kind)
+	if err != nil {
+		return err
+	}
+	defer release()
+	return qfs.Append(ctx, handler, path, r)
+}
+
+// Delete removes a file or directory from the filesystem. Deleting a
+// cross-referenced path removes only the cross-reference, leaving the content
+// in the filesystem that holds it. Deleting content that cross-references
+// resolve to returns an error that wraps qfs.ErrPreconditionFailed
 func (m *Mux) Delete(ctx context.Context, path string) (err error) {
+	if path == "" {
+		return fmt.Errorf("%w: empty path", qfs.ErrInvalidPath)
+	}
+	if removed, err := m.removeCrossRef(ctx, path); err != nil {
+		return err
+	} else if removed {
+		m.events.Publish(qfs.Event{Type: qfs.EventFileDeleted, FSType: qfs.PathKind(path), Path: path})
+		return nil
+	}
+	if err := m.checkUnreferenced(path); err != nil {
+		return err
+	}
 	kind := qfs.PathKind(path)
 	handler, ok := m.handlers[kind]
 	if !ok {
 		return noMuxerError(kind, path)
 	}
+	release, err := m.acquire(ctx, kind)
+	if err != nil {
+		return err
+	}
+	defer release()
 
 	return handler.Delete(ctx, path)
 }
@@ -2,12 +2,19 @@ package muxfs
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/localfs"
+	"github.com/qri-io/qfs/qfstest"
 	"github.com/qri-io/qfs/qipfs"
 )
 
@@ -147,3 +154,458 @@ func TestRepoLockPerContext(t *testing.T) {
 	}
 
 }
+
+func TestBudgets(t *testing.T) {
+	ctx := context.Background()
+	mfs := &Mux{}
+	if err := mfs.SetFilesystem(slowFS{delay: time.Millisecond * 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	mfs.SetBudget("local", time.Millisecond*10)
+	if _, err := mfs.Has(ctx, "/a/path"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Has to exceed budget. got: %v", err)
+	}
+	if _, err := mfs.Get(ctx, "/a/path"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected Get to exceed budget. got: %v", err)
+	}
+
+	mfs.SetBudget("local", 0)
+	if _, err := mfs.Has(ctx, "/a/path"); err != nil {
+		t.Errorf("expected removing the budget to succeed. got: %v", err)
+	}
+	if _, err := mfs.Get(ctx, "/a/path"); err != nil {
+		t.Errorf("expected removing the budget to succeed. got: %v", err)
+	}
+}
+
+func TestBudgetCallerDeadline(t *testing.T) {
+	mfs := &Mux{}
+	if err := mfs.SetFilesystem(slowFS{delay: time.Millisecond * 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	// budgets can change while calls are running
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			mfs.SetBudget("local", time.Second)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	_, err := mfs.Has(ctx, "/a/path")
+	<-done
+	if !errors.Is(err, context.DeadlineExceeded) || strings.Contains(err.Error(), "budget") {
+		t.Errorf("expected the caller's own deadline to be returned unwrapped. got: %v", err)
+	}
+
+	mfs.SetBudget("local", 0)
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := mfs.StatPath(ctx, "/a/path"); err != nil && strings.Contains(err.Error(), "budget") {
+		t.Errorf("expected no budget error without a budget. got: %v", err)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	ctx := context.Background()
+	mfs := &Mux{}
+	blocked := blockingFS{started: make(chan struct{}, 1), unblock: make(chan struct{})}
+	if err := mfs.SetFilesystem(blocked); err != nil {
+		t.Fatal(err)
+	}
+	mfs.SetConcurrencyLimit("local", 1)
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := mfs.Get(ctx, "/a/path")
+			errs <- err
+		}()
+	}
+	<-blocked.started
+	for {
+		stats, ok := mfs.ConcurrencyStats("local")
+		if !ok {
+			t.Fatal("expected stats for a limited filesystem")
+		}
+		if stats.Queued == 1 {
+			if stats.Active != 1 || stats.Limit != 1 {
+				t.Errorf("unexpected stats: %#v", stats)
+			}
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, time.Millisecond*10)
+	defer cancel()
+	if _, err := mfs.Has(timeout, "/a/path"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a queued call to give up when its context is done. got: %v", err)
+	}
+
+	close(blocked.unblock)
+	for i := 0; i < 2; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+	if stats, _ := mfs.ConcurrencyStats("local"); stats.Active != 0 || stats.Queued != 0 {
+		t.Errorf("expected finished calls to release their slots. got: %#v", stats)
+	}
+
+	mfs.SetConcurrencyLimit("local", 0)
+	if _, ok := mfs.ConcurrencyStats("local"); ok {
+		t.Error("expected removing the limit to remove stats")
+	}
+}
+
+func TestConcurrencyLimitConcurrentUpdates(t *testing.T) {
+	ctx := context.Background()
+	mfs := &Mux{}
+	if err := mfs.SetFilesystem(qfs.NewMemFS()); err != nil {
+		t.Fatal(err)
+	}
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(n int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				mfs.SetConcurrencyLimit(qfs.MemFilestoreType, (n+j)%3)
+				mfs.ConcurrencyStats(qfs.MemFilestoreType)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, err := mfs.Has(ctx, "/mem/QmFoo"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGetRange(t *testing.T) {
+	ctx := context.Background()
+	mfs := &Mux{}
+	if err := mfs.SetFilesystem(rangeFS{}); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := mfs.GetRange(ctx, "/a/path", 2, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(f); s != "2:3" {
+		t.Errorf("expected range to be passed to the handler. got: %q", s)
+	}
+}
+
+func TestAppendImmutable(t *testing.T) {
+	ctx := context.Background()
+	mfs := &Mux{}
+	if err := mfs.SetFilesystem(qfs.NewMemFS()); err != nil {
+		t.Fatal(err)
+	}
+	if err := mfs.Append(ctx, "/mem/QmFoo", strings.NewReader("a")); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected appending to an immutable store to return ErrUnsupported. got: %v", err)
+	}
+}
+
+func TestPathSemantics(t *testing.T) {
+	ctx := context.Background()
+	mem := qfs.NewMemFS()
+	mfs := &Mux{}
+	if err := mfs.SetFilesystem(mem); err != nil {
+		t.Fatal(err)
+	}
+	root, err := mem.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qfstest.AssertPathSemantics(t, mfs, root+"/b.txt")
+}
+
+// rangeFS is a "local" filesystem that responds to ranged reads with the
+// requested range
+type rangeFS struct {
+	slowFS
+}
+
+func (rangeFS) GetRange(ctx context.Context, path string, offset, length int64) (qfs.File, error) {
+	return qfs.NewMemfileBytes(path, []byte(fmt.Sprintf("%d:%d", offset, length))), nil
+}
+
+// slowFS is a "local" filesystem that takes delay to respond to reads
+type slowFS struct {
+	delay time.Duration
+}
+
+func (slowFS) Type() string { return "local" }
+
+func (fs slowFS) Has(ctx context.Context, path string) (bool, error) {
+	select {
+	case <-time.After(fs.delay):
+		return true, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (fs slowFS) Get(ctx context.Context, path string) (qfs.File, error) {
+	time.Sleep(fs.delay)
+	return qfs.NewMemfileBytes(path, []byte("slow")), nil
+}
+
+func (slowFS) Put(ctx context.Context, file qfs.File) (string, error) {
+	return "", qfs.ErrReadOnly
+}
+
+func (slowFS) Delete(ctx context.Context, path string) error {
+	return qfs.ErrReadOnly
+}
+
+func TestEvents(t *testing.T) {
+	ctx := context.Background()
+	mem := qfs.NewMemFS()
+	mfs := &Mux{}
+	if err := mfs.SetFilesystem(mem); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []qfs.Event
+	mfs.Subscribe(func(e qfs.Event) {
+		got = append(got, e)
+	})
+
+	path, err := mfs.Put(ctx, qfs.NewMemfileBytes("/mem/a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mem.Put(ctx, qfs.NewMemfileBytes("b.txt", []byte("world"))); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected events from writes through the mux and to the handler directly. got: %d", len(got))
+	}
+	if got[0].Type != qfs.EventFilePut || got[0].FSType != qfs.MemFilestoreType || got[0].Path != path {
+		t.Errorf("event mismatch. got: %v", got[0])
+	}
+}
+
+// blockingFS blocks Get & Has until unblock is closed, signalling started on
+// the first call
+type blockingFS struct {
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (blockingFS) Type() string { return "local" }
+
+func (fs blockingFS) wait(ctx context.Context) error {
+	select {
+	case fs.started <- struct{}{}:
+	default:
+	}
+	select {
+	case <-fs.unblock:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (fs blockingFS) Has(ctx context.Context, path string) (bool, error) {
+	return true, fs.wait(ctx)
+}
+
+func (fs blockingFS) Get(ctx context.Context, path string) (qfs.File, error) {
+	if err := fs.wait(ctx); err != nil {
+		return nil, err
+	}
+	return qfs.NewMemfileBytes(path, []byte("unblocked")), nil
+}
+
+func (blockingFS) Put(ctx context.Context, file qfs.File) (string, error) {
+	return "", qfs.ErrReadOnly
+}
+
+func (blockingFS) Delete(ctx context.Context, path string) error {
+	return qfs.ErrReadOnly
+}
+
+func TestDeduplicate(t *testing.T) {
+	ctx := context.Background()
+	mem := qfs.NewMemFS()
+	ipfs := ipfsMemFS{mem: qfs.NewMemFS(), pins: map[string]bool{}}
+	local, err := localfs.NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux := &Mux{}
+	for _, fs := range []qfs.Filesystem{mem, ipfs, local} {
+		if err := mux.SetFilesystem(fs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir, err := ioutil.TempDir("", "qfs_dedupe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	refsPath := filepath.Join(dir, "refs.json")
+
+	if err := mux.SetDeduplicate(ctx, "/mem/refs.json"); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected saving cross-references to a content-addressed filesystem to be unsupported. got: %v", err)
+	}
+	if err := mux.SetDeduplicate(ctx, refsPath); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []qfs.Event
+	mux.Subscribe(func(e qfs.Event) { events = append(events, e) })
+
+	ref, err := ipfs.Put(ctx, qfs.NewMemfileBytes("/ipfs/data.txt", []byte("shared")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := mux.Put(ctx, qfs.NewMemfileBytes("/mem/data.txt", []byte("shared")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := mem.Has(ctx, key); exists {
+		t.Errorf("expected duplicate content not to be written")
+	}
+	if got := mux.CrossRefs()[key]; got != ref {
+		t.Errorf("cross-reference mismatch. want: %q got: %q", ref, got)
+	}
+	if !ipfs.pins[ref] {
+		t.Errorf("expected referenced content to be pinned")
+	}
+	putEvent := false
+	for _, e := range events {
+		putEvent = putEvent || (e.Type == qfs.EventFilePut && e.Path == key)
+	}
+	if !putEvent {
+		t.Errorf("expected a put event for the cross-referenced path. got: %v", events)
+	}
+	if exists, err := mux.Has(ctx, key); err != nil || !exists {
+		t.Errorf("expected cross-referenced path to exist. got: %t, %v", exists, err)
+	}
+	if res, err := mux.HasMany(ctx, []string{key}); err != nil || !res[key] {
+		t.Errorf("expected HasMany to resolve cross-reference. got: %v, %v", res, err)
+	}
+	f, err := mux.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(f); s != "shared" {
+		t.Errorf("contents mismatch. got: %q", s)
+	}
+	if err := mux.Delete(ctx, ref); !errors.Is(err, qfs.ErrPreconditionFailed) {
+		t.Errorf("expected deleting referenced content to fail. got: %v", err)
+	}
+
+	// cross-references are saved, so a new mux resolves them
+	reopened := &Mux{}
+	for _, fs := range []qfs.Filesystem{mem, ipfs, local} {
+		if err := reopened.SetFilesystem(fs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := reopened.SetDeduplicate(ctx, refsPath); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := reopened.Has(ctx, key); err != nil || !exists {
+		t.Errorf("expected saved cross-reference to resolve. got: %t, %v", exists, err)
+	}
+
+	unique, err := reopened.Put(ctx, qfs.NewMemfileBytes("/mem/unique.txt", []byte("unique")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := mem.Has(ctx, unique); !exists {
+		t.Errorf("expected unique content to be written")
+	}
+
+	if err := reopened.Delete(ctx, key); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := reopened.Has(ctx, key); exists {
+		t.Errorf("expected delete to remove the cross-reference")
+	}
+	if exists, _ := ipfs.Has(ctx, ref); !exists {
+		t.Errorf("expected delete to leave referenced content in place")
+	}
+	if ipfs.pins[ref] {
+		t.Errorf("expected delete to unpin content nothing references")
+	}
+
+	if err := reopened.SetDeduplicate(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if key, err = reopened.Put(ctx, qfs.NewMemfileBytes("/mem/data.txt", []byte("shared"))); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := mem.Has(ctx, key); !exists {
+		t.Errorf("expected content to be written with deduplication off")
+	}
+}
+
+// ipfsMemFS is a content-addressed filesystem of type "ipfs" that hashes
+// content the same way as MemFS
+type ipfsMemFS struct {
+	mem  *qfs.MemFS
+	pins map[string]bool
+}
+
+func (ipfsMemFS) Type() string { return "ipfs" }
+
+func (ipfsMemFS) IsContentAddressedFilesystem() {}
+
+func (fs ipfsMemFS) Has(ctx context.Context, path string) (bool, error) {
+	return fs.mem.Has(ctx, ipfsToMemPath(path))
+}
+
+func (fs ipfsMemFS) Get(ctx context.Context, path string) (qfs.File, error) {
+	return fs.mem.Get(ctx, ipfsToMemPath(path))
+}
+
+func (fs ipfsMemFS) Put(ctx context.Context, file qfs.File) (string, error) {
+	key, err := fs.mem.Put(ctx, file)
+	return strings.Replace(key, "/mem/", "/ipfs/", 1), err
+}
+
+func (fs ipfsMemFS) Delete(ctx context.Context, path string) error {
+	return fs.mem.Delete(ctx, ipfsToMemPath(path))
+}
+
+func (fs ipfsMemFS) Pin(ctx context.Context, key string, recursive bool) error {
+	fs.pins[key] = true
+	return nil
+}
+
+func (fs ipfsMemFS) Unpin(ctx context.Context, key string, recursive bool) error {
+	delete(fs.pins, key)
+	return nil
+}
+
+func (fs ipfsMemFS) Pins(ctx context.Context) ([]qfs.Pin, error) {
+	pins := make([]qfs.Pin, 0, len(fs.pins))
+	for key := range fs.pins {
+		pins = append(pins, qfs.Pin{Key: key, Recursive: true})
+	}
+	return pins, nil
+}
+
+func ipfsToMemPath(path string) string {
+	return strings.Replace(path, "/ipfs/", "/mem/", 1)
+}
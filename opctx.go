@@ -0,0 +1,53 @@
+package qfs
+
+import "context"
+
+// opCtxKey is the type of context keys for per-operation metadata
+type opCtxKey int
+
+const (
+	requestIDKey opCtxKey = iota
+	actorKey
+	preconditionKey
+)
+
+// WithRequestID returns a copy of ctx carrying a request ID. Filesystems &
+// middleware include the ID when logging or recording the operations they
+// perform with ctx, so hosts can attribute storage operations to requests
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID set on ctx with WithRequestID, or the
+// empty string if no ID is set
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithActor returns a copy of ctx carrying an identifier for the user or
+// service performing an operation
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// Actor returns the actor set on ctx with WithActor, or the empty string if no
+// actor is set
+func Actor(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey).(string)
+	return actor
+}
+
+// LogFields appends any operation metadata set on ctx to keysAndValues,
+// for use with structured log methods:
+//
+//	log.Debugw("getting", qfs.LogFields(ctx, "path", path)...)
+func LogFields(ctx context.Context, keysAndValues ...interface{}) []interface{} {
+	if id := RequestID(ctx); id != "" {
+		keysAndValues = append(keysAndValues, "requestID", id)
+	}
+	if actor := Actor(ctx); actor != "" {
+		keysAndValues = append(keysAndValues, "actor", actor)
+	}
+	return keysAndValues
+}
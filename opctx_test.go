@@ -0,0 +1,30 @@
+package qfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOperationContext(t *testing.T) {
+	ctx := context.Background()
+	if RequestID(ctx) != "" || Actor(ctx) != "" {
+		t.Error("expected empty context to have no request ID or actor")
+	}
+	if diff := cmp.Diff([]interface{}{"path", "/a"}, LogFields(ctx, "path", "/a")); diff != "" {
+		t.Errorf("log fields mismatch. (-want +got):\n%s", diff)
+	}
+
+	ctx = WithActor(WithRequestID(ctx, "req_1"), "user_1")
+	if RequestID(ctx) != "req_1" {
+		t.Errorf("request ID mismatch. want: %q got: %q", "req_1", RequestID(ctx))
+	}
+	if Actor(ctx) != "user_1" {
+		t.Errorf("actor mismatch. want: %q got: %q", "user_1", Actor(ctx))
+	}
+	expect := []interface{}{"path", "/a", "requestID", "req_1", "actor", "user_1"}
+	if diff := cmp.Diff(expect, LogFields(ctx, "path", "/a")); diff != "" {
+		t.Errorf("log fields mismatch. (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,81 @@
+package qfs
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// NamePrefix returns the prefix of paths to content stored on fs, eg: "/ipfs"
+// or "/mem". Prefixes are only meaningful for filesystems whose paths are
+// namespaced by filesystem type, like content-addressed filesystems
+func NamePrefix(fs Filesystem) string {
+	return "/" + fs.Type()
+}
+
+// JoinPath builds a store path of the form /[fsType]/[hash]/[subpath]. The
+// subpath elements are joined & cleaned with path.Join
+func JoinPath(fsType, hash string, subpath ...string) string {
+	p := "/" + fsType + "/" + hash
+	if sub := strings.TrimPrefix(path.Join(subpath...), "/"); sub != "" && sub != "." {
+		p += "/" + sub
+	}
+	return p
+}
+
+// SplitStorePath splits a path of the form /[prefix]/[hash]/[subpath] into
+// its parts. Paths that don't start with a slash have no prefix, so a bare
+// hash or a hash followed by a subpath splits into hash & subpath. prefix
+// includes its leading slash. subpath has no leading, trailing or repeated
+// slashes
+func SplitStorePath(p string) (prefix, hash, subpath string) {
+	if strings.HasPrefix(p, "/") {
+		p = p[1:]
+		i := strings.IndexByte(p, '/')
+		if i < 0 {
+			return "/" + p, "", ""
+		}
+		prefix, p = "/"+p[:i], strings.TrimLeft(p[i+1:], "/")
+	}
+	hash = p
+	if i := strings.IndexByte(p, '/'); i >= 0 {
+		hash, subpath = p[:i], cleanSubpath(p[i+1:])
+	}
+	return prefix, hash, subpath
+}
+
+// cleanSubpath drops empty elements from a slash-separated path
+func cleanSubpath(p string) string {
+	parts := strings.Split(p, "/")
+	kept := parts[:0]
+	for _, part := range parts {
+		if part != "" {
+			kept = append(kept, part)
+		}
+	}
+	return strings.Join(kept, "/")
+}
+
+// IsRootPath reports whether p names the root of a content-addressed
+// filesystem of type fsType rather than content within it: the empty path,
+// "/", or the bare prefix with or without a trailing slash, eg: "/mem/"
+func IsRootPath(fsType, p string) bool {
+	p = strings.Trim(p, "/")
+	return p == "" || p == fsType
+}
+
+// ValidatePath returns an error wrapping ErrInvalidPath if p isn't a path to
+// content on a content-addressed filesystem of type fsType, which is any path
+// of the form /[fsType]/[CID]/[subpath]
+func ValidatePath(fsType, p string) error {
+	prefix, hash, _ := SplitStorePath(p)
+	if prefix != "/"+fsType {
+		return fmt.Errorf("%w: %q doesn't start with /%s/", ErrInvalidPath, p, fsType)
+	}
+	if _, err := cid.Decode(hash); err != nil {
+		return fmt.Errorf("%w: %q: %s", ErrInvalidPath, p, err)
+	}
+	return nil
+}
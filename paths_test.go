@@ -0,0 +1,86 @@
+package qfs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNamePrefix(t *testing.T) {
+	if got := NamePrefix(NewMemFS()); got != "/mem" {
+		t.Errorf("prefix mismatch. want: %q got: %q", "/mem", got)
+	}
+}
+
+func TestJoinPath(t *testing.T) {
+	cases := []struct {
+		fsType, hash string
+		subpath      []string
+		expect       string
+	}{
+		{"ipfs", "QmFoo", nil, "/ipfs/QmFoo"},
+		{"ipfs", "QmFoo", []string{"a", "b.json"}, "/ipfs/QmFoo/a/b.json"},
+		{"mem", "QmFoo", []string{"/a/"}, "/mem/QmFoo/a"},
+		{"mem", "QmFoo", []string{""}, "/mem/QmFoo"},
+	}
+	for _, c := range cases {
+		if got := JoinPath(c.fsType, c.hash, c.subpath...); got != c.expect {
+			t.Errorf("JoinPath(%q, %q, %v) mismatch. want: %q got: %q", c.fsType, c.hash, c.subpath, c.expect, got)
+		}
+	}
+}
+
+func TestSplitStorePath(t *testing.T) {
+	cases := []struct {
+		in                    string
+		prefix, hash, subpath string
+	}{
+		{"/ipfs/QmFoo", "/ipfs", "QmFoo", ""},
+		{"/ipfs/QmFoo/", "/ipfs", "QmFoo", ""},
+		{"/mem/QmFoo/a/b.json", "/mem", "QmFoo", "a/b.json"},
+		{"/mem//QmFoo//a///b.json/", "/mem", "QmFoo", "a/b.json"},
+		{"QmFoo", "", "QmFoo", ""},
+		{"QmFoo/a", "", "QmFoo", "a"},
+		{"/ipfs", "/ipfs", "", ""},
+		{"", "", "", ""},
+	}
+	for _, c := range cases {
+		prefix, hash, subpath := SplitStorePath(c.in)
+		if prefix != c.prefix || hash != c.hash || subpath != c.subpath {
+			t.Errorf("SplitStorePath(%q) mismatch. want: %q %q %q got: %q %q %q", c.in, c.prefix, c.hash, c.subpath, prefix, hash, subpath)
+		}
+	}
+}
+
+func TestIsRootPath(t *testing.T) {
+	for _, p := range []string{"", "/", "//", "/mem", "/mem/"} {
+		if !IsRootPath("mem", p) {
+			t.Errorf("expected %q to be a root path", p)
+		}
+	}
+	for _, p := range []string{"/mem/QmFoo", "/ipfs", "/memory/"} {
+		if IsRootPath("mem", p) {
+			t.Errorf("expected %q not to be a root path", p)
+		}
+	}
+}
+
+func TestValidatePath(t *testing.T) {
+	valid := "/ipfs/QmRRPhMvnZpHtGVnAXdNyrsyPKRb8RZjCb9USm1VQzKCnD"
+	if err := ValidatePath("ipfs", valid); err != nil {
+		t.Errorf("expected %q to be valid. got: %v", valid, err)
+	}
+	if err := ValidatePath("ipfs", valid+"/body.json"); err != nil {
+		t.Errorf("expected paths within content to be valid. got: %v", err)
+	}
+
+	for _, p := range []string{
+		"/mem/QmRRPhMvnZpHtGVnAXdNyrsyPKRb8RZjCb9USm1VQzKCnD",
+		"/ipfs/not_a_hash",
+		"QmRRPhMvnZpHtGVnAXdNyrsyPKRb8RZjCb9USm1VQzKCnD",
+		"",
+	} {
+		if err := ValidatePath("ipfs", p); !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("expected %q to be invalid. got: %v", p, err)
+		}
+	}
+}
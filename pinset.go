@@ -0,0 +1,47 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+)
+
+// Pin describes a single pinned key
+type Pin struct {
+	Key       string `json:"key" mapstructure:"key"`
+	Recursive bool   `json:"recursive" mapstructure:"recursive"`
+}
+
+// PinListerFS is an opt-in interface for pinning filesystems that can list
+// their pins. Only directly & recursively pinned keys should be listed,
+// content pinned indirectly by a recursive pin is implied
+type PinListerFS interface {
+	PinningFS
+	Pins(ctx context.Context) ([]Pin, error)
+}
+
+// ExportPinset lists every pin in fs, returning the pinset as a JSON file at
+// path. Writing the file somewhere safe & passing it to ImportPinset on a
+// fresh store restores exactly what was pinned
+func ExportPinset(ctx context.Context, fs PinListerFS, path string) (File, error) {
+	pins, err := fs.Pins(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewJSONFile(path, pins), nil
+}
+
+// ImportPinset reads a pinset file created by ExportPinset, pinning each key
+// in fs. Pinning a key requires the content it refers to, either locally or
+// from the network. ImportPinset stops at the first key that fails to pin
+func ImportPinset(ctx context.Context, fs PinningFS, f File) error {
+	pins := []Pin{}
+	if err := DecodeJSON(f, &pins); err != nil {
+		return err
+	}
+	for _, p := range pins {
+		if err := fs.Pin(ctx, p.Key, p.Recursive); err != nil {
+			return fmt.Errorf("pinning %q: %w", p.Key, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package qfs
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type pinsetTestFS struct {
+	pins map[string]bool
+}
+
+func (fs *pinsetTestFS) Pin(ctx context.Context, key string, recursive bool) error {
+	fs.pins[key] = recursive
+	return nil
+}
+
+func (fs *pinsetTestFS) Unpin(ctx context.Context, key string, recursive bool) error {
+	delete(fs.pins, key)
+	return nil
+}
+
+func (fs *pinsetTestFS) Pins(ctx context.Context) ([]Pin, error) {
+	pins := make([]Pin, 0, len(fs.pins))
+	for key, recursive := range fs.pins {
+		pins = append(pins, Pin{Key: key, Recursive: recursive})
+	}
+	sort.Slice(pins, func(i, j int) bool { return pins[i].Key < pins[j].Key })
+	return pins, nil
+}
+
+func TestExportImportPinset(t *testing.T) {
+	ctx := context.Background()
+	src := &pinsetTestFS{pins: map[string]bool{
+		"/ipfs/QmA": true,
+		"/ipfs/QmB": false,
+	}}
+
+	f, err := ExportPinset(ctx, src, "pinset.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.MediaType() != JSONMediaType {
+		t.Errorf("media type mismatch. want: %q got: %q", JSONMediaType, f.MediaType())
+	}
+
+	dst := &pinsetTestFS{pins: map[string]bool{}}
+	if err := ImportPinset(ctx, dst, f); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(src.pins, dst.pins); diff != "" {
+		t.Errorf("pinset mismatch. (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,31 @@
+package qfs
+
+import "context"
+
+// Precondition guards a Put to a mutable filesystem, so a retried write can't
+// clobber an update made since the write was first attempted. Filesystems
+// that support preconditions read them from the context passed to Put, and
+// return an error wrapping ErrPreconditionFailed if they don't hold.
+// Content-addressed filesystems ignore preconditions, their writes never
+// replace existing content
+type Precondition struct {
+	// IfMatch requires the path being written to hold a file with this ETag,
+	// as reported by StatPath. Empty skips the check
+	IfMatch string
+	// IdempotencyToken identifies a logical write. A Put repeating the token
+	// of a completed Put returns the first Put's result without writing
+	// again. Empty skips the check
+	IdempotencyToken string
+}
+
+// WithPrecondition returns a copy of ctx carrying a write precondition
+func WithPrecondition(ctx context.Context, p Precondition) context.Context {
+	return context.WithValue(ctx, preconditionKey, p)
+}
+
+// PreconditionFrom returns the precondition set on ctx with WithPrecondition,
+// and false if none is set
+func PreconditionFrom(ctx context.Context) (Precondition, bool) {
+	p, ok := ctx.Value(preconditionKey).(Precondition)
+	return p, ok
+}
@@ -0,0 +1,59 @@
+package qfs
+
+import "context"
+
+// PutWithProgress places f on fs, calling fn each time fs reads from f.
+// Progress is reported from whichever goroutine fs reads from. The total size
+// is known for files that implement SizeFile & for Memdir trees of them
+func PutWithProgress(ctx context.Context, fs Filesystem, f File, fn func(p CopyProgress)) (string, error) {
+	progress := newCopyProgress(fn, sizeOf(f))
+	return fs.Put(ctx, progress.wrap(f))
+}
+
+// sizeOf returns the size of f if f is a file that implements SizeFile, or a
+// Memdir of files that do. Other files have a size of -1
+func sizeOf(f File) int64 {
+	if dir, ok := f.(*Memdir); ok {
+		dir.lk.Lock()
+		links := append([]File(nil), dir.links...)
+		dir.lk.Unlock()
+		var total int64
+		for _, ch := range links {
+			n := sizeOf(ch)
+			if n < 0 {
+				return -1
+			}
+			total += n
+		}
+		return total
+	}
+	if sf, ok := f.(SizeFile); ok && !f.IsDirectory() {
+		return sf.Size()
+	}
+	return -1
+}
+
+// listedSize adds up the sizes of the files beneath the directory at path,
+// returning -1 unless fs implements ListingFS & reports the size of each file
+func listedSize(ctx context.Context, fs Filesystem, path string) int64 {
+	lfs, ok := fs.(ListingFS)
+	if !ok {
+		return -1
+	}
+	entries, err := lfs.List(ctx, path)
+	if err != nil {
+		return -1
+	}
+	var total int64
+	for _, e := range entries {
+		n := e.Size
+		if e.IsDir {
+			n = listedSize(ctx, fs, e.Path)
+		}
+		if n < 0 {
+			return -1
+		}
+		total += n
+	}
+	return total
+}
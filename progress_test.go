@@ -0,0 +1,52 @@
+package qfs
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestPutWithProgress(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	var last CopyProgress
+	fn := func(p CopyProgress) { last = p }
+
+	if _, err := PutWithProgress(ctx, fs, NewMemfileBytes("/a.txt", []byte("hello")), fn); err != nil {
+		t.Fatal(err)
+	}
+	if last.Bytes != 5 || last.Total != 5 || last.Path != "/a.txt" {
+		t.Errorf("progress mismatch. want: 5 5 /a.txt got: %d %d %s", last.Bytes, last.Total, last.Path)
+	}
+
+	dir := NewMemdir("/b",
+		NewMemfileBytes("c.txt", []byte("ccc")),
+		NewMemdir("e",
+			NewMemfileBytes("d.txt", []byte("dd")),
+		),
+	)
+	dirPath, err := PutWithProgress(ctx, fs, dir, fn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Bytes != 5 || last.Total != 5 || last.Files != 2 {
+		t.Errorf("directory progress mismatch. want: 5 5 2 got: %d %d %d", last.Bytes, last.Total, last.Files)
+	}
+
+	unsized := NewMemdir("/f", NewMemfileReader("g.txt", strings.NewReader("g")))
+	if _, err := PutWithProgress(ctx, NewMemFS(), unsized, fn); err != nil {
+		t.Fatal(err)
+	}
+	if last.Total != -1 {
+		t.Errorf("expected a directory with an unsized file to have an unknown total. got: %d", last.Total)
+	}
+
+	last = CopyProgress{}
+	if _, err := Copy(ctx, fs, NewMemFS(), dirPath, OptCopyProgress(fn)); err != nil {
+		t.Fatal(err)
+	}
+	if last.Bytes != 5 || last.Total != 5 {
+		t.Errorf("copy progress mismatch. want: 5 5 got: %d %d", last.Bytes, last.Total)
+	}
+}
@@ -0,0 +1,159 @@
+package qfstest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/qri-io/qfs"
+)
+
+// Topology describes how the stores of a MemNetwork are connected
+type Topology int
+
+const (
+	// FullMesh connects every store to every other store
+	FullMesh Topology = iota
+	// Ring connects each store to the stores before & after it, wrapping
+	// around from the last store to the first
+	Ring
+	// Star connects the first store to every other store, which aren't
+	// connected to each other
+	Star
+)
+
+// String implements the fmt.Stringer interface
+func (t Topology) String() string {
+	switch t {
+	case FullMesh:
+		return "full mesh"
+	case Ring:
+		return "ring"
+	case Star:
+		return "star"
+	default:
+		return fmt.Sprintf("Topology(%d)", int(t))
+	}
+}
+
+// MemNetwork is a cluster of qfs.MemFS stores connected in a topology, for
+// testing replication entirely in memory. Connections are made in a fixed
+// order, so stores ask their peers for content in the same order every run.
+// MemNetwork methods aren't safe for concurrent use, but the stores are
+type MemNetwork struct {
+	Nodes    []*qfs.MemFS
+	topology Topology
+}
+
+// NewMemNetwork creates n stores connected in topology
+func NewMemNetwork(n int, topology Topology) *MemNetwork {
+	net := &MemNetwork{
+		Nodes:    make([]*qfs.MemFS, n),
+		topology: topology,
+	}
+	for i := range net.Nodes {
+		net.Nodes[i] = qfs.NewMemFS()
+	}
+	net.Heal()
+	return net
+}
+
+// Node returns the store at index i
+func (net *MemNetwork) Node(i int) *qfs.MemFS {
+	return net.Nodes[i]
+}
+
+// Topology returns the topology the network was created with
+func (net *MemNetwork) Topology() Topology {
+	return net.topology
+}
+
+// edges lists the pairs of stores the topology connects, lowest index first
+func (net *MemNetwork) edges() [][2]int {
+	n := len(net.Nodes)
+	var edges [][2]int
+	switch net.topology {
+	case Ring:
+		for i := 0; i < n; i++ {
+			a, b := i, (i+1)%n
+			if a == b || (n == 2 && i == 1) {
+				continue
+			}
+			if a > b {
+				a, b = b, a
+			}
+			edges = append(edges, [2]int{a, b})
+		}
+	case Star:
+		for i := 1; i < n; i++ {
+			edges = append(edges, [2]int{0, i})
+		}
+	default:
+		for i := 0; i < n; i++ {
+			for j := i + 1; j < n; j++ {
+				edges = append(edges, [2]int{i, j})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+// Connected reports whether stores i & j are connected
+func (net *MemNetwork) Connected(i, j int) bool {
+	for _, peer := range net.Nodes[i].Peers() {
+		if peer == net.Nodes[j] {
+			return true
+		}
+	}
+	return false
+}
+
+// Connect connects stores i & j, regardless of the topology
+func (net *MemNetwork) Connect(i, j int) {
+	net.Nodes[i].AddConnection(net.Nodes[j])
+}
+
+// Disconnect removes the connection between stores i & j
+func (net *MemNetwork) Disconnect(i, j int) {
+	net.Nodes[i].RemoveConnection(net.Nodes[j])
+}
+
+// Partition splits the network into groups of store indexes, removing every
+// connection between stores in different groups. Stores that aren't in any
+// group are isolated. Connections within a group are left as they are, so a
+// group can only reach stores it was already connected to
+func (net *MemNetwork) Partition(groups ...[]int) {
+	group := make(map[int]int, len(net.Nodes))
+	for g, members := range groups {
+		for _, i := range members {
+			group[i] = g
+		}
+	}
+	for i := range net.Nodes {
+		for j := i + 1; j < len(net.Nodes); j++ {
+			gi, iok := group[i]
+			gj, jok := group[j]
+			if !iok || !jok || gi != gj {
+				net.Disconnect(i, j)
+			}
+		}
+	}
+}
+
+// Heal removes every connection & reconnects the stores in the network's
+// topology, undoing partitions & any calls to Connect or Disconnect
+func (net *MemNetwork) Heal() {
+	for i := range net.Nodes {
+		for j := i + 1; j < len(net.Nodes); j++ {
+			net.Disconnect(i, j)
+		}
+	}
+	for _, e := range net.edges() {
+		net.Connect(e[0], e[1])
+	}
+}
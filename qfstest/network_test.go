@@ -0,0 +1,83 @@
+package qfstest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestMemNetworkTopologies(t *testing.T) {
+	cases := []struct {
+		topology Topology
+		edges    [][2]int
+	}{
+		{FullMesh, [][2]int{{0, 1}, {0, 2}, {0, 3}, {1, 2}, {1, 3}, {2, 3}}},
+		{Ring, [][2]int{{0, 1}, {0, 3}, {1, 2}, {2, 3}}},
+		{Star, [][2]int{{0, 1}, {0, 2}, {0, 3}}},
+	}
+	for _, c := range cases {
+		t.Run(c.topology.String(), func(t *testing.T) {
+			net := NewMemNetwork(4, c.topology)
+			want := map[[2]int]bool{}
+			for _, e := range c.edges {
+				want[e] = true
+			}
+			for i := 0; i < 4; i++ {
+				for j := i + 1; j < 4; j++ {
+					if got := net.Connected(i, j); got != want[[2]int{i, j}] {
+						t.Errorf("nodes %d & %d: expected connected: %t. got: %t", i, j, want[[2]int{i, j}], got)
+					}
+					if net.Connected(i, j) != net.Connected(j, i) {
+						t.Errorf("nodes %d & %d: expected connections to be symmetric", i, j)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMemNetworkPartition(t *testing.T) {
+	ctx := context.Background()
+	net := NewMemNetwork(4, FullMesh)
+	path, err := net.Node(0).Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := net.Node(3).Get(ctx, path); err != nil {
+		t.Fatalf("expected a connected store to fetch from its peer. got: %v", err)
+	}
+
+	net.Partition([]int{0, 1}, []int{2, 3})
+	if _, err := net.Node(1).Get(ctx, path); err != nil {
+		t.Errorf("expected a store to fetch from its own side of a partition. got: %v", err)
+	}
+	if _, err := net.Node(3).Get(ctx, path); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected a partitioned store to miss content. got: %v", err)
+	}
+
+	net.Heal()
+	if _, err := net.Node(3).Get(ctx, path); err != nil {
+		t.Errorf("expected healing the network to restore fetches. got: %v", err)
+	}
+}
+
+func TestMemNetworkConnectedConcurrentChanges(t *testing.T) {
+	net := NewMemNetwork(2, FullMesh)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			net.Node(0).RemoveConnection(net.Node(1))
+			net.Node(0).AddConnection(net.Node(1))
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		net.Connected(0, 1)
+	}
+	<-done
+	if !net.Connected(0, 1) {
+		t.Error("expected stores to end up connected")
+	}
+}
@@ -0,0 +1,531 @@
+// Package qfstest provides a scriptable fake filesystem for testing code that
+// depends on qfs. Unlike qfs.MemFS, the fake doesn't hash content: files are
+// stored at the paths they're given, responses & failures are set up ahead of
+// time, and every call is recorded so tests can assert on how a filesystem
+// was used. MemNetwork wires qfs.MemFS stores into clusters for testing
+// replication
+package qfstest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+// FilestoreType is the default type reported by a Fake
+const FilestoreType = "fake"
+
+// Method names recorded in calls & used to script behaviour
+const (
+	MethodHas        = "Has"
+	MethodHasMany    = "HasMany"
+	MethodGet        = "Get"
+	MethodGetMany    = "GetMany"
+	MethodPut        = "Put"
+	MethodDelete     = "Delete"
+	MethodDeleteMany = "DeleteMany"
+	MethodList       = "List"
+	MethodPin        = "Pin"
+	MethodUnpin      = "Unpin"
+)
+
+// Call is a single recorded call to a Fake. Calls that operate on many paths
+// are recorded once per path
+type Call struct {
+	Method string
+	Path   string
+}
+
+// Fake is a scriptable qfs.Filesystem. The zero value isn't usable, create
+// fakes with New. Fakes are safe for concurrent use
+type Fake struct {
+	fsType string
+
+	lk      sync.Mutex
+	files   map[string][]byte
+	dirs    map[string]struct{}
+	pins    map[string]bool
+	calls   []Call
+	errs    []scriptedErr
+	latency map[string]time.Duration
+	putPath func(f qfs.File) string
+}
+
+var (
+	_ qfs.Filesystem = (*Fake)(nil)
+	_ qfs.BatchFS    = (*Fake)(nil)
+	_ qfs.ListingFS  = (*Fake)(nil)
+	_ qfs.PinningFS  = (*Fake)(nil)
+)
+
+// scriptedErr is an error returned by calls that match method & path. times
+// is the number of calls left to fail, -1 fails every matching call
+type scriptedErr struct {
+	method string
+	path   string
+	err    error
+	times  int
+}
+
+// New creates an empty Fake
+func New() *Fake {
+	return &Fake{
+		fsType:  FilestoreType,
+		files:   map[string][]byte{},
+		dirs:    map[string]struct{}{},
+		pins:    map[string]bool{},
+		latency: map[string]time.Duration{},
+	}
+}
+
+// SetType changes the type the fake reports, for faking a specific kind of
+// filesystem, eg: "ipfs"
+func (f *Fake) SetType(fsType string) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.fsType = fsType
+}
+
+// SetFile adds a canned file at path, creating parent directories
+func (f *Fake) SetFile(path string, data []byte) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.setFile(path, data)
+}
+
+// SetDir adds an empty directory at path, creating parent directories
+func (f *Fake) SetDir(path string) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.setDir(path)
+}
+
+// SetPutPath sets a function that chooses the path Put returns for a file,
+// for faking content-addressed filesystems. Files are still stored at the
+// returned path. By default Put returns the file's FullPath
+func (f *Fake) SetPutPath(fn func(file qfs.File) string) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.putPath = fn
+}
+
+// FailOn makes every call to method with path return err. An empty path
+// matches any path. Errors are checked in the order they're scripted
+func (f *Fake) FailOn(method, path string, err error) {
+	f.failTimes(method, path, err, -1)
+}
+
+// FailNext makes the next n calls to method with path return err, after which
+// calls behave normally. An empty path matches any path
+func (f *Fake) FailNext(method, path string, n int, err error) {
+	f.failTimes(method, path, err, n)
+}
+
+func (f *Fake) failTimes(method, path string, err error, times int) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.errs = append(f.errs, scriptedErr{method: method, path: path, err: err, times: times})
+}
+
+// SetLatency delays every call to method by d. Delayed calls return early
+// with the context error if their context is cancelled
+func (f *Fake) SetLatency(method string, d time.Duration) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.latency[method] = d
+}
+
+// Reset clears recorded calls, scripted errors & latency. Stored files are
+// kept
+func (f *Fake) Reset() {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.calls = nil
+	f.errs = nil
+	f.latency = map[string]time.Duration{}
+}
+
+// Calls returns a copy of every call made to the fake, in order
+func (f *Fake) Calls() []Call {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallCount returns the number of calls made to method
+func (f *Fake) CallCount(method string) int {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	n := 0
+	for _, c := range f.calls {
+		if c.Method == method {
+			n++
+		}
+	}
+	return n
+}
+
+// AssertCalled fails t if method was never called with path. An empty path
+// matches any path
+func (f *Fake) AssertCalled(t testing.TB, method, path string) {
+	t.Helper()
+	if !f.called(method, path) {
+		t.Errorf("expected %s to be called with path %q. calls: %v", method, path, f.Calls())
+	}
+}
+
+// AssertNotCalled fails t if method was called with path. An empty path
+// matches any path
+func (f *Fake) AssertNotCalled(t testing.TB, method, path string) {
+	t.Helper()
+	if f.called(method, path) {
+		t.Errorf("expected %s not to be called with path %q. calls: %v", method, path, f.Calls())
+	}
+}
+
+func (f *Fake) called(method, path string) bool {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	for _, c := range f.calls {
+		if c.Method == method && (path == "" || c.Path == path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Type returns the filesystem type, FilestoreType unless changed with SetType
+func (f *Fake) Type() string {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.fsType
+}
+
+// Has reports whether a file or directory is stored at path
+func (f *Fake) Has(ctx context.Context, path string) (bool, error) {
+	if err := f.call(ctx, MethodHas, path); err != nil {
+		return false, err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.exists(path), nil
+}
+
+// HasMany reports whether each path exists
+func (f *Fake) HasMany(ctx context.Context, paths []string) (map[string]bool, error) {
+	if err := f.call(ctx, MethodHasMany, paths...); err != nil {
+		return nil, err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	res := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		res[p] = f.exists(p)
+	}
+	return res, nil
+}
+
+// Get returns the file or directory at path, or an error wrapping
+// qfs.ErrNotFound
+func (f *Fake) Get(ctx context.Context, path string) (qfs.File, error) {
+	if err := f.call(ctx, MethodGet, path); err != nil {
+		return nil, err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.get(path)
+}
+
+// GetMany returns the files at paths in order
+func (f *Fake) GetMany(ctx context.Context, paths []string) ([]qfs.File, error) {
+	if err := f.call(ctx, MethodGetMany, paths...); err != nil {
+		return nil, err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	res := make([]qfs.File, 0, len(paths))
+	for _, p := range paths {
+		file, err := f.get(p)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, file)
+	}
+	return res, nil
+}
+
+// Put stores file, reading its contents in full. Directories are stored
+// recursively
+func (f *Fake) Put(ctx context.Context, file qfs.File) (string, error) {
+	if err := f.call(ctx, MethodPut, file.FullPath()); err != nil {
+		return "", err
+	}
+
+	f.lk.Lock()
+	putPath := f.putPath
+	f.lk.Unlock()
+	root := file.FullPath()
+	if putPath != nil {
+		root = putPath(file)
+	}
+
+	err := qfs.Walk(file, func(child qfs.File) error {
+		p := path.Join(root, strings.TrimPrefix(child.FullPath(), file.FullPath()))
+		if child.IsDirectory() {
+			f.SetDir(p)
+			return nil
+		}
+		data, err := ioutil.ReadAll(child)
+		if err != nil {
+			return err
+		}
+		f.SetFile(p, data)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return root, nil
+}
+
+// Delete removes the file or directory at path, including any children
+func (f *Fake) Delete(ctx context.Context, path string) error {
+	if err := f.call(ctx, MethodDelete, path); err != nil {
+		return err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.delete(path)
+}
+
+// DeleteMany removes each path, stopping at the first error
+func (f *Fake) DeleteMany(ctx context.Context, paths []string) error {
+	if err := f.call(ctx, MethodDeleteMany, paths...); err != nil {
+		return err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	for _, p := range paths {
+		if err := f.delete(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the entries of dir sorted by name
+func (f *Fake) List(ctx context.Context, dir string) ([]qfs.DirEntry, error) {
+	if err := f.call(ctx, MethodList, dir); err != nil {
+		return nil, err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	dir = cleanPath(dir)
+	if _, ok := f.files[dir]; ok {
+		return nil, qfs.ErrNotDirectory
+	}
+	if _, ok := f.dirs[dir]; !ok {
+		return nil, fmt.Errorf("%w: %s", qfs.ErrNotFound, dir)
+	}
+
+	entries := []qfs.DirEntry{}
+	for _, name := range f.children(dir) {
+		p := path.Join(dir, name)
+		entry := qfs.DirEntry{Name: name, Path: p, Size: -1}
+		if data, ok := f.files[p]; ok {
+			entry.Size = int64(len(data))
+		} else {
+			entry.IsDir = true
+		}
+		entries = append(entries, entry)
+	}
+	qfs.SortDirEntries(entries)
+	return entries, nil
+}
+
+// Pin records a pin on key. Pins can be checked with Pinned
+func (f *Fake) Pin(ctx context.Context, key string, recursive bool) error {
+	if err := f.call(ctx, MethodPin, key); err != nil {
+		return err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.pins[key] = recursive
+	return nil
+}
+
+// Unpin removes a pin on key, returning an error wrapping qfs.ErrNotPinned if
+// key isn't pinned
+func (f *Fake) Unpin(ctx context.Context, key string, recursive bool) error {
+	if err := f.call(ctx, MethodUnpin, key); err != nil {
+		return err
+	}
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	if _, ok := f.pins[key]; !ok {
+		return fmt.Errorf("%w: %s", qfs.ErrNotPinned, key)
+	}
+	delete(f.pins, key)
+	return nil
+}
+
+// Pinned reports whether key is pinned
+func (f *Fake) Pinned(key string) bool {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	_, ok := f.pins[key]
+	return ok
+}
+
+// call records a call to method for each path, waits out any latency set for
+// method & returns the first scripted error that matches
+func (f *Fake) call(ctx context.Context, method string, paths ...string) error {
+	f.lk.Lock()
+	if len(paths) == 0 {
+		f.calls = append(f.calls, Call{Method: method})
+	}
+	for _, p := range paths {
+		f.calls = append(f.calls, Call{Method: method, Path: p})
+	}
+	delay := f.latency[method]
+	err := f.scriptedErr(method, paths)
+	f.lk.Unlock()
+
+	if delay > 0 {
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+	return err
+}
+
+// scriptedErr finds the first scripted error matching a call, using up one of
+// its failures. the caller must hold the lock
+func (f *Fake) scriptedErr(method string, paths []string) error {
+	for i, se := range f.errs {
+		if se.method != method || se.times == 0 || !matchesAny(se.path, paths) {
+			continue
+		}
+		if se.times > 0 {
+			f.errs[i].times--
+		}
+		return se.err
+	}
+	return nil
+}
+
+func matchesAny(pattern string, paths []string) bool {
+	if pattern == "" {
+		return true
+	}
+	for _, p := range paths {
+		if p == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPath drops trailing & repeated slashes. The empty path is left empty
+func cleanPath(p string) string {
+	if p == "" {
+		return ""
+	}
+	return path.Clean(p)
+}
+
+// the methods below must be called with the lock held
+
+func (f *Fake) exists(path string) bool {
+	path = cleanPath(path)
+	if _, ok := f.files[path]; ok {
+		return true
+	}
+	_, ok := f.dirs[path]
+	return ok
+}
+
+func (f *Fake) setFile(p string, data []byte) {
+	p = cleanPath(p)
+	f.files[p] = data
+	f.setDir(path.Dir(p))
+}
+
+func (f *Fake) setDir(p string) {
+	for p != "." && p != "/" && p != "" {
+		f.dirs[p] = struct{}{}
+		p = path.Dir(p)
+	}
+}
+
+func (f *Fake) get(p string) (qfs.File, error) {
+	p = cleanPath(p)
+	if data, ok := f.files[p]; ok {
+		return qfs.NewMemfileBytes(p, data), nil
+	}
+	if _, ok := f.dirs[p]; !ok {
+		return nil, fmt.Errorf("%w: %s", qfs.ErrNotFound, p)
+	}
+
+	dir := qfs.NewMemdir(p)
+	for _, name := range f.children(p) {
+		child, err := f.get(path.Join(p, name))
+		if err != nil {
+			return nil, err
+		}
+		dir.AddChildren(child)
+	}
+	return dir, nil
+}
+
+func (f *Fake) delete(p string) error {
+	if p == "" {
+		return fmt.Errorf("%w: empty path", qfs.ErrInvalidPath)
+	}
+	p = cleanPath(p)
+	if !f.exists(p) {
+		return fmt.Errorf("%w: %s", qfs.ErrNotFound, p)
+	}
+	prefix := strings.TrimSuffix(p, "/") + "/"
+	for fp := range f.files {
+		if fp == p || strings.HasPrefix(fp, prefix) {
+			delete(f.files, fp)
+		}
+	}
+	for dp := range f.dirs {
+		if dp == p || strings.HasPrefix(dp, prefix) {
+			delete(f.dirs, dp)
+		}
+	}
+	return nil
+}
+
+// children returns the sorted names of the direct children of dir
+func (f *Fake) children(dir string) []string {
+	names := []string{}
+	add := func(p string) {
+		if p != dir && path.Dir(p) == dir {
+			names = append(names, path.Base(p))
+		}
+	}
+	for p := range f.files {
+		add(p)
+	}
+	for p := range f.dirs {
+		add(p)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,183 @@
+package qfstest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+	fs := New()
+	fs.SetFile("/a/b.txt", []byte("b"))
+
+	path, err := fs.Put(ctx, qfs.NewMemdir("/a/c",
+		qfs.NewMemfileBytes("d.txt", []byte("d")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/a/c" {
+		t.Errorf("expected Put to return the file path. got: %q", path)
+	}
+
+	f, err := fs.Get(ctx, "/a/c/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "d" {
+		t.Errorf("contents mismatch. want: %q got: %q", "d", string(data))
+	}
+
+	entries, err := fs.List(ctx, "/a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "b.txt" || !entries[1].IsDir {
+		t.Errorf("unexpected listing: %#v", entries)
+	}
+
+	if err := fs.Delete(ctx, "/a/c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Get(ctx, "/a/c/d.txt"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got: %v", err)
+	}
+
+	fs.AssertCalled(t, MethodPut, "/a/c")
+	fs.AssertCalled(t, MethodGet, "/a/c/d.txt")
+	fs.AssertNotCalled(t, MethodHas, "")
+	if n := fs.CallCount(MethodGet); n != 2 {
+		t.Errorf("expected 2 calls to Get, got: %d", n)
+	}
+}
+
+func TestFakeScriptedErrors(t *testing.T) {
+	ctx := context.Background()
+	fs := New()
+	fs.SetFile("/a.txt", []byte("a"))
+	fs.SetFile("/b.txt", []byte("b"))
+
+	fs.FailOn(MethodGet, "/a.txt", qfs.ErrUnavailable)
+	fs.FailNext(MethodPut, "", 1, qfs.ErrReadOnly)
+
+	if _, err := fs.Get(ctx, "/a.txt"); !errors.Is(err, qfs.ErrUnavailable) {
+		t.Errorf("expected scripted error, got: %v", err)
+	}
+	if _, err := fs.Get(ctx, "/b.txt"); err != nil {
+		t.Errorf("expected unscripted path to succeed, got: %v", err)
+	}
+	if _, err := qfs.GetMany(ctx, fs, []string{"/b.txt", "/a.txt"}); err != nil {
+		t.Errorf("expected errors scripted for Get not to apply to GetMany, got: %v", err)
+	}
+
+	file := qfs.NewMemfileBytes("/c.txt", []byte("c"))
+	if _, err := fs.Put(ctx, file); !errors.Is(err, qfs.ErrReadOnly) {
+		t.Errorf("expected first Put to fail, got: %v", err)
+	}
+	if _, err := fs.Put(ctx, qfs.NewMemfileBytes("/c.txt", []byte("c"))); err != nil {
+		t.Errorf("expected second Put to succeed, got: %v", err)
+	}
+
+	fs.Reset()
+	if _, err := fs.Get(ctx, "/a.txt"); err != nil {
+		t.Errorf("expected Reset to clear scripted errors, got: %v", err)
+	}
+	if calls := fs.Calls(); len(calls) != 1 {
+		t.Errorf("expected Reset to clear recorded calls. got: %v", calls)
+	}
+}
+
+func TestFakeLatency(t *testing.T) {
+	fs := New()
+	fs.SetLatency(MethodHas, time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	if _, err := fs.Has(ctx, "/a.txt"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected latency to be cut short by the context, got: %v", err)
+	}
+}
+
+func TestFakePins(t *testing.T) {
+	ctx := context.Background()
+	fs := New()
+	fs.SetType("ipfs")
+	fs.SetPutPath(func(f qfs.File) string { return "/ipfs/QmFake" })
+
+	path, err := fs.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/ipfs/QmFake" {
+		t.Errorf("expected scripted put path, got: %q", path)
+	}
+	if err := fs.Pin(ctx, path, true); err != nil {
+		t.Fatal(err)
+	}
+	if !fs.Pinned(path) {
+		t.Errorf("expected %q to be pinned", path)
+	}
+	if err := fs.Unpin(ctx, path, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Unpin(ctx, path, true); !errors.Is(err, qfs.ErrNotPinned) {
+		t.Errorf("expected ErrNotPinned, got: %v", err)
+	}
+}
+
+func TestPathSemantics(t *testing.T) {
+	ctx := context.Background()
+
+	fake := New()
+	fake.SetFile("/a/b.txt", []byte("b"))
+	AssertPathSemantics(t, fake, "/a/b.txt")
+
+	mem := qfs.NewMemFS()
+	root, err := mem.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	AssertPathSemantics(t, mem, root+"/b.txt")
+}
+
+func TestConcurrentAccess(t *testing.T) {
+	mem := qfs.NewMemFS()
+	AssertConcurrentAccess(t, mem)
+
+	cache := qfs.NewMemFS(qfs.WithMaxBytes(1<<20), qfs.WithLRUEviction())
+	AssertConcurrentAccess(t, cache)
+}
+
+func TestOpenWriteFSAdapter(t *testing.T) {
+	ctx := context.Background()
+	fs := qfs.NewOpenWriteFS(qfs.NewMemFS())
+
+	root, err := fs.WriteFile(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := fs.OpenFile(ctx, root+"/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if _, err := fs.OpenFile(ctx, root+"/missing.txt"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("OpenFile: expected ErrNotFound. got: %v", err)
+	}
+
+	AssertPathSemantics(t, fs, root+"/b.txt")
+	AssertConcurrentAccess(t, fs)
+}
@@ -0,0 +1,169 @@
+package qfstest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/qri-io/qfs"
+)
+
+// AssertPathSemantics checks fs follows the path rules documented on
+// qfs.Filesystem: trailing & repeated slashes are ignored, and the empty path
+// & content-addressed store roots name nothing. file must be the path of a
+// file stored on fs, beneath at least one directory
+func AssertPathSemantics(t *testing.T, fs qfs.Filesystem, file string) {
+	t.Helper()
+	ctx := context.Background()
+
+	want, err := getString(ctx, fs, file)
+	if err != nil {
+		t.Fatalf("getting %q: %s", file, err)
+	}
+	i := strings.LastIndexByte(file, '/')
+	for _, p := range []string{file + "/", file[:i] + "//" + file[i+1:]} {
+		got, err := getString(ctx, fs, p)
+		if err != nil {
+			t.Errorf("Get(%q): expected the same file as %q. got error: %s", p, file, err)
+		} else if got != want {
+			t.Errorf("Get(%q): contents mismatch. want: %q got: %q", p, want, got)
+		}
+		if exists, err := fs.Has(ctx, p); err != nil || !exists {
+			t.Errorf("Has(%q): expected true. got: %t, %v", p, exists, err)
+		}
+	}
+
+	caps := qfs.Capabilities(fs)
+	roots := []string{""}
+	if caps.ContentAddressed {
+		prefix := qfs.NamePrefix(fs)
+		roots = append(roots, "/", prefix, prefix+"/")
+	}
+	for _, p := range roots {
+		if exists, err := fs.Has(ctx, p); err != nil || exists {
+			t.Errorf("Has(%q): expected false. got: %t, %v", p, exists, err)
+		}
+		if _, err := fs.Get(ctx, p); !errors.Is(err, qfs.ErrNotFound) {
+			t.Errorf("Get(%q): expected ErrNotFound. got: %v", p, err)
+		}
+		err := fs.Delete(ctx, p)
+		if err == nil {
+			t.Errorf("Delete(%q): expected an error", p)
+		} else if caps.CanDelete && !errors.Is(err, qfs.ErrInvalidPath) {
+			t.Errorf("Delete(%q): expected ErrInvalidPath. got: %v", p, err)
+		}
+	}
+}
+
+// AssertConcurrentAccess exercises fs from many goroutines at once, putting,
+// reading, listing & deleting overlapping content. Stores implementing
+// qfs.MerkleDagStore also have their block-level methods exercised. Run tests
+// that call it with -race to catch unsynchronized access
+func AssertConcurrentAccess(t *testing.T, fs qfs.Filesystem) {
+	t.Helper()
+	const workers, rounds = 8, 10
+	ctx := context.Background()
+
+	errs := make(chan error, workers)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				if err := concurrentRound(ctx, fs, w, r); err != nil {
+					errs <- fmt.Errorf("worker %d round %d: %w", w, r, err)
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// concurrentRound puts content shared by every worker & content unique to
+// worker w, reads both back, then deletes the unique content
+func concurrentRound(ctx context.Context, fs qfs.Filesystem, w, r int) error {
+	shared := qfs.NewMemdir("/shared",
+		qfs.NewMemfileBytes("shared.txt", []byte("shared")),
+	)
+	if _, err := fs.Put(ctx, shared); err != nil {
+		return fmt.Errorf("putting shared content: %w", err)
+	}
+
+	name := fmt.Sprintf("worker_%d_%d", w, r)
+	body := fmt.Sprintf("worker %d round %d", w, r)
+	root, err := fs.Put(ctx, qfs.NewMemdir("/"+name,
+		qfs.NewMemfileBytes("body.txt", []byte(body)),
+		qfs.NewMemfileBytes("shared.txt", []byte("shared")),
+	))
+	if err != nil {
+		return fmt.Errorf("putting: %w", err)
+	}
+	if got, err := getString(ctx, fs, root+"/body.txt"); err != nil {
+		return fmt.Errorf("getting: %w", err)
+	} else if got != body {
+		return fmt.Errorf("contents mismatch. want: %q got: %q", body, got)
+	}
+	if exists, err := fs.Has(ctx, root); err != nil || !exists {
+		return fmt.Errorf("has: expected true. got: %t, %v", exists, err)
+	}
+	if entries, err := qfs.List(ctx, fs, root); err != nil {
+		return fmt.Errorf("listing: %w", err)
+	} else if len(entries) != 2 {
+		return fmt.Errorf("listing: expected 2 entries. got: %d", len(entries))
+	}
+
+	if store, ok := fs.(qfs.MerkleDagStore); ok {
+		if err := concurrentDagRound(store, body); err != nil {
+			return err
+		}
+	}
+
+	if err := fs.Delete(ctx, root); err != nil {
+		return fmt.Errorf("deleting: %w", err)
+	}
+	return nil
+}
+
+func concurrentDagRound(store qfs.MerkleDagStore, body string) error {
+	id, err := store.PutBlock([]byte(body))
+	if err != nil {
+		return fmt.Errorf("putting block: %w", err)
+	}
+	node, err := store.PutNode(qfs.NewLinks(qfs.Link{Name: "body", Cid: id, IsFile: true}))
+	if err != nil {
+		return fmt.Errorf("putting node: %w", err)
+	}
+	if _, err := store.GetNode(node.Cid); err != nil {
+		return fmt.Errorf("getting node: %w", err)
+	}
+	r, err := store.GetBlock(id)
+	if err != nil {
+		return fmt.Errorf("getting block: %w", err)
+	}
+	if data, err := ioutil.ReadAll(r); err != nil {
+		return fmt.Errorf("reading block: %w", err)
+	} else if string(data) != body {
+		return fmt.Errorf("block contents mismatch. want: %q got: %q", body, string(data))
+	}
+	return nil
+}
+
+func getString(ctx context.Context, fs qfs.Filesystem, path string) (string, error) {
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	return string(data), err
+}
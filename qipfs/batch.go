@@ -0,0 +1,110 @@
+package qipfs
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	merkledag "github.com/ipfs/go-merkledag"
+	unixfile "github.com/ipfs/go-unixfs/file"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+	"github.com/qri-io/qfs"
+)
+
+// GetMany opens many keys at once. With an in-process node root blocks for
+// every key are requested together in a single bitswap session, which is
+// also used to read the contents of each file. Fetching hundreds of blocks
+// this way is much faster than a series of calls to Get, each of which waits
+// on the network before the next starts. Keys with paths beneath a hash are
+// resolved individually before their blocks are fetched in the batch
+func (fst *Filestore) GetMany(ctx context.Context, keys []string) ([]qfs.File, error) {
+	if err := fst.closed(); err != nil {
+		return nil, err
+	}
+	if fst.node == nil {
+		res := make([]qfs.File, 0, len(keys))
+		for _, key := range keys {
+			f, err := fst.getKey(ctx, key)
+			if err != nil {
+				qfs.CloseAll(res)
+				return nil, fmt.Errorf("getting %q: %w", key, err)
+			}
+			res = append(res, f)
+		}
+		return res, nil
+	}
+
+	ids := make([]cid.Cid, len(keys))
+	unique := make([]cid.Cid, 0, len(keys))
+	seen := map[cid.Cid]struct{}{}
+	for i, key := range keys {
+		id, err := fst.keyCid(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("getting %q: %w", key, err)
+		}
+		ids[i] = id
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			unique = append(unique, id)
+		}
+	}
+
+	// the session reads file contents after GetMany returns & must outlive
+	// this call, only the root block requests are canceled on return
+	ses := merkledag.NewSession(ctx, fst.node.DAG)
+	getCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	nodes := make(map[cid.Cid]format.Node, len(unique))
+	for opt := range ses.GetMany(getCtx, unique) {
+		if opt.Err != nil {
+			return nil, opt.Err
+		}
+		nodes[opt.Node.Cid()] = opt.Node
+	}
+
+	dserv := merkledag.NewReadOnlyDagService(ses)
+	res := make([]qfs.File, 0, len(keys))
+	for i, key := range keys {
+		nd, ok := nodes[ids[i]]
+		if !ok {
+			qfs.CloseAll(res)
+			return nil, fmt.Errorf("getting %q: %w", key, qfs.ErrNotFound)
+		}
+		ufsNode, err := unixfile.NewUnixfsFile(ctx, dserv, nd)
+		if err != nil {
+			qfs.CloseAll(res)
+			return nil, fmt.Errorf("getting %q: %w", key, err)
+		}
+		f, err := newIPFSFile(key, ufsNode)
+		if err != nil {
+			qfs.CloseAll(res)
+			return nil, fmt.Errorf("getting %q: %w", key, err)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// DeleteMany unpins each key, stopping at the first error
+func (fst *Filestore) DeleteMany(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := fst.Delete(ctx, key); err != nil {
+			return fmt.Errorf("deleting %q: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// keyCid returns the CID key refers to. Bare hashes & /ipfs/ paths to a hash
+// are parsed, other paths are resolved
+func (fst *Filestore) keyCid(ctx context.Context, key string) (cid.Cid, error) {
+	if id, err := cid.Parse(key); err == nil {
+		return id, nil
+	}
+	resolved, err := fst.capi.ResolvePath(ctx, path.New(key))
+	if err != nil {
+		return cid.Undef, err
+	}
+	return resolved.Cid(), nil
+}
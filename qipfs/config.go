@@ -2,7 +2,9 @@ package qipfs
 
 import (
 	"errors"
+	"fmt"
 
+	chunker "github.com/ipfs/go-ipfs-chunker"
 	"github.com/ipfs/go-ipfs/core"
 	"github.com/mitchellh/mapstructure"
 )
@@ -26,6 +28,8 @@ type StoreCfg struct {
 	// weather or not to serve the local IPFS HTTP API. does not apply when
 	// operating over HTTP via a URL
 	EnableAPI bool
+	// API configures the HTTP API served when EnableAPI is true
+	API APIConfig
 	// enable experimental IPFS pubsub service. does not apply when
 	// operating over HTTP via a URL
 	EnablePubSub bool
@@ -34,6 +38,39 @@ type StoreCfg struct {
 	// AdditionalSwarmListeningAddrs allows you to add a list of
 	// addresses you want the underlying libp2p swarm to listen on
 	AdditionalSwarmListeningAddrs []string
+	// Provide sets how written content is announced to the DHT. Individual
+	// writes can override it with WithProvideMode
+	Provide ProvideMode
+	// ChunkSize is the size in bytes of the blocks files are split into as
+	// they're added. Files are streamed through the chunker, so adding a file
+	// never holds more than a few chunks in memory. Changing the chunk size
+	// changes the hashes of files larger than one chunk. Zero uses the IPFS
+	// default of 256KiB
+	ChunkSize int
+	// RemoveOnDelete makes Delete remove the blocks of deleted content that
+	// no remaining pin references, instead of leaving them for the next
+	// garbage collection. Deletes read the DAG of each key to find
+	// unreferenced blocks first
+	RemoveOnDelete bool
+}
+
+// APIConfig configures the HTTP API, gateway & web UI an in-process node
+// serves. The zero value serves a read-only gateway & web UI alongside the API
+// on the repo's configured API addresses
+type APIConfig struct {
+	// Addrs are multiaddrs to listen on, eg: "/ip4/127.0.0.1/tcp/5001". A port
+	// of 0 binds a random free port. Defaults to the repo's Addresses.API
+	Addrs []string
+	// DisableGateway stops serving content at /ipfs & /ipns paths
+	DisableGateway bool
+	// WritableGateway accepts writes through the gateway
+	WritableGateway bool
+	// DisableWebUI stops serving the IPFS web UI
+	DisableWebUI bool
+	// GatewayAddrs are multiaddrs to serve a standalone gateway on, separate
+	// from the API. The standalone gateway only serves /ipfs & /ipns paths.
+	// A port of 0 binds a random free port
+	GatewayAddrs []string
 }
 
 func mapToConfig(cfgmap map[string]interface{}) (*StoreCfg, error) {
@@ -69,5 +106,8 @@ func (cfg *StoreCfg) Validate() error {
 	if cfg.Path == "" && cfg.URL == "" {
 		return ErrNoRepoPath
 	}
+	if cfg.ChunkSize < 0 || cfg.ChunkSize > chunker.ChunkSizeLimit {
+		return fmt.Errorf("chunk size must be between 0 and %d bytes, got: %d", chunker.ChunkSizeLimit, cfg.ChunkSize)
+	}
 	return nil
 }
@@ -0,0 +1,64 @@
+package qipfs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/qri-io/qfs"
+)
+
+// Fetch gets key, first connecting to any hinted peers so content they hold
+// can be found without a DHT lookup. Peers that can't be reached are logged &
+// don't fail the fetch. A deadline hint bounds both the Get & reads of the
+// returned file, which fetch blocks as they're read. Gateway hints are
+// ignored, content is only fetched from peers
+func (fst *Filestore) Fetch(ctx context.Context, key string, hints ...qfs.FetchHint) (qfs.File, error) {
+	if err := fst.closed(); err != nil {
+		return nil, err
+	}
+	h := qfs.ApplyFetchHints(hints...)
+	if h.Deadline.IsZero() {
+		fst.connectPeers(ctx, h.Peers)
+		return fst.Get(ctx, key)
+	}
+
+	// the returned file reads with ctx, which is released once it's closed
+	ctx, cancel := context.WithTimeout(ctx, time.Until(h.Deadline))
+	fst.connectPeers(ctx, h.Peers)
+	f, err := fst.Get(ctx, key)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return qfs.FileWithCancel(f, cancel), nil
+}
+
+// connectPeers connects to each peer multiaddr concurrently, waiting for all
+// attempts to finish
+func (fst *Filestore) connectPeers(ctx context.Context, addrs []string) {
+	wg := sync.WaitGroup{}
+	for _, addr := range addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err != nil {
+			log.Debugw("parsing peer hint", qfs.LogFields(ctx, "addr", addr, "err", err)...)
+			continue
+		}
+		info, err := peer.AddrInfoFromP2pAddr(maddr)
+		if err != nil {
+			log.Debugw("parsing peer hint", qfs.LogFields(ctx, "addr", addr, "err", err)...)
+			continue
+		}
+
+		wg.Add(1)
+		go func(info peer.AddrInfo) {
+			defer wg.Done()
+			if err := fst.capi.Swarm().Connect(ctx, info); err != nil {
+				log.Debugw("connecting to hinted peer", qfs.LogFields(ctx, "peer", info.ID, "err", err)...)
+			}
+		}(*info)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,43 @@
+package qipfs
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestFetch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	fs, err := NewFilesystem(ctx, map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := fs.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// unparseable peer hints are skipped, the deadline outlives Fetch so the
+	// file can be read after it returns
+	f, err := qfs.Fetch(ctx, fs, key,
+		qfs.HintPeer("not a multiaddr"),
+		qfs.HintDeadline(time.Now().Add(time.Minute)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(f); s != "hello" {
+		t.Errorf("contents mismatch. want: %q got: %q", "hello", s)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -3,29 +3,37 @@ package qipfs
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	ipfs_config "github.com/ipfs/go-ipfs-config"
 	files "github.com/ipfs/go-ipfs-files"
 	ipfs_commands "github.com/ipfs/go-ipfs/commands"
 	core "github.com/ipfs/go-ipfs/core"
 	coreapi "github.com/ipfs/go-ipfs/core/coreapi"
 	ipfs_corehttp "github.com/ipfs/go-ipfs/core/corehttp"
+	corerepo "github.com/ipfs/go-ipfs/core/corerepo"
 	ipfsrepo "github.com/ipfs/go-ipfs/repo"
 	fsrepo "github.com/ipfs/go-ipfs/repo/fsrepo"
 	format "github.com/ipfs/go-ipld-format"
 	logging "github.com/ipfs/go-log"
+	resolver "github.com/ipfs/go-path/resolver"
 	unixfs "github.com/ipfs/go-unixfs"
 	coreiface "github.com/ipfs/interface-go-ipfs-core"
 	caopts "github.com/ipfs/interface-go-ipfs-core/options"
 	"github.com/ipfs/interface-go-ipfs-core/path"
 	corepath "github.com/ipfs/interface-go-ipfs-core/path"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
 	httpapi "github.com/qri-io/go-ipfs-http-client"
 	"github.com/qri-io/qfs"
 )
@@ -45,12 +53,45 @@ type Filestore struct {
 
 	doneCh  chan struct{}
 	doneErr error
+
+	// apiAddrs & gatewayAddrs are the addresses the HTTP API & standalone
+	// gateway are bound to
+	apiAddrs     []string
+	gatewayAddrs []string
+	// apiListeners holds every listener of the HTTP API & gateway
+	apiListeners []manet.Listener
+	apiWg        sync.WaitGroup
+	apiErrLk     sync.Mutex
+	apiErr       error
+
+	locksLk sync.Mutex
+	locks   map[string]io.Closer
+
+	// pinLk is held for writing across a Delete, from planning which blocks
+	// to remove to removing them, & for reading while content is added or
+	// pinned, so nothing can start referencing a block marked for removal
+	pinLk sync.RWMutex
+
+	events qfs.EventBus
 }
 
 var (
-	_ qfs.Filesystem     = (*Filestore)(nil)
-	_ qfs.MerkleDagStore = (*Filestore)(nil)
-	_ qfs.CAFS           = (*Filestore)(nil)
+	_ qfs.Filesystem      = (*Filestore)(nil)
+	_ qfs.HasManyFS       = (*Filestore)(nil)
+	_ qfs.LockFS          = (*Filestore)(nil)
+	_ qfs.MerkleDagStore  = (*Filestore)(nil)
+	_ qfs.CAFS            = (*Filestore)(nil)
+	_ qfs.PinListerFS     = (*Filestore)(nil)
+	_ qfs.RangeGetter     = (*Filestore)(nil)
+	_ qfs.ListingFS       = (*Filestore)(nil)
+	_ qfs.DeletePlannerFS = (*Filestore)(nil)
+	_ qfs.BatchFS         = (*Filestore)(nil)
+	_ qfs.StatPathFS      = (*Filestore)(nil)
+	_ qfs.HashOnlyFS      = (*Filestore)(nil)
+	_ qfs.EventPublisher  = (*Filestore)(nil)
+	_ qfs.FetcherFS       = (*Filestore)(nil)
+	_ qfs.DiskUsageFS     = (*Filestore)(nil)
+	_ qfs.RawBlockPutter  = (*Filestore)(nil)
 )
 
 // NewFilesystem creates a new local filesystem PathResolver
@@ -114,6 +155,13 @@ func NewFilesystem(ctx context.Context, cfgMap map[string]interface{}) (qfs.File
 		doneCh: make(chan struct{}),
 	}
 
+	if node.IsOnline && cfg.EnableAPI {
+		if err := fst.serveAPI(); err != nil {
+			node.Close()
+			return nil, fmt.Errorf("serving IPFS HTTP api: %w", err)
+		}
+	}
+
 	go fst.handleContextClose()
 	return fst, nil
 }
@@ -157,13 +205,13 @@ func NewFilesystemFromNode(ctx context.Context, node *core.IpfsNode) (qfs.Merkle
 }
 
 // Type distinguishes this filesystem from others by a unique string prefix
-func (fst Filestore) Type() string { return FilestoreType }
+func (fst *Filestore) Type() string { return FilestoreType }
 
-func (fst Filestore) IsContentAddressedFilesystem() {}
+func (fst *Filestore) IsContentAddressedFilesystem() {}
 
 func (fs *Filestore) GetNode(id cid.Cid, path ...string) (qfs.DagNode, error) {
 	if len(path) > 0 {
-		return nil, fmt.Errorf("unsupported: path values on ipfs.Filestore.GetNode")
+		return nil, fmt.Errorf("%w: path values on ipfs.Filestore.GetNode", qfs.ErrUnsupported)
 	}
 	node, err := fs.capi.Dag().Get(fs.ctx, id)
 	if err != nil {
@@ -215,6 +263,24 @@ func (fs *Filestore) PutBlock(d []byte) (id cid.Cid, err error) {
 	return bs.Path().Root(), nil
 }
 
+// PutRawBlock stores data verbatim under id, implementing the
+// qfs.RawBlockPutter interface. The block isn't pinned
+func (fs *Filestore) PutRawBlock(ctx context.Context, id cid.Cid, data []byte) error {
+	prefix := id.Prefix()
+	format := "v0"
+	if prefix.Version != 0 {
+		format = cid.CodecToStr[prefix.Codec]
+	}
+	bs, err := fs.capi.Block().Put(ctx, bytes.NewReader(data), caopts.Block.Format(format), caopts.Block.Hash(prefix.MhType, prefix.MhLength))
+	if err != nil {
+		return err
+	}
+	if got := bs.Path().Cid(); !got.Equals(id) {
+		return fmt.Errorf("block %s was stored as %s", id, got)
+	}
+	return nil
+}
+
 func (fs *Filestore) PutFile(f fs.File) (qfs.PutResult, error) {
 	path, err := fs.capi.Unixfs().Add(fs.ctx, files.NewReaderFile(f), caopts.Unixfs.CidVersion(0))
 	if err != nil {
@@ -288,28 +354,28 @@ func (fst *Filestore) GoOnline() error {
 		return err
 	}
 
-	*fst = Filestore{
-		ctx:  fst.ctx,
-		cfg:  cfg,
-		node: node,
-		capi: capi,
-
-		doneCh:  fst.doneCh,
-		doneErr: fst.doneErr,
-	}
+	// assign fields individually, replacing the struct would reset the event
+	// bus, API listeners & locks
+	fst.cfg = cfg
+	fst.node = node
+	fst.capi = capi
 
 	if cfg.EnableAPI {
-		go func() {
-			if err := fst.serveAPI(); err != nil {
-				log.Errorf("error serving IPFS HTTP api: %w", err)
-			}
-		}()
+		if err := fst.serveAPI(); err != nil {
+			return fmt.Errorf("serving IPFS HTTP api: %w", err)
+		}
 	}
 
 	return nil
 }
 
 func (fst *Filestore) Has(ctx context.Context, key string) (exists bool, err error) {
+	if err := fst.closed(); err != nil {
+		return false, err
+	}
+	if qfs.IsRootPath(FilestoreType, key) {
+		return false, nil
+	}
 	id, err := cid.Parse(key)
 	if err != nil {
 		return false, err
@@ -327,26 +393,372 @@ func (fst *Filestore) Has(ctx context.Context, key string) (exists bool, err err
 	return st != nil, nil
 }
 
+// StatPath describes the unixfs file or directory at key using only local
+// data, like Has. Keys whose root block isn't stored locally don't exist.
+// Paths beneath the root are resolved offline
+func (fst *Filestore) StatPath(ctx context.Context, key string) (qfs.PathInfo, error) {
+	info := qfs.PathInfo{Size: -1}
+	if err := fst.closed(); err != nil {
+		return info, err
+	}
+	if qfs.IsRootPath(FilestoreType, key) {
+		return info, nil
+	}
+	_, hash, _ := qfs.SplitStorePath(key)
+	root, err := cid.Decode(hash)
+	if err != nil {
+		return info, err
+	}
+	if exists, err := fst.Has(ctx, root.String()); err != nil || !exists {
+		return info, err
+	}
+
+	offline, err := fst.capi.WithOptions(caopts.Api.Offline(true))
+	if err != nil {
+		return info, err
+	}
+	// a missing link or block beneath an existing root doesn't exist locally
+	resolved, err := offline.ResolvePath(ctx, path.New(key))
+	if isPathNotFound(err) {
+		return info, nil
+	} else if err != nil {
+		return info, err
+	}
+	node, err := offline.Unixfs().Get(ctx, resolved)
+	if isPathNotFound(err) {
+		return info, nil
+	} else if err != nil {
+		return info, err
+	}
+	defer node.Close()
+
+	info.Exists = true
+	info.Cid = resolved.Cid()
+	if _, ok := node.(files.Directory); ok {
+		info.IsDir = true
+	} else if size, err := node.Size(); err == nil {
+		info.Size = size
+	}
+	return info, nil
+}
+
+// DiskUsage returns the bytes of the blocks in the DAG at key, walked offline.
+// Blocks linked more than once are counted once. An empty key returns the
+// size of the repo, which requires an in-process node
+func (fst *Filestore) DiskUsage(ctx context.Context, key string) (int64, error) {
+	if err := fst.closed(); err != nil {
+		return 0, err
+	}
+	if key == "" {
+		if fst.node == nil {
+			return 0, fmt.Errorf("%w: repo size requires an in-process node", qfs.ErrUnsupported)
+		}
+		stat, err := corerepo.RepoSize(ctx, fst.node)
+		if err != nil {
+			return 0, err
+		}
+		return int64(stat.RepoSize), nil
+	}
+
+	offline, err := fst.capi.WithOptions(caopts.Api.Offline(true))
+	if err != nil {
+		return 0, err
+	}
+	resolved, err := offline.ResolvePath(ctx, path.New(key))
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	visited := map[cid.Cid]struct{}{}
+	queue := []cid.Cid{resolved.Cid()}
+	for len(queue) > 0 {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		id := queue[0]
+		queue = queue[1:]
+		if _, ok := visited[id]; ok {
+			continue
+		}
+		visited[id] = struct{}{}
+
+		node, err := offline.Dag().Get(ctx, id)
+		if err != nil {
+			return 0, err
+		}
+		total += int64(len(node.RawData()))
+		for _, l := range node.Links() {
+			queue = append(queue, l.Cid)
+		}
+	}
+	return total, nil
+}
+
+// HasMany checks for the existence of many keys. With an in-process node
+// keys are checked directly against the blockstore, skipping per-call API
+// overhead
+func (fst *Filestore) HasMany(ctx context.Context, keys []string) (map[string]bool, error) {
+	res := make(map[string]bool, len(keys))
+	if fst.node == nil {
+		for _, key := range keys {
+			exists, err := fst.Has(ctx, key)
+			if err != nil {
+				return nil, err
+			}
+			res[key] = exists
+		}
+		return res, nil
+	}
+
+	for _, key := range keys {
+		id, err := cid.Parse(key)
+		if err != nil {
+			return nil, err
+		}
+		exists, err := fst.node.Blockstore.Has(id)
+		if err != nil {
+			return nil, err
+		}
+		res[key] = exists
+	}
+	return res, nil
+}
+
 func (fst *Filestore) Get(ctx context.Context, key string) (qfs.File, error) {
+	if err := fst.closed(); err != nil {
+		return nil, err
+	}
+	if qfs.IsRootPath(FilestoreType, key) {
+		return nil, qfs.ErrNotFound
+	}
 	return fst.getKey(ctx, key)
 }
 
+// GetRange fetches a unixfs file, seeking to offset and limiting reads to
+// length bytes. unixfs files seek by skipping to the block containing offset,
+// so blocks before the range aren't fetched
+func (fst *Filestore) GetRange(ctx context.Context, key string, offset, length int64) (qfs.File, error) {
+	f, err := fst.getKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return qfs.NewRangeFile(f, offset, length)
+}
+
+// List returns the entries of a unixfs directory. Entry paths are the
+// /ipfs/ paths of each child
+func (fst *Filestore) List(ctx context.Context, key string) ([]qfs.DirEntry, error) {
+	p := path.New(key)
+	node, err := fst.capi.Unixfs().Get(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	defer node.Close()
+	if _, ok := node.(files.Directory); !ok {
+		return nil, qfs.ErrNotDirectory
+	}
+
+	res, err := fst.capi.Unixfs().Ls(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	entries := []qfs.DirEntry{}
+	for ent := range res {
+		if ent.Err != nil {
+			return nil, ent.Err
+		}
+		entry := qfs.DirEntry{
+			Name:  ent.Name,
+			Path:  pathFromHash(ent.Cid.String()),
+			Size:  -1,
+			IsDir: ent.Type == coreiface.TDirectory,
+		}
+		if !entry.IsDir {
+			entry.Size = int64(ent.Size)
+		}
+		entries = append(entries, entry)
+	}
+	qfs.SortDirEntries(entries)
+	return entries, ctx.Err()
+}
+
 // Put adds a file and pins
 func (fst *Filestore) Put(ctx context.Context, file qfs.File) (key string, err error) {
-	hash, err := fst.AddFile(file, true)
+	if err := fst.closed(); err != nil {
+		return "", err
+	}
+	if err := qfs.CheckCAFSPutPath(FilestoreType, file.FullPath()); err != nil {
+		return "", err
+	}
+	fst.pinLk.RLock()
+	defer fst.pinLk.RUnlock()
+
+	mode := fst.provideMode(ctx)
+	api, err := fst.writeAPI(mode)
+	if err != nil {
+		return "", err
+	}
+	node, err := addNode(file)
 	if err != nil {
-		log.Infof("error adding bytes: %w", err)
+		return "", err
+	}
+	p, err := api.Unixfs().Add(ctx, node, fst.addOptions()...)
+	if err != nil {
+		log.Infow("error adding bytes", qfs.LogFields(ctx, "err", err)...)
 		return
 	}
-	return pathFromHash(hash), nil
+	key = pathFromHash(p.Cid().String())
+	if err = fst.provide(ctx, mode, p); err != nil {
+		return key, err
+	}
+	fst.events.Publish(qfs.Event{Type: qfs.EventFilePut, FSType: FilestoreType, Path: key})
+	return key, nil
 }
 
+// Subscribe calls fn after each successful Put, Delete, Pin & Unpin,
+// implementing the qfs.EventPublisher interface
+func (fst *Filestore) Subscribe(fn qfs.EventHandler) func() {
+	return fst.events.Subscribe(fn)
+}
+
+// HashOnly returns the path Put would return for file by adding it with the
+// hash-only option, which chunks & hashes the file without writing blocks to
+// the repo
+func (fst *Filestore) HashOnly(ctx context.Context, file qfs.File) (string, error) {
+	if err := fst.closed(); err != nil {
+		return "", err
+	}
+	if err := qfs.CheckCAFSPutPath(FilestoreType, file.FullPath()); err != nil {
+		return "", err
+	}
+	opts := append(fst.addOptions(), caopts.Unixfs.HashOnly(true), caopts.Unixfs.Pin(false))
+	node, err := addNode(file)
+	if err != nil {
+		return "", err
+	}
+	p, err := fst.capi.Unixfs().Add(ctx, node, opts...)
+	if err != nil {
+		return "", err
+	}
+	return pathFromHash(p.Cid().String()), nil
+}
+
+// addNode converts file to the node added to unixfs. Directories are
+// converted with all their children & symlinks are added as unixfs symlink
+// nodes
+func addNode(file qfs.File) (files.Node, error) {
+	if target, ok := qfs.IsSymlink(file); ok {
+		return files.NewLinkFile(target, nil), nil
+	}
+	if !file.IsDirectory() {
+		return files.NewReaderFile(file), nil
+	}
+
+	var entries []files.DirEntry
+	for {
+		child, err := file.NextFile()
+		if errors.Is(err, io.EOF) {
+			return files.NewSliceDirectory(entries), nil
+		} else if err != nil {
+			return nil, err
+		}
+		node, err := addNode(child)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, files.FileEntry(child.FileName(), node))
+	}
+}
+
+// addOptions configures how files are chunked & hashed when added
+func (fst *Filestore) addOptions() []caopts.UnixfsAddOption {
+	opts := []caopts.UnixfsAddOption{caopts.Unixfs.CidVersion(0)}
+	if fst.cfg != nil && fst.cfg.ChunkSize > 0 {
+		opts = append(opts, caopts.Unixfs.Chunker(fmt.Sprintf("size-%d", fst.cfg.ChunkSize)))
+	}
+	return opts
+}
+
+// Delete unpins key. Unpinned blocks stay in the repo until garbage
+// collection unless StoreCfg.RemoveOnDelete is set, in which case blocks of
+// key that no other pin references are removed. Deleting a key that isn't
+// pinned isn't an error
 func (fst *Filestore) Delete(ctx context.Context, key string) error {
-	err := fst.Unpin(ctx, key, true)
+	if err := fst.closed(); err != nil {
+		return err
+	}
+	if qfs.IsRootPath(FilestoreType, key) {
+		return fmt.Errorf("%w: can't delete the store root %q", qfs.ErrInvalidPath, key)
+	}
+
+	fst.pinLk.Lock()
+	defer fst.pinLk.Unlock()
+
+	var unreferenced []cid.Cid
+	if fst.cfg != nil && fst.cfg.RemoveOnDelete {
+		impact, err := fst.PlanDelete(ctx, key)
+		if err != nil {
+			return fmt.Errorf("finding blocks to remove for %q: %w", key, err)
+		}
+		unreferenced = impact.Unreferenced
+	}
+
+	err := fst.unpin(ctx, key)
+	unpinned := err == nil
+	if err != nil && !errors.Is(err, qfs.ErrNotPinned) {
+		return err
+	}
+	removed, err := fst.removeBlocks(ctx, unreferenced)
 	if err != nil {
-		if err.Error() == "not pinned" {
-			return nil
+		return fmt.Errorf("removing blocks of %q: %w", key, err)
+	}
+	if unpinned || removed > 0 {
+		fst.events.Publish(qfs.Event{Type: qfs.EventFileDeleted, FSType: FilestoreType, Path: key})
+	}
+	return nil
+}
+
+// removeBlocks deletes blocks from the local repo, returning the number
+// removed. Removal is forced so blocks that are already gone are skipped.
+// Pinned blocks aren't removed & return an error
+func (fst *Filestore) removeBlocks(ctx context.Context, ids []cid.Cid) (removed int, err error) {
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	offline, err := fst.capi.WithOptions(caopts.Api.Offline(true))
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		if err := offline.Block().Rm(ctx, path.IpfsPath(id), caopts.Block.Force(true)); err != nil {
+			return removed, fmt.Errorf("%s: %w", id, err)
 		}
+		removed++
+	}
+	return removed, nil
+}
+
+// isBlockNotFound reports whether err is a missing block
+func isBlockNotFound(err error) bool {
+	return errors.Is(err, format.ErrNotFound) || errors.Is(err, blockstore.ErrNotFound)
+}
+
+// isPathNotFound reports whether err is from resolving a path with a missing
+// link or block. A nil error is found
+func isPathNotFound(err error) bool {
+	if err == nil {
+		return false
+	}
+	var noLink resolver.ErrNoLink
+	return errors.As(err, &noLink) || isBlockNotFound(err)
+}
+
+// closed returns qfs.ErrClosed once the filestore's context is cancelled,
+// after which the underlying repo is released
+func (fst *Filestore) closed() error {
+	if fst.ctx.Err() != nil {
+		return qfs.ErrClosed
 	}
 	return nil
 }
@@ -357,19 +769,108 @@ func (fst *Filestore) getKey(ctx context.Context, key string) (qfs.File, error)
 		return nil, err
 	}
 
+	return newIPFSFile(key, node)
+}
+
+// newIPFSFile wraps a unixfs node as a qfs.File
+// NewFile wraps a unixfs node read from IPFS as a qfs.File at key. Children of
+// directories are given paths beneath key
+func NewFile(key string, node files.Node) (qfs.File, error) {
+	return newIPFSFile(key, node)
+}
+
+func newIPFSFile(key string, node files.Node) (qfs.File, error) {
+	if link, ok := node.(*files.Symlink); ok {
+		return qfs.NewMemsymlink(key, link.Target), nil
+	}
+	if dir, ok := node.(files.Directory); ok {
+		return &ipfsDir{path: key, dir: dir, it: dir.Entries()}, nil
+	}
+
 	if rdr, ok := node.(io.ReadCloser); ok {
-		return ipfsFile{path: key, r: rdr}, nil
+		size, err := node.Size()
+		if err != nil {
+			size = -1
+		}
+		return ipfsFile{path: key, r: rdr, size: size}, nil
 	}
 
 	return nil, fmt.Errorf("path is neither a file nor a directory")
 }
 
+// Pin pins cid, announcing it according to the provide mode set on ctx
 func (fst *Filestore) Pin(ctx context.Context, cid string, recursive bool) error {
-	return fst.capi.Pin().Add(ctx, path.New(cid))
+	fst.pinLk.RLock()
+	defer fst.pinLk.RUnlock()
+
+	mode := fst.provideMode(ctx)
+	api, err := fst.writeAPI(mode)
+	if err != nil {
+		return err
+	}
+	p := path.New(cid)
+	if err := api.Pin().Add(ctx, p, caopts.Pin.Recursive(recursive)); err != nil {
+		return err
+	}
+	if err := fst.provide(ctx, mode, p); err != nil {
+		return err
+	}
+	fst.events.Publish(qfs.Event{Type: qfs.EventPinned, FSType: FilestoreType, Path: cid})
+	return nil
 }
 
 func (fst *Filestore) Unpin(ctx context.Context, cid string, recursive bool) error {
-	return fst.capi.Pin().Rm(ctx, path.New(cid))
+	if err := fst.unpin(ctx, cid); err != nil {
+		return err
+	}
+	fst.events.Publish(qfs.Event{Type: qfs.EventUnpinned, FSType: FilestoreType, Path: cid})
+	return nil
+}
+
+func (fst *Filestore) unpin(ctx context.Context, cid string) error {
+	err := fst.capi.Pin().Rm(ctx, path.New(cid))
+	// the pinner reports "not pinned or pinned indirectly", which reaches HTTP
+	// API clients as a plain string, so it can only be matched by message
+	if err != nil && strings.HasPrefix(err.Error(), "not pinned") {
+		return fmt.Errorf("%w: %s", qfs.ErrNotPinned, err)
+	}
+	return err
+}
+
+// Pins lists all direct & recursive pins, implementing the qfs.PinListerFS
+// interface
+func (fst *Filestore) Pins(ctx context.Context) ([]qfs.Pin, error) {
+	// cancel on return so the listing goroutine stops if we return early
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	res, err := fst.capi.Pin().Ls(ctx, caopts.Pin.Ls.All())
+	if err != nil {
+		return nil, err
+	}
+
+	pins := []qfs.Pin{}
+	for p := range res {
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		switch p.Type() {
+		case "recursive":
+			pins = append(pins, qfs.Pin{Key: p.Path().String(), Recursive: true})
+		case "direct":
+			pins = append(pins, qfs.Pin{Key: p.Path().String()})
+		}
+	}
+	return pins, ctx.Err()
+}
+
+// PlanDelete reports the impact of unpinning key without unpinning it. All
+// other pins are treated as references that keep content
+func (fst *Filestore) PlanDelete(ctx context.Context, key string) (qfs.Impact, error) {
+	pins, err := fst.Pins(ctx)
+	if err != nil {
+		return qfs.Impact{Path: key}, err
+	}
+	return qfs.PlanDeleteDAG(ctx, fst, key, pins)
 }
 
 // PinsetDifference returns a map of "Recursive"-pinned hashes that are not in
@@ -412,7 +913,6 @@ func (fst *Filestore) PinsetDifference(ctx context.Context, set map[string]struc
 func (fst *Filestore) handleContextClose() {
 	<-fst.ctx.Done()
 	fst.doneErr = fst.ctx.Err()
-	log.Debugf("closing repo")
 
 	defer close(fst.doneCh)
 
@@ -420,6 +920,13 @@ func (fst *Filestore) handleContextClose() {
 		return
 	}
 
+	// release API ports before the repo, so callers waiting on Done can
+	// immediately reuse them
+	if err := fst.stopAPI(); err != nil {
+		fst.doneErr = err
+	}
+	log.Debugf("closing repo")
+
 	if err := fst.node.Repo.Close(); err != nil {
 		log.Error(err)
 	}
@@ -447,44 +954,161 @@ func (fs *Filestore) UsingHTTPBacking() bool {
 	return fs.httpClient != nil
 }
 
-// serveAPI makes an IPFS node available over an HTTP api
+// serveAPI makes an IPFS node available over an HTTP api, listening on each
+// configured address before returning & serving in the background
 func (fs *Filestore) serveAPI() error {
 	if fs.node == nil {
 		return fmt.Errorf("in-process IPFS node is required to serve IPFS HTTP API")
 	}
 
 	cfg := fs.cfg
-	addr := ""
-	if cfg.Repo != nil {
-		if ipfscfg, err := cfg.Repo.Config(); err == nil {
-			// TODO (b5): apparantly ipfs config supports multiple API multiaddrs?
-			// I dunno, for now just go with the most likely case of only assigning
-			// an address if one string is supplied
-			if len(ipfscfg.Addresses.API) == 1 {
-				addr = ipfscfg.Addresses.API[0]
-			}
+	addrs := cfg.API.Addrs
+	if len(addrs) == 0 && fs.node.Repo != nil {
+		if ipfscfg, err := fs.node.Repo.Config(); err == nil {
+			addrs = ipfscfg.Addresses.API
 		}
 	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("no IPFS HTTP API address configured")
+	}
 
 	opts := []ipfs_corehttp.ServeOption{
-		ipfs_corehttp.GatewayOption(true, "/ipfs", "/ipns"),
-		ipfs_corehttp.WebUIOption,
 		ipfs_corehttp.CommandsOption(cmdCtx(fs.node, cfg.Path)),
 	}
+	if !cfg.API.DisableGateway {
+		opts = append(opts, ipfs_corehttp.GatewayOption(cfg.API.WritableGateway, "/ipfs", "/ipns"))
+	}
+	if !cfg.API.DisableWebUI {
+		opts = append(opts, ipfs_corehttp.WebUIOption)
+	}
+
+	listeners, err := listenAll(addrs)
+	if err != nil {
+		return err
+	}
+	var gatewayListeners []manet.Listener
+	if len(cfg.API.GatewayAddrs) > 0 {
+		if gatewayListeners, err = listenAll(cfg.API.GatewayAddrs); err != nil {
+			closeAll(listeners)
+			return err
+		}
+	}
 
-	// TODO (b5): I've added this fmt.Println because the corehttp package includes a println
-	// call to the affect of "API server listening on [addr]", which will be confusing to our
-	// users. We should chat with the protocol folks about making that print statement mutable
-	// or configurable
-	fmt.Println("starting IPFS HTTP API:")
-	return ipfs_corehttp.ListenAndServe(fs.node, addr, opts...)
+	fs.apiAddrs = fs.serveHTTP("api", listeners, opts)
+	if len(gatewayListeners) > 0 {
+		gatewayOpts := []ipfs_corehttp.ServeOption{
+			ipfs_corehttp.GatewayOption(cfg.API.WritableGateway, "/ipfs", "/ipns"),
+		}
+		fs.gatewayAddrs = fs.serveHTTP("gateway", gatewayListeners, gatewayOpts)
+	}
+	log.Debugw("serving IPFS HTTP api", "addrs", fs.apiAddrs, "gatewayAddrs", fs.gatewayAddrs)
+	return nil
+}
+
+// serveHTTP serves the node on each listener in the background, returning the
+// addresses listeners are bound to
+func (fs *Filestore) serveHTTP(name string, listeners []manet.Listener, opts []ipfs_corehttp.ServeOption) []string {
+	fs.apiListeners = append(fs.apiListeners, listeners...)
+	addrs := make([]string, len(listeners))
+	for i, lis := range listeners {
+		// listening on port 0 binds a random port, report the one we got
+		addrs[i] = lis.Multiaddr().String()
+		fs.apiWg.Add(1)
+		go func(lis manet.Listener) {
+			defer fs.apiWg.Done()
+			err := ipfs_corehttp.Serve(fs.node, manet.NetListener(lis), opts...)
+			// servers stopping because the filestore is closing isn't an error
+			if err != nil && fs.ctx.Err() == nil {
+				log.Errorw("serving IPFS HTTP "+name, "addr", lis.Multiaddr().String(), "err", err)
+				fs.apiErrLk.Lock()
+				if fs.apiErr == nil {
+					fs.apiErr = fmt.Errorf("serving IPFS HTTP %s at %s: %w", name, lis.Multiaddr(), err)
+				}
+				fs.apiErrLk.Unlock()
+			}
+		}(lis)
+	}
+	return addrs
+}
+
+// listenAll binds every address in addrs, closing any bound listeners if an
+// address fails
+func listenAll(addrs []string) ([]manet.Listener, error) {
+	listeners := make([]manet.Listener, 0, len(addrs))
+	for _, addr := range addrs {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err == nil {
+			var lis manet.Listener
+			if lis, err = manet.Listen(maddr); err == nil {
+				listeners = append(listeners, lis)
+				continue
+			}
+		}
+		closeAll(listeners)
+		return nil, fmt.Errorf("listening on %q: %w", addr, err)
+	}
+	return listeners, nil
+}
+
+func closeAll(listeners []manet.Listener) {
+	for _, lis := range listeners {
+		lis.Close()
+	}
+}
+
+// apiShutdownTimeout is how long HTTP API servers have to finish in-flight
+// requests when the filestore closes before their listeners are closed
+const apiShutdownTimeout = time.Second * 5
+
+// stopAPI waits for HTTP API servers to shut down, which they do gracefully
+// once the node stops. Servers that outlast apiShutdownTimeout have their
+// listeners closed. Returns the first error a server failed with
+func (fst *Filestore) stopAPI() error {
+	stopped := make(chan struct{})
+	go func() {
+		fst.apiWg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-time.After(apiShutdownTimeout):
+		log.Debugf("closing IPFS HTTP api listeners")
+		closeAll(fst.apiListeners)
+		<-stopped
+	}
+
+	fst.apiErrLk.Lock()
+	defer fst.apiErrLk.Unlock()
+	return fst.apiErr
+}
+
+// APIAddrs returns the multiaddrs the IPFS HTTP API is listening on, nil if
+// the API isn't being served. Addresses configured with port 0 are reported
+// with the port that was bound, so tests & other processes can connect to
+// them
+func (fst *Filestore) APIAddrs() []string {
+	return append([]string(nil), fst.apiAddrs...)
+}
+
+// GatewayAddrs returns the multiaddrs the standalone gateway configured with
+// APIConfig.GatewayAddrs is listening on, nil if no standalone gateway is
+// being served. Like APIAddrs, bound ports are reported in place of port 0
+func (fst *Filestore) GatewayAddrs() []string {
+	return append([]string(nil), fst.gatewayAddrs...)
 }
 
 // AddFile adds a file to the top level IPFS Node
 func (fst *Filestore) AddFile(file qfs.File, pin bool) (hash string, err error) {
 	ctx := context.Background()
+	fst.pinLk.RLock()
+	defer fst.pinLk.RUnlock()
 
-	path, err := fst.capi.Unixfs().Add(ctx, files.NewReaderFile(file), caopts.Unixfs.CidVersion(0))
+	node, err := addNode(file)
+	if err != nil {
+		return "", err
+	}
+	path, err := fst.capi.Unixfs().Add(ctx, node, fst.addOptions()...)
 	if err != nil {
 		return "", err
 	}
@@ -519,7 +1143,7 @@ func openRepo(ctx context.Context, cfg *StoreCfg) (ipfsrepo.Repo, error) {
 }
 
 func pathFromHash(hash string) string {
-	return fmt.Sprintf("/%s/%s", FilestoreType, hash)
+	return qfs.JoinPath(FilestoreType, hash)
 }
 
 type ipfsDagNode struct {
@@ -548,9 +1172,15 @@ func (n ipfsDagNode) Links() qfs.Links {
 type ipfsFile struct {
 	path string
 	r    io.ReadCloser
+	size int64
 }
 
-var _ qfs.File = (*ipfsFile)(nil)
+var (
+	_ qfs.File     = (*ipfsFile)(nil)
+	_ qfs.SizeFile = (*ipfsFile)(nil)
+	_ qfs.StatFile = (*ipfsFile)(nil)
+	_ qfs.SeekFile = (*ipfsFile)(nil)
+)
 
 // Read proxies to the response body reader
 func (f ipfsFile) Read(p []byte) (int, error) {
@@ -562,6 +1192,20 @@ func (f ipfsFile) Close() error {
 	return f.r.Close()
 }
 
+// Seek proxies to the underlying reader. unixfs files support seeking,
+// qfs.ErrNotSeekable is returned for readers that don't
+func (f ipfsFile) Seek(offset int64, whence int) (int64, error) {
+	if s, ok := f.r.(io.Seeker); ok {
+		return s.Seek(offset, whence)
+	}
+	return 0, qfs.ErrNotSeekable
+}
+
+// Size returns the size of the unixfs file in bytes, -1 if unknown
+func (f ipfsFile) Size() int64 {
+	return f.size
+}
+
 // IsDirectory satisfies the qfs.File interface
 func (f ipfsFile) IsDirectory() bool {
 	return false
@@ -595,6 +1239,75 @@ func (f ipfsFile) ModTime() time.Time {
 	return time.Time{}
 }
 
+// Stat returns info describing the file
+func (f ipfsFile) Stat() (fs.FileInfo, error) {
+	return qfs.FileInfo(f), nil
+}
+
+// ipfsDir is a unixfs directory. Children are fetched as they're iterated
+type ipfsDir struct {
+	path string
+	dir  files.Directory
+	it   files.DirIterator
+}
+
+var (
+	_ qfs.File     = (*ipfsDir)(nil)
+	_ qfs.StatFile = (*ipfsDir)(nil)
+)
+
+// Read errors, ipfsDir is a directory
+func (d *ipfsDir) Read(p []byte) (int, error) {
+	return 0, qfs.ErrNotFile
+}
+
+// Close releases the directory
+func (d *ipfsDir) Close() error {
+	return d.dir.Close()
+}
+
+// IsDirectory satisfies the qfs.File interface
+func (d *ipfsDir) IsDirectory() bool {
+	return true
+}
+
+// NextFile returns the next child of the directory, or io.EOF when all
+// children have been read
+func (d *ipfsDir) NextFile() (qfs.File, error) {
+	if !d.it.Next() {
+		if err := d.it.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+	return newIPFSFile(d.path+"/"+d.it.Name(), d.it.Node())
+}
+
+// FileName returns the base name of the directory
+func (d *ipfsDir) FileName() string {
+	return filepath.Base(d.path)
+}
+
+// FullPath returns the path used to fetch this directory
+func (d *ipfsDir) FullPath() string {
+	return d.path
+}
+
+// MediaType is a directory mime-type stand-in
+func (d *ipfsDir) MediaType() string {
+	return "application/x-directory"
+}
+
+// ModTime is always zero, ipfs directories are immutable
+func (d *ipfsDir) ModTime() time.Time {
+	return time.Time{}
+}
+
+// Stat returns info describing the directory
+func (d *ipfsDir) Stat() (fs.FileInfo, error) {
+	return qfs.FileInfo(d), nil
+}
+
 // extracted from github.com/ipfs/go-ipfs/cmd/ipfswatch/main.go
 func cmdCtx(node *core.IpfsNode, repoPath string) ipfs_commands.Context {
 	return ipfs_commands.Context{
@@ -3,6 +3,7 @@ package qipfs
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -10,7 +11,11 @@ import (
 	"time"
 
 	"github.com/google/go-cmp/cmp"
+	cid "github.com/ipfs/go-cid"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
 	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/qfstest"
 )
 
 func TestFS(t *testing.T) {
@@ -86,6 +91,54 @@ func TestCreatedWithAPIAddrFS(t *testing.T) {
 	}
 }
 
+func TestAPIAddrsReportBoundPorts(t *testing.T) {
+	ctx, done := context.WithCancel(context.Background())
+	defer done()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	f, err := NewFilesystem(ctx, map[string]interface{}{
+		"path":             path,
+		"enableAPI":        true,
+		"disableBootstrap": true,
+		"api": map[string]interface{}{
+			"addrs":        []string{"/ip4/127.0.0.1/tcp/0"},
+			"gatewayAddrs": []string{"/ip4/127.0.0.1/tcp/0"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fst := f.(*Filestore)
+	// the API is served once the store is online
+	if err := fst.GoOnline(); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, addrs := range map[string][]string{
+		"api":     fst.APIAddrs(),
+		"gateway": fst.GatewayAddrs(),
+	} {
+		if len(addrs) != 1 {
+			t.Fatalf("expected 1 %s address, got: %v", name, addrs)
+		}
+		maddr, err := ma.NewMultiaddr(addrs[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if port, _ := maddr.ValueForProtocol(ma.P_TCP); port == "0" || port == "" {
+			t.Errorf("expected %s address to report the bound port, got: %s", name, maddr)
+		}
+		conn, err := manet.Dial(maddr)
+		if err != nil {
+			t.Errorf("dialing %s address %s: %s", name, maddr, err)
+			continue
+		}
+		conn.Close()
+	}
+}
+
 func BenchmarkRead(b *testing.B) {
 	ctx, done := context.WithCancel(context.Background())
 	defer done()
@@ -265,3 +318,326 @@ func InitTestRepo(t *testing.T) string {
 
 	return path
 }
+
+func TestDeleteRemovesData(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	for _, remove := range []bool{false, true} {
+		fsCtx, closeFS := context.WithCancel(ctx)
+		f, err := NewFilesystem(fsCtx, map[string]interface{}{
+			"path":           path,
+			"removeOnDelete": remove,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		fs := f.(*Filestore)
+
+		key, err := fs.Put(ctx, qfs.NewMemfileBytes("data.txt", []byte(fmt.Sprintf("remove: %t", remove))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := fs.Delete(ctx, key); err != nil {
+			t.Fatal(err)
+		}
+		exists, err := fs.Has(ctx, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if exists == remove {
+			t.Errorf("removeOnDelete %t: expected block to exist after delete to be %t", remove, !remove)
+		}
+
+		// release the repo lock before opening it again
+		closeFS()
+		<-fs.Done()
+	}
+}
+
+func TestStatPathMissingChild(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	f, err := NewFilesystem(ctx, map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := f.(*Filestore)
+
+	root, err := fs.Put(ctx, qfs.NewMemdir("/dir",
+		qfs.NewMemfileBytes("a.txt", []byte("a")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := fs.StatPath(ctx, root+"/a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Exists || info.IsDir || info.Size != 1 {
+		t.Errorf("unexpected info for an existing file: %#v", info)
+	}
+
+	info, err = fs.StatPath(ctx, root+"/missing.txt")
+	if err != nil {
+		t.Fatalf("expected a missing child not to error. got: %s", err)
+	}
+	if info.Exists {
+		t.Errorf("expected a missing child not to exist")
+	}
+}
+
+func TestGetSize(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	f, err := NewFilesystem(ctx, map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := f.(*Filestore)
+
+	file, err := fs.Put(ctx, qfs.NewMemfileBytes("hello.txt", []byte("hello world")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := fs.Put(ctx, qfs.NewMemdir("/dir",
+		qfs.NewMemfileBytes("hello.txt", []byte("hello world")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range []string{file, root + "/hello.txt"} {
+		got, err := fs.Get(ctx, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sf, ok := got.(qfs.SizeFile)
+		if !ok {
+			t.Fatalf("expected %s to implement qfs.SizeFile", p)
+		}
+		if sf.Size() != 11 {
+			t.Errorf("%s size mismatch. want: 11 got: %d", p, sf.Size())
+		}
+		got.Close()
+	}
+}
+
+func TestHashOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	f, err := NewFilesystem(ctx, map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := f.(*Filestore)
+
+	data := []byte("hashed, but not stored")
+	hashed, err := qfs.HashOnly(ctx, fs, qfs.NewMemfileBytes("a.txt", data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if has, err := fs.Has(ctx, hashed); err != nil {
+		t.Fatal(err)
+	} else if has {
+		t.Errorf("expected HashOnly to store nothing")
+	}
+
+	put, err := fs.Put(ctx, qfs.NewMemfileBytes("a.txt", data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashed != put {
+		t.Errorf("path mismatch. HashOnly: %s Put: %s", hashed, put)
+	}
+
+	link := qfs.NewMemsymlink("link", "a.txt")
+	if hashed, err = fs.HashOnly(ctx, link); err != nil {
+		t.Fatal(err)
+	}
+	if put, err = fs.Put(ctx, qfs.NewMemsymlink("link", "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if hashed != put {
+		t.Errorf("symlink path mismatch. HashOnly: %s Put: %s", hashed, put)
+	}
+}
+
+func TestSymlinkRoundTrip(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	f, err := NewFilesystem(ctx, map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := f.(*Filestore)
+
+	tree := func() qfs.File {
+		return qfs.NewMemdir("/a",
+			qfs.NewMemfileBytes("b.txt", []byte("b")),
+			qfs.NewMemdir("c",
+				qfs.NewMemsymlink("link", "../b.txt"),
+			),
+		)
+	}
+	root, err := fs.Put(ctx, tree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed, err := fs.HashOnly(ctx, tree())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hashed != root {
+		t.Errorf("path mismatch. HashOnly: %s Put: %s", hashed, root)
+	}
+
+	got, err := fs.Get(ctx, root+"/c/link")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target, ok := qfs.IsSymlink(got); !ok || target != "../b.txt" {
+		t.Errorf("expected a symlink to ../b.txt. got: %q, %t", target, ok)
+	}
+
+	dir, err := fs.Get(ctx, root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{}
+	if err := qfs.Walk(dir, func(f qfs.File) error {
+		if target, ok := qfs.IsSymlink(f); ok {
+			links[f.FullPath()] = target
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[root+"/c/link"] != "../b.txt" {
+		t.Errorf("expected walking the tree to find the link. got: %v", links)
+	}
+}
+
+func TestGoOnlineKeepsSubscribers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	f, err := NewFilesystem(ctx, map[string]interface{}{
+		"path":             path,
+		"disableBootstrap": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fst := f.(*Filestore)
+
+	puts := 0
+	unsubscribe := fst.Subscribe(func(e qfs.Event) {
+		if e.Type == qfs.EventFilePut {
+			puts++
+		}
+	})
+	defer unsubscribe()
+
+	if err := fst.GoOnline(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fst.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("a"))); err != nil {
+		t.Fatal(err)
+	}
+	if puts != 1 {
+		t.Errorf("expected subscribers to outlive GoOnline. got %d put events", puts)
+	}
+}
+
+func TestPathSemantics(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	path := InitTestRepo(t)
+	defer os.RemoveAll(path)
+
+	fs, err := NewFilesystem(ctx, map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := fs.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	qfstest.AssertPathSemantics(t, fs, root+"/b.txt")
+}
+
+func TestBackupRestore(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	newFilestore := func() *Filestore {
+		path := InitTestRepo(t)
+		t.Cleanup(func() { os.RemoveAll(path) })
+		fs, err := NewFilesystem(ctx, map[string]interface{}{"path": path})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fs.(*Filestore)
+	}
+	src, dst := newFilestore(), newFilestore()
+
+	root, err := src.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("b.txt", []byte("b")),
+		qfs.NewMemdir("c", qfs.NewMemfileBytes("d.txt", []byte("d"))),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := cid.Parse(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups := qfs.NewMemFS()
+	manifestPath, err := qfs.Backup(ctx, src, backups, []cid.Cid{id}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots, err := qfs.Restore(ctx, backups, manifestPath, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 || !roots[0].Equals(id) {
+		t.Errorf("expected the backup root to be restored. got: %v", roots)
+	}
+
+	f, err := dst.Get(ctx, root+"/c/d.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(f); s != "d" {
+		t.Errorf("contents mismatch. want: %q got: %q", "d", s)
+	}
+}
@@ -0,0 +1,77 @@
+package qipfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	lockfile "github.com/ipfs/go-fs-lock"
+	"github.com/qri-io/qfs"
+)
+
+// lockPollInterval is the time to wait between attempts to acquire a lock
+// held by someone else
+const lockPollInterval = time.Millisecond * 10
+
+// errNoLockDir is returned when locking a filestore that has no local repo
+// to hold lock files
+var errNoLockDir = errors.New("qipfs: locks require a local repo path")
+
+// Lock acquires a named advisory lock, stored as a lock file within the IPFS
+// repo directory. Lock files sit alongside the repo's own "repo.lock", and
+// use the same locking mechanism, so any process with access to the repo can
+// cooperate through them. Lock polls until the lock is acquired or ctx is
+// done
+func (fst *Filestore) Lock(ctx context.Context, name string) error {
+	if fst.cfg == nil || fst.cfg.Path == "" {
+		return errNoLockDir
+	}
+
+	for {
+		lk, err := lockfile.Lock(fst.cfg.Path, lockFilename(name))
+		if err == nil {
+			fst.locksLk.Lock()
+			if fst.locks == nil {
+				fst.locks = map[string]io.Closer{}
+			}
+			fst.locks[name] = lk
+			fst.locksLk.Unlock()
+			return nil
+		}
+		if !errors.As(err, new(lockfile.LockedError)) {
+			return err
+		}
+
+		select {
+		case <-time.After(lockPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Unlock releases a lock acquired with Lock
+func (fst *Filestore) Unlock(name string) error {
+	fst.locksLk.Lock()
+	defer fst.locksLk.Unlock()
+	lk, ok := fst.locks[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", qfs.ErrNotLocked, name)
+	}
+	delete(fst.locks, name)
+	return lk.Close()
+}
+
+// Locked reports whether a named lock is currently held by any process
+func (fst *Filestore) Locked(name string) (bool, error) {
+	if fst.cfg == nil || fst.cfg.Path == "" {
+		return false, errNoLockDir
+	}
+	return lockfile.Locked(fst.cfg.Path, lockFilename(name))
+}
+
+func lockFilename(name string) string {
+	return fmt.Sprintf("qfs_%s.lock", name)
+}
@@ -0,0 +1,482 @@
+// Package mfsfs is a mutable filesystem backed by the Files API (MFS) of an
+// IPFS node. Paths are human-readable names like /ipfs-files/photos/cat.jpg
+// instead of hashes. Content is stored as unixfs in the node's blockstore, &
+// every change updates the node's MFS root, so files written here show up in
+// "ipfs files ls" & are safe from garbage collection
+package mfsfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	gopath "path"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	files "github.com/ipfs/go-ipfs-files"
+	"github.com/ipfs/go-ipfs/core"
+	"github.com/ipfs/go-ipfs/core/coreapi"
+	format "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log"
+	mfs "github.com/ipfs/go-mfs"
+	unixfile "github.com/ipfs/go-unixfs/file"
+	coreiface "github.com/ipfs/interface-go-ipfs-core"
+	caopts "github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/qipfs"
+)
+
+// FilestoreType uniquely identifies this filestore
+const FilestoreType = "ipfs-files"
+
+var log = logging.Logger("mfsfs")
+
+// FS is a qfs.Filesystem of paths in the MFS root of an IPFS node
+type FS struct {
+	// node returns the current node, which is looked up on every operation
+	// because stores replace their node when they go online
+	node func() *core.IpfsNode
+
+	// lk serializes changes, which take several MFS operations each
+	lk sync.Mutex
+
+	// apiLk guards the CoreAPI built for apiNode
+	apiLk   sync.Mutex
+	apiNode *core.IpfsNode
+	capi    coreiface.CoreAPI
+}
+
+// putCount numbers the temporary paths files are written to before they're
+// moved into place
+var putCount uint64
+
+// compile-time assertions
+var (
+	_ qfs.Filesystem = (*FS)(nil)
+	_ qfs.ListingFS  = (*FS)(nil)
+	_ qfs.RenameFS   = (*FS)(nil)
+)
+
+// NewFilesystemFromNode creates a filesystem over the MFS root of node. The
+// node must be in-process, MFS isn't available over the HTTP API
+func NewFilesystemFromNode(node *core.IpfsNode) (*FS, error) {
+	return newFS(func() *core.IpfsNode { return node })
+}
+
+// NewFilesystemFromStore creates a filesystem over the MFS root of the node
+// backing fst, returning qfs.ErrUnsupported if fst is backed by the HTTP API.
+// The node is looked up on every operation, so the filesystem follows fst
+// when it goes online
+func NewFilesystemFromStore(fst *qipfs.Filestore) (*FS, error) {
+	return newFS(fst.Node)
+}
+
+func newFS(node func() *core.IpfsNode) (*FS, error) {
+	fs := &FS{node: node}
+	if _, _, err := fs.nodeAPI(); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+// nodeAPI returns the current node & a CoreAPI for it. The CoreAPI is rebuilt
+// when the node changes
+func (fs *FS) nodeAPI() (*core.IpfsNode, coreiface.CoreAPI, error) {
+	node := fs.node()
+	if node == nil || node.FilesRoot == nil {
+		return nil, nil, fmt.Errorf("%w: node has no MFS root", qfs.ErrUnsupported)
+	}
+	fs.apiLk.Lock()
+	defer fs.apiLk.Unlock()
+	if fs.apiNode != node {
+		capi, err := coreapi.NewCoreAPI(node)
+		if err != nil {
+			return nil, nil, err
+		}
+		fs.apiNode, fs.capi = node, capi
+	}
+	return node, fs.capi, nil
+}
+
+// Type distinguishes this filesystem from others by a unique string prefix
+func (fs *FS) Type() string {
+	return FilestoreType
+}
+
+// Has returns whether a file or directory exists at path
+func (fs *FS) Has(ctx context.Context, path string) (bool, error) {
+	p, err := mfsPath(path)
+	if err != nil {
+		return false, err
+	}
+	node, _, err := fs.nodeAPI()
+	if err != nil {
+		return false, err
+	}
+	if _, err := lookup(node.FilesRoot, p); err != nil {
+		if errors.Is(err, qfs.ErrNotFound) || errors.Is(err, qfs.ErrNotDirectory) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Get opens the file or directory at path. Directories are a snapshot, later
+// changes aren't visible while iterating
+func (fs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
+	p, err := mfsPath(path)
+	if err != nil {
+		return nil, err
+	}
+	node, _, err := fs.nodeAPI()
+	if err != nil {
+		return nil, err
+	}
+	fsn, err := lookup(node.FilesRoot, p)
+	if err != nil {
+		return nil, err
+	}
+	nd, err := fsn.GetNode()
+	if err != nil {
+		return nil, err
+	}
+	n, err := unixfile.NewUnixfsFile(ctx, node.DAG, nd)
+	if err != nil {
+		return nil, err
+	}
+	return qipfs.NewFile(strings.TrimSuffix(path, "/"), n)
+}
+
+// List returns the entries of the directory at path, implementing the
+// qfs.ListingFS interface
+func (fs *FS) List(ctx context.Context, path string) ([]qfs.DirEntry, error) {
+	p, err := mfsPath(path)
+	if err != nil {
+		return nil, err
+	}
+	node, _, err := fs.nodeAPI()
+	if err != nil {
+		return nil, err
+	}
+	fsn, err := lookup(node.FilesRoot, p)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := fsn.(*mfs.Directory)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", qfs.ErrNotDirectory, path)
+	}
+	listing, err := dir.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]qfs.DirEntry, 0, len(listing))
+	for _, l := range listing {
+		entry := qfs.DirEntry{
+			Name:  l.Name,
+			Path:  joinPath(p, l.Name),
+			Size:  -1,
+			IsDir: mfs.NodeType(l.Type) == mfs.TDir,
+		}
+		if !entry.IsDir {
+			entry.Size = l.Size
+		}
+		entries = append(entries, entry)
+	}
+	qfs.SortDirEntries(entries)
+	return entries, nil
+}
+
+// Put writes file to its path, creating parent directories as needed &
+// replacing anything already at the path. Directories are written with all
+// their children. File is written to a temporary path & moved into place
+// once complete, so a failed Put leaves the path as it was. MFS can't read
+// symlinks, so they're unsupported
+func (fs *FS) Put(ctx context.Context, file qfs.File) (string, error) {
+	p, err := mfsPath(file.FullPath())
+	if err != nil {
+		return "", err
+	}
+	if p == "/" {
+		return "", fmt.Errorf("%w: can't replace the MFS root", qfs.ErrInvalidPath)
+	}
+	node, capi, err := fs.nodeAPI()
+	if err != nil {
+		return "", err
+	}
+
+	fs.lk.Lock()
+	defer fs.lk.Unlock()
+	root := node.FilesRoot
+	tmpName := fmt.Sprintf(".qfs_put_%d", atomic.AddUint64(&putCount, 1))
+	err = fs.put(ctx, node.DAG, capi, root, "/"+tmpName, file)
+	if err == nil {
+		err = move(root, root.GetDirectory(), tmpName, p)
+	}
+	if err != nil {
+		if rmErr := unlink(root.GetDirectory(), tmpName); rmErr != nil {
+			log.Errorf("removing temporary path %q: %s", tmpName, rmErr)
+		}
+		return "", err
+	}
+	if err := flush(ctx, root); err != nil {
+		return "", err
+	}
+	return joinPath(p), nil
+}
+
+func (fs *FS) put(ctx context.Context, dag format.DAGService, capi coreiface.CoreAPI, root *mfs.Root, p string, file qfs.File) error {
+	if _, ok := qfs.IsSymlink(file); ok {
+		return fmt.Errorf("%w: MFS can't read symlinks back, %q", qfs.ErrUnsupported, file.FullPath())
+	}
+	dirPath, name := gopath.Split(p)
+	parent, err := mkdirAll(root, dirPath)
+	if err != nil {
+		return err
+	}
+	if err := unlink(parent, name); err != nil {
+		return err
+	}
+
+	if file.IsDirectory() {
+		if _, err := parent.Mkdir(name); err != nil {
+			return err
+		}
+		for {
+			child, err := file.NextFile()
+			if errors.Is(err, io.EOF) {
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if err := fs.put(ctx, dag, capi, root, gopath.Join(p, child.FileName()), child); err != nil {
+				return err
+			}
+		}
+	}
+
+	// MFS references the content, so it doesn't need a pin to survive
+	// garbage collection
+	added, err := capi.Unixfs().Add(ctx, files.NewReaderFile(file), caopts.Unixfs.Pin(false), caopts.Unixfs.CidVersion(0))
+	if err != nil {
+		return err
+	}
+	nd, err := dag.Get(ctx, added.Cid())
+	if err != nil {
+		return err
+	}
+	return parent.AddChild(name, nd)
+}
+
+// Delete removes the file or directory at path, including everything within
+// a directory
+func (fs *FS) Delete(ctx context.Context, path string) error {
+	p, err := mfsPath(path)
+	if err != nil {
+		return err
+	}
+	if p == "/" {
+		return fmt.Errorf("%w: can't delete the MFS root", qfs.ErrInvalidPath)
+	}
+	node, _, err := fs.nodeAPI()
+	if err != nil {
+		return err
+	}
+
+	fs.lk.Lock()
+	defer fs.lk.Unlock()
+	dirPath, name := gopath.Split(p)
+	parent, err := lookupDir(node.FilesRoot, dirPath)
+	if err != nil {
+		return err
+	}
+	if _, err := child(parent, name); err != nil {
+		return fmt.Errorf("%w: %q", err, path)
+	}
+	if err := parent.Unlink(name); err != nil {
+		return err
+	}
+	return flush(ctx, node.FilesRoot)
+}
+
+// Rename moves the file or directory at from to to, creating parent
+// directories of to as needed & replacing anything already there. Content
+// isn't copied, implementing the qfs.RenameFS interface
+func (fs *FS) Rename(ctx context.Context, from, to string) error {
+	src, err := mfsPath(from)
+	if err != nil {
+		return err
+	}
+	dst, err := mfsPath(to)
+	if err != nil {
+		return err
+	}
+	if src == "/" || dst == "/" {
+		return fmt.Errorf("%w: can't move the MFS root", qfs.ErrInvalidPath)
+	}
+	if strings.HasPrefix(dst, src+"/") {
+		return fmt.Errorf("%w: can't move %q inside itself", qfs.ErrInvalidPath, from)
+	}
+	node, _, err := fs.nodeAPI()
+	if err != nil {
+		return err
+	}
+
+	fs.lk.Lock()
+	defer fs.lk.Unlock()
+	root := node.FilesRoot
+	srcDirPath, srcName := gopath.Split(src)
+	srcDir, err := lookupDir(root, srcDirPath)
+	if err != nil {
+		return err
+	}
+	if _, err := child(srcDir, srcName); err != nil {
+		return fmt.Errorf("%w: %q", err, from)
+	}
+	if src == dst {
+		return nil
+	}
+	if err := move(root, srcDir, srcName, dst); err != nil {
+		return err
+	}
+	return flush(ctx, root)
+}
+
+// move replaces the entry at dst with the entry srcName of srcDir, creating
+// parent directories of dst as needed
+func move(root *mfs.Root, srcDir *mfs.Directory, srcName, dst string) error {
+	fsn, err := child(srcDir, srcName)
+	if err != nil {
+		return err
+	}
+	nd, err := fsn.GetNode()
+	if err != nil {
+		return err
+	}
+
+	dstDirPath, dstName := gopath.Split(dst)
+	dstDir, err := mkdirAll(root, dstDirPath)
+	if err != nil {
+		return err
+	}
+	if err := unlink(dstDir, dstName); err != nil {
+		return err
+	}
+	if err := dstDir.AddChild(dstName, nd); err != nil {
+		return err
+	}
+	return srcDir.Unlink(srcName)
+}
+
+// flush writes pending changes up to the MFS root & waits for the node to
+// record the new root
+func flush(ctx context.Context, root *mfs.Root) error {
+	if _, err := mfs.FlushPath(ctx, root, "/"); err != nil {
+		log.Errorf("flushing MFS root: %s", err)
+		return err
+	}
+	return nil
+}
+
+// lookup returns the MFS node at p
+func lookup(root *mfs.Root, p string) (mfs.FSNode, error) {
+	var cur mfs.FSNode = root.GetDirectory()
+	for _, name := range strings.Split(strings.Trim(p, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		dir, ok := cur.(*mfs.Directory)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", qfs.ErrNotDirectory, joinPath(p))
+		}
+		next, err := child(dir, name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, joinPath(p))
+		}
+		cur = next
+	}
+	return cur, nil
+}
+
+// lookupDir returns the MFS directory at p
+func lookupDir(root *mfs.Root, p string) (*mfs.Directory, error) {
+	fsn, err := lookup(root, p)
+	if err != nil {
+		return nil, err
+	}
+	dir, ok := fsn.(*mfs.Directory)
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", qfs.ErrNotDirectory, joinPath(p))
+	}
+	return dir, nil
+}
+
+// mkdirAll returns the MFS directory at p, creating it & any missing parents
+func mkdirAll(root *mfs.Root, p string) (*mfs.Directory, error) {
+	cur := root.GetDirectory()
+	for _, name := range strings.Split(strings.Trim(p, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		fsn, err := child(cur, name)
+		if errors.Is(err, qfs.ErrNotFound) {
+			if cur, err = cur.Mkdir(name); err != nil {
+				return nil, err
+			}
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		dir, ok := fsn.(*mfs.Directory)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", qfs.ErrNotDirectory, joinPath(p))
+		}
+		cur = dir
+	}
+	return cur, nil
+}
+
+// child returns the entry name of dir, translating MFS's not-exist error to
+// qfs.ErrNotFound
+func child(dir *mfs.Directory, name string) (mfs.FSNode, error) {
+	fsn, err := dir.Child(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, qfs.ErrNotFound
+	}
+	return fsn, err
+}
+
+// unlink removes the entry name from dir if it exists
+func unlink(dir *mfs.Directory, name string) error {
+	if _, err := child(dir, name); errors.Is(err, qfs.ErrNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return dir.Unlink(name)
+}
+
+// mfsPath converts a /ipfs-files/ path to an absolute path within MFS
+func mfsPath(p string) (string, error) {
+	prefix := "/" + FilestoreType
+	if p != prefix && !strings.HasPrefix(p, prefix+"/") {
+		return "", fmt.Errorf("%w: %q doesn't start with %s/", qfs.ErrInvalidPath, p, prefix)
+	}
+	rel := strings.TrimPrefix(p, prefix)
+	for _, name := range strings.Split(rel, "/") {
+		if name == "." || name == ".." {
+			return "", fmt.Errorf("%w: %q contains a relative path element", qfs.ErrInvalidPath, p)
+		}
+	}
+	return gopath.Clean("/" + rel), nil
+}
+
+// joinPath converts an MFS path back to a /ipfs-files/ path
+func joinPath(elem ...string) string {
+	return gopath.Join(append([]string{"/" + FilestoreType}, elem...)...)
+}
@@ -0,0 +1,185 @@
+package mfsfs
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/qipfs"
+)
+
+func TestFS(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	path, err := ioutil.TempDir("", "qfs_mfsfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+	if err := qipfs.InitRepo(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	f, err := qipfs.NewFilesystem(ctx, map[string]interface{}{"path": path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs, err := NewFilesystemFromStore(f.(*qipfs.Filestore))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := fs.Put(ctx, qfs.NewMemfileBytes("/ipfs-files/photos/cat.txt", []byte("meow")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "/ipfs-files/photos/cat.txt" {
+		t.Errorf("expected put to return the file's path. got: %q", key)
+	}
+	if _, err := fs.Put(ctx, qfs.NewMemfileBytes(key, []byte("purr"))); err != nil {
+		t.Fatal(err)
+	}
+	got, err := fs.Get(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(got); s != "purr" {
+		t.Errorf("expected put to replace the existing file. got: %q", s)
+	}
+
+	if _, err := fs.Put(ctx, qfs.NewMemdir("/ipfs-files/photos/dogs",
+		qfs.NewMemfileBytes("rex.txt", []byte("woof")),
+	)); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := fs.List(ctx, "/ipfs-files/photos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Path != key || entries[0].Size != 4 || !entries[1].IsDir {
+		t.Errorf("unexpected listing: %v", entries)
+	}
+	if _, err := fs.List(ctx, key); !errors.Is(err, qfs.ErrNotDirectory) {
+		t.Errorf("expected listing a file to return ErrNotDirectory. got: %v", err)
+	}
+
+	if err := qfs.Rename(ctx, fs, "/ipfs-files/photos/dogs", "/ipfs-files/pets/dogs"); err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := fs.Has(ctx, "/ipfs-files/photos/dogs"); err != nil || exists {
+		t.Errorf("expected rename to remove the old path. got: %t, %v", exists, err)
+	}
+	got, err = fs.Get(ctx, "/ipfs-files/pets/dogs/rex.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(got); s != "woof" {
+		t.Errorf("contents mismatch after rename. got: %q", s)
+	}
+	if err := fs.Rename(ctx, "/ipfs-files/pets", "/ipfs-files/pets/more"); !errors.Is(err, qfs.ErrInvalidPath) {
+		t.Errorf("expected moving a directory inside itself to return ErrInvalidPath. got: %v", err)
+	}
+
+	if err := fs.Delete(ctx, "/ipfs-files/pets"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Get(ctx, "/ipfs-files/pets/dogs/rex.txt"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected deleted path to return ErrNotFound. got: %v", err)
+	}
+	if err := fs.Delete(ctx, "/ipfs-files/pets"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected deleting a missing path to return ErrNotFound. got: %v", err)
+	}
+	if _, err := fs.Get(ctx, "/ipfs-files/photos/../cat.txt"); !errors.Is(err, qfs.ErrInvalidPath) {
+		t.Errorf("expected relative path to return ErrInvalidPath. got: %v", err)
+	}
+	if _, err := fs.Put(ctx, qfs.NewMemsymlink("/ipfs-files/link", "photos")); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected putting a symlink to be unsupported. got: %v", err)
+	}
+}
+
+func TestPutFailureKeepsPath(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	fs := newTestFS(ctx, t, nil)
+
+	key, err := fs.Put(ctx, qfs.NewMemfileBytes("/ipfs-files/a/b.txt", []byte("b")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fs.Put(ctx, qfs.NewMemdir("/ipfs-files/a",
+		qfs.NewMemfileBytes("c.txt", []byte("c")),
+		qfs.NewMemsymlink("link", "c.txt"),
+	)); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Fatalf("expected putting a symlink to be unsupported. got: %v", err)
+	}
+
+	got, err := fs.Get(ctx, key)
+	if err != nil {
+		t.Fatalf("expected a failed put to keep the existing path. got: %v", err)
+	}
+	if _, s := qfs.FileString(got); s != "b" {
+		t.Errorf("contents mismatch after failed put. got: %q", s)
+	}
+	entries, err := fs.List(ctx, "/ipfs-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "a" {
+		t.Errorf("expected a failed put to leave no temporary paths. got: %v", entries)
+	}
+}
+
+func TestFollowsStoreOnline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	var fst *qipfs.Filestore
+	fs := newTestFS(ctx, t, func(f *qipfs.Filestore) { fst = f })
+
+	if err := fst.GoOnline(); err != nil {
+		t.Fatal(err)
+	}
+	key, err := fs.Put(ctx, qfs.NewMemfileBytes("/ipfs-files/a.txt", []byte("a")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	online, err := NewFilesystemFromNode(fst.Node())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, err := online.Has(ctx, key); err != nil || !exists {
+		t.Errorf("expected put to write to the online node. got: %t, %v", exists, err)
+	}
+}
+
+// newTestFS creates a filesystem over a new repo, passing the store to
+// withStore if it's non-nil
+func newTestFS(ctx context.Context, t *testing.T, withStore func(*qipfs.Filestore)) *FS {
+	t.Helper()
+	path, err := ioutil.TempDir("", "qfs_mfsfs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(path) })
+	if err := qipfs.InitRepo(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	f, err := qipfs.NewFilesystem(ctx, map[string]interface{}{
+		"path":             path,
+		"disableBootstrap": true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withStore != nil {
+		withStore(f.(*qipfs.Filestore))
+	}
+	fs, err := NewFilesystemFromStore(f.(*qipfs.Filestore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs
+}
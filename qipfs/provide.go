@@ -0,0 +1,83 @@
+package qipfs
+
+import (
+	"context"
+	"fmt"
+
+	coreiface "github.com/ipfs/interface-go-ipfs-core"
+	caopts "github.com/ipfs/interface-go-ipfs-core/options"
+	"github.com/ipfs/interface-go-ipfs-core/path"
+	"github.com/qri-io/qfs"
+)
+
+// ProvideMode controls how content written to a filestore is announced to
+// the DHT
+type ProvideMode int
+
+const (
+	// ProvideDefault leaves announcements to the node, which queues new roots
+	// to be provided in the background
+	ProvideDefault ProvideMode = iota
+	// ProvideAlways announces new roots & all of their blocks before a write
+	// returns, failing the write if the announcement fails
+	ProvideAlways
+	// ProvideNever writes without announcing, for private content. The node's
+	// reprovider may still announce stored blocks later unless the repo's
+	// Reprovider.Strategy is set to exclude them. Requires an embedded node
+	ProvideNever
+)
+
+// provideCtxKey is the context key for per-write provide modes
+type provideCtxKey struct{}
+
+// WithProvideMode returns a copy of ctx that sets how content written with
+// ctx by Put & Pin is announced, overriding StoreCfg.Provide
+func WithProvideMode(ctx context.Context, mode ProvideMode) context.Context {
+	return context.WithValue(ctx, provideCtxKey{}, mode)
+}
+
+// provideMode returns the mode set on ctx, falling back to the configured mode
+func (fst *Filestore) provideMode(ctx context.Context) ProvideMode {
+	if mode, ok := ctx.Value(provideCtxKey{}).(ProvideMode); ok {
+		return mode
+	}
+	if fst.cfg != nil {
+		return fst.cfg.Provide
+	}
+	return ProvideDefault
+}
+
+// writeAPI returns the core API to write with. ProvideNever writes use an
+// offline API, which swaps in a provider that announces nothing
+func (fst *Filestore) writeAPI(mode ProvideMode) (coreiface.CoreAPI, error) {
+	if mode != ProvideNever {
+		return fst.capi, nil
+	}
+	if fst.node == nil {
+		return nil, fmt.Errorf("%w: suppressing provides requires an embedded IPFS node", qfs.ErrUnsupported)
+	}
+	return fst.capi.WithOptions(caopts.Api.Offline(true))
+}
+
+// provide announces p & every block beneath it if mode is ProvideAlways
+func (fst *Filestore) provide(ctx context.Context, mode ProvideMode, p path.Path) error {
+	if mode != ProvideAlways {
+		return nil
+	}
+	if err := fst.capi.Dht().Provide(ctx, p, caopts.Dht.Recursive(true)); err != nil {
+		return fmt.Errorf("providing %s: %w", p, err)
+	}
+	return nil
+}
+
+// Reprovide announces all content the node's reprovider strategy covers now,
+// instead of waiting for the next reprovide interval
+func (fst *Filestore) Reprovide(ctx context.Context) error {
+	if err := fst.closed(); err != nil {
+		return err
+	}
+	if fst.node == nil || fst.node.Provider == nil {
+		return fmt.Errorf("%w: reproviding requires an embedded IPFS node", qfs.ErrUnsupported)
+	}
+	return fst.node.Provider.Reprovide(ctx)
+}
@@ -0,0 +1,27 @@
+package qipfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestProvideMode(t *testing.T) {
+	ctx := context.Background()
+	fst := &Filestore{ctx: ctx, cfg: &StoreCfg{Provide: ProvideAlways}}
+
+	if got := fst.provideMode(ctx); got != ProvideAlways {
+		t.Errorf("expected configured mode to apply. got: %d", got)
+	}
+	if got := fst.provideMode(WithProvideMode(ctx, ProvideNever)); got != ProvideNever {
+		t.Errorf("expected context mode to override configured mode. got: %d", got)
+	}
+	if _, err := fst.writeAPI(ProvideNever); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected suppressing provides without a node to return ErrUnsupported. got: %v", err)
+	}
+	if err := fst.Reprovide(ctx); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected reproviding without a node to return ErrUnsupported. got: %v", err)
+	}
+}
@@ -0,0 +1,86 @@
+package qipfs
+
+import (
+	"context"
+	"fmt"
+
+	bitswap "github.com/ipfs/go-bitswap"
+	"github.com/libp2p/go-libp2p-core/metrics"
+	"github.com/qri-io/qfs"
+)
+
+// Bandwidth is a traffic total in bytes & the current rate in bytes per
+// second, in each direction
+type Bandwidth struct {
+	TotalIn  int64
+	TotalOut int64
+	RateIn   float64
+	RateOut  float64
+}
+
+func bandwidthFromStats(s metrics.Stats) Bandwidth {
+	return Bandwidth{
+		TotalIn:  s.TotalIn,
+		TotalOut: s.TotalOut,
+		RateIn:   s.RateIn,
+		RateOut:  s.RateOut,
+	}
+}
+
+// NetworkStats describes block exchange & bandwidth use of an IPFS node.
+// Comparing these with storage timings tells a slow store apart from a slow
+// network
+type NetworkStats struct {
+	// WantlistSize is the number of blocks the node is waiting on
+	WantlistSize int
+	// BitswapPeers is the number of peers the node is exchanging blocks with
+	BitswapPeers            int
+	BlocksReceived          uint64
+	BlocksSent              uint64
+	DataReceived            uint64
+	DataSent                uint64
+	DuplicateBlocksReceived uint64
+	// Bandwidth is the node's total bandwidth across all protocols
+	Bandwidth Bandwidth
+	// PeerBandwidth is bandwidth broken down by peer ID
+	PeerBandwidth map[string]Bandwidth
+}
+
+// NetworkStats reports bitswap & bandwidth statistics for the embedded IPFS
+// node. Bitswap stats are zero when the node is offline, bandwidth stats are
+// zero if bandwidth metrics are disabled. Filestores backed by the HTTP API
+// don't have access to node internals & return qfs.ErrUnsupported
+func (fst *Filestore) NetworkStats(ctx context.Context) (NetworkStats, error) {
+	stats := NetworkStats{}
+	if err := fst.closed(); err != nil {
+		return stats, err
+	}
+	if fst.node == nil {
+		return stats, fmt.Errorf("%w: network stats require an embedded IPFS node", qfs.ErrUnsupported)
+	}
+
+	if bs, ok := fst.node.Exchange.(*bitswap.Bitswap); ok {
+		st, err := bs.Stat()
+		if err != nil {
+			return stats, err
+		}
+		stats.WantlistSize = len(st.Wantlist)
+		stats.BitswapPeers = len(st.Peers)
+		stats.BlocksReceived = st.BlocksReceived
+		stats.BlocksSent = st.BlocksSent
+		stats.DataReceived = st.DataReceived
+		stats.DataSent = st.DataSent
+		stats.DuplicateBlocksReceived = st.DupBlksReceived
+	}
+
+	if rep := fst.node.Reporter; rep != nil {
+		stats.Bandwidth = bandwidthFromStats(rep.GetBandwidthTotals())
+		byPeer := rep.GetBandwidthByPeer()
+		stats.PeerBandwidth = make(map[string]Bandwidth, len(byPeer))
+		for id, s := range byPeer {
+			stats.PeerBandwidth[id.Pretty()] = bandwidthFromStats(s)
+		}
+	}
+
+	return stats, ctx.Err()
+}
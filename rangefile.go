@@ -0,0 +1,92 @@
+package qfs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+)
+
+// RangeGetter is an opt-in interface for filesystems that can fetch part of a
+// file without reading the whole thing
+type RangeGetter interface {
+	// GetRange fetches length bytes of the file at path, starting at offset.
+	// A negative length reads to the end of the file
+	GetRange(ctx context.Context, path string, offset, length int64) (File, error)
+}
+
+// GetRange fetches part of a file, using fs's GetRange method if fs
+// implements RangeGetter, and falling back to NewRangeFile over a full Get if
+// not. A negative length reads to the end of the file
+func GetRange(ctx context.Context, fs Filesystem, path string, offset, length int64) (File, error) {
+	if rg, ok := fs.(RangeGetter); ok {
+		return rg.GetRange(ctx, path, offset, length)
+	}
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRangeFile(f, offset, length)
+}
+
+// NewRangeFile limits reads of f to length bytes starting at offset. Files
+// that implement SeekFile are seeked to offset, other files have offset bytes
+// read & discarded. A negative length reads to the end of the file. Closing
+// the returned file closes f, f is closed on error
+func NewRangeFile(f File, offset, length int64) (File, error) {
+	if f.IsDirectory() {
+		f.Close()
+		return nil, ErrNotFile
+	}
+
+	if offset > 0 {
+		seeked := false
+		if sf, ok := f.(SeekFile); ok {
+			if _, err := sf.Seek(offset, io.SeekStart); err == nil {
+				seeked = true
+			} else if err != ErrNotSeekable {
+				f.Close()
+				return nil, err
+			}
+		}
+		if !seeked {
+			if _, err := io.CopyN(ioutil.Discard, f, offset); err != nil && err != io.EOF {
+				f.Close()
+				return nil, err
+			}
+		}
+	}
+
+	rf := &rangeFile{File: f, r: f, size: -1}
+	if sf, ok := f.(SizeFile); ok && sf.Size() >= 0 {
+		rf.size = sf.Size() - offset
+		if rf.size < 0 {
+			rf.size = 0
+		}
+	}
+	if length >= 0 {
+		rf.r = io.LimitReader(f, length)
+		if length < rf.size {
+			rf.size = length
+		}
+	}
+	return rf, nil
+}
+
+// rangeFile is a window into another file
+type rangeFile struct {
+	File
+	r    io.Reader
+	size int64
+}
+
+var _ SizeFile = (*rangeFile)(nil)
+
+// Read reads from within the range
+func (f *rangeFile) Read(p []byte) (int, error) {
+	return f.r.Read(p)
+}
+
+// Size returns the length of the range, -1 if unknown
+func (f *rangeFile) Size() int64 {
+	return f.size
+}
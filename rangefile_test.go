@@ -0,0 +1,58 @@
+package qfs
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func TestGetRange(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	path, err := fs.Put(ctx, NewMemfileBytes("a.txt", []byte("hello world")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		offset, length int64
+		expect         string
+	}{
+		{0, 5, "hello"},
+		{6, 5, "world"},
+		{6, -1, "world"},
+		{6, 100, "world"},
+		{20, 5, ""},
+	}
+
+	for _, c := range cases {
+		f, err := GetRange(ctx, fs, path, c.offset, c.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.expect != string(data) {
+			t.Errorf("range [%d,%d] mismatch. want: %q got: %q", c.offset, c.length, c.expect, string(data))
+		}
+		if size := f.(SizeFile).Size(); size != int64(len(c.expect)) {
+			t.Errorf("range [%d,%d] size mismatch. want: %d got: %d", c.offset, c.length, len(c.expect), size)
+		}
+	}
+}
+
+func TestNewRangeFileUnseekable(t *testing.T) {
+	f, err := NewRangeFile(NewMemfileReader("a.txt", bytes.NewBufferString("hello world")), 6, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(f); s != "wor" {
+		t.Errorf("range mismatch. want: %q got: %q", "wor", s)
+	}
+	if size := f.(SizeFile).Size(); size != -1 {
+		t.Errorf("expected unknown size for range of unsized file. got: %d", size)
+	}
+}
@@ -0,0 +1,164 @@
+package qfs
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ReaderAtFile is a file backed by an io.ReaderAt, like a memory-mapped file
+// or a remote object fetched with range requests. Reads go straight to the
+// ReaderAt at the file's current offset, so nothing beyond the caller's
+// buffer is held in memory. ReadAt never moves the offset, so a ReaderAtFile
+// can be read again by seeking back to the start, and any number of files
+// can share one ReaderAt. The caller owns the ReaderAt & closes it once
+// every file reading from it is done
+type ReaderAtFile struct {
+	lk      sync.Mutex
+	ra      io.ReaderAt
+	size    int64
+	off     int64
+	path    string
+	modTime time.Time
+}
+
+var (
+	_ File          = (*ReaderAtFile)(nil)
+	_ SizeFile      = (*ReaderAtFile)(nil)
+	_ SeekFile      = (*ReaderAtFile)(nil)
+	_ StatFile      = (*ReaderAtFile)(nil)
+	_ PathSetter    = (*ReaderAtFile)(nil)
+	_ ModTimeSetter = (*ReaderAtFile)(nil)
+	_ io.ReaderAt   = (*ReaderAtFile)(nil)
+)
+
+// NewReaderAtFile creates a file that reads size bytes from ra. Closing the
+// file doesn't close ra, which may be shared with other files
+func NewReaderAtFile(path string, ra io.ReaderAt, size int64) *ReaderAtFile {
+	return &ReaderAtFile{
+		ra:      ra,
+		size:    size,
+		path:    path,
+		modTime: time.Now(),
+	}
+}
+
+// Read implements the io.Reader interface, reading from the ReaderAt at the
+// current offset
+func (f *ReaderAtFile) Read(p []byte) (int, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	n, err := f.ReadAt(p, f.off)
+	f.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// ReadAt implements the io.ReaderAt interface, limiting reads to the file
+// size. ReadAt doesn't use or move the offset Read & Seek use
+func (f *ReaderAtFile) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("ReaderAtFile.ReadAt: negative offset")
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+	if remain := f.size - off; int64(len(p)) > remain {
+		p = p[:remain]
+		n, err := f.ra.ReadAt(p, off)
+		if err == nil {
+			err = io.EOF
+		}
+		return n, err
+	}
+	return f.ra.ReadAt(p, off)
+}
+
+// Seek implements the io.Seeker interface
+func (f *ReaderAtFile) Seek(offset int64, whence int) (int64, error) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.off
+	case io.SeekEnd:
+		offset += f.size
+	default:
+		return 0, errors.New("ReaderAtFile.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("ReaderAtFile.Seek: negative position")
+	}
+	f.off = offset
+	return offset, nil
+}
+
+// Close is a no-op, leaving the shared ReaderAt open
+func (f *ReaderAtFile) Close() error {
+	return nil
+}
+
+// FileName returns the base of the file path
+func (f *ReaderAtFile) FileName() string {
+	return filepath.Base(f.FullPath())
+}
+
+// FullPath returns the file path
+func (f *ReaderAtFile) FullPath() string {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.path
+}
+
+// SetPath implements the PathSetter interface
+func (f *ReaderAtFile) SetPath(path string) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.path = path
+}
+
+// IsDirectory always returns false
+func (*ReaderAtFile) IsDirectory() bool {
+	return false
+}
+
+// NextFile returns ErrNotDirectory, ReaderAtFile isn't a directory
+func (*ReaderAtFile) NextFile() (File, error) {
+	return nil, ErrNotDirectory
+}
+
+// MediaType returns a mime type based on file extension
+func (f *ReaderAtFile) MediaType() string {
+	return mime.TypeByExtension(filepath.Ext(f.FullPath()))
+}
+
+// ModTime returns the time the file was created
+func (f *ReaderAtFile) ModTime() time.Time {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	return f.modTime
+}
+
+// SetModTime implements the ModTimeSetter interface
+func (f *ReaderAtFile) SetModTime(t time.Time) {
+	f.lk.Lock()
+	defer f.lk.Unlock()
+	f.modTime = t
+}
+
+// Size returns the size of the file
+func (f *ReaderAtFile) Size() int64 {
+	return f.size
+}
+
+// Stat returns info describing the file
+func (f *ReaderAtFile) Stat() (fs.FileInfo, error) {
+	return FileInfo(f), nil
+}
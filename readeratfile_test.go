@@ -0,0 +1,82 @@
+package qfs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"testing"
+)
+
+func TestReaderAtFile(t *testing.T) {
+	ctx := context.Background()
+	ra := &closeReaderAt{r: bytes.NewReader([]byte("hello world, and more"))}
+	f := NewReaderAtFile("/a.txt", ra, 11)
+
+	if f.Size() != 11 {
+		t.Errorf("size mismatch. want: 11 got: %d", f.Size())
+	}
+
+	fs := NewMemFS()
+	path, err := fs.Put(ctx, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := fs.Get(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := FileString(got); s != "hello world" {
+		t.Errorf("put contents mismatch. want: %q got: %q", "hello world", s)
+	}
+
+	// rewinding re-reads the file without buffering its contents
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello world" {
+		t.Errorf("reread mismatch. want: %q got: %q", "hello world", string(data))
+	}
+
+	r, err := NewRangeFile(f, 6, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err = ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "world" {
+		t.Errorf("range mismatch. want: %q got: %q", "world", string(data))
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if ra.closed {
+		t.Error("expected closing the file to leave the shared ReaderAt open")
+	}
+	other := NewReaderAtFile("/b.txt", ra, 5)
+	if _, s := FileString(other); s != "hello" {
+		t.Errorf("shared read mismatch. want: %q got: %q", "hello", s)
+	}
+}
+
+// closeReaderAt records whether it's been closed
+type closeReaderAt struct {
+	r      io.ReaderAt
+	closed bool
+}
+
+func (c *closeReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	return c.r.ReadAt(p, off)
+}
+
+func (c *closeReaderAt) Close() error {
+	c.closed = true
+	return nil
+}
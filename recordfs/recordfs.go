@@ -0,0 +1,182 @@
+// Package recordfs records the responses of a filesystem to a fixture
+// archive & replays them later without the original backend. Fixtures let
+// integration tests run against content fetched once from a real HTTP or IPFS
+// filesystem, deterministically & without network access
+package recordfs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+// ErrNotRecorded is returned by a Replay for paths the fixture has no
+// response for
+var ErrNotRecorded = errors.New("no recorded response")
+
+// Archive is a fixture of recorded responses, encoded as JSON
+type Archive struct {
+	// Type is the type of the recorded filesystem, which a Replay reports as
+	// its own
+	Type string `json:"type"`
+	// Has maps paths passed to Has to the recorded result
+	Has map[string]bool `json:"has,omitempty"`
+	// Get maps paths passed to Get to the recorded response
+	Get map[string]*Response `json:"get,omitempty"`
+}
+
+// Response is a recorded Get response. Exactly one of File & NotFound is set
+type Response struct {
+	File     *Node `json:"file,omitempty"`
+	NotFound bool  `json:"notFound,omitempty"`
+}
+
+// Node is a recorded file or directory
+type Node struct {
+	Name      string      `json:"name"`
+	Path      string      `json:"path"`
+	MediaType string      `json:"mediaType,omitempty"`
+	ModTime   time.Time   `json:"modTime"`
+	Mode      fs.FileMode `json:"mode,omitempty"`
+	// Metadata holds user-defined attributes of files that implement
+	// qfs.MetadataFile
+	Metadata map[string]string `json:"metadata,omitempty"`
+	IsDir    bool              `json:"isDir,omitempty"`
+	Data     []byte            `json:"data,omitempty"`
+	Children []*Node           `json:"children,omitempty"`
+}
+
+// Recorder wraps a filesystem, recording the result of every Has & Get call.
+// Files returned by Get are read in full when they're fetched, so recording
+// large trees holds them in memory. Put & Delete pass through unrecorded
+type Recorder struct {
+	qfs.Filesystem
+
+	lk      sync.Mutex
+	archive Archive
+}
+
+var _ qfs.Filesystem = (*Recorder)(nil)
+
+// NewRecorder creates a Recorder that records responses from fs
+func NewRecorder(fs qfs.Filesystem) *Recorder {
+	return &Recorder{
+		Filesystem: fs,
+		archive: Archive{
+			Type: fs.Type(),
+			Has:  map[string]bool{},
+			Get:  map[string]*Response{},
+		},
+	}
+}
+
+// Has checks for path on the underlying filesystem, recording the result
+func (r *Recorder) Has(ctx context.Context, path string) (bool, error) {
+	exists, err := r.Filesystem.Has(ctx, path)
+	if err != nil {
+		return false, err
+	}
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.archive.Has[path] = exists
+	return exists, nil
+}
+
+// Get fetches path from the underlying filesystem, recording the response.
+// Errors that wrap qfs.ErrNotFound are recorded, other errors aren't
+func (r *Recorder) Get(ctx context.Context, path string) (qfs.File, error) {
+	f, err := r.Filesystem.Get(ctx, path)
+	if err != nil {
+		if errors.Is(err, qfs.ErrNotFound) {
+			r.record(path, &Response{NotFound: true})
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	node, err := newNode(f)
+	if err != nil {
+		return nil, err
+	}
+	r.record(path, &Response{File: node})
+	return node.file(), nil
+}
+
+func (r *Recorder) record(path string, res *Response) {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	r.archive.Get[path] = res
+}
+
+// Archive returns the responses recorded so far
+func (r *Recorder) Archive() Archive {
+	r.lk.Lock()
+	defer r.lk.Unlock()
+	a := Archive{
+		Type: r.archive.Type,
+		Has:  make(map[string]bool, len(r.archive.Has)),
+		Get:  make(map[string]*Response, len(r.archive.Get)),
+	}
+	for k, v := range r.archive.Has {
+		a.Has[k] = v
+	}
+	for k, v := range r.archive.Get {
+		a.Get[k] = v
+	}
+	return a
+}
+
+// WriteArchive writes the responses recorded so far to w as JSON
+func (r *Recorder) WriteArchive(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.Archive())
+}
+
+// newNode reads f & any children into a Node
+func newNode(f qfs.File) (*Node, error) {
+	n := &Node{
+		Name:      f.FileName(),
+		Path:      f.FullPath(),
+		MediaType: f.MediaType(),
+		ModTime:   f.ModTime(),
+		IsDir:     f.IsDirectory(),
+	}
+	if mf, ok := f.(qfs.MetadataFile); ok {
+		n.Mode = mf.Mode()
+		n.Metadata = mf.Metadata()
+	}
+
+	if !n.IsDir {
+		data, err := ioutil.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("recording %q: %w", n.Path, err)
+		}
+		n.Data = data
+		return n, nil
+	}
+
+	for {
+		ch, err := f.NextFile()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("recording %q: %w", n.Path, err)
+		}
+		chNode, err := newNode(ch)
+		ch.Close()
+		if err != nil {
+			return nil, err
+		}
+		n.Children = append(n.Children, chNode)
+	}
+	return n, nil
+}
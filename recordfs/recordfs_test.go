@@ -0,0 +1,124 @@
+package recordfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/qfs"
+)
+
+func TestRecordReplay(t *testing.T) {
+	ctx := context.Background()
+	mem := qfs.NewMemFS()
+	dirPath, err := mem.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("b.txt", []byte("bbb")),
+		qfs.NewMemdir("c",
+			qfs.NewMemfileBytes("d.json", []byte(`{"d":true}`)),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	filePath, err := mem.Put(ctx, qfs.NewMemfileBytes("e.txt", []byte("e")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := NewRecorder(mem)
+	dir, err := rec.Get(ctx, dirPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPaths := walkPaths(t, dir)
+	f, err := rec.Get(ctx, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantInfo, err := qfs.Stat(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rec.Get(ctx, "/mem/QmMissing"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got: %v", err)
+	}
+	if _, err := rec.Has(ctx, filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := rec.WriteArchive(buf); err != nil {
+		t.Fatal(err)
+	}
+	replay, err := ReadReplay(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if replay.Type() != qfs.MemFilestoreType {
+		t.Errorf("expected replay to report the recorded type. got: %q", replay.Type())
+	}
+
+	dir, err = replay.Get(ctx, dirPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wantPaths, walkPaths(t, dir)); diff != "" {
+		t.Errorf("replayed tree mismatch (-want +got):\n%s", diff)
+	}
+
+	f, err = replay.Get(ctx, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "e" {
+		t.Errorf("contents mismatch. want: %q got: %q", "e", string(data))
+	}
+	gotInfo, err := qfs.Stat(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotInfo.ModTime().Equal(wantInfo.ModTime()) || gotInfo.Mode() != wantInfo.Mode() {
+		t.Errorf("file info mismatch. want: %v %v got: %v %v", wantInfo.ModTime(), wantInfo.Mode(), gotInfo.ModTime(), gotInfo.Mode())
+	}
+
+	if _, err := replay.Get(ctx, "/mem/QmMissing"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected recorded not found to replay as ErrNotFound, got: %v", err)
+	}
+	if _, err := replay.Get(ctx, "/mem/QmNeverFetched"); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("expected ErrNotRecorded for an unrecorded path, got: %v", err)
+	}
+	if has, err := replay.Has(ctx, dirPath); err != nil || !has {
+		t.Errorf("expected paths fetched with Get to exist. has: %t err: %v", has, err)
+	}
+	if _, err := replay.Put(ctx, qfs.NewMemfileBytes("f.txt", nil)); !errors.Is(err, qfs.ErrReadOnly) {
+		t.Errorf("expected ErrReadOnly, got: %v", err)
+	}
+}
+
+func walkPaths(t *testing.T, f qfs.File) []string {
+	t.Helper()
+	var paths []string
+	err := qfs.Walk(f, func(f qfs.File) error {
+		paths = append(paths, f.FullPath())
+		if !f.IsDirectory() {
+			data, err := ioutil.ReadAll(f)
+			if err != nil {
+				return err
+			}
+			paths = append(paths, string(data))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
@@ -0,0 +1,170 @@
+package recordfs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+// Replay is a read-only filesystem that answers Has & Get with the responses
+// recorded in an Archive. Paths without a recorded response return an error
+// wrapping ErrNotRecorded, so tests fail loudly when they drift from their
+// fixtures
+type Replay struct {
+	archive Archive
+}
+
+var (
+	_ qfs.Filesystem = (*Replay)(nil)
+	_ qfs.ReadOnlyFS = (*Replay)(nil)
+)
+
+// NewReplay creates a Replay from an archive
+func NewReplay(a Archive) *Replay {
+	return &Replay{archive: a}
+}
+
+// ReadReplay creates a Replay from an archive written by
+// Recorder.WriteArchive
+func ReadReplay(r io.Reader) (*Replay, error) {
+	a := Archive{}
+	if err := json.NewDecoder(r).Decode(&a); err != nil {
+		return nil, fmt.Errorf("reading fixture archive: %w", err)
+	}
+	return NewReplay(a), nil
+}
+
+// OpenReplay reads a Replay from the archive file at path
+func OpenReplay(path string) (*Replay, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadReplay(f)
+}
+
+// Type returns the type of the recorded filesystem
+func (r *Replay) Type() string {
+	return r.archive.Type
+}
+
+// Has returns the recorded result of Has for path. Paths that were only
+// passed to Get exist if Get returned a file
+func (r *Replay) Has(ctx context.Context, path string) (bool, error) {
+	if exists, ok := r.archive.Has[path]; ok {
+		return exists, nil
+	}
+	if res, ok := r.archive.Get[path]; ok {
+		return !res.NotFound, nil
+	}
+	return false, fmt.Errorf("%w: has %q", ErrNotRecorded, path)
+}
+
+// Get returns the recorded response for path
+func (r *Replay) Get(ctx context.Context, path string) (qfs.File, error) {
+	res, ok := r.archive.Get[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: get %q", ErrNotRecorded, path)
+	}
+	if res.NotFound || res.File == nil {
+		return nil, fmt.Errorf("%w: %s", qfs.ErrNotFound, path)
+	}
+	return res.File.file(), nil
+}
+
+// IsReadOnlyFilesystem marks replays as read-only
+func (r *Replay) IsReadOnlyFilesystem() {}
+
+// Put returns qfs.ErrReadOnly, replays can't be written to
+func (r *Replay) Put(ctx context.Context, file qfs.File) (string, error) {
+	return "", qfs.ErrReadOnly
+}
+
+// Delete returns qfs.ErrReadOnly, replays can't be written to
+func (r *Replay) Delete(ctx context.Context, path string) error {
+	return qfs.ErrReadOnly
+}
+
+// file returns a fresh qfs.File that reads the recorded node
+func (n *Node) file() qfs.File {
+	if n.IsDir {
+		return &replayDir{node: n}
+	}
+	return &replayFile{node: n, r: bytes.NewReader(n.Data)}
+}
+
+// mode returns the recorded mode, defaulting to the modes qfs.FileInfo uses
+// for nodes recorded from files that don't report one
+func (n *Node) mode() fs.FileMode {
+	if n.Mode != 0 {
+		return n.Mode
+	}
+	if n.IsDir {
+		return fs.ModeDir | 0755
+	}
+	return 0644
+}
+
+// replayFile is a recorded file, keeping the recorded media type & mod time
+type replayFile struct {
+	node *Node
+	r    *bytes.Reader
+}
+
+var (
+	_ qfs.File         = (*replayFile)(nil)
+	_ qfs.SizeFile     = (*replayFile)(nil)
+	_ qfs.SeekFile     = (*replayFile)(nil)
+	_ qfs.MetadataFile = (*replayFile)(nil)
+)
+
+func (f *replayFile) Read(p []byte) (int, error)                   { return f.r.Read(p) }
+func (f *replayFile) Seek(offset int64, whence int) (int64, error) { return f.r.Seek(offset, whence) }
+func (f *replayFile) Close() error                                 { return nil }
+func (f *replayFile) IsDirectory() bool                            { return false }
+func (f *replayFile) NextFile() (qfs.File, error)                  { return nil, qfs.ErrNotDirectory }
+func (f *replayFile) FileName() string                             { return f.node.Name }
+func (f *replayFile) FullPath() string                             { return f.node.Path }
+func (f *replayFile) MediaType() string                            { return f.node.MediaType }
+func (f *replayFile) ModTime() time.Time                           { return f.node.ModTime }
+func (f *replayFile) Size() int64                                  { return int64(len(f.node.Data)) }
+func (f *replayFile) Mode() fs.FileMode                            { return f.node.mode() }
+func (f *replayFile) Metadata() map[string]string                  { return f.node.Metadata }
+
+// replayDir is a recorded directory, children are returned in recorded order
+type replayDir struct {
+	node *Node
+	next int
+}
+
+var (
+	_ qfs.File         = (*replayDir)(nil)
+	_ qfs.MetadataFile = (*replayDir)(nil)
+)
+
+func (d *replayDir) Read(p []byte) (int, error)  { return 0, qfs.ErrNotFile }
+func (d *replayDir) Close() error                { return nil }
+func (d *replayDir) IsDirectory() bool           { return true }
+func (d *replayDir) FileName() string            { return d.node.Name }
+func (d *replayDir) FullPath() string            { return d.node.Path }
+func (d *replayDir) MediaType() string           { return d.node.MediaType }
+func (d *replayDir) ModTime() time.Time          { return d.node.ModTime }
+func (d *replayDir) Mode() fs.FileMode           { return d.node.mode() }
+func (d *replayDir) Metadata() map[string]string { return d.node.Metadata }
+
+func (d *replayDir) NextFile() (qfs.File, error) {
+	if d.next >= len(d.node.Children) {
+		return nil, io.EOF
+	}
+	ch := d.node.Children[d.next]
+	d.next++
+	return ch.file(), nil
+}
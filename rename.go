@@ -0,0 +1,24 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+)
+
+// RenameFS is an opt-in interface for mutable filesystems that can move a
+// file or directory to a new path without rewriting its content
+type RenameFS interface {
+	Filesystem
+	// Rename moves the file or directory at from to to, replacing any file
+	// already at to. Rename must return ErrNotFound if from doesn't exist
+	Rename(ctx context.Context, from, to string) error
+}
+
+// Rename moves the file or directory at from to to using fs's Rename method,
+// returning ErrUnsupported if fs doesn't implement RenameFS
+func Rename(ctx context.Context, fs Filesystem, from, to string) error {
+	if rfs, ok := fs.(RenameFS); ok {
+		return rfs.Rename(ctx, from, to)
+	}
+	return fmt.Errorf("%w: %s filesystem can't rename", ErrUnsupported, fs.Type())
+}
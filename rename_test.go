@@ -0,0 +1,13 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestRenameUnsupported(t *testing.T) {
+	if err := Rename(context.Background(), NewMemFS(), "/mem/a", "/mem/b"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected renaming on a filesystem without RenameFS to be unsupported. got: %v", err)
+	}
+}
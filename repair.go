@@ -0,0 +1,110 @@
+package qfs
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// BlockGetter fetches raw block data by CID. Every MerkleDagStore is a
+// BlockGetter, making other mounts & MemFS network peers valid repair sources
+type BlockGetter interface {
+	GetBlock(id cid.Cid) (r io.Reader, err error)
+}
+
+// RepairReport is the result of a repair pass
+type RepairReport struct {
+	// Healed lists blocks that were refetched, written & verified
+	Healed []cid.Cid
+	// Unhealed lists corrupt or missing blocks no source could provide
+	Unhealed []cid.Cid
+	// Audit is the audit of the store after repairs were made
+	Audit AuditReport
+}
+
+// Repair audits the blocks reachable from roots, refetching any corrupt or
+// missing blocks from sources, which are tried in order. Refetched data is
+// only written to store if it hashes to the expected CID, and written blocks
+// are re-verified against store. Blocks linked from healed blocks are audited
+// in turn, so repairs can descend into previously unreachable subtrees
+func Repair(ctx context.Context, store MerkleDagStore, roots []cid.Cid, sources ...BlockGetter) (RepairReport, error) {
+	report := RepairReport{}
+	attempted := map[cid.Cid]struct{}{}
+
+	for {
+		audit, err := Audit(ctx, store, roots)
+		if err != nil {
+			return report, err
+		}
+		report.Audit = audit
+
+		healed := 0
+		bad := append(append([]cid.Cid{}, audit.Corrupt...), audit.Missing...)
+		for _, id := range bad {
+			if _, ok := attempted[id]; ok {
+				continue
+			}
+			attempted[id] = struct{}{}
+
+			ok, err := repairBlock(ctx, store, id, sources)
+			if err != nil {
+				return report, err
+			}
+			if ok {
+				report.Healed = append(report.Healed, id)
+				healed++
+			} else {
+				report.Unhealed = append(report.Unhealed, id)
+			}
+		}
+
+		// nothing new was healed, so another audit won't find new blocks
+		if healed == 0 {
+			return report, nil
+		}
+	}
+}
+
+// repairBlock tries each source in turn for valid data for id, writing the
+// first valid block it finds to store. repairBlock reports whether the block
+// in store verifies after writing
+func repairBlock(ctx context.Context, store MerkleDagStore, id cid.Cid, sources []BlockGetter) (bool, error) {
+	for _, src := range sources {
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+
+		r, err := src.GetBlock(id)
+		if err != nil {
+			log.Debugw("repair source missing block", LogFields(ctx, "cid", id.String(), "err", err)...)
+			continue
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil {
+			log.Debugw("repair reading block", LogFields(ctx, "cid", id.String(), "err", err)...)
+			continue
+		}
+		sum, err := id.Prefix().Sum(data)
+		if err != nil {
+			return false, err
+		}
+		if !sum.Equals(id) {
+			log.Debugw("repair source has corrupt block", LogFields(ctx, "cid", id.String())...)
+			continue
+		}
+
+		if _, err := store.PutBlock(data); err != nil {
+			return false, err
+		}
+		ok, err := auditBlock(store, id)
+		if err != nil && !isNotFound(err) {
+			return false, err
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
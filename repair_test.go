@@ -0,0 +1,65 @@
+package qfs
+
+import (
+	"context"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+func TestRepair(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemFS()
+	peer := NewMemFS()
+
+	put := func(fs *MemFS, data string) cid.Cid {
+		id, err := fs.PutBlock([]byte(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+
+	a := put(store, "a")
+	put(peer, "a")
+	// b is only available from the peer
+	b := put(peer, "b")
+	// d is unavailable everywhere
+	d := put(NewMemFS(), "d")
+
+	root, err := store.PutNode(NewLinks(
+		Link{Name: "a", Cid: a},
+		Link{Name: "b", Cid: b},
+		Link{Name: "d", Cid: d},
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// corrupt a
+	store.Files[a.String()] = fsFile{data: bytesData([]byte("not a"))}
+
+	report, err := Repair(ctx, store, []cid.Cid{root.Cid}, NewMemFS(), peer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	healed := map[cid.Cid]bool{}
+	for _, id := range report.Healed {
+		healed[id] = true
+	}
+	for name, id := range map[string]cid.Cid{"a": a, "b": b} {
+		if !healed[id] {
+			t.Errorf("expected %s to be healed", name)
+		}
+	}
+	if len(report.Unhealed) != 1 || !report.Unhealed[0].Equals(d) {
+		t.Errorf("expected d to be unhealed. got: %v", report.Unhealed)
+	}
+	if len(report.Audit.Corrupt) != 0 {
+		t.Errorf("expected no corrupt blocks after repair. got: %v", report.Audit.Corrupt)
+	}
+	if len(report.Audit.Missing) != 1 || !report.Audit.Missing[0].Equals(d) {
+		t.Errorf("expected only d to be missing after repair. got: %v", report.Audit.Missing)
+	}
+}
@@ -0,0 +1,195 @@
+// Package scanfs inspects the contents of files as they're read from a
+// filesystem, letting hosts that serve user-uploaded content plug in malware
+// or secret scanning on the read path
+package scanfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/qri-io/qfs"
+)
+
+// ErrRejected is matched by errors returned when a Scanner rejects a file
+var ErrRejected = errors.New("rejected by scanner")
+
+// RejectedError is returned by reads of a file a Scanner rejected. It matches
+// ErrRejected with errors.Is, and unwraps to the scanner's reason
+type RejectedError struct {
+	Path   string
+	Reason error
+}
+
+// Error implements the error interface
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s: %s: %s", ErrRejected, e.Path, e.Reason)
+}
+
+// Unwrap returns the reason the scanner gave for rejecting the file
+func (e *RejectedError) Unwrap() error { return e.Reason }
+
+// Is reports whether target is ErrRejected
+func (e *RejectedError) Is(target error) bool { return target == ErrRejected }
+
+// Scanner inspects files as they're read
+type Scanner interface {
+	// Scan begins inspecting the file at path, returning nil to skip it
+	Scan(ctx context.Context, path string) Scan
+}
+
+// Scan receives the contents of a single file as they're read. An error
+// from Write or Close rejects the file
+type Scan interface {
+	// Write is called with each block of bytes read from the file
+	Write(p []byte) (int, error)
+	// Close is called once the file has been read to EOF, or once the sample
+	// size is reached when sampling. Close is also called when reading stops
+	// early because of an error or because the file is closed, in which case
+	// its error is ignored
+	Close() error
+}
+
+// FS wraps a filesystem, passing the contents of every file returned by Get
+// through a Scanner. Reads of a rejected file return a *RejectedError in place
+// of the remaining bytes. Bytes read before the scanner rejects a file have
+// already been delivered, so hosts that must never serve rejected content
+// should read files in full before responding, or sample a prefix they can
+// buffer
+type FS struct {
+	qfs.Filesystem
+	scanner    Scanner
+	sampleSize int64
+}
+
+var _ qfs.Filesystem = (*FS)(nil)
+
+// Option is a function type for passing to New
+type Option func(sfs *FS)
+
+// OptionSampleSize limits scanning to the first size bytes of each file. The
+// scan is closed once size bytes have been read, and the rest of the file is
+// delivered unscanned. Zero scans files in full
+func OptionSampleSize(size int64) Option {
+	return func(sfs *FS) {
+		sfs.sampleSize = size
+	}
+}
+
+// New creates an FS that scans files read from fs with scanner
+func New(fs qfs.Filesystem, scanner Scanner, opts ...Option) *FS {
+	sfs := &FS{
+		Filesystem: fs,
+		scanner:    scanner,
+	}
+	for _, opt := range opts {
+		opt(sfs)
+	}
+	return sfs
+}
+
+// Get fetches path from the underlying filesystem, scanning the file or each
+// file within a directory as it's read
+func (sfs *FS) Get(ctx context.Context, path string) (qfs.File, error) {
+	f, err := sfs.Filesystem.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return sfs.wrap(ctx, f), nil
+}
+
+func (sfs *FS) wrap(ctx context.Context, f qfs.File) qfs.File {
+	sf := &scannedFile{File: f, ctx: ctx, fs: sfs}
+	if !f.IsDirectory() {
+		sf.scan = sfs.scanner.Scan(ctx, f.FullPath())
+	}
+	return sf
+}
+
+// scannedFile feeds the bytes read from a file to a scan. scannedFile only
+// exposes qfs.File methods, so callers can't seek past unscanned bytes
+type scannedFile struct {
+	qfs.File
+	ctx  context.Context
+	fs   *FS
+	scan Scan
+	// seen counts bytes passed to scan
+	seen int64
+	// err is the rejection, once the scan fails
+	err error
+}
+
+func (f *scannedFile) Read(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+	n, err := f.File.Read(p)
+	if f.scan == nil {
+		return n, err
+	}
+	if err != nil && err != io.EOF {
+		f.abort()
+		return n, err
+	}
+
+	if n > 0 {
+		b := p[:n]
+		if limit := f.fs.sampleSize; limit > 0 && f.seen+int64(n) > limit {
+			b = b[:limit-f.seen]
+		}
+		if _, werr := f.scan.Write(b); werr != nil {
+			return 0, f.reject(werr)
+		}
+		f.seen += int64(len(b))
+		if f.fs.sampleSize > 0 && f.seen >= f.fs.sampleSize {
+			if cerr := f.finish(); cerr != nil {
+				return 0, cerr
+			}
+		}
+	}
+	if err == io.EOF && f.scan != nil {
+		if cerr := f.finish(); cerr != nil {
+			return 0, cerr
+		}
+	}
+	return n, err
+}
+
+// finish closes the scan, rejecting the file if it fails
+func (f *scannedFile) finish() error {
+	scan := f.scan
+	f.scan = nil
+	if err := scan.Close(); err != nil {
+		return f.reject(err)
+	}
+	return nil
+}
+
+func (f *scannedFile) reject(reason error) error {
+	f.abort()
+	f.err = &RejectedError{Path: f.FullPath(), Reason: reason}
+	return f.err
+}
+
+// abort closes a scan that won't see the rest of the file, ignoring its result
+func (f *scannedFile) abort() {
+	if f.scan != nil {
+		f.scan.Close()
+		f.scan = nil
+	}
+}
+
+// Close closes the scan if the file wasn't read in full, then the file
+func (f *scannedFile) Close() error {
+	f.abort()
+	return f.File.Close()
+}
+
+func (f *scannedFile) NextFile() (qfs.File, error) {
+	next, err := f.File.NextFile()
+	if err != nil {
+		return nil, err
+	}
+	return f.fs.wrap(f.ctx, next), nil
+}
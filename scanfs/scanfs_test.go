@@ -0,0 +1,206 @@
+package scanfs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/qri-io/qfs"
+)
+
+func TestScan(t *testing.T) {
+	ctx := context.Background()
+	mem := qfs.NewMemFS()
+	path, err := mem.Put(ctx, qfs.NewMemdir("/a",
+		qfs.NewMemfileBytes("clean.txt", []byte("hello")),
+		qfs.NewMemfileBytes("secret.txt", []byte("token: SECRET")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := New(mem, secretScanner{})
+	f, err := fs.Get(ctx, path+"/clean.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, s := qfs.FileString(f); s != "hello" {
+		t.Errorf("contents mismatch. want: %q got: %q", "hello", s)
+	}
+
+	f, err = fs.Get(ctx, path+"/secret.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = ioutil.ReadAll(f)
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("expected reading a rejected file to return ErrRejected. got: %v", err)
+	}
+	if !errors.Is(err, errSecret) {
+		t.Errorf("expected rejection to wrap the scanner's reason. got: %v", err)
+	}
+
+	dir, err := fs.Get(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = qfs.Walk(dir, func(f qfs.File) error {
+		if f.IsDirectory() {
+			return nil
+		}
+		_, err := ioutil.ReadAll(f)
+		return err
+	})
+	if !errors.Is(err, ErrRejected) {
+		t.Errorf("expected files within directories to be scanned. got: %v", err)
+	}
+}
+
+func TestScanSample(t *testing.T) {
+	ctx := context.Background()
+	mem := qfs.NewMemFS()
+	path, err := mem.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("hello, SECRET")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fs := New(mem, secretScanner{}, OptionSampleSize(5))
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		t.Fatalf("expected bytes beyond the sample to go unscanned. got: %v", err)
+	}
+	if string(data) != "hello, SECRET" {
+		t.Errorf("contents mismatch. got: %q", string(data))
+	}
+}
+
+func TestScanClosedOnEarlyStop(t *testing.T) {
+	ctx := context.Background()
+	errRead := errors.New("read failed")
+	errWrite := errors.New("write failed")
+	mem := qfs.NewMemFS()
+	path, err := mem.Put(ctx, qfs.NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		description string
+		fs          qfs.Filesystem
+		writeErr    error
+		read        func(f qfs.File) error
+	}{
+		{"closed before EOF", mem, nil, func(f qfs.File) error {
+			_, err := f.Read(make([]byte, 2))
+			return err
+		}},
+		{"read error", failingReadFS{mem, errRead}, nil, func(f qfs.File) error {
+			if _, err := ioutil.ReadAll(f); !errors.Is(err, errRead) {
+				t.Errorf("expected read error to pass through. got: %v", err)
+			}
+			return nil
+		}},
+		{"write error", mem, errWrite, func(f qfs.File) error {
+			if _, err := ioutil.ReadAll(f); !errors.Is(err, ErrRejected) {
+				t.Errorf("expected write error to reject the file. got: %v", err)
+			}
+			return nil
+		}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.description, func(t *testing.T) {
+			scanner := &closeCountingScanner{writeErr: c.writeErr}
+			f, err := New(c.fs, scanner).Get(ctx, path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := c.read(f); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if scanner.closes != 1 {
+				t.Errorf("expected scan to be closed once. got: %d", scanner.closes)
+			}
+		})
+	}
+}
+
+// closeCountingScanner counts the scans it creates that are closed
+type closeCountingScanner struct {
+	writeErr error
+	closes   int
+}
+
+func (s *closeCountingScanner) Scan(ctx context.Context, path string) Scan {
+	return &closeCountingScan{s}
+}
+
+type closeCountingScan struct {
+	scanner *closeCountingScanner
+}
+
+func (s *closeCountingScan) Write(p []byte) (int, error) {
+	if s.scanner.writeErr != nil {
+		return 0, s.scanner.writeErr
+	}
+	return len(p), nil
+}
+
+func (s *closeCountingScan) Close() error {
+	s.scanner.closes++
+	return nil
+}
+
+// failingReadFS returns files whose reads fail with err
+type failingReadFS struct {
+	qfs.Filesystem
+	err error
+}
+
+func (fs failingReadFS) Get(ctx context.Context, path string) (qfs.File, error) {
+	f, err := fs.Filesystem.Get(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return failingReadFile{f, fs.err}, nil
+}
+
+type failingReadFile struct {
+	qfs.File
+	err error
+}
+
+func (f failingReadFile) Read(p []byte) (int, error) { return 0, f.err }
+
+var errSecret = errors.New("found a secret")
+
+// secretScanner rejects files that contain the string "SECRET"
+type secretScanner struct{}
+
+func (secretScanner) Scan(ctx context.Context, path string) Scan {
+	return &secretScan{}
+}
+
+type secretScan struct {
+	buf bytes.Buffer
+}
+
+func (s *secretScan) Write(p []byte) (int, error) {
+	return s.buf.Write(p)
+}
+
+func (s *secretScan) Close() error {
+	if bytes.Contains(s.buf.Bytes(), []byte("SECRET")) {
+		return errSecret
+	}
+	return nil
+}
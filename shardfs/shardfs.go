@@ -0,0 +1,316 @@
+// Package shardfs distributes blocks across several content-addressed stores
+// while presenting them as a single qfs.MerkleDagStore, scaling storage
+// beyond one repo or disk. Blocks are placed by consistent hashing of their
+// multihash, so adding a shard only moves the share of blocks it takes over.
+// shardfs is experimental
+package shardfs
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	iofs "io/fs"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	cid "github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	multihash "github.com/multiformats/go-multihash"
+	"github.com/qri-io/qfs"
+)
+
+// FilestoreType uniquely identifies this filestore
+const FilestoreType = "shard"
+
+// DefaultVirtualNodes is the number of points each shard is given on the
+// hash ring
+const DefaultVirtualNodes = 64
+
+// Shard is a named store blocks are distributed to. Names place the shard
+// on the hash ring, so a shard must keep its name for blocks to be found
+type Shard struct {
+	Name  string
+	Store qfs.MerkleDagStore
+}
+
+// Config adjusts the behaviour of an FS instance
+type Config struct {
+	// VirtualNodes is the number of points each shard is given on the hash
+	// ring. More points spread blocks more evenly
+	VirtualNodes int
+	// Prefix describes how shards derive block identifiers, so PutBlock can
+	// place a block before it's written. Only the hash function matters for
+	// placement. Every shard must hash blocks with it
+	Prefix cid.Prefix
+}
+
+// Option is a function type for passing to New
+type Option func(cfg *Config)
+
+// OptionVirtualNodes sets the number of points each shard has on the ring
+func OptionVirtualNodes(n int) Option {
+	return func(cfg *Config) {
+		cfg.VirtualNodes = n
+	}
+}
+
+// OptionPrefix sets the prefix blocks are hashed with for placement
+func OptionPrefix(p cid.Prefix) Option {
+	return func(cfg *Config) {
+		cfg.Prefix = p
+	}
+}
+
+// DefaultConfig places blocks hashed with sha2-256, giving each shard
+// DefaultVirtualNodes points on the ring
+func DefaultConfig() *Config {
+	return &Config{
+		VirtualNodes: DefaultVirtualNodes,
+		Prefix: cid.Prefix{
+			Version:  1,
+			Codec:    cid.Raw,
+			MhType:   multihash.SHA2_256,
+			MhLength: -1,
+		},
+	}
+}
+
+// FS is a qfs.MerkleDagStore that distributes blocks across shards.
+// Writes go to the shard that owns a block's multihash. Reads ask the owner
+// first, then the remaining shards, so content written before a shard was
+// added stays readable. Nodes & files are hashed by the shard that stores
+// them, so they're written to the owner of the identifier the first write
+// produces, which may leave a copy on a second shard
+type FS struct {
+	cfg    *Config
+	shards []Shard
+	ring   []point
+}
+
+// point is a position on the hash ring owned by a shard
+type point struct {
+	hash  uint64
+	shard int
+}
+
+var _ qfs.MerkleDagStore = (*FS)(nil)
+
+// New creates a sharded store. Shard names must be unique
+func New(shards []Shard, opts ...Option) (*FS, error) {
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("shardfs requires at least one shard")
+	}
+	cfg := DefaultConfig()
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.VirtualNodes <= 0 {
+		cfg.VirtualNodes = DefaultVirtualNodes
+	}
+
+	names := map[string]struct{}{}
+	sfs := &FS{cfg: cfg, shards: shards}
+	for i, s := range shards {
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate shard name %q", s.Name)
+		}
+		names[s.Name] = struct{}{}
+		for v := 0; v < cfg.VirtualNodes; v++ {
+			sfs.ring = append(sfs.ring, point{hash: ringHash([]byte(fmt.Sprintf("%s#%d", s.Name, v))), shard: i})
+		}
+	}
+	sort.Slice(sfs.ring, func(i, j int) bool { return sfs.ring[i].hash < sfs.ring[j].hash })
+	return sfs, nil
+}
+
+// Type distinguishes this filesystem from others by a unique string prefix
+func (sfs *FS) Type() string {
+	return FilestoreType
+}
+
+// Owner returns the name of the shard that stores id
+func (sfs *FS) Owner(id cid.Cid) string {
+	return sfs.shards[sfs.owner(id)].Name
+}
+
+// owner returns the index of the shard that owns id. Placement depends only on
+// the multihash, so CIDs of the same content with different versions or codecs
+// have the same owner
+func (sfs *FS) owner(id cid.Cid) int {
+	key := ringHash(id.Hash())
+	i := sort.Search(len(sfs.ring), func(i int) bool { return sfs.ring[i].hash >= key })
+	if i == len(sfs.ring) {
+		i = 0
+	}
+	return sfs.ring[i].shard
+}
+
+// ringHash positions data on the hash ring
+func ringHash(data []byte) uint64 {
+	sum := sha256.Sum256(data)
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// lookup calls get with the owner of id, then every other shard in order
+// until one doesn't return a not found error
+func (sfs *FS) lookup(id cid.Cid, get func(store qfs.MerkleDagStore) error) error {
+	owner := sfs.owner(id)
+	err := get(sfs.shards[owner].Store)
+	if err == nil || !isNotFound(err) {
+		return err
+	}
+	for i, s := range sfs.shards {
+		if i == owner {
+			continue
+		}
+		if err := get(s.Store); err == nil || !isNotFound(err) {
+			return err
+		}
+	}
+	return err
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, qfs.ErrNotFound) || errors.Is(err, format.ErrNotFound)
+}
+
+// GetNode fetches the node at path beneath id. Each node on the path is
+// fetched from the shard that stores it
+func (sfs *FS) GetNode(id cid.Cid, path ...string) (node qfs.DagNode, err error) {
+	if id, err = sfs.resolve(id, path); err != nil {
+		return nil, err
+	}
+	err = sfs.lookup(id, func(store qfs.MerkleDagStore) error {
+		node, err = store.GetNode(id)
+		return err
+	})
+	return node, err
+}
+
+// resolve walks path beneath root one name at a time, returning the
+// identifier path names. Children can be stored on a different shard than
+// their parent, so each step looks its node up separately
+func (sfs *FS) resolve(root cid.Cid, path []string) (cid.Cid, error) {
+	id := root
+	for _, p := range path {
+		for _, name := range strings.Split(p, "/") {
+			if name == "" {
+				continue
+			}
+			var node qfs.DagNode
+			err := sfs.lookup(id, func(store qfs.MerkleDagStore) (err error) {
+				node, err = store.GetNode(id)
+				return err
+			})
+			if err != nil {
+				return cid.Undef, err
+			}
+			link := node.Links().Get(name)
+			if link == nil {
+				return cid.Undef, fmt.Errorf("%w: %s/%s", qfs.ErrNotFound, root, strings.Join(path, "/"))
+			}
+			id = link.Cid
+		}
+	}
+	return id, nil
+}
+
+// PutNode writes a node to the shard that owns the node's identifier
+func (sfs *FS) PutNode(links qfs.Links) (qfs.PutResult, error) {
+	first := sfs.owner(linksKey(links))
+	res, err := sfs.shards[first].Store.PutNode(links)
+	if err != nil {
+		return res, err
+	}
+	if owner := sfs.owner(res.Cid); owner != first {
+		return sfs.shards[owner].Store.PutNode(links)
+	}
+	return res, nil
+}
+
+// linksKey derives a stand-in identifier from links, spreading the first
+// write of nodes across shards
+func linksKey(links qfs.Links) cid.Cid {
+	h := sha256.New()
+	for _, l := range links.SortedSlice() {
+		h.Write([]byte(l.Name))
+		h.Write(l.Cid.Bytes())
+	}
+	mh, _ := multihash.Encode(h.Sum(nil), multihash.SHA2_256)
+	return cid.NewCidV1(cid.Raw, mh)
+}
+
+// GetBlock fetches a block from the shard that stores it
+func (sfs *FS) GetBlock(id cid.Cid) (r io.Reader, err error) {
+	err = sfs.lookup(id, func(store qfs.MerkleDagStore) error {
+		r, err = store.GetBlock(id)
+		return err
+	})
+	return r, err
+}
+
+// PutBlock writes a block to the shard that owns it. Shards that hash blocks
+// differently from the configured prefix return an error
+func (sfs *FS) PutBlock(d []byte) (cid.Cid, error) {
+	want, err := sfs.cfg.Prefix.Sum(d)
+	if err != nil {
+		return cid.Undef, err
+	}
+	s := sfs.shards[sfs.owner(want)]
+	id, err := s.Store.PutBlock(d)
+	if err != nil {
+		return id, err
+	}
+	if string(id.Hash()) != string(want.Hash()) {
+		return id, fmt.Errorf("shard %q hashed block as %s, expected the multihash of %s", s.Name, id, want)
+	}
+	return id, nil
+}
+
+// PutFile writes a file to the shard that owns its identifier. Files are read
+// into memory, so they can be written a second time if the shard's identifier
+// for the file is owned by another shard
+func (sfs *FS) PutFile(f iofs.File) (qfs.PutResult, error) {
+	stat, err := f.Stat()
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	data, err := ioutil.ReadAll(f)
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	if err := f.Close(); err != nil {
+		return qfs.PutResult{}, err
+	}
+
+	want, err := sfs.cfg.Prefix.Sum(data)
+	if err != nil {
+		return qfs.PutResult{}, err
+	}
+	first := sfs.owner(want)
+	res, err := sfs.shards[first].Store.PutFile(qfs.NewMemfileBytes(stat.Name(), data))
+	if err != nil {
+		return res, err
+	}
+	if owner := sfs.owner(res.Cid); owner != first {
+		return sfs.shards[owner].Store.PutFile(qfs.NewMemfileBytes(stat.Name(), data))
+	}
+	return res, nil
+}
+
+// GetFile fetches the file at path beneath root. Each node on the path is
+// fetched from the shard that stores it
+func (sfs *FS) GetFile(root cid.Cid, path ...string) (rc io.ReadCloser, err error) {
+	id, err := sfs.resolve(root, path)
+	if err != nil {
+		return nil, err
+	}
+	err = sfs.lookup(id, func(store qfs.MerkleDagStore) error {
+		rc, err = store.GetFile(id)
+		return err
+	})
+	return rc, err
+}
@@ -0,0 +1,161 @@
+package shardfs
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	cid "github.com/ipfs/go-cid"
+	"github.com/qri-io/qfs"
+)
+
+func newShards(names ...string) []Shard {
+	shards := make([]Shard, len(names))
+	for i, name := range names {
+		shards[i] = Shard{Name: name, Store: qfs.NewMemFS()}
+	}
+	return shards
+}
+
+func TestShardBlocks(t *testing.T) {
+	shards := newShards("a", "b", "c")
+	sfs, err := New(shards)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := make([]cid.Cid, 0, 100)
+	for i := 0; i < 100; i++ {
+		id, err := sfs.PutBlock([]byte(fmt.Sprintf("block %d", i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	for _, s := range shards {
+		if n := s.Store.(*qfs.MemFS).ObjectCount(); n == 0 {
+			t.Errorf("expected blocks to be spread across shards. shard %q has none", s.Name)
+		}
+	}
+
+	for i, id := range ids {
+		owner := shards[sfs.owner(id)].Store
+		if _, err := owner.GetBlock(id); err != nil {
+			t.Errorf("expected block %d to be stored on its owner %q. got: %v", i, sfs.Owner(id), err)
+		}
+		r, err := sfs.GetBlock(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(r)
+		if string(data) != fmt.Sprintf("block %d", i) {
+			t.Errorf("block %d contents mismatch. got: %q", i, string(data))
+		}
+	}
+
+	// adding a shard moves some blocks' owner, reads fall back to find them
+	grown, err := New(append(shards, newShards("d")...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	moved := 0
+	for _, id := range ids {
+		if grown.Owner(id) != sfs.Owner(id) {
+			if grown.Owner(id) != "d" {
+				t.Errorf("expected blocks to only move to the new shard. %s moved to %q", id, grown.Owner(id))
+			}
+			moved++
+		}
+		if _, err := grown.GetBlock(id); err != nil {
+			t.Errorf("expected blocks to stay readable after adding a shard. got: %v", err)
+		}
+	}
+	if moved == 0 || moved == len(ids) {
+		t.Errorf("expected the new shard to take over some blocks. moved: %d", moved)
+	}
+}
+
+func TestShardNodesAndFiles(t *testing.T) {
+	sfs, err := New(newShards("a", "b", "c"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := sfs.PutFile(qfs.NewMemfileBytes("a.txt", []byte("hello")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rc, err := sfs.GetFile(file.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := ioutil.ReadAll(rc); string(data) != "hello" {
+		t.Errorf("file contents mismatch. got: %q", string(data))
+	}
+
+	node, err := sfs.PutNode(qfs.NewLinks(file.ToLink("a.txt", true)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := sfs.GetNode(node.Cid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Links().Get("a.txt") == nil {
+		t.Errorf("expected node to link to a.txt")
+	}
+
+	// children placed on other shards than their parent resolve by path
+	links := qfs.NewLinks()
+	for i := 0; i < 20; i++ {
+		res, err := sfs.PutFile(qfs.NewMemfileBytes(fmt.Sprintf("%d.txt", i), []byte(fmt.Sprintf("file %d", i))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		links.Add(res.ToLink(fmt.Sprintf("%d.txt", i), true))
+	}
+	dir, err := sfs.PutNode(links)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := sfs.PutNode(qfs.NewLinks(dir.ToLink("dir", false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	elsewhere := 0
+	for _, l := range links.Slice() {
+		if sfs.owner(l.Cid) != sfs.owner(dir.Cid) {
+			elsewhere++
+		}
+	}
+	if elsewhere == 0 {
+		t.Fatal("expected some files to be stored on another shard than their directory")
+	}
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("%d.txt", i)
+		for _, path := range [][]string{{"dir", name}, {"dir/" + name}} {
+			rc, err := sfs.GetFile(root.Cid, path...)
+			if err != nil {
+				t.Fatalf("getting file %v: %s", path, err)
+			}
+			if data, _ := ioutil.ReadAll(rc); string(data) != fmt.Sprintf("file %d", i) {
+				t.Errorf("file %v contents mismatch. got: %q", path, string(data))
+			}
+		}
+	}
+	got, err = sfs.GetNode(root.Cid, "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Cid().Equals(dir.Cid) {
+		t.Errorf("node cid mismatch. want: %s got: %s", dir.Cid, got.Cid())
+	}
+	if _, err := sfs.GetFile(root.Cid, "dir", "missing.txt"); !errors.Is(err, qfs.ErrNotFound) {
+		t.Errorf("expected a missing path to return ErrNotFound. got: %v", err)
+	}
+
+	if _, err := New(newShards("a", "a")); err == nil {
+		t.Error("expected duplicate shard names to fail")
+	}
+}
@@ -0,0 +1,68 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+
+	cid "github.com/ipfs/go-cid"
+)
+
+// PathInfo describes the object at a path without its contents
+type PathInfo struct {
+	// Exists is false if nothing is stored at the path
+	Exists bool
+	// Size is the length of a file in bytes, -1 if unknown. Directories always
+	// have a size of -1
+	Size int64
+	// IsDir is true if the path is a directory
+	IsDir bool
+	// Cid identifies the object on content-addressed filesystems, cid.Undef
+	// if the filesystem isn't content-addressed or the CID isn't known
+	Cid cid.Cid
+	// ETag identifies the current version of a file on mutable filesystems
+	// that support conditional writes, for use with Precondition.IfMatch
+	ETag string
+}
+
+// StatPathFS is an opt-in interface for filesystems that can describe a path
+// more cheaply than opening it, eg: with a block stat, HTTP HEAD request or
+// os.Stat. It's a richer sibling of Has, letting callers like sync planners
+// budget transfers before making them
+type StatPathFS interface {
+	Filesystem
+	// StatPath describes the object at path. Paths that don't exist return a
+	// PathInfo with Exists set to false, not an error
+	StatPath(ctx context.Context, path string) (PathInfo, error)
+}
+
+// StatPath describes the object at path, using fs's StatPath method if fs
+// implements StatPathFS, and falling back to opening path with Get if not.
+// The fallback reports sizes for files that implement SizeFile & CIDs for
+// paths to an entire hash on content-addressed filesystems
+func StatPath(ctx context.Context, fs Filesystem, path string) (PathInfo, error) {
+	if spfs, ok := fs.(StatPathFS); ok {
+		return spfs.StatPath(ctx, path)
+	}
+
+	info := PathInfo{Size: -1}
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return info, nil
+		}
+		return info, err
+	}
+	defer f.Close()
+
+	info.Exists = true
+	info.IsDir = f.IsDirectory()
+	if sf, ok := f.(SizeFile); ok && !info.IsDir {
+		info.Size = sf.Size()
+	}
+	if _, ok := fs.(CAFS); ok {
+		if id, err := cidFromPath(path); err == nil {
+			info.Cid = id
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,51 @@
+package qfs
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStatPath(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	dir, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("bbb")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wrapping MemFS hides its StatPath method, exercising the fallback
+	fallback := struct{ Filesystem }{fs}
+
+	for name, sfs := range map[string]Filesystem{"memfs": fs, "fallback": fallback} {
+		t.Run(name, func(t *testing.T) {
+			info, err := StatPath(ctx, sfs, dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.Exists || !info.IsDir || info.Size != -1 {
+				t.Errorf("unexpected directory info: %#v", info)
+			}
+			if name == "memfs" && info.Cid.String() != dir[len("/mem/"):] {
+				t.Errorf("expected directory CID %q, got: %q", dir[len("/mem/"):], info.Cid)
+			}
+
+			info, err = StatPath(ctx, sfs, dir+"/b.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.Exists || info.IsDir || info.Size != 3 {
+				t.Errorf("unexpected file info: %#v", info)
+			}
+
+			info, err = StatPath(ctx, sfs, "/mem/QmNotFound")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Exists {
+				t.Errorf("expected missing path not to exist: %#v", info)
+			}
+		})
+	}
+}
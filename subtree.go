@@ -0,0 +1,75 @@
+package qfs
+
+import (
+	"context"
+	"path"
+	"strings"
+)
+
+// Subtree presents the paths beneath root in fs as a read-only Filesystem of
+// its own. Paths given to the returned filesystem are resolved relative to
+// root, so with a root of "/ipfs/QmFoo", Get(ctx, "body.json") resolves
+// "/ipfs/QmFoo/body.json". Relative paths can't climb above root
+func Subtree(fs Filesystem, root string) Filesystem {
+	return &subtree{
+		fs:   fs,
+		root: strings.TrimSuffix(root, "/"),
+	}
+}
+
+type subtree struct {
+	fs   Filesystem
+	root string
+}
+
+var (
+	_ Filesystem     = (*subtree)(nil)
+	_ CapabilitiesFS = (*subtree)(nil)
+	_ ReadOnlyFS     = (*subtree)(nil)
+)
+
+// Type returns the type of the underlying filesystem
+func (st *subtree) Type() string {
+	return st.fs.Type()
+}
+
+// Has returns whether the path relative to the subtree root exists
+func (st *subtree) Has(ctx context.Context, p string) (bool, error) {
+	return st.fs.Has(ctx, st.resolve(p))
+}
+
+// Get fetches the path relative to the subtree root
+func (st *subtree) Get(ctx context.Context, p string) (File, error) {
+	return st.fs.Get(ctx, st.resolve(p))
+}
+
+// Put always returns ErrReadOnly, subtrees are read-only views
+func (st *subtree) Put(ctx context.Context, file File) (string, error) {
+	return "", ErrReadOnly
+}
+
+// Delete always returns ErrReadOnly, subtrees are read-only views
+func (st *subtree) Delete(ctx context.Context, p string) error {
+	return ErrReadOnly
+}
+
+// IsReadOnlyFilesystem marks subtrees as read-only views
+func (st *subtree) IsReadOnlyFilesystem() {}
+
+// Capabilities reports the subtree as content-addressed if the filesystem it
+// views is
+func (st *subtree) Capabilities() CapabilitySet {
+	caps := DeriveCapabilities(st)
+	caps.ContentAddressed = Capabilities(st.fs).ContentAddressed
+	return caps
+}
+
+// resolve joins a relative path onto the subtree root. Cleaning against a
+// leading slash drops any ".." elements that would escape the root
+func (st *subtree) resolve(p string) string {
+	p = path.Clean("/" + p)
+	if p == "/" {
+		return st.root
+	}
+	return st.root + p
+}
@@ -0,0 +1,67 @@
+package qfs
+
+import (
+	"context"
+	"io/ioutil"
+	"testing"
+)
+
+func TestSubtree(t *testing.T) {
+	ctx := context.Background()
+	mfs := NewMemFS()
+
+	root, err := mfs.Put(ctx, NewMemdir("/",
+		NewMemfileBytes("body.json", []byte(`[1,2,3]`)),
+		NewMemdir("viz",
+			NewMemfileBytes("index.html", []byte(`<html></html>`)),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	st := Subtree(mfs, root+"/")
+	if st.Type() != MemFilestoreType {
+		t.Errorf("type mismatch. want: %q got: %q", MemFilestoreType, st.Type())
+	}
+
+	cases := []struct {
+		path, data string
+	}{
+		{"body.json", `[1,2,3]`},
+		{"/body.json", `[1,2,3]`},
+		{"viz/index.html", `<html></html>`},
+		{"viz/../body.json", `[1,2,3]`},
+		{"../../body.json", `[1,2,3]`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			f, err := st.Get(ctx, c.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := ioutil.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != c.data {
+				t.Errorf("data mismatch. want: %q got: %q", c.data, string(data))
+			}
+		})
+	}
+
+	if has, err := st.Has(ctx, "viz/index.html"); err != nil || !has {
+		t.Errorf("expected subtree to have viz/index.html. has: %t err: %v", has, err)
+	}
+	if has, _ := st.Has(ctx, "nope.json"); has {
+		t.Errorf("expected subtree not to have nope.json")
+	}
+
+	if _, err := st.Put(ctx, NewMemfileBytes("a.txt", []byte("a"))); err != ErrReadOnly {
+		t.Errorf("expected Put to return ErrReadOnly. got: %v", err)
+	}
+	if err := st.Delete(ctx, "body.json"); err != ErrReadOnly {
+		t.Errorf("expected Delete to return ErrReadOnly. got: %v", err)
+	}
+}
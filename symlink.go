@@ -0,0 +1,98 @@
+package qfs
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SymlinkFile is an opt-in interface for files that are symbolic links.
+// Filesystems that support symlinks store them as links rather than copying
+// the content they point to. Reading a symlink returns its target
+type SymlinkFile interface {
+	File
+	// SymlinkTarget returns the path the link points to, exactly as stored.
+	// Targets may be relative to the link's directory & needn't exist
+	SymlinkTarget() string
+}
+
+// IsSymlink returns the target of f if f is a symbolic link
+func IsSymlink(f File) (target string, ok bool) {
+	if sf, ok := f.(SymlinkFile); ok {
+		return sf.SymlinkTarget(), true
+	}
+	return "", false
+}
+
+// Memsymlink is an in-memory symbolic link
+type Memsymlink struct {
+	path    string
+	target  string
+	r       *strings.Reader
+	modTime time.Time
+}
+
+var (
+	_ SymlinkFile   = (*Memsymlink)(nil)
+	_ SizeFile      = (*Memsymlink)(nil)
+	_ StatFile      = (*Memsymlink)(nil)
+	_ MetadataFile  = (*Memsymlink)(nil)
+	_ PathSetter    = (*Memsymlink)(nil)
+	_ ModTimeSetter = (*Memsymlink)(nil)
+)
+
+// NewMemsymlink creates a symbolic link at path that points to target
+func NewMemsymlink(path, target string) *Memsymlink {
+	return &Memsymlink{
+		path:    path,
+		target:  target,
+		r:       strings.NewReader(target),
+		modTime: time.Now(),
+	}
+}
+
+// SymlinkTarget implements the SymlinkFile interface
+func (l *Memsymlink) SymlinkTarget() string { return l.target }
+
+// Read reads the link's target
+func (l *Memsymlink) Read(p []byte) (int, error) { return l.r.Read(p) }
+
+// Close does nothing, links hold no resources
+func (l *Memsymlink) Close() error { return nil }
+
+// FileName returns the base of the link's path
+func (l *Memsymlink) FileName() string { return filepath.Base(l.path) }
+
+// FullPath returns the path of the link itself
+func (l *Memsymlink) FullPath() string { return l.path }
+
+// SetPath implements the PathSetter interface
+func (l *Memsymlink) SetPath(path string) { l.path = path }
+
+// IsDirectory always returns false, links aren't followed
+func (l *Memsymlink) IsDirectory() bool { return false }
+
+// NextFile returns ErrNotDirectory, links aren't followed
+func (l *Memsymlink) NextFile() (File, error) { return nil, ErrNotDirectory }
+
+// MediaType of a symlink is "inode/symlink"
+func (l *Memsymlink) MediaType() string { return "inode/symlink" }
+
+// ModTime returns the time the link was created
+func (l *Memsymlink) ModTime() time.Time { return l.modTime }
+
+// SetModTime implements the ModTimeSetter interface
+func (l *Memsymlink) SetModTime(t time.Time) { l.modTime = t }
+
+// Size is the length of the target
+func (l *Memsymlink) Size() int64 { return int64(len(l.target)) }
+
+// Mode marks the file as a symlink
+func (l *Memsymlink) Mode() fs.FileMode { return fs.ModeSymlink | 0777 }
+
+// Metadata returns nil, links carry no attributes
+func (l *Memsymlink) Metadata() map[string]string { return nil }
+
+// Stat returns info describing the link
+func (l *Memsymlink) Stat() (fs.FileInfo, error) { return FileInfo(l), nil }
@@ -0,0 +1,67 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+)
+
+func TestMemsymlink(t *testing.T) {
+	dir := NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("b")),
+		NewMemsymlink("link", "b.txt"),
+	)
+
+	links := map[string]string{}
+	if err := Walk(dir, func(f File) error {
+		if target, ok := IsSymlink(f); ok {
+			links[f.FullPath()] = target
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links["/a/link"] != "b.txt" {
+		t.Errorf("expected walk to visit the link without following it. got: %v", links)
+	}
+
+	l := NewMemsymlink("/c", "../d")
+	fi, err := l.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&fs.ModeSymlink == 0 {
+		t.Errorf("expected symlink mode. got: %s", fi.Mode())
+	}
+	if _, s := FileString(l); s != "../d" {
+		t.Errorf("expected reading a link to return its target. got: %q", s)
+	}
+	if _, ok := IsSymlink(NewMemfileBytes("e.txt", []byte("e"))); ok {
+		t.Error("expected regular file not to be a symlink")
+	}
+}
+
+func TestMemFSSymlinkUnsupported(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+
+	cases := []File{
+		NewMemsymlink("link", "b.txt"),
+		NewMemdir("/a",
+			NewMemfileBytes("b.txt", []byte("b")),
+			NewMemsymlink("link", "b.txt"),
+		),
+	}
+	for _, f := range cases {
+		if _, err := fs.Put(ctx, f); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("Put %s: expected ErrUnsupported. got: %v", f.FullPath(), err)
+		}
+	}
+	if _, err := fs.HashOnly(ctx, NewMemsymlink("link", "b.txt")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("HashOnly: expected ErrUnsupported. got: %v", err)
+	}
+	if err := fs.PutFileAtKey(ctx, "key", NewMemsymlink("link", "b.txt")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("PutFileAtKey: expected ErrUnsupported. got: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package sync
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/qri-io/qfs"
+)
+
+// modTime reads the modification time of the file at path
+func modTime(ctx context.Context, fs qfs.Filesystem, path string) (time.Time, error) {
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+	info, err := qfs.Stat(f)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// pathFile overrides the path of a file, placing it in the destination tree.
+// Children of directories are placed beneath the new path
+type pathFile struct {
+	qfs.File
+	path string
+}
+
+var _ qfs.PathSetter = (*pathFile)(nil)
+
+func (f *pathFile) FileName() string {
+	return path.Base(f.path)
+}
+
+func (f *pathFile) FullPath() string {
+	return f.path
+}
+
+func (f *pathFile) SetPath(p string) {
+	f.path = p
+}
+
+func (f *pathFile) NextFile() (qfs.File, error) {
+	next, err := f.File.NextFile()
+	if err != nil {
+		return nil, err
+	}
+	return &pathFile{File: next, path: path.Join(f.path, next.FileName())}, nil
+}
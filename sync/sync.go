@@ -0,0 +1,410 @@
+// Package sync keeps a directory tree in one filesystem in agreement with a
+// tree in another, copying & deleting only the entries that differ
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/qri-io/qfs"
+)
+
+// ChangeKind enumerates the ways an entry can differ between trees
+type ChangeKind string
+
+const (
+	// Add is an entry that exists in the source tree but not the destination
+	Add ChangeKind = "add"
+	// Modify is a file whose content differs between trees
+	Modify ChangeKind = "modify"
+	// Delete is an entry that exists in the destination tree but not the source
+	Delete ChangeKind = "delete"
+	// Replace is an entry that's a file in one tree & a directory in the other,
+	// written in place of the destination entry. A directory replacing a file
+	// is followed by adds for the files in it. Destinations that aren't
+	// content-addressed delete the entry first, which requires Options.Delete
+	Replace ChangeKind = "replace"
+)
+
+// Change describes a single entry that differs between trees
+type Change struct {
+	Kind ChangeKind
+	// Path is the slash-separated path of the entry relative to the roots of
+	// both trees, eg: "data/body.csv"
+	Path  string
+	IsDir bool
+	// Size is the size of the source file for adds & modifications, -1 if
+	// unknown
+	Size int64
+}
+
+// Report describes the outcome of a Sync
+type Report struct {
+	// Path is the root of the destination tree after syncing. Content-addressed
+	// destinations produce a new root for each change
+	Path string
+	// Changes lists differing entries sorted by path. Only files are listed as
+	// added or modified; added directories are described by the files in them.
+	// Deletes are only listed if Options.Delete is set
+	Changes []Change
+	// Unchanged is the number of entries found to be equal. Directories that
+	// are compared by hash count once, without counting their children
+	Unchanged int
+}
+
+// Options configures a Sync
+type Options struct {
+	// SrcPath is the directory in the source filesystem to sync from
+	SrcPath string
+	// DstPath is the directory in the destination filesystem to sync to. On
+	// content-addressed destinations DstPath is the previous version of the
+	// tree to compare against, and may be empty if there isn't one
+	DstPath string
+	// Delete removes entries from the destination that aren't in the source &
+	// allows replacing entries that change between a file & a directory.
+	// Destinations that aren't content-addressed must be able to delete
+	Delete bool
+	// DryRun reports changes without making them
+	DryRun bool
+}
+
+// Sync makes the tree at opts.DstPath in dst match the tree at opts.SrcPath in
+// src. When both filesystems are content-addressed & of the same type,
+// entries are compared by hash, skipping unchanged directories without
+// reading them. Otherwise files are compared by size & modification time,
+// treating any file that's larger, smaller or newer in src as modified.
+//
+// Content-addressed filesystems can't change a tree in place, so when there
+// are changes a new tree is written to dst, reading changed entries from src &
+// unchanged entries from the previous destination tree
+func Sync(ctx context.Context, src, dst qfs.Filesystem, opts Options) (Report, error) {
+	_, dstCAFS := dst.(qfs.CAFS)
+	if opts.DstPath == "" && !dstCAFS {
+		return Report{}, fmt.Errorf("sync: destination path is required")
+	}
+	if opts.Delete && !dstCAFS && !qfs.Capabilities(dst).CanDelete {
+		return Report{}, fmt.Errorf("%w: sync: %s filesystem can't delete", qfs.ErrUnsupported, dst.Type())
+	}
+
+	s := &syncer{
+		src:     src,
+		dst:     dst,
+		opts:    opts,
+		dstCAFS: dstCAFS,
+		report:  Report{Path: opts.DstPath},
+	}
+	if _, srcCAFS := src.(qfs.CAFS); srcCAFS && dstCAFS && src.Type() == dst.Type() {
+		s.byHash = true
+	}
+
+	if s.byHash && opts.SrcPath == opts.DstPath {
+		s.report.Unchanged = 1
+		return s.report, nil
+	}
+	if err := s.diffDir(ctx, "", opts.SrcPath, opts.DstPath); err != nil {
+		return s.report, err
+	}
+	sort.SliceStable(s.report.Changes, func(i, j int) bool {
+		return s.report.Changes[i].Path < s.report.Changes[j].Path
+	})
+
+	if opts.DryRun || len(s.report.Changes) == 0 {
+		return s.report, nil
+	}
+	return s.report, s.apply(ctx)
+}
+
+// syncer holds the state of a single Sync call
+type syncer struct {
+	src, dst qfs.Filesystem
+	opts     Options
+	dstCAFS  bool
+	byHash   bool
+	report   Report
+}
+
+func (s *syncer) change(kind ChangeKind, rel string, e qfs.DirEntry) {
+	s.report.Changes = append(s.report.Changes, Change{
+		Kind:  kind,
+		Path:  rel,
+		IsDir: e.IsDir,
+		Size:  e.Size,
+	})
+}
+
+// diffDir compares the source directory at srcPath with the destination
+// directory at dstPath. An empty dstPath is a directory that doesn't exist
+func (s *syncer) diffDir(ctx context.Context, rel, srcPath, dstPath string) error {
+	srcEntries, err := qfs.List(ctx, s.src, srcPath)
+	if err != nil {
+		return err
+	}
+
+	dstEntries := map[string]qfs.DirEntry{}
+	if dstPath != "" {
+		entries, err := qfs.List(ctx, s.dst, dstPath)
+		if err != nil && !errors.Is(err, qfs.ErrNotFound) {
+			return err
+		}
+		for _, e := range entries {
+			dstEntries[e.Name] = e
+		}
+	}
+
+	for _, se := range srcEntries {
+		childRel := path.Join(rel, se.Name)
+		de, ok := dstEntries[se.Name]
+		delete(dstEntries, se.Name)
+
+		if !ok {
+			if err := s.addAll(ctx, childRel, se); err != nil {
+				return err
+			}
+			continue
+		}
+		if se.IsDir != de.IsDir {
+			s.change(Replace, childRel, se)
+			if se.IsDir {
+				if err := s.addAll(ctx, childRel, se); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if se.IsDir {
+			if s.byHash && se.Path == de.Path {
+				s.report.Unchanged++
+				continue
+			}
+			if err := s.diffDir(ctx, childRel, se.Path, s.dstChildPath(dstPath, de)); err != nil {
+				return err
+			}
+			continue
+		}
+
+		changed, err := s.fileChanged(ctx, se, de, s.dstChildPath(dstPath, de))
+		if err != nil {
+			return err
+		}
+		if changed {
+			s.change(Modify, childRel, se)
+		} else {
+			s.report.Unchanged++
+		}
+	}
+
+	if s.opts.Delete {
+		for name, de := range dstEntries {
+			s.change(Delete, path.Join(rel, name), de)
+		}
+	}
+	return nil
+}
+
+// dstChildPath returns the path to get a destination entry with. Listings on
+// content-addressed filesystems address each entry by its own hash
+func (s *syncer) dstChildPath(dstPath string, de qfs.DirEntry) string {
+	if s.dstCAFS {
+		return de.Path
+	}
+	return path.Join(dstPath, de.Name)
+}
+
+// addAll records an add for e, or for every file beneath e if e is a directory
+func (s *syncer) addAll(ctx context.Context, rel string, e qfs.DirEntry) error {
+	if !e.IsDir {
+		s.change(Add, rel, e)
+		return nil
+	}
+	entries, err := qfs.List(ctx, s.src, e.Path)
+	if err != nil {
+		return err
+	}
+	for _, child := range entries {
+		if err := s.addAll(ctx, path.Join(rel, child.Name), child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileChanged compares a source & destination file
+func (s *syncer) fileChanged(ctx context.Context, se, de qfs.DirEntry, dstPath string) (bool, error) {
+	if s.byHash {
+		return se.Path != de.Path, nil
+	}
+	if se.Size >= 0 && de.Size >= 0 && se.Size != de.Size {
+		return true, nil
+	}
+
+	srcTime, err := modTime(ctx, s.src, se.Path)
+	if err != nil {
+		return false, err
+	}
+	dstTime, err := modTime(ctx, s.dst, dstPath)
+	if err != nil {
+		return false, err
+	}
+	return srcTime.After(dstTime), nil
+}
+
+// apply makes the changes in the report
+func (s *syncer) apply(ctx context.Context) error {
+	if s.dstCAFS {
+		p := newPatch(s.report.Changes)
+		root, err := s.patchDir(ctx, p, "", s.opts.DstPath, "/"+path.Base(s.opts.SrcPath))
+		if err != nil {
+			return err
+		}
+		newPath, err := s.dst.Put(ctx, root)
+		if err != nil {
+			return fmt.Errorf("sync: writing tree: %w", err)
+		}
+		s.report.Path = newPath
+		return nil
+	}
+
+	if !s.opts.Delete {
+		for _, c := range s.report.Changes {
+			if c.Kind == Replace {
+				return fmt.Errorf("%w: sync: %q changed between a file & a directory, replacing it requires Options.Delete", qfs.ErrPreconditionFailed, c.Path)
+			}
+		}
+	}
+
+	for _, c := range s.report.Changes {
+		dstPath := path.Join(s.opts.DstPath, c.Path)
+		if c.Kind == Delete || c.Kind == Replace {
+			if err := s.dst.Delete(ctx, dstPath); err != nil {
+				return fmt.Errorf("sync: deleting %q: %w", c.Path, err)
+			}
+			if c.Kind == Delete {
+				continue
+			}
+		}
+		if c.Kind == Replace && c.IsDir {
+			// the files in the directory are added by the changes that follow
+			if _, err := s.dst.Put(ctx, qfs.NewMemdir(dstPath)); err != nil {
+				return fmt.Errorf("sync: writing %q: %w", c.Path, err)
+			}
+			continue
+		}
+
+		f, err := s.src.Get(ctx, path.Join(s.opts.SrcPath, c.Path))
+		if err != nil {
+			return fmt.Errorf("sync: reading %q: %w", c.Path, err)
+		}
+		_, err = s.dst.Put(ctx, &pathFile{File: f, path: dstPath})
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("sync: writing %q: %w", c.Path, err)
+		}
+	}
+	return nil
+}
+
+// patch indexes the changes a Sync makes by path
+type patch struct {
+	changes map[string]Change
+	// dirs holds every directory with a change beneath it
+	dirs map[string]bool
+}
+
+func newPatch(changes []Change) *patch {
+	p := &patch{changes: map[string]Change{}, dirs: map[string]bool{}}
+	for _, c := range changes {
+		p.changes[c.Path] = c
+		for dir := path.Dir(c.Path); dir != "."; dir = path.Dir(dir) {
+			p.dirs[dir] = true
+		}
+		p.dirs[""] = true
+	}
+	return p
+}
+
+// patchDir returns the destination directory at dstPath with the changes
+// beneath rel applied, as a directory to write at name. dstPath is empty if
+// the destination directory doesn't exist. Children are opened as they're
+// read: changed files from the source, unchanged entries from the
+// destination
+func (s *syncer) patchDir(ctx context.Context, p *patch, rel, dstPath, name string) (qfs.File, error) {
+	existing := map[string]qfs.DirEntry{}
+	if dstPath != "" {
+		entries, err := qfs.List(ctx, s.dst, dstPath)
+		if err != nil && !errors.Is(err, qfs.ErrNotFound) {
+			return nil, err
+		}
+		for _, e := range entries {
+			existing[e.Name] = e
+		}
+	}
+
+	names := make([]string, 0, len(existing))
+	for n := range existing {
+		names = append(names, n)
+	}
+	for childRel := range p.changes {
+		if n, ok := childName(rel, childRel); ok {
+			if _, dup := existing[n]; !dup {
+				existing[n] = qfs.DirEntry{Name: n}
+				names = append(names, n)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	i := 0
+	next := func() (qfs.File, error) {
+		for i < len(names) {
+			n := names[i]
+			i++
+			childRel := path.Join(rel, n)
+			childPath := path.Join(name, n)
+
+			c, changed := p.changes[childRel]
+			switch {
+			case changed && c.Kind == Delete:
+				continue
+			case changed && c.Kind == Replace && c.IsDir:
+				// build the directory from the adds beneath it
+				return s.patchDir(ctx, p, childRel, "", childPath)
+			case changed:
+				f, err := s.src.Get(ctx, path.Join(s.opts.SrcPath, childRel))
+				if err != nil {
+					return nil, fmt.Errorf("sync: reading %q: %w", childRel, err)
+				}
+				return &pathFile{File: f, path: childPath}, nil
+			case p.dirs[childRel]:
+				return s.patchDir(ctx, p, childRel, existing[n].Path, childPath)
+			}
+			f, err := s.dst.Get(ctx, existing[n].Path)
+			if err != nil {
+				return nil, err
+			}
+			return &pathFile{File: f, path: childPath}, nil
+		}
+		return nil, io.EOF
+	}
+	return qfs.NewFuncDir(name, next), nil
+}
+
+// childName returns the name of the entry directly beneath dir that contains
+// rel, reporting false if rel isn't beneath dir
+func childName(dir, rel string) (string, bool) {
+	if dir != "" {
+		if !strings.HasPrefix(rel, dir+"/") {
+			return "", false
+		}
+		rel = rel[len(dir)+1:]
+	}
+	if i := strings.IndexByte(rel, '/'); i >= 0 {
+		rel = rel[:i]
+	}
+	return rel, true
+}
@@ -0,0 +1,277 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/qri-io/qfs"
+	"github.com/qri-io/qfs/aferofs"
+	"github.com/qri-io/qfs/localfs"
+	"github.com/spf13/afero"
+)
+
+func TestSyncByHash(t *testing.T) {
+	ctx := context.Background()
+	src, dst := qfs.NewMemFS(), qfs.NewMemFS()
+
+	v1 := func() qfs.File {
+		return qfs.NewMemdir("/tree",
+			qfs.NewMemfileBytes("a.txt", []byte("a")),
+			qfs.NewMemfileBytes("b.txt", []byte("b")),
+			qfs.NewMemdir("c",
+				qfs.NewMemfileBytes("d.txt", []byte("d")),
+			),
+		)
+	}
+	if _, err := src.Put(ctx, v1()); err != nil {
+		t.Fatal(err)
+	}
+	dstPath, err := dst.Put(ctx, v1())
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcPath, err := src.Put(ctx, qfs.NewMemdir("/tree",
+		qfs.NewMemfileBytes("a.txt", []byte("a")),
+		qfs.NewMemfileBytes("b.txt", []byte("bb")),
+		qfs.NewMemfileBytes("e.txt", []byte("e")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Sync(ctx, src, dst, Options{SrcPath: srcPath, DstPath: dstPath, Delete: true, DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Change{
+		{Kind: Modify, Path: "b.txt", Size: 2},
+		{Kind: Delete, Path: "c", IsDir: true, Size: -1},
+		{Kind: Add, Path: "e.txt", Size: 1},
+	}
+	if diff := cmp.Diff(expect, report.Changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+	if report.Unchanged != 1 {
+		t.Errorf("expected 1 unchanged entry. got: %d", report.Unchanged)
+	}
+	if report.Path != dstPath {
+		t.Errorf("expected a dry run not to change the destination path")
+	}
+
+	// without Delete, entries missing from the source are kept
+	report, err = Sync(ctx, src, dst, Options{SrcPath: srcPath, DstPath: dstPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := qfs.List(ctx, dst, report.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if diff := cmp.Diff([]string{"a.txt", "b.txt", "c", "e.txt"}, names); diff != "" {
+		t.Errorf("synced entries mismatch (-want +got):\n%s", diff)
+	}
+	if got, err := readString(ctx, dst, report.Path+"/b.txt"); err != nil || got != "bb" {
+		t.Errorf("expected b.txt to be modified. got: %q, %v", got, err)
+	}
+
+	report, err = Sync(ctx, src, dst, Options{SrcPath: srcPath, DstPath: dstPath, Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Path != srcPath {
+		t.Errorf("expected synced path to match source hash. want: %q got: %q", srcPath, report.Path)
+	}
+
+	report, err = Sync(ctx, src, dst, Options{SrcPath: srcPath, DstPath: report.Path, Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Changes) != 0 {
+		t.Errorf("expected syncing identical trees to make no changes. got: %v", report.Changes)
+	}
+}
+
+func TestSyncBySizeAndModTime(t *testing.T) {
+	ctx := context.Background()
+	fs, err := localfs.NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcDir, err := ioutil.TempDir("", "qfs_sync_src")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(srcDir)
+	dstDir, err := ioutil.TempDir("", "qfs_sync_dst")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dstDir)
+
+	writeFile(t, filepath.Join(srcDir, "same.txt"), "same")
+	writeFile(t, filepath.Join(srcDir, "newer.txt"), "newer")
+	writeFile(t, filepath.Join(srcDir, "sub", "added.txt"), "added")
+	writeFile(t, filepath.Join(dstDir, "same.txt"), "same")
+	writeFile(t, filepath.Join(dstDir, "newer.txt"), "older")
+	writeFile(t, filepath.Join(dstDir, "stale.txt"), "stale")
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(dstDir, "newer.txt"), past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Sync(ctx, fs, fs, Options{SrcPath: srcDir, DstPath: dstDir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Change{
+		{Kind: Modify, Path: "newer.txt", Size: 5},
+		{Kind: Add, Path: "sub/added.txt", Size: 5},
+	}
+	if diff := cmp.Diff(expect, report.Changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+
+	for name, expect := range map[string]string{
+		"newer.txt":     "newer",
+		"sub/added.txt": "added",
+		"stale.txt":     "stale",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(dstDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expect {
+			t.Errorf("%s content mismatch. want: %q got: %q", name, expect, string(data))
+		}
+	}
+}
+
+func TestSyncTypeChange(t *testing.T) {
+	ctx := context.Background()
+	fs := aferofs.NewFS(afero.NewMemMapFs())
+
+	// a is a directory in the source & a file in the destination, b the reverse
+	for _, f := range []qfs.File{
+		qfs.NewMemfileBytes("/src/a/file.txt", []byte("a")),
+		qfs.NewMemfileBytes("/src/b", []byte("b")),
+		qfs.NewMemfileBytes("/dst/a", []byte("old a")),
+		qfs.NewMemfileBytes("/dst/b/file.txt", []byte("old b")),
+	} {
+		if _, err := fs.Put(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := Sync(ctx, fs, fs, Options{SrcPath: "/src", DstPath: "/dst"}); !errors.Is(err, qfs.ErrPreconditionFailed) {
+		t.Errorf("expected replacing without Delete to fail. got: %v", err)
+	}
+	if got, err := readString(ctx, fs, "/dst/a"); err != nil || got != "old a" {
+		t.Errorf("expected a failed sync to leave the destination alone. got: %q, %v", got, err)
+	}
+
+	report, err := Sync(ctx, fs, fs, Options{SrcPath: "/src", DstPath: "/dst", Delete: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []Change{
+		{Kind: Replace, Path: "a", IsDir: true, Size: -1},
+		{Kind: Add, Path: "a/file.txt", Size: 1},
+		{Kind: Replace, Path: "b", Size: 1},
+	}
+	if diff := cmp.Diff(expect, report.Changes); diff != "" {
+		t.Errorf("changes mismatch (-want +got):\n%s", diff)
+	}
+	for name, expect := range map[string]string{
+		"/dst/a/file.txt": "a",
+		"/dst/b":          "b",
+	} {
+		if got, err := readString(ctx, fs, name); err != nil || got != expect {
+			t.Errorf("%s content mismatch. want: %q got: %q, %v", name, expect, got, err)
+		}
+	}
+}
+
+func TestSyncDeleteUnsupported(t *testing.T) {
+	ctx := context.Background()
+	fs, err := localfs.NewFS(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "qfs_sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeFile(t, filepath.Join(dir, "src", "a.txt"), "a")
+	writeFile(t, filepath.Join(dir, "dst", "b.txt"), "b")
+
+	opts := Options{SrcPath: filepath.Join(dir, "src"), DstPath: filepath.Join(dir, "dst"), Delete: true}
+	if _, err := Sync(ctx, fs, fs, opts); !errors.Is(err, qfs.ErrUnsupported) {
+		t.Errorf("expected Delete to be rejected for a destination that can't delete. got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "dst", "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected a rejected sync to write nothing. got: %v", err)
+	}
+}
+
+func TestSyncTypeChangeByHash(t *testing.T) {
+	ctx := context.Background()
+	src, dst := qfs.NewMemFS(), qfs.NewMemFS()
+
+	srcPath, err := src.Put(ctx, qfs.NewMemdir("/tree",
+		qfs.NewMemdir("a",
+			qfs.NewMemfileBytes("file.txt", []byte("a")),
+		),
+		qfs.NewMemfileBytes("b", []byte("b")),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstPath, err := dst.Put(ctx, qfs.NewMemdir("/tree",
+		qfs.NewMemfileBytes("a", []byte("old a")),
+		qfs.NewMemdir("b",
+			qfs.NewMemfileBytes("file.txt", []byte("old b")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Sync(ctx, src, dst, Options{SrcPath: srcPath, DstPath: dstPath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Path != srcPath {
+		t.Errorf("expected synced path to match source hash. want: %q got: %q", srcPath, report.Path)
+	}
+}
+
+func readString(ctx context.Context, fs qfs.Filesystem, path string) (string, error) {
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	data, err := ioutil.ReadAll(f)
+	return string(data), err
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,55 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// DiskUsageFS is an opt-in interface for filesystems that can report the
+// bytes they store, for monitoring quotas
+type DiskUsageFS interface {
+	Filesystem
+	// DiskUsage returns the bytes stored at path, including the contents of
+	// directories. Content-addressed stores count shared content once. An
+	// empty path returns the usage of the whole store
+	DiskUsage(ctx context.Context, path string) (int64, error)
+}
+
+// DiskUsage returns the bytes stored at path in fs, using fs's DiskUsage
+// method if fs implements DiskUsageFS, and falling back to adding up the
+// sizes of the files at path if not. The fallback reads files that don't
+// report a size & can't report the usage of a whole store
+func DiskUsage(ctx context.Context, fs Filesystem, path string) (int64, error) {
+	if dufs, ok := fs.(DiskUsageFS); ok {
+		return dufs.DiskUsage(ctx, path)
+	}
+	if path == "" {
+		return 0, fmt.Errorf("%w: %s filesystem can't report its total usage", ErrUnsupported, fs.Type())
+	}
+
+	f, err := fs.Get(ctx, path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var total int64
+	err = Walk(f, func(f File) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if f.IsDirectory() {
+			return nil
+		}
+		if sf, ok := f.(SizeFile); ok && sf.Size() >= 0 {
+			total += sf.Size()
+			return nil
+		}
+		n, err := io.Copy(ioutil.Discard, f)
+		total += n
+		return err
+	})
+	return total, err
+}
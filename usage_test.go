@@ -0,0 +1,60 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	ctx := context.Background()
+	fs := NewMemFS()
+	dir, err := fs.Put(ctx, NewMemdir("/a",
+		NewMemfileBytes("b.txt", []byte("bbb")),
+		NewMemdir("c",
+			NewMemfileBytes("d.txt", []byte("ddddd")),
+			NewMemfileBytes("copy.txt", []byte("bbb")),
+		),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wrapping MemFS hides its DiskUsage method, exercising the fallback
+	fallback := struct{ Filesystem }{fs}
+	got, err := DiskUsage(ctx, fallback, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != 11 {
+		t.Errorf("expected fallback to add up file sizes. want: 11 got: %d", got)
+	}
+	if got, err = DiskUsage(ctx, fallback, dir+"/c/d.txt"); err != nil || got != 5 {
+		t.Errorf("expected file usage of 5. got: %d, %v", got, err)
+	}
+	if _, err := DiskUsage(ctx, fallback, ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected fallback store usage to be unsupported. got: %v", err)
+	}
+
+	// MemFS counts each object once, including directory blocks, so usage of
+	// the only root is the size of the store
+	got, err = DiskUsage(ctx, fs, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := fs.Stats().TotalBytes; got != total {
+		t.Errorf("expected usage of the only root to match store total. want: %d got: %d", total, got)
+	}
+	if _, err := fs.Put(ctx, NewMemdir("/e", NewMemfileBytes("f.txt", []byte("ffff")))); err != nil {
+		t.Fatal(err)
+	}
+	if again, err := DiskUsage(ctx, fs, dir); err != nil || again != got {
+		t.Errorf("expected other roots not to change usage. want: %d got: %d, %v", got, again, err)
+	}
+	if total, err := DiskUsage(ctx, fs, ""); err != nil || total != fs.Stats().TotalBytes {
+		t.Errorf("expected empty path to return store total. want: %d got: %d, %v", fs.Stats().TotalBytes, total, err)
+	}
+	if _, err := DiskUsage(ctx, fs, dir+"/missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected missing path to return ErrNotFound. got: %v", err)
+	}
+}
@@ -0,0 +1,166 @@
+package qfs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// WalkError pairs an error with the path of the file that caused it
+type WalkError struct {
+	Path string
+	Err  error
+}
+
+// Error implements the error interface
+func (e WalkError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e WalkError) Unwrap() error {
+	return e.Err
+}
+
+// WalkErrors is a list of errors encountered during a walk, sorted by path
+type WalkErrors []WalkError
+
+// Error implements the error interface
+func (es WalkErrors) Error() string {
+	strs := make([]string, len(es))
+	for i, e := range es {
+		strs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d errors walking files:\n%s", len(es), strings.Join(strs, "\n"))
+}
+
+// WalkConcurrent visits every file and directory in the tree beneath root,
+// reading directories across a pool of workers. This speeds up walks of trees
+// whose NextFile calls are slow, like those backed by IPFS or HTTP.
+// visit is called concurrently & in no particular order, directories are
+// visited before their children are read.
+// Errors from visit or from reading a directory don't stop the walk, the
+// children of a directory that errors are skipped. All errors are returned
+// together as WalkErrors sorted by path, so the result is the same regardless
+// of scheduling. If ctx is cancelled the walk stops & ctx.Err() is returned
+func WalkConcurrent(ctx context.Context, root File, workers int, visit func(f File) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+
+	w := &concurrentWalker{ctx: ctx, visit: visit}
+	w.cond = sync.NewCond(&w.lk)
+	if root.IsDirectory() {
+		w.queue = []File{root}
+	} else {
+		w.visitFile(root)
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			w.work()
+		}()
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(w.errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(w.errs, func(i, j int) bool { return w.errs[i].Path < w.errs[j].Path })
+	return w.errs
+}
+
+// concurrentWalker holds the shared state of a WalkConcurrent call
+type concurrentWalker struct {
+	ctx   context.Context
+	visit func(f File) error
+
+	lk     sync.Mutex
+	cond   *sync.Cond
+	queue  []File // directories waiting to be read
+	active int    // directories being read
+	errs   WalkErrors
+}
+
+// work reads queued directories until none are left & no directories are
+// being read, which could add more
+func (w *concurrentWalker) work() {
+	for {
+		w.lk.Lock()
+		for len(w.queue) == 0 && w.active > 0 {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.lk.Unlock()
+			return
+		}
+		dir := w.queue[len(w.queue)-1]
+		w.queue = w.queue[:len(w.queue)-1]
+		w.active++
+		w.lk.Unlock()
+
+		w.readDir(dir)
+
+		w.lk.Lock()
+		w.active--
+		if w.active == 0 && len(w.queue) == 0 {
+			// wake idle workers so they can exit
+			w.cond.Broadcast()
+		}
+		w.lk.Unlock()
+	}
+}
+
+// readDir visits dir, visiting file children & queuing directory children
+func (w *concurrentWalker) readDir(dir File) {
+	if w.ctx.Err() != nil {
+		return
+	}
+	if err := w.visit(dir); err != nil {
+		w.addErr(dir, err)
+		return
+	}
+
+	for {
+		if w.ctx.Err() != nil {
+			return
+		}
+		f, err := dir.NextFile()
+		if err == io.EOF {
+			return
+		} else if err != nil {
+			w.addErr(dir, err)
+			return
+		}
+
+		if f.IsDirectory() {
+			w.lk.Lock()
+			w.queue = append(w.queue, f)
+			w.lk.Unlock()
+			w.cond.Signal()
+			continue
+		}
+		w.visitFile(f)
+	}
+}
+
+func (w *concurrentWalker) visitFile(f File) {
+	if err := w.visit(f); err != nil {
+		w.addErr(f, err)
+	}
+}
+
+func (w *concurrentWalker) addErr(f File, err error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+	w.errs = append(w.errs, WalkError{Path: f.FullPath(), Err: err})
+}
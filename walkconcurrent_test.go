@@ -0,0 +1,86 @@
+package qfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWalkConcurrent(t *testing.T) {
+	newTree := func() File {
+		root := NewMemdir("/root")
+		for i := 0; i < 5; i++ {
+			dir := NewMemdir(fmt.Sprintf("/dir_%d", i))
+			for j := 0; j < 10; j++ {
+				dir.AddChildren(NewMemfileBytes(fmt.Sprintf("file_%d.txt", j), []byte("foo")))
+			}
+			root.AddChildren(dir)
+		}
+		return root
+	}
+
+	expect := []string{}
+	if err := Walk(newTree(), func(f File) error {
+		expect = append(expect, f.FullPath())
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(expect)
+
+	lk := sync.Mutex{}
+	got := []string{}
+	err := WalkConcurrent(context.Background(), newTree(), 4, func(f File) error {
+		lk.Lock()
+		defer lk.Unlock()
+		got = append(got, f.FullPath())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	if diff := cmp.Diff(expect, got); diff != "" {
+		t.Errorf("visited paths mismatch. (-want +got):\n%s", diff)
+	}
+
+	errFail := errors.New("fail")
+	err = WalkConcurrent(context.Background(), newTree(), 4, func(f File) error {
+		if f.FileName() == "file_3.txt" || f.FullPath() == "/root/dir_2" {
+			return errFail
+		}
+		return nil
+	})
+	walkErrs, ok := err.(WalkErrors)
+	if !ok {
+		t.Fatalf("expected WalkErrors. got: %T %v", err, err)
+	}
+	gotErrPaths := []string{}
+	for _, e := range walkErrs {
+		gotErrPaths = append(gotErrPaths, e.Path)
+		if !errors.Is(e, errFail) {
+			t.Errorf("expected error for %q to wrap visit error", e.Path)
+		}
+	}
+	expectErrPaths := []string{
+		"/root/dir_0/file_3.txt",
+		"/root/dir_1/file_3.txt",
+		"/root/dir_2",
+		"/root/dir_3/file_3.txt",
+		"/root/dir_4/file_3.txt",
+	}
+	if diff := cmp.Diff(expectErrPaths, gotErrPaths); diff != "" {
+		t.Errorf("error paths mismatch. (-want +got):\n%s", diff)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := WalkConcurrent(ctx, newTree(), 4, func(f File) error { return nil }); err != context.Canceled {
+		t.Errorf("expected cancelled walk to return context.Canceled. got: %v", err)
+	}
+}